@@ -22,6 +22,14 @@ func init() {
 
 // @host localhost:8080
 // @BasePath /
+
+// @securityDefinitions.apikey Bearer
+// @in header
+// @name Authorization
+
+// @securityDefinitions.apikey ApiKey
+// @in header
+// @name X-API-Key
 func main() {
 	flag.Parse()
 	config := apiserver.NewConfig()
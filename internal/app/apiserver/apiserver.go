@@ -4,15 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/paramonies/avito-rest-advert/internal/app/handler"
+	"github.com/paramonies/avito-rest-advert/internal/app/ratelimit"
 	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/search"
 	"github.com/paramonies/avito-rest-advert/internal/app/service"
+	"github.com/paramonies/avito-rest-advert/internal/app/storage"
 )
 
 func Start(config *Config) error {
@@ -22,14 +28,84 @@ func Start(config *Config) error {
 		return err
 	}
 
+	rules, err := service.NewContentRules(config.Screening)
+	if err != nil {
+		return err
+	}
+
+	if err := config.List.Validate(); err != nil {
+		return err
+	}
+
+	if err := config.Search.Validate(); err != nil {
+		return err
+	}
+
+	store, err := storage.NewBlobStore(config.Storage)
+	if err != nil {
+		return err
+	}
+
 	repo := repository.NewAdvertRepository(db)
-	service := service.NewAdvertService(repo)
-	handler := handler.NewHandler(service)
+	index, err := newSearchIndex(config.Search, db, repo)
+	if err != nil {
+		return err
+	}
+	suggester := search.NewSuggester(repo, config.Search.BatchSize)
+	started := time.Now()
+	if err := suggester.Rebuild(); err != nil {
+		return fmt.Errorf("search: failed to load the suggestions: %w", err)
+	}
+	log.Printf("Suggestions of %d names loaded in %s", suggester.Len(), time.Since(started).Round(time.Millisecond))
+	index = service.WithUpdaters(index, suggester)
+	moderationRepo := repository.NewModerationPostgres(db)
+	moderation := service.NewModerationService(moderationRepo, index, time.Duration(config.ModerationLeaseSec)*time.Second)
+	variants := service.NewVariantPool(repo, index, store, config.Storage.VariantQueueSize)
+	variants.Start(config.Storage.VariantWorkers)
+	uploader := service.NewUploadService(repo, index, store, variants, config.Storage.MaxUploadBytes)
+	links := service.NewLinkPolicy(config.PictureLinks, storageHosts(config.Storage)...)
+	var linkChecker *service.LinkChecker
+	if config.PictureLinks.CheckIntervalSec > 0 {
+		timeout := time.Duration(config.PictureLinks.CheckTimeoutSec) * time.Second
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		client := service.NewLinkCheckClient(timeout)
+		linkChecker = service.NewLinkChecker(repo, index, client, time.Duration(config.PictureLinks.CheckIntervalSec)*time.Second, config.PictureLinks.CheckBatchSize)
+		linkChecker.Start()
+	}
+	categoryRepo := repository.NewCategoryPostgres(db)
+	categories := service.NewCategoryService(categoryRepo)
+	userRepo := repository.NewUserPostgres(db)
+	users, err := service.NewUserService(userRepo, config.Auth)
+	if err != nil {
+		return err
+	}
+	if config.Auth.JWKS != "" {
+		log.Printf("Auth: %d token keys loaded, send SIGHUP to reload %s", users.Keys(), config.Auth.JWKS)
+		reloadKeysOnHangup(users)
+	}
+	apiKeys := service.NewAPIKeyService(repository.NewAPIKeyPostgres(db), userRepo)
+	service := service.NewAdvertService(repo, categoryRepo, index, suggester, store, links, config.List, rules...)
+	limits, err := ratelimit.NewStore(config.RateLimit)
+	if err != nil {
+		return err
+	}
+	limiter := ratelimit.NewLimiter(limits, config.RateLimit.Rules)
+	handler := handler.NewHandler(service, categories, moderation, uploader, users, apiKeys, limiter, config.AdminToken)
+
+	router, err := newRouter(handler, config.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	if _, ok := store.(*storage.FileStore); ok && config.Storage.ServePath != "" {
+		router.Static(config.Storage.ServePath, config.Storage.Dir)
+	}
 
 	srv := new(Server)
 
 	go func() {
-		if err := srv.Run("8080", handler.InitRoutes()); err != nil {
+		if err := srv.Run("8080", router); err != nil {
 			log.Fatalf("error occured while running http server: %s", err.Error())
 		}
 	}()
@@ -47,6 +123,11 @@ func Start(config *Config) error {
 		log.Fatalf("error occured on server shutting down: %s", err.Error())
 	}
 
+	variants.Stop()
+	if linkChecker != nil {
+		linkChecker.Stop()
+	}
+
 	if err := db.Close(); err != nil {
 		log.Fatalf("error occured on db connection close: %s", err.Error())
 	}
@@ -54,6 +135,61 @@ func Start(config *Config) error {
 	return nil
 }
 
+// newSearchIndex builds the index selected by config.Index, the Postgres
+// search is used when the index is not set. The in-memory index is loaded
+// from the adverts table before the server starts.
+func newSearchIndex(config search.Config, db *sqlx.DB, repo *repository.AdvertRepository) (service.SearchIndex, error) {
+	if config.Index != search.IndexMemory {
+		return repository.NewSearchPostgres(db), nil
+	}
+
+	index := search.NewMemoryIndex(repo, config.BatchSize)
+	started := time.Now()
+	if err := index.Rebuild(); err != nil {
+		return nil, fmt.Errorf("search: failed to build the index: %w", err)
+	}
+	log.Printf("Search index of %d adverts built in %s", index.Len(), time.Since(started).Round(time.Millisecond))
+	return index, nil
+}
+
+// reloadKeysOnHangup reloads the token keys on SIGHUP, so the keys of the
+// external issuer are rotated without a restart.
+func reloadKeysOnHangup(users *service.UserService) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			if err := users.ReloadKeys(); err != nil {
+				log.Printf("%s, the previous keys are kept", err.Error())
+				continue
+			}
+			log.Printf("Auth: %d token keys reloaded", users.Keys())
+		}
+	}()
+}
+
+// storageHosts returns the hosts the uploaded pictures are served from.
+func storageHosts(config storage.Config) []string {
+	var hosts []string
+	for _, link := range []string{config.BaseURL, config.S3PublicURL, config.S3Endpoint} {
+		if u, err := url.Parse(link); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
+}
+
+// newRouter routes the requests to the handler. The client address, which
+// the rate limits of the ip key are counted by, is taken from the forwarding
+// headers of the trusted proxies only, none are trusted by default.
+func newRouter(h *handler.Handler, trustedProxies []string) (*gin.Engine, error) {
+	router := h.InitRoutes()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("trusted_proxies: %w", err)
+	}
+	return router, nil
+}
+
 func newDB(config *Config) (*sqlx.DB, error) {
 	dbURL := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
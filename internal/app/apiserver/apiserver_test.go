@@ -0,0 +1,54 @@
+package apiserver
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/handler"
+	"github.com/paramonies/avito-rest-advert/internal/app/ratelimit"
+)
+
+func TestNewRouter_clientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rules := []ratelimit.Rule{{Route: "POST /auth/login", Key: ratelimit.KeyIP, Requests: 1, PeriodSec: 60}}
+
+	tests := []struct {
+		name               string
+		trustedProxies     []string
+		expectedStatusCode int
+	}{
+		// a client naming another address in every request is limited by
+		// its own one
+		{name: "No trusted proxies", expectedStatusCode: 429},
+		{name: "Client is not a proxy", trustedProxies: []string{"10.0.0.0/8"}, expectedStatusCode: 429},
+		// the proxy forwards the requests of two clients
+		{name: "Trusted proxy", trustedProxies: []string{"192.0.2.0/24"}, expectedStatusCode: 400},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules)
+			router, err := newRouter(handler.NewHandler(nil, nil, nil, nil, nil, nil, limiter, ""), test.trustedProxies)
+			assert.Equal(t, err, nil)
+
+			var w *httptest.ResponseRecorder
+			for _, forwardedFor := range []string{"203.0.113.1", "203.0.113.2"} {
+				w = httptest.NewRecorder()
+				req := httptest.NewRequest("POST", "/auth/login", bytes.NewBufferString(`{`))
+				req.RemoteAddr = "192.0.2.1:50000"
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+				router.ServeHTTP(w, req)
+			}
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+		})
+	}
+}
+
+func TestNewRouter_invalidProxy(t *testing.T) {
+	_, err := newRouter(handler.NewHandler(nil, nil, nil, nil, nil, nil, nil, ""), []string{"proxy.local"})
+	assert.NotEqual(t, err, nil)
+}
@@ -1,5 +1,12 @@
 package apiserver
 
+import (
+	"github.com/paramonies/avito-rest-advert/internal/app/ratelimit"
+	"github.com/paramonies/avito-rest-advert/internal/app/search"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+	"github.com/paramonies/avito-rest-advert/internal/app/storage"
+)
+
 type Config struct {
 	SrvHost    string `toml:"srv_host"`
 	SrvPort    string `toml:"srv_port"`
@@ -8,6 +15,22 @@ type Config struct {
 	DBUser     string `toml:"db_user"`
 	DBPassword string `toml:"db_password"`
 	DBName     string `toml:"db_name"`
+	AdminToken string `toml:"admin_token"`
+
+	// TrustedProxies are the addresses and networks of the reverse proxies
+	// allowed to name the client in X-Forwarded-For and X-Real-IP
+	TrustedProxies []string `toml:"trusted_proxies"`
+
+	ModerationLeaseSec int `toml:"moderation_lease_sec"`
+
+	Screening service.ScreeningConfig `toml:"screening"`
+	Storage   storage.Config          `toml:"storage"`
+
+	PictureLinks service.LinkConfig `toml:"picture_links"`
+	List         service.ListConfig `toml:"list"`
+	Search       search.Config      `toml:"search"`
+	Auth         service.AuthConfig `toml:"auth"`
+	RateLimit    ratelimit.Config   `toml:"rate_limit"`
 }
 
 func NewConfig() *Config {
@@ -0,0 +1,70 @@
+// Package apperror holds the error kinds shared by every layer. Repositories
+// and services wrap their errors into one of the kinds, and the handler maps
+// a kind to an HTTP status without knowing the concrete error.
+package apperror
+
+import "errors"
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrUnavailable  = errors.New("service unavailable")
+
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// Error is an error of a known kind. errors.Is matches it both against
+// itself and against its kind, errors.As reaches the wrapped cause.
+type Error struct {
+	kind    error
+	message string
+	cause   error
+}
+
+// New returns a sentinel error of the given kind.
+func New(kind error, message string) error {
+	return &Error{kind: kind, message: message}
+}
+
+// Wrap marks err as an error of the given kind keeping its message.
+func Wrap(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{kind: kind, cause: err}
+}
+
+func (e *Error) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return e.cause.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Opaque reports whether the message of err comes from a cause marked with
+// Wrap. Such a message is meant for the logs, not for the clients.
+func Opaque(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.message == ""
+}
+
+// Kind returns the kind of err or nil if err is not of a known kind.
+func Kind(err error) error {
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrValidation, ErrForbidden, ErrUnauthorized, ErrUnavailable, ErrTooManyRequests} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
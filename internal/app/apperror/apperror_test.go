@@ -0,0 +1,41 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_Is(t *testing.T) {
+	errMissing := New(ErrNotFound, "advertisement not found")
+	wrapped := fmt.Errorf("%w: id 1", errMissing)
+
+	assert.True(t, errors.Is(wrapped, errMissing))
+	assert.True(t, errors.Is(wrapped, ErrNotFound))
+	assert.False(t, errors.Is(wrapped, ErrConflict))
+	assert.Equal(t, "advertisement not found: id 1", wrapped.Error())
+}
+
+func TestError_Wrap(t *testing.T) {
+	err := Wrap(ErrUnavailable, io.ErrUnexpectedEOF)
+
+	assert.True(t, errors.Is(err, ErrUnavailable))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	assert.Equal(t, io.ErrUnexpectedEOF.Error(), err.Error())
+	assert.Nil(t, Wrap(ErrUnavailable, nil))
+}
+
+func TestError_Opaque(t *testing.T) {
+	assert.True(t, Opaque(fmt.Errorf("saving: %w", Wrap(ErrConflict, io.ErrUnexpectedEOF))))
+	assert.False(t, Opaque(New(ErrConflict, "already exists")))
+	assert.False(t, Opaque(io.ErrUnexpectedEOF))
+}
+
+func TestError_Kind(t *testing.T) {
+	assert.Equal(t, ErrConflict, Kind(fmt.Errorf("saving: %w", New(ErrConflict, "already exists"))))
+	assert.Equal(t, ErrValidation, Kind(ErrValidation))
+	assert.Nil(t, Kind(errors.New("something went wrong")))
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// jwk is a JSON Web Key (RFC 7517), the fields of the key types the service
+// accepts: "oct" (HS256), "RSA" (RS256) and "OKP" with the Ed25519 curve
+// (EdDSA, RFC 8037).
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+// ParseJWKS parses a JSON Web Key Set or a single key. The encryption keys
+// and the keys of other types and algorithms are skipped, a malformed key of
+// an accepted type is an error.
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+		jwk
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	if set.Keys == nil && set.KeyType != "" {
+		set.Keys = []jwk{set.jwk}
+	}
+
+	var keys []Key
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, ok, err := k.key()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (kid %q): %w", i, k.KeyId, err)
+		}
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// LoadJWKS loads the keys of the JWKS file at path or of every *.json file of
+// the directory at path. The kids of the keys have to be unique.
+func LoadJWKS(path string) ([]Key, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	var keys []Key
+	ids := make(map[string]string)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileKeys, err := ParseJWKS(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwks %s: %w", file, err)
+		}
+		for _, key := range fileKeys {
+			if key.Id == "" {
+				continue
+			}
+			if other, ok := ids[key.Id]; ok {
+				return nil, fmt.Errorf("jwks %s: kid %q is already used in %s", file, key.Id, other)
+			}
+			ids[key.Id] = file
+		}
+		keys = append(keys, fileKeys...)
+	}
+	return keys, nil
+}
+
+func (k jwk) key() (Key, bool, error) {
+	switch {
+	case k.KeyType == "oct" && (k.Algorithm == "" || k.Algorithm == AlgHS256):
+		secret, err := decodeKeyParam("k", k.K)
+		if err != nil {
+			return Key{}, false, err
+		}
+		return Key{Id: k.KeyId, Algorithm: AlgHS256, secret: secret}, true, nil
+
+	case k.KeyType == "RSA" && (k.Algorithm == "" || k.Algorithm == AlgRS256):
+		n, err := decodeKeyParam("n", k.N)
+		if err != nil {
+			return Key{}, false, err
+		}
+		e, err := decodeKeyParam("e", k.E)
+		if err != nil {
+			return Key{}, false, err
+		}
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if public.N.BitLen() < 2048 || len(e) > 4 || public.E < 3 || public.E%2 == 0 {
+			return Key{}, false, fmt.Errorf("RSA keys shorter than 2048 bits or with an invalid exponent are not accepted")
+		}
+		return NewRSAKey(k.KeyId, public), true, nil
+
+	case k.KeyType == "OKP" && k.Crv == "Ed25519" && (k.Algorithm == "" || k.Algorithm == AlgEdDSA):
+		x, err := decodeKeyParam("x", k.X)
+		if err != nil {
+			return Key{}, false, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return Key{}, false, fmt.Errorf("parameter \"x\" should be %d bytes long", ed25519.PublicKeySize)
+		}
+		return NewEd25519Key(k.KeyId, x), true, nil
+	}
+	return Key{}, false, nil
+}
+
+func decodeKeyParam(name, value string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("parameter %q is missing", name)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("parameter %q: %w", name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaJWK := fmt.Sprintf(`{"kty":"RSA","kid":"rsa-1","use":"sig","alg":"RS256","n":%q,"e":%q}`,
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()))
+	edJWK := fmt.Sprintf(`{"kty":"OKP","kid":"ed-1","crv":"Ed25519","x":%q}`, b64(edPublic))
+	data := `{"keys":[` + rsaJWK + `,` + edJWK + `,
+		{"kty":"oct","kid":"hs-1","k":"c2VjcmV0"},
+		{"kty":"EC","kid":"ec-1","crv":"P-256","x":"AA","y":"AA"},
+		{"kty":"RSA","kid":"enc-1","use":"enc","n":"AA","e":"AQAB"}
+	]}`
+
+	keys, err := ParseJWKS([]byte(data))
+	require.NoError(t, err)
+	require.Equal(t, 3, len(keys))
+	assert.Equal(t, []string{"rsa-1", "ed-1", "hs-1"}, []string{keys[0].Id, keys[1].Id, keys[2].Id})
+	assert.Equal(t, []string{AlgRS256, AlgEdDSA, AlgHS256}, []string{keys[0].Algorithm, keys[1].Algorithm, keys[2].Algorithm})
+
+	now := time.Unix(1625000000, 0)
+	claims := Claims{Subject: "7", ExpiresAt: now.Add(time.Hour).Unix()}
+	set := NewKeySet(keys...)
+	for _, token := range []string{
+		signToken(AlgRS256, "rsa-1", claims, signRS256(rsaKey)),
+		signToken(AlgEdDSA, "ed-1", claims, signEdDSA(edPrivate)),
+		NewHS256("secret").Sign(claims),
+	} {
+		_, err := set.Verify(token, now)
+		assert.NoError(t, err)
+	}
+
+	// a single key is a set of one key
+	keys, err = ParseJWKS([]byte(edJWK))
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(keys))
+
+	for _, data := range []string{
+		`{"keys":[{"kty":"OKP","kid":"ed-2","crv":"Ed25519","x":"AAAA"}]}`,
+		`{"keys":[{"kty":"RSA","kid":"rsa-2","n":"AQAB","e":"AQAB"}]}`,
+		`{"keys":[{"kty":"oct","kid":"hs-2"}]}`,
+		`{"keys":[{"kty":"oct","kid":"hs-2","k":"!!"}]}`,
+		`{"keys":`,
+	} {
+		_, err := ParseJWKS([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, data string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600))
+	}
+	write("2021-06.json", `{"kty":"oct","kid":"2021-06","k":"b2xk"}`)
+	write("2021-07.json", `{"keys":[{"kty":"oct","kid":"2021-07","k":"bmV3"}]}`)
+	write("readme.txt", `not a key`)
+
+	keys, err := LoadJWKS(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2021-06", "2021-07"}, []string{keys[0].Id, keys[1].Id})
+
+	keys, err = LoadJWKS(filepath.Join(dir, "2021-07.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(keys))
+
+	write("copy.json", `{"kty":"oct","kid":"2021-06","k":"b2xk"}`)
+	_, err = LoadJWKS(dir)
+	assert.Error(t, err)
+
+	_, err = LoadJWKS(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+// Package auth issues and verifies the JSON Web Tokens (RFC 7519) the users
+// of the API are authenticated with. The service signs its own tokens with
+// HS256, tokens of an external issuer are verified with the keys of its
+// JSON Web Key Set (RFC 7517).
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// Claims are the registered claims the service uses, Subject is the id of
+// the user. The times are seconds since the epoch.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Role      string   `json:"role,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// Audience is the aud claim, a single string or an array of them.
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// Contains reports whether the audience includes the recipient.
+func (a Audience) Contains(recipient string) bool {
+	for _, r := range a {
+		if r == recipient {
+			return true
+		}
+	}
+	return false
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyId     string `json:"kid,omitempty"`
+}
+
+// HS256 signs and verifies tokens with HMAC SHA-256.
+type HS256 struct {
+	secret []byte
+}
+
+// NewHS256 uses a random secret when the secret is empty, the tokens then
+// stop working after a restart and differ between instances.
+func NewHS256(secret string) HS256 {
+	if secret != "" {
+		return HS256{secret: []byte(secret)}
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		panic(err)
+	}
+	return HS256{secret: random}
+}
+
+func (k HS256) Sign(claims Claims) string {
+	head, _ := json.Marshal(header{Algorithm: AlgHS256, Type: "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := encodeSegment(head) + "." + encodeSegment(payload)
+	return signed + "." + encodeSegment(k.mac(signed))
+}
+
+// Verify checks the signature and the validity of the token at now and
+// returns its claims.
+func (k HS256) Verify(token string, now time.Time) (Claims, error) {
+	return NewKeySet(k.Key()).Verify(token, now)
+}
+
+// Key returns the key verifying the tokens signed by k.
+func (k HS256) Key() Key {
+	return Key{Algorithm: AlgHS256, secret: k.secret}
+}
+
+func (k HS256) mac(signed string) []byte {
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHS256(t *testing.T) {
+	key := NewHS256("secret")
+	now := time.Unix(1625000000, 0)
+	claims := Claims{Subject: "7", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}
+
+	token := key.Sign(claims)
+	assert.Equal(t, 3, len(strings.Split(token, ".")))
+
+	verified, err := key.Verify(token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, claims, verified)
+
+	_, err = key.Verify(token, now.Add(time.Hour))
+	assert.Equal(t, ErrExpiredToken, err)
+
+	// another secret, a changed payload, another algorithm and garbage are rejected
+	_, err = NewHS256("other").Verify(token, now)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	parts := strings.Split(token, ".")
+	forged := strings.Split(key.Sign(Claims{Subject: "8", ExpiresAt: claims.ExpiresAt}), ".")
+	_, err = key.Verify(parts[0]+"."+forged[1]+"."+parts[2], now)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	_, err = key.Verify(none+"."+parts[1]+".", now)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	for _, token := range []string{"", "abc", "a.b.c", "!!.!!.!!"} {
+		_, err = key.Verify(token, now)
+		assert.Equal(t, ErrInvalidToken, err)
+	}
+
+	// without a secret a random one is used
+	assert.NotEqual(t, NewHS256("").Sign(claims), NewHS256("").Sign(claims))
+}
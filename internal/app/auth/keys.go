@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The signature algorithms of RFC 7518 and RFC 8037 the service accepts.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key verifies the signatures of one algorithm. Id is the kid the tokens
+// signed with the key name it by, it may be empty for the only key of an
+// algorithm.
+type Key struct {
+	Id        string
+	Algorithm string
+
+	secret  []byte
+	rsa     *rsa.PublicKey
+	ed25519 ed25519.PublicKey
+}
+
+func NewRSAKey(id string, public *rsa.PublicKey) Key {
+	return Key{Id: id, Algorithm: AlgRS256, rsa: public}
+}
+
+func NewEd25519Key(id string, public ed25519.PublicKey) Key {
+	return Key{Id: id, Algorithm: AlgEdDSA, ed25519: public}
+}
+
+func (k Key) verify(signed string, signature []byte) bool {
+	switch k.Algorithm {
+	case AlgHS256:
+		return hmac.Equal(signature, HS256{secret: k.secret}.mac(signed))
+	case AlgRS256:
+		digest := sha256.Sum256([]byte(signed))
+		return rsa.VerifyPKCS1v15(k.rsa, crypto.SHA256, digest[:], signature) == nil
+	case AlgEdDSA:
+		return ed25519.Verify(k.ed25519, []byte(signed), signature)
+	}
+	return false
+}
+
+// KeySet verifies tokens with any of its keys, so tokens signed with the old
+// and the new key are both accepted while the keys are rotated. A token is
+// checked with the key named by its kid, a token without kid with the keys
+// of its algorithm. The algorithm of the token has to be the one of the key.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []Key
+}
+
+func NewKeySet(keys ...Key) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// Set replaces the keys of the set.
+func (s *KeySet) Set(keys []Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *KeySet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// Verify checks the signature and the validity of the token at now and
+// returns its claims. A token without the exp claim is invalid, it would
+// never expire.
+func (s *KeySet) Verify(token string, now time.Time) (Claims, error) {
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, ErrInvalidToken
+	}
+	var head header
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return claims, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !s.verify(head, parts[0]+"."+parts[1], signature) {
+		return claims, ErrInvalidToken
+	}
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return claims, ErrInvalidToken
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return claims, ErrInvalidToken
+	}
+	if claims.ExpiresAt == 0 {
+		return claims, ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return claims, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+func (s *KeySet) verify(head header, signed string, signature []byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Algorithm != head.Algorithm || (head.KeyId != "" && key.Id != head.KeyId) {
+			continue
+		}
+		if key.verify(signed, signature) {
+			return true
+		}
+	}
+	return false
+}
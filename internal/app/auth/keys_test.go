@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signToken signs the claims the way an external issuer does.
+func signToken(alg, kid string, claims Claims, sign func(signed []byte) []byte) string {
+	payload, _ := json.Marshal(claims)
+	return signPayload(alg, kid, payload, sign)
+}
+
+func signPayload(alg, kid string, payload []byte, sign func(signed []byte) []byte) string {
+	head, _ := json.Marshal(header{Algorithm: alg, Type: "JWT", KeyId: kid})
+	signed := encodeSegment(head) + "." + encodeSegment(payload)
+	return signed + "." + encodeSegment(sign([]byte(signed)))
+}
+
+func signRS256(private *rsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest[:])
+		if err != nil {
+			panic(err)
+		}
+		return signature
+	}
+}
+
+func signEdDSA(private ed25519.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		return ed25519.Sign(private, signed)
+	}
+}
+
+func TestKeySet_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	local := NewHS256("secret")
+
+	now := time.Unix(1625000000, 0)
+	claims := Claims{Subject: "7", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}
+	keys := NewKeySet(local.Key(), NewRSAKey("rsa-1", &rsaKey.PublicKey), NewEd25519Key("ed-1", edPublic))
+
+	tests := []struct {
+		name          string
+		token         string
+		expectedError error
+	}{
+		{name: "HS256", token: local.Sign(claims)},
+		{name: "RS256", token: signToken(AlgRS256, "rsa-1", claims, signRS256(rsaKey))},
+		{name: "EdDSA", token: signToken(AlgEdDSA, "ed-1", claims, signEdDSA(edPrivate))},
+		{name: "Without kid", token: signToken(AlgEdDSA, "", claims, signEdDSA(edPrivate))},
+		{
+			name:          "Unknown kid",
+			token:         signToken(AlgEdDSA, "ed-2", claims, signEdDSA(edPrivate)),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Other key",
+			token:         signToken(AlgEdDSA, "ed-1", claims, signEdDSA(otherPrivate)),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			// the kid names the RSA key, the algorithm has to be the key's one
+			name:          "Algorithm of another key",
+			token:         signToken(AlgEdDSA, "rsa-1", claims, signEdDSA(edPrivate)),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Not yet valid",
+			token:         signToken(AlgEdDSA, "ed-1", Claims{Subject: "7", NotBefore: now.Add(time.Minute).Unix(), ExpiresAt: claims.ExpiresAt}, signEdDSA(edPrivate)),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Without expiration",
+			token:         signPayload(AlgEdDSA, "ed-1", []byte(`{"sub":"7","iat":1625000000}`), signEdDSA(edPrivate)),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Zero expiration",
+			token:         local.Sign(Claims{Subject: "7", ExpiresAt: 0}),
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:          "Expired",
+			token:         signToken(AlgRS256, "rsa-1", Claims{Subject: "7", ExpiresAt: now.Unix()}, signRS256(rsaKey)),
+			expectedError: ErrExpiredToken,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verified, err := keys.Verify(test.token, now)
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError == nil {
+				assert.Equal(t, claims, verified)
+			}
+		})
+	}
+
+	// tokens of a removed key are rejected
+	keys.Set([]Key{local.Key()})
+	_, err = keys.Verify(signToken(AlgEdDSA, "ed-1", claims, signEdDSA(edPrivate)), now)
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestAudience(t *testing.T) {
+	var claims Claims
+	require.NoError(t, json.Unmarshal([]byte(`{"sub":"7","aud":"adverts"}`), &claims))
+	assert.Equal(t, Audience{"adverts"}, claims.Audience)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"sub":"7","aud":["billing","adverts"]}`), &claims))
+	assert.True(t, claims.Audience.Contains("adverts"))
+	assert.False(t, claims.Audience.Contains("search"))
+
+	data, _ := json.Marshal(Claims{Subject: "7", Audience: Audience{"adverts"}})
+	assert.Equal(t, `{"sub":"7","aud":"adverts","iat":0,"exp":0}`, string(data))
+}
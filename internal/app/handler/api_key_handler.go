@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+type apiKeyInput struct {
+	Name       string       `json:"name"`
+	OwnerId    *int         `json:"owner-id"`
+	Scopes     model.Scopes `json:"scopes"`
+	DailyQuota int          `json:"daily-quota"`
+}
+
+// @Summary выпустить API ключ
+// @Tags Admin
+// @Description Выпустить ключ для заголовка X-API-Key. Ключ возвращается только в этом ответе, хранится его хеш.
+// @Description Ключу со scope adverts:write нужен владелец: объявления, созданные с ключом, принадлежат ему.
+// @Description daily-quota - число запросов в сутки (UTC), 0 - без ограничения. Требуется право api_keys:manage
+// @ID issue-api-key
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param input body InputAPIKey true "API key"
+// @Success 201 {object} APIKeyIssuedOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 422 {object} ValidationMessage422
+// @Failure 500 {object} AdvertMessage500
+// @Router /api-keys [post]
+func (h *Handler) issueAPIKey(ctx *gin.Context) {
+	var input apiKeyInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+
+	key, err := h.apiKeys.IssueAPIKey(model.APIKey{
+		Name:       input.Name,
+		OwnerId:    input.OwnerId,
+		Scopes:     input.Scopes,
+		DailyQuota: input.DailyQuota,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// @Summary список API ключей
+// @Tags Admin
+// @Description Все ключи, включая отозванные, с числом запросов за текущие сутки (UTC) и временем последнего запроса.
+// @Description Требуется право api_keys:manage
+// @ID get-api-keys
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Success 200 {array} APIKeyOk
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 500 {object} AdvertMessage500
+// @Router /api-keys [get]
+func (h *Handler) getAPIKeys(ctx *gin.Context) {
+	keys, err := h.apiKeys.GetAPIKeys()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// @Summary отозвать API ключ
+// @Tags Admin
+// @Description Отозвать ключ, запросы с ним перестают приниматься. Требуется право api_keys:manage
+// @ID revoke-api-key
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param id path int true "API key ID"
+// @Success 200 {object} StatusMessageOk
+// @Failure 400 {object} APIKeyMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} APIKeyMessage404
+// @Failure 500 {object} AdvertMessage500
+// @Router /api-keys/{id} [delete]
+func (h *Handler) revokeAPIKey(ctx *gin.Context) {
+	keyId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		SendErrorResponse(ctx, http.StatusBadRequest, "api key id must be integer")
+		return
+	}
+
+	if err := h.apiKeys.RevokeAPIKey(keyId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, statusMessage{"ok"})
+}
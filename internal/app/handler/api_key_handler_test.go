@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+func TestHandler_apiKeys(t *testing.T) {
+	type mockBehaviorType func(k *mock.MockAPIKeys)
+	ownerId := 7
+
+	tests := []struct {
+		name                 string
+		inputMethod          string
+		inputPath            string
+		inputBody            string
+		inputAdminToken      string
+		inputAPIKey          string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:            "Issue",
+			inputMethod:     "POST",
+			inputPath:       "/api-keys",
+			inputBody:       `{"name":"feed","owner-id":7,"scopes":["adverts:read","adverts:write"],"daily-quota":1000}`,
+			inputAdminToken: "secret",
+			mockBehavior: func(k *mock.MockAPIKeys) {
+				k.EXPECT().IssueAPIKey(model.APIKey{Name: "feed", OwnerId: &ownerId,
+					Scopes: model.Scopes{model.ScopeAdvertsRead, model.ScopeAdvertsWrite}, DailyQuota: 1000}).
+					Return(model.APIKey{Id: 3, Name: "feed", Prefix: "ak_abcdefgh", Key: "ak_abcdefghijk", KeyHash: "hash", OwnerId: &ownerId,
+						Scopes: model.Scopes{model.ScopeAdvertsRead, model.ScopeAdvertsWrite}, DailyQuota: 1000}, nil)
+			},
+			expectedStatusCode:   201,
+			expectedResponseBody: `{"id":3,"name":"feed","prefix":"ak_abcdefgh","key":"ak_abcdefghijk","owner-id":7,"scopes":["adverts:read","adverts:write"],"daily-quota":1000,"used-today":0}`,
+		},
+		{
+			name:            "Issue without owner",
+			inputMethod:     "POST",
+			inputPath:       "/api-keys",
+			inputBody:       `{"name":"feed","scopes":["adverts:write"]}`,
+			inputAdminToken: "secret",
+			mockBehavior: func(k *mock.MockAPIKeys) {
+				errs := &service.ValidationError{}
+				errs.Add("owner-id", service.CodeRequired, `the field "owner-id" is required for the scope "adverts:write"`, nil)
+				k.EXPECT().IssueAPIKey(gomock.Any()).Return(model.APIKey{}, errs)
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"owner-id","code":"required","message":"the field \"owner-id\" is required for the scope \"adverts:write\""}]}`,
+		},
+		{
+			name:            "List",
+			inputMethod:     "GET",
+			inputPath:       "/api-keys",
+			inputAdminToken: "secret",
+			mockBehavior: func(k *mock.MockAPIKeys) {
+				k.EXPECT().GetAPIKeys().Return([]model.APIKey{{Id: 3, Name: "feed", Prefix: "ak_abcdefgh", KeyHash: "hash",
+					Scopes: model.Scopes{model.ScopeAdmin}, UsedToday: 12}}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"id":3,"name":"feed","prefix":"ak_abcdefgh","scopes":["admin"],"daily-quota":0,"used-today":12}]`,
+		},
+		{
+			name:        "List with admin key",
+			inputMethod: "GET",
+			inputPath:   "/api-keys",
+			inputAPIKey: "ak_admin",
+			mockBehavior: func(k *mock.MockAPIKeys) {
+				k.EXPECT().AuthenticateAPIKey("ak_admin").Return(model.Principal{Role: model.RoleAdmin, KeyId: 4, Scopes: model.Scopes{model.ScopeAdmin}}, nil)
+				k.EXPECT().GetAPIKeys().Return([]model.APIKey{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:        "List with feed key",
+			inputMethod: "GET",
+			inputPath:   "/api-keys",
+			inputAPIKey: "ak_feed",
+			mockBehavior: func(k *mock.MockAPIKeys) {
+				k.EXPECT().AuthenticateAPIKey("ak_feed").Return(model.Principal{UserId: 7, Role: model.RoleSeller, KeyId: 3, Scopes: model.Scopes{model.ScopeAdvertsWrite}}, nil)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"permission \"api_keys:manage\" is required","permission":"api_keys:manage"}`,
+		},
+		{
+			name:            "Revoke",
+			inputMethod:     "DELETE",
+			inputPath:       "/api-keys/3",
+			inputAdminToken: "secret",
+			mockBehavior: func(k *mock.MockAPIKeys) {
+				k.EXPECT().RevokeAPIKey(3).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:            "Revoke revoked",
+			inputMethod:     "DELETE",
+			inputPath:       "/api-keys/3",
+			inputAdminToken: "secret",
+			mockBehavior: func(k *mock.MockAPIKeys) {
+				k.EXPECT().RevokeAPIKey(3).Return(repository.ErrAPIKeyNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"api key not found"}`,
+		},
+		{
+			name:                 "Revoke wrong id",
+			inputMethod:          "DELETE",
+			inputPath:            "/api-keys/abc",
+			inputAdminToken:      "secret",
+			mockBehavior:         func(k *mock.MockAPIKeys) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"api key id must be integer"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockAPIKeys := mock.NewMockAPIKeys(c)
+			test.mockBehavior(mockAPIKeys)
+
+			handler := NewHandler(nil, nil, nil, nil, nil, mockAPIKeys, nil, "secret")
+			router := gin.New()
+			router.Use(handleErrors, handler.authenticate)
+			apiKeys := router.Group("/api-keys", handler.permit(service.PermManageAPIKeys))
+			apiKeys.POST("", handler.issueAPIKey)
+			apiKeys.GET("", handler.getAPIKeys)
+			apiKeys.DELETE("/:id", handler.revokeAPIKey)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.inputMethod, test.inputPath, bytes.NewBufferString(test.inputBody))
+			req.Header.Set("X-Admin-Token", test.inputAdminToken)
+			req.Header.Set("X-API-Key", test.inputAPIKey)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+const (
+	// principalKey is the key of the model.Principal in the gin context
+	principalKey = "principal"
+	// authErrorKey is the key of the error of an invalid API key or token
+	authErrorKey = "auth_error"
+)
+
+type credentialsInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type roleInput struct {
+	Role string `json:"role"`
+}
+
+// @Summary зарегистрироваться
+// @Tags Auth
+// @Description Создать пользователя. Пароль от 8 до 72 байт, email хранится в нижнем регистре.
+// @Description Роль buyer или seller, по умолчанию seller
+// @ID register
+// @Accept  json
+// @Produce  json
+// @Param input body InputRegistration true "Email, password and role"
+// @Success 201 {object} UserOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 409 {object} UserMessage409
+// @Failure 422 {object} ValidationMessage422
+// @Failure 500 {object} AdvertMessage500
+// @Router /auth/register [post]
+func (h *Handler) register(ctx *gin.Context) {
+	var input credentialsInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+
+	user, err := h.users.Register(model.Credentials{Email: input.Email, Password: input.Password}, model.Role(input.Role))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, user)
+}
+
+// @Summary войти
+// @Tags Auth
+// @Description Получить токен для заголовка Authorization: Bearer
+// @ID login
+// @Accept  json
+// @Produce  json
+// @Param input body InputCredentials true "Email and password"
+// @Success 200 {object} TokenOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} LoginMessage401
+// @Failure 500 {object} AdvertMessage500
+// @Router /auth/login [post]
+func (h *Handler) login(ctx *gin.Context) {
+	var input credentialsInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+
+	token, err := h.users.Login(model.Credentials{Email: input.Email, Password: input.Password})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, token)
+}
+
+// @Summary мои объявления
+// @Tags Auth
+// @Description Объявления пользователя в любом статусе, включая архивные, сначала новые
+// @ID get-own-adverts
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Success 200 {array} OwnAdvertOk
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /me/adverts [get]
+func (h *Handler) getOwnAdverts(ctx *gin.Context) {
+	adverts, err := h.service.GetOwnAdverts(principal(ctx))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adverts)
+}
+
+// @Summary назначить роль
+// @Tags Admin
+// @Description Назначить пользователю роль. Новая роль действует в токенах, выданных после изменения. Требуется право users:manage
+// @ID set-user-role
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param id path int true "User ID"
+// @Param input body InputRole true "Role"
+// @Success 200 {object} UserOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} UserMessage404
+// @Failure 422 {object} ValidationMessage422
+// @Failure 500 {object} AdvertMessage500
+// @Router /users/{id}/role [put]
+func (h *Handler) setUserRole(ctx *gin.Context) {
+	userId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		SendErrorResponse(ctx, http.StatusBadRequest, "user id must be integer")
+		return
+	}
+	var input roleInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+
+	user, err := h.users.SetRole(userId, model.Role(input.Role))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// authenticate puts the principal of the request into the context: the
+// owner of the API key or the user of the bearer token, the admin role is
+// given by the admin token. A request without them goes on anonymously. An
+// invalid key or token is refused by requireAuth only, so a stale one does
+// not break the public reads; a key over its daily quota is refused at once.
+func (h *Handler) authenticate(ctx *gin.Context) {
+	var user model.Principal
+	var err error
+	if key := ctx.GetHeader("X-API-Key"); key != "" {
+		user, err = h.apiKeys.AuthenticateAPIKey(key)
+	} else if token, ok := bearerToken(ctx); ok {
+		user, err = h.users.Authenticate(token)
+	}
+	if errors.Is(err, apperror.ErrTooManyRequests) {
+		ctx.Error(err)
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		ctx.Set(authErrorKey, err)
+	}
+	if h.isAdmin(ctx) {
+		user.Role = model.RoleAdmin
+	}
+
+	ctx.Set(principalKey, user)
+	ctx.Next()
+}
+
+// requireAuth answers the requests with an invalid key or token or anonymous
+// ones with 401, it goes after authenticate.
+func (h *Handler) requireAuth(ctx *gin.Context) {
+	if value, ok := ctx.Get(authErrorKey); ok {
+		err := value.(error)
+		if errors.Is(err, service.ErrInvalidToken) {
+			ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		}
+		ctx.Error(err)
+		ctx.Abort()
+		return
+	}
+	if principal(ctx).Anonymous() {
+		ctx.Header("WWW-Authenticate", "Bearer")
+		ctx.Error(service.ErrAuthRequired)
+		ctx.Abort()
+		return
+	}
+	ctx.Next()
+}
+
+// permit guards the endpoints the service layer does not check, the
+// request is answered with 403 naming the permission the caller lacks. An
+// anonymous caller with an invalid key or token is answered as by
+// requireAuth.
+func (h *Handler) permit(permission service.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		err := service.Authorize(principal(ctx), permission)
+		if errors.Is(err, service.ErrAuthRequired) {
+			h.requireAuth(ctx)
+			return
+		}
+		if err != nil {
+			ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// principal returns the caller put into the context by authenticate.
+func principal(ctx *gin.Context) model.Principal {
+	if value, ok := ctx.Get(principalKey); ok {
+		return value.(model.Principal)
+	}
+	return model.Principal{}
+}
+
+func bearerToken(ctx *gin.Context) (string, bool) {
+	header := ctx.GetHeader("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[len("Bearer "):])
+	return token, token != ""
+}
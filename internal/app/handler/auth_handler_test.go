@@ -0,0 +1,417 @@
+package handler
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+// owner is the signed in seller of the handler tests.
+var owner = model.Principal{UserId: 7, Role: model.RoleSeller}
+
+// signedIn stands for authenticate in the tests of the handlers behind it.
+func signedIn(principal model.Principal) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(principalKey, principal)
+		ctx.Next()
+	}
+}
+
+func TestHandler_register(t *testing.T) {
+	type mockBehaviorType func(u *mock.MockUsers)
+
+	tests := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Ok",
+			inputBody: `{"email":"Ivan@example.com","password":"correct horse","role":"buyer"}`,
+			mockBehavior: func(u *mock.MockUsers) {
+				u.EXPECT().Register(model.Credentials{Email: "Ivan@example.com", Password: "correct horse"}, model.RoleBuyer).
+					Return(model.User{Id: 7, Email: "ivan@example.com", Role: model.RoleBuyer, PasswordHash: "hash"}, nil)
+			},
+			expectedStatusCode:   201,
+			expectedResponseBody: `{"id":7,"email":"ivan@example.com","role":"buyer"}`,
+		},
+		{
+			name:      "Taken email",
+			inputBody: `{"email":"ivan@example.com","password":"correct horse"}`,
+			mockBehavior: func(u *mock.MockUsers) {
+				u.EXPECT().Register(gomock.Any(), model.Role("")).Return(model.User{}, repository.ErrUserExists)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"type":"about:blank","title":"Conflict","status":409,"detail":"a user with this email already exists"}`,
+		},
+		{
+			name:      "Short password",
+			inputBody: `{"email":"ivan@example.com","password":"horse"}`,
+			mockBehavior: func(u *mock.MockUsers) {
+				errs := &service.ValidationError{}
+				errs.Add("password", service.CodeMinLength, `length of the field "password" should be at least 8`, nil)
+				u.EXPECT().Register(gomock.Any(), gomock.Any()).Return(model.User{}, errs)
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"password","code":"min_length","message":"length of the field \"password\" should be at least 8"}]}`,
+		},
+		{
+			name:                 "Malformed body",
+			inputBody:            `{"email":`,
+			mockBehavior:         func(u *mock.MockUsers) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"validation failed","errors":[{"field":"","code":"malformed","message":"invalid input body"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockUsers := mock.NewMockUsers(c)
+			test.mockBehavior(mockUsers)
+
+			handler := NewHandler(nil, nil, nil, nil, mockUsers, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/auth/register", handler.register)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/auth/register", bytes.NewBufferString(test.inputBody))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_login(t *testing.T) {
+	type mockBehaviorType func(u *mock.MockUsers)
+	expiresAt := time.Date(2021, 7, 1, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Ok",
+			inputBody: `{"email":"ivan@example.com","password":"correct horse"}`,
+			mockBehavior: func(u *mock.MockUsers) {
+				u.EXPECT().Login(model.Credentials{Email: "ivan@example.com", Password: "correct horse"}).
+					Return(model.Token{Token: "a.b.c", TokenType: "Bearer", ExpiresAt: expiresAt}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"token":"a.b.c","token-type":"Bearer","expires-at":"2021-07-01T13:00:00Z"}`,
+		},
+		{
+			name:      "Wrong password",
+			inputBody: `{"email":"ivan@example.com","password":"wrong horse"}`,
+			mockBehavior: func(u *mock.MockUsers) {
+				u.EXPECT().Login(gomock.Any()).Return(model.Token{}, service.ErrInvalidCredentials)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"invalid email or password"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockUsers := mock.NewMockUsers(c)
+			test.mockBehavior(mockUsers)
+
+			handler := NewHandler(nil, nil, nil, nil, mockUsers, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/auth/login", handler.login)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/auth/login", bytes.NewBufferString(test.inputBody))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_setUserRole(t *testing.T) {
+	type mockBehaviorType func(u *mock.MockUsers)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		inputBody            string
+		inputPrincipal       model.Principal
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:           "Ok",
+			inputURL:       "/users/9/role",
+			inputBody:      `{"role":"moderator"}`,
+			inputPrincipal: model.Principal{UserId: 1, Role: model.RoleAdmin},
+			mockBehavior: func(u *mock.MockUsers) {
+				u.EXPECT().SetRole(9, model.RoleModerator).Return(model.User{Id: 9, Email: "ivan@example.com", Role: model.RoleModerator}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":9,"email":"ivan@example.com","role":"moderator"}`,
+		},
+		{
+			name:           "Unknown user",
+			inputURL:       "/users/9/role",
+			inputBody:      `{"role":"moderator"}`,
+			inputPrincipal: model.Principal{UserId: 1, Role: model.RoleAdmin},
+			mockBehavior: func(u *mock.MockUsers) {
+				u.EXPECT().SetRole(9, model.RoleModerator).Return(model.User{}, repository.ErrUserNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"user not found"}`,
+		},
+		{
+			name:                 "Wrong id",
+			inputURL:             "/users/abc/role",
+			inputBody:            `{"role":"moderator"}`,
+			inputPrincipal:       model.Principal{UserId: 1, Role: model.RoleAdmin},
+			mockBehavior:         func(u *mock.MockUsers) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"user id must be integer"}`,
+		},
+		{
+			name:                 "Moderator",
+			inputURL:             "/users/9/role",
+			inputBody:            `{"role":"admin"}`,
+			inputPrincipal:       model.Principal{UserId: 9, Role: model.RoleModerator},
+			mockBehavior:         func(u *mock.MockUsers) {},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"permission \"users:manage\" is required","permission":"users:manage"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockUsers := mock.NewMockUsers(c)
+			test.mockBehavior(mockUsers)
+
+			handler := NewHandler(nil, nil, nil, nil, mockUsers, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors, signedIn(test.inputPrincipal))
+			router.PUT("/users/:id/role", handler.permit(service.PermManageUsers), handler.setUserRole)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", test.inputURL, bytes.NewBufferString(test.inputBody))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_authenticate(t *testing.T) {
+	type mockBehaviorType func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService)
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		inputPath            string
+		inputAuthorization   string
+		inputAPIKey          string
+		inputAdminToken      string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedChallenge    string
+		expectedResponseBody string
+	}{
+		{
+			name:               "User",
+			inputAuthorization: "Bearer user-token",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				u.EXPECT().Authenticate("user-token").Return(owner, nil)
+				s.EXPECT().GetOwnAdverts(owner).Return([]model.Advert{
+					{Id: 1, Name: "Велосипед", Price: 5000, CategoryId: 3, Status: model.StatusDraft, CreatedAt: &createdAt},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"id":1,"name":"Велосипед","price":5000,"category-id":3,"status":"draft","created-at":"2021-07-01T12:00:00Z"}]`,
+		},
+		{
+			name:               "Lower case scheme",
+			inputAuthorization: "bearer user-token",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				u.EXPECT().Authenticate("user-token").Return(owner, nil)
+				s.EXPECT().GetOwnAdverts(owner).Return([]model.Advert{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:               "User and admin",
+			inputAuthorization: "Bearer user-token",
+			inputAdminToken:    "secret",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				u.EXPECT().Authenticate("user-token").Return(owner, nil)
+				s.EXPECT().GetOwnAdverts(model.Principal{UserId: 7, Role: model.RoleAdmin}).Return([]model.Advert{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:            "Admin",
+			inputAdminToken: "secret",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				s.EXPECT().GetOwnAdverts(model.Principal{Role: model.RoleAdmin}).Return(nil, service.ErrAuthRequired)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authentication required"}`,
+		},
+		{
+			name:                 "No token",
+			mockBehavior:         func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {},
+			expectedStatusCode:   401,
+			expectedChallenge:    "Bearer",
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authentication required"}`,
+		},
+		{
+			name:                 "Other scheme",
+			inputAuthorization:   "Basic aXZhbjpob3JzZQ==",
+			mockBehavior:         func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {},
+			expectedStatusCode:   401,
+			expectedChallenge:    "Bearer",
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authentication required"}`,
+		},
+		{
+			name:               "Invalid token",
+			inputAuthorization: "Bearer expired-token",
+			inputAdminToken:    "secret",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				u.EXPECT().Authenticate("expired-token").Return(model.Principal{}, service.ErrInvalidToken)
+			},
+			expectedStatusCode:   401,
+			expectedChallenge:    `Bearer error="invalid_token"`,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"invalid or expired token"}`,
+		},
+		{
+			name:                 "Public read",
+			inputPath:            "/list",
+			mockBehavior:         func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"user-id":0}`,
+		},
+		{
+			name:               "Public read with user",
+			inputPath:          "/list",
+			inputAuthorization: "Bearer user-token",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				u.EXPECT().Authenticate("user-token").Return(owner, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"user-id":7}`,
+		},
+		{
+			name:               "Public read with invalid token",
+			inputPath:          "/list",
+			inputAuthorization: "Bearer expired-token",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				u.EXPECT().Authenticate("expired-token").Return(model.Principal{}, service.ErrInvalidToken)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"user-id":0}`,
+		},
+		{
+			name:        "API key",
+			inputAPIKey: "ak_feed",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				key := model.Principal{UserId: 7, Role: model.RoleSeller, KeyId: 3, Scopes: model.Scopes{model.ScopeAdvertsRead}}
+				k.EXPECT().AuthenticateAPIKey("ak_feed").Return(key, nil)
+				s.EXPECT().GetOwnAdverts(key).Return([]model.Advert{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:        "API key without scope",
+			inputAPIKey: "ak_feed",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				key := model.Principal{UserId: 7, Role: model.RoleSeller, KeyId: 3, Scopes: model.Scopes{model.ScopeAdvertsWrite}}
+				k.EXPECT().AuthenticateAPIKey("ak_feed").Return(key, nil)
+				s.EXPECT().GetOwnAdverts(key).Return(nil, &service.PermissionError{Permission: service.PermReadOwnAdverts})
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"permission \"adverts:read_own\" is required","permission":"adverts:read_own"}`,
+		},
+		{
+			name:        "Revoked API key",
+			inputAPIKey: "ak_revoked",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				k.EXPECT().AuthenticateAPIKey("ak_revoked").Return(model.Principal{}, service.ErrInvalidAPIKey)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"invalid or revoked api key"}`,
+		},
+		{
+			name:        "Public read over quota",
+			inputPath:   "/list",
+			inputAPIKey: "ak_feed",
+			mockBehavior: func(u *mock.MockUsers, k *mock.MockAPIKeys, s *mock.MockService) {
+				k.EXPECT().AuthenticateAPIKey("ak_feed").Return(model.Principal{}, service.ErrQuotaExceeded)
+			},
+			expectedStatusCode:   429,
+			expectedResponseBody: `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"daily quota of the api key is exceeded"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockUsers := mock.NewMockUsers(c)
+			mockAPIKeys := mock.NewMockAPIKeys(c)
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockUsers, mockAPIKeys, mockService)
+
+			handler := NewHandler(mockService, nil, nil, nil, mockUsers, mockAPIKeys, nil, "secret")
+			router := gin.New()
+			router.Use(handleErrors, handler.authenticate)
+			router.GET("/me/adverts", handler.requireAuth, handler.getOwnAdverts)
+			router.GET("/list", func(ctx *gin.Context) { ctx.JSON(200, gin.H{"user-id": principal(ctx).UserId}) })
+
+			if test.inputPath == "" {
+				test.inputPath = "/me/adverts"
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.inputPath, nil)
+			req.Header.Set("Authorization", test.inputAuthorization)
+			req.Header.Set("X-API-Key", test.inputAPIKey)
+			req.Header.Set("X-Admin-Token", test.inputAdminToken)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Header().Get("WWW-Authenticate"), test.expectedChallenge)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+type categoryInput struct {
+	ParentId   *int                  `json:"parent-id"`
+	Name       string                `json:"name" binding:"required"`
+	Attributes model.AttributeSchema `json:"attributes"`
+}
+
+// @Summary дерево категорий
+// @Tags Category
+// @Description Получить корневые категории с подкатегориями любой глубины, подкатегории упорядочены по названию
+// @ID get-categories
+// @Accept  html
+// @Produce  json
+// @Success 200 {array} CategoryOk
+// @Failure 500 {object} AdvertMessage500
+// @Router /categories [get]
+func (h *Handler) getCategories(ctx *gin.Context) {
+	categories, err := h.categories.GetCategoryTree()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, categories)
+}
+
+// @Summary получить категорию
+// @Tags Category
+// @Description Получить категорию по id с ее подкатегориями
+// @ID get-category
+// @Accept  html
+// @Produce  json
+// @Param id path int true "Category ID"
+// @Success 200 {object} CategoryOk
+// @Failure 400 {object} CategoryMessage400
+// @Failure 404 {object} CategoryMessage404
+// @Failure 500 {object} AdvertMessage500
+// @Router /categories/{id} [get]
+func (h *Handler) getCategory(ctx *gin.Context) {
+	categoryId, ok := parseCategoryId(ctx)
+	if !ok {
+		return
+	}
+
+	category, err := h.categories.GetCategory(categoryId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+}
+
+// @Summary создать категорию
+// @Tags Admin
+// @Description Создать категорию, без parent-id категория создается корневой. В attributes задается схема атрибутов
+// @Description объявлений категории. Требуется право categories:manage
+// @ID create-category
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param input body InputCategory true "Category"
+// @Success 200 {object} CreateCategoryMessageOk
+// @Failure 400 {object} CategoryMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 409 {object} CategoryMessage409
+// @Failure 422 {object} ValidationMessage422
+// @Failure 500 {object} AdvertMessage500
+// @Router /categories [post]
+func (h *Handler) createCategory(ctx *gin.Context) {
+	var input categoryInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+
+	id, err := h.categories.CreateCategory(model.Category{ParentId: input.ParentId, Name: input.Name, Attributes: input.Attributes})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]interface{}{"id": id})
+}
+
+// @Summary изменить категорию
+// @Tags Admin
+// @Description Переименовать категорию или перенести ее к другому родителю, без parent-id категория становится корневой.
+// @Description Категорию нельзя перенести в ее собственное поддерево. Схема атрибутов заменяется целиком, объявления
+// @Description проверяются по новой схеме при следующем изменении. Требуется право categories:manage
+// @ID update-category
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param id path int true "Category ID"
+// @Param input body InputCategory true "Category"
+// @Success 200 {object} StatusMessageOk
+// @Failure 400 {object} CategoryMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} CategoryMessage404
+// @Failure 409 {object} CategoryMessage409
+// @Failure 422 {object} ValidationMessage422
+// @Failure 500 {object} AdvertMessage500
+// @Router /categories/{id} [put]
+func (h *Handler) updateCategory(ctx *gin.Context) {
+	categoryId, ok := parseCategoryId(ctx)
+	if !ok {
+		return
+	}
+
+	var input categoryInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+
+	if err := h.categories.UpdateCategory(categoryId, model.Category{ParentId: input.ParentId, Name: input.Name, Attributes: input.Attributes}); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, statusMessage{"ok"})
+}
+
+// @Summary удалить категорию
+// @Tags Admin
+// @Description Удалить категорию без подкатегорий и объявлений. Требуется право categories:manage
+// @ID delete-category
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param id path int true "Category ID"
+// @Success 200 {object} StatusMessageOk
+// @Failure 400 {object} CategoryMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} CategoryMessage404
+// @Failure 409 {object} CategoryMessage409
+// @Failure 500 {object} AdvertMessage500
+// @Router /categories/{id} [delete]
+func (h *Handler) deleteCategory(ctx *gin.Context) {
+	categoryId, ok := parseCategoryId(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.categories.DeleteCategory(categoryId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, statusMessage{"ok"})
+}
+
+func parseCategoryId(ctx *gin.Context) (int, bool) {
+	categoryId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		SendErrorResponse(ctx, http.StatusBadRequest, "category id must be integer")
+		return 0, false
+	}
+	return categoryId, true
+}
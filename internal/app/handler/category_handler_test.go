@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+func TestHandler_getCategories(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockCategories)
+	parentId := 1
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Tree",
+			inputURL: "/categories",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().GetCategoryTree().Return([]model.Category{
+					{Id: 1, Name: "Транспорт", Children: []model.Category{{Id: 2, ParentId: &parentId, Name: "Велосипеды"}}},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"id":1,"name":"Транспорт","children":[{"id":2,"parent-id":1,"name":"Велосипеды"}]}]`,
+		},
+		{
+			name:     "Category",
+			inputURL: "/categories/2",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().GetCategory(2).Return(model.Category{Id: 2, ParentId: &parentId, Name: "Велосипеды"}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":2,"parent-id":1,"name":"Велосипеды"}`,
+		},
+		{
+			name:     "Not found",
+			inputURL: "/categories/7",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().GetCategory(7).Return(model.Category{}, repository.ErrCategoryNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"category not found"}`,
+		},
+		{
+			name:                 "Wrong id",
+			inputURL:             "/categories/bikes",
+			mockBehavior:         func(s *mock.MockCategories) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"category id must be integer"}`,
+		},
+		{
+			name:     "Server error",
+			inputURL: "/categories",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().GetCategoryTree().Return(nil, errors.New("something went wrong"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockCategories := mock.NewMockCategories(c)
+			test.mockBehavior(mockCategories)
+
+			handler := NewHandler(nil, mockCategories, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.GET("/categories", handler.getCategories)
+			router.GET("/categories/:id", handler.getCategory)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.inputURL, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_changeCategory(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockCategories)
+	parentId := 1
+
+	tests := []struct {
+		name                 string
+		inputMethod          string
+		inputURL             string
+		inputBody            string
+		inputToken           string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:        "Create",
+			inputMethod: "POST",
+			inputURL:    "/categories",
+			inputBody:   `{"parent-id":1,"name":"Велосипеды"}`,
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().CreateCategory(model.Category{ParentId: &parentId, Name: "Велосипеды"}).Return(2, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":2}`,
+		},
+		{
+			name:        "Create with attributes",
+			inputMethod: "POST",
+			inputURL:    "/categories",
+			inputBody:   `{"parent-id":1,"name":"Квартиры","attributes":[{"name":"rooms","type":"int","required":true,"min":1}]}`,
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockCategories) {
+				min := 1.0
+				s.EXPECT().CreateCategory(model.Category{ParentId: &parentId, Name: "Квартиры", Attributes: model.AttributeSchema{
+					{Name: "rooms", Type: model.AttributeInt, Required: true, Min: &min},
+				}}).Return(3, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":3}`,
+		},
+		{
+			name:                 "Create without name",
+			inputMethod:          "POST",
+			inputURL:             "/categories",
+			inputBody:            `{"parent-id":1}`,
+			inputToken:           "secret",
+			mockBehavior:         func(s *mock.MockCategories) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"name","code":"required","message":"the field \"name\" is required"}]}`,
+		},
+		{
+			name:        "Create with unknown parent",
+			inputMethod: "POST",
+			inputURL:    "/categories",
+			inputBody:   `{"parent-id":1,"name":"Велосипеды"}`,
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockCategories) {
+				errs := &service.ValidationError{}
+				errs.Add("parent-id", service.CodeInvalid, "the parent category does not exist", nil)
+				s.EXPECT().CreateCategory(model.Category{ParentId: &parentId, Name: "Велосипеды"}).Return(0, errs)
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"parent-id","code":"invalid","message":"the parent category does not exist"}]}`,
+		},
+		{
+			name:                 "Create without admin token",
+			inputMethod:          "POST",
+			inputURL:             "/categories",
+			inputBody:            `{"name":"Велосипеды"}`,
+			mockBehavior:         func(s *mock.MockCategories) {},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authentication required"}`,
+		},
+		{
+			name:        "Update",
+			inputMethod: "PUT",
+			inputURL:    "/categories/2",
+			inputBody:   `{"name":"Велосипеды"}`,
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().UpdateCategory(2, model.Category{Name: "Велосипеды"}).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:        "Update duplicate",
+			inputMethod: "PUT",
+			inputURL:    "/categories/2",
+			inputBody:   `{"name":"Велосипеды"}`,
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().UpdateCategory(2, model.Category{Name: "Велосипеды"}).Return(repository.ErrCategoryExists)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"type":"about:blank","title":"Conflict","status":409,"detail":"the parent already has a category with this name"}`,
+		},
+		{
+			name:        "Delete",
+			inputMethod: "DELETE",
+			inputURL:    "/categories/4",
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().DeleteCategory(4).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:        "Delete in use",
+			inputMethod: "DELETE",
+			inputURL:    "/categories/1",
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockCategories) {
+				s.EXPECT().DeleteCategory(1).Return(repository.ErrCategoryInUse)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"type":"about:blank","title":"Conflict","status":409,"detail":"category has subcategories or advertisements"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockCategories := mock.NewMockCategories(c)
+			test.mockBehavior(mockCategories)
+
+			handler := NewHandler(nil, mockCategories, nil, nil, nil, nil, nil, "secret")
+			router := gin.New()
+			router.Use(handleErrors, handler.authenticate)
+			manage := handler.permit(service.PermManageCategories)
+			router.POST("/categories", manage, handler.createCategory)
+			router.PUT("/categories/:id", manage, handler.updateCategory)
+			router.DELETE("/categories/:id", manage, handler.deleteCategory)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.inputMethod, test.inputURL, bytes.NewBufferString(test.inputBody))
+			req.Header.Set("X-Admin-Token", test.inputToken)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+// handleErrors renders the error a handler attached with ctx.Error as
+// problem details, choosing the status by the error kind. Errors of an
+// unknown kind are answered with 500 and the wrapped causes, database and
+// network errors among them, with the text of their kind. Neither exposes
+// its own text; the logger middleware still prints them.
+func handleErrors(ctx *gin.Context) {
+	ctx.Next()
+
+	if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+		return
+	}
+	sendProblem(ctx, errorProblem(ctx.Errors.Last().Err))
+}
+
+func errorProblem(err error) problem {
+	var invalid *service.ValidationError
+	if errors.As(err, &invalid) {
+		statusCode := http.StatusUnprocessableEntity
+		if invalid.IsMalformed() {
+			statusCode = http.StatusBadRequest
+		}
+		p := newProblem(statusCode, "validation failed")
+		p.Errors = invalid.Errors
+		return p
+	}
+
+	var blocked *service.BlockedError
+	if errors.As(err, &blocked) {
+		p := newProblem(http.StatusUnprocessableEntity, err.Error())
+		p.Rules = blocked.Hits
+		return p
+	}
+
+	var denied *service.PermissionError
+	if errors.As(err, &denied) {
+		p := newProblem(http.StatusForbidden, err.Error())
+		p.Permission = string(denied.Permission)
+		return p
+	}
+
+	statusCode := errorStatus(err)
+	if statusCode == http.StatusInternalServerError {
+		return newProblem(statusCode, "internal server error")
+	}
+	if apperror.Opaque(err) {
+		return newProblem(statusCode, apperror.Kind(err).Error())
+	}
+	return newProblem(statusCode, err.Error())
+}
+
+func errorStatus(err error) int {
+	switch apperror.Kind(err) {
+	case apperror.ErrNotFound:
+		return http.StatusNotFound
+	case apperror.ErrConflict:
+		return http.StatusConflict
+	case apperror.ErrValidation:
+		return http.StatusUnprocessableEntity
+	case apperror.ErrForbidden:
+		return http.StatusForbidden
+	case apperror.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case apperror.ErrUnavailable:
+		return http.StatusServiceUnavailable
+	case apperror.ErrTooManyRequests:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+func TestHandler_handleErrors(t *testing.T) {
+	tests := []struct {
+		name                 string
+		inputError           error
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Not found",
+			inputError:           fmt.Errorf("loading advert: %w", apperror.New(apperror.ErrNotFound, "advertisement not found")),
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"loading advert: advertisement not found"}`,
+		},
+		{
+			name:                 "Conflict",
+			inputError:           apperror.New(apperror.ErrConflict, "advertisement status has been changed"),
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"type":"about:blank","title":"Conflict","status":409,"detail":"advertisement status has been changed"}`,
+		},
+		{
+			name:                 "Unauthorized",
+			inputError:           apperror.New(apperror.ErrUnauthorized, "invalid email or password"),
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"invalid email or password"}`,
+		},
+		{
+			name:                 "Unavailable",
+			inputError:           apperror.Wrap(apperror.ErrUnavailable, errors.New("connection refused")),
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"service unavailable"}`,
+		},
+		{
+			name:                 "Database constraint",
+			inputError:           fmt.Errorf("saving advert: %w", apperror.Wrap(apperror.ErrValidation, errors.New("pq: insert or update on table \"adverts\" violates foreign key constraint \"adverts_category_id_fkey\""))),
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed"}`,
+		},
+		{
+			name:                 "Too many requests",
+			inputError:           apperror.New(apperror.ErrTooManyRequests, "daily quota of the api key is exceeded"),
+			expectedStatusCode:   429,
+			expectedResponseBody: `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"daily quota of the api key is exceeded"}`,
+		},
+		{
+			name: "Blocked",
+			inputError: &service.BlockedError{Hits: model.RuleHits{
+				{Rule: "banned_words", Verdict: model.VerdictBlock, Message: "banned words: casino"},
+			}},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"advertisement is blocked by content rules","rules":[{"rule":"banned_words","verdict":"block","message":"banned words: casino"}]}`,
+		},
+		{
+			name:                 "Unknown error",
+			inputError:           errors.New("pq: relation \"adverts\" does not exist"),
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(handleErrors)
+			router.GET("/fail", func(ctx *gin.Context) {
+				ctx.Error(test.inputError)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/fail", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Header().Get("Content-Type"), problemContentType)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
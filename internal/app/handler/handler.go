@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/ratelimit"
 	"github.com/paramonies/avito-rest-advert/internal/app/service"
 
 	_ "github.com/paramonies/avito-rest-advert/docs"
@@ -15,21 +19,77 @@ import (
 )
 
 type Handler struct {
-	service service.Service
+	service    service.Service
+	categories service.Categories
+	moderation service.Moderation
+	uploader   service.Uploader
+	users      service.Users
+	apiKeys    service.APIKeys
+	limiter    *ratelimit.Limiter
+	adminToken string
 }
 
-func NewHandler(service service.Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service service.Service, categories service.Categories, moderation service.Moderation, uploader service.Uploader, users service.Users, apiKeys service.APIKeys, limiter *ratelimit.Limiter, adminToken string) *Handler {
+	return &Handler{service: service, categories: categories, moderation: moderation, uploader: uploader, users: users, apiKeys: apiKeys, limiter: limiter, adminToken: adminToken}
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.Default()
+	// the public reads are served anonymously, the changes require a token
+	// or an API key. The advert operations are checked by the service, the
+	// other endpoints by permit. The rate limits apply once the caller is
+	// known
+	router.Use(handleErrors, h.authenticate, h.limit)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	router.POST("/create", h.createAdvert)
+	router.POST("/auth/register", h.register)
+	router.POST("/auth/login", h.login)
+	router.GET("/me/adverts", h.requireAuth, h.getOwnAdverts)
+
+	router.POST("/create", h.requireAuth, h.createAdvert)
 	router.GET("/get/:id", h.getAdvertById)
 	router.GET("/list", h.getList)
+	router.GET("/search", h.search)
+	router.GET("/suggest", h.suggest)
+
+	adverts := router.Group("/adverts")
+	{
+		adverts.PUT("/:id", h.requireAuth, h.updateAdvert)
+		adverts.PATCH("/:id", h.requireAuth, h.patchAdvert)
+		adverts.DELETE("/:id", h.requireAuth, h.deleteAdvert)
+		adverts.POST("/:id/archive", h.requireAuth, h.archiveAdvert)
+		adverts.POST("/:id/restore", h.requireAuth, h.restoreAdvert)
+		adverts.POST("/:id/transitions", h.requireAuth, h.transitionAdvert)
+		adverts.POST("/:id/pictures", h.requireAuth, h.uploadPicture)
+		adverts.PUT("/:id/pictures/order", h.requireAuth, h.reorderPictures)
+		adverts.POST("/:id/pictures/:picture_id/main", h.requireAuth, h.setMainPicture)
+	}
+
+	categories := router.Group("/categories")
+	{
+		categories.GET("", h.permit(service.PermReadAdverts), h.getCategories)
+		categories.GET("/:id", h.permit(service.PermReadAdverts), h.getCategory)
+		categories.POST("", h.permit(service.PermManageCategories), h.createCategory)
+		categories.PUT("/:id", h.permit(service.PermManageCategories), h.updateCategory)
+		categories.DELETE("/:id", h.permit(service.PermManageCategories), h.deleteCategory)
+	}
+
+	moderation := router.Group("/moderation", h.permit(service.PermModerateAdverts))
+	{
+		moderation.GET("/queue", h.getModerationQueue)
+		moderation.POST("/:id/decision", h.decideModeration)
+		moderation.GET("/:id/decisions", h.getModerationDecisions)
+	}
+
+	apiKeys := router.Group("/api-keys", h.permit(service.PermManageAPIKeys))
+	{
+		apiKeys.POST("", h.issueAPIKey)
+		apiKeys.GET("", h.getAPIKeys)
+		apiKeys.DELETE("/:id", h.revokeAPIKey)
+	}
+
+	router.PUT("/users/:id/role", h.permit(service.PermManageUsers), h.setUserRole)
 
 	return router
 }
@@ -40,28 +100,33 @@ func (h *Handler) InitRoutes() *gin.Engine {
 // @ID create-advert
 // @Accept  json
 // @Produce  json
+// @Security Bearer
+// @Security ApiKey
 // @Param input body InputAdvert true "Advert info"
 // @Success 200 {object} CreateMessageOk
 // @Failure 400 {object} CreateMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 422 {object} ValidationMessage422
+// @Failure 429 {object} QuotaMessage429
 // @Failure 500 {object} CreateMessage500
+// @Header 200,429 {integer} RateLimit-Remaining "Requests left before the limit is hit"
+// @Header 429 {integer} Retry-After "Seconds to wait before the next request"
 // @Router /create [post]
 func (h *Handler) createAdvert(ctx *gin.Context) {
 	var input model.Advert
 
-	if err := ctx.BindJSON(&input); err != nil {
-		SendErrorResponse(ctx, http.StatusBadRequest, "invalid input body")
+	if !bindJSON(ctx, &input) {
 		return
 	}
 
-	id, err := h.service.CreateAdvert(input)
+	id, hits, err := h.service.CreateAdvert(principal(ctx), input)
 	if err != nil {
-		SendErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, map[string]interface{}{
-		"id": id,
-	})
+	ctx.JSON(http.StatusOK, createMessage{Id: id, Rules: hits})
 }
 
 // @Summary получить объявление
@@ -74,14 +139,13 @@ func (h *Handler) createAdvert(ctx *gin.Context) {
 // @Param fields query string false "Additional Advert fields in response" Enums(description, pictures)
 // @Success 200 {object} GetMessageOk
 // @Failure 400 {object} GetMessage400
+// @Failure 404 {object} AdvertMessage404
 // @Failure 500 {object} GetMessage500
 // @Router /get/{id} [get]
 func (h *Handler) getAdvertById(ctx *gin.Context) {
 	//..../get/:id?fields=description,pictures
-	id := ctx.Param("id")
-	advertId, err := strconv.Atoi(id)
-	if err != nil {
-		SendErrorResponse(ctx, http.StatusBadRequest, "advertisement id must be integer")
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
 		return
 	}
 
@@ -103,9 +167,9 @@ func (h *Handler) getAdvertById(ctx *gin.Context) {
 		}
 	}
 
-	advert, err := h.service.GetAdvertById(advertId, fieldsValid)
+	advert, err := h.service.GetAdvertById(principal(ctx), advertId, fieldsValid)
 	if err != nil {
-		SendErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		ctx.Error(err)
 		return
 	}
 
@@ -114,49 +178,339 @@ func (h *Handler) getAdvertById(ctx *gin.Context) {
 
 // @Summary получить список объявлений
 // @Tags Advert
-// @Description Получить список объявлений по номеру страницы. На одной странице должно присутствовать 10 объявлений
+// @Description Получить страницу списка объявлений с фильтрами, общим количеством и ссылками на соседние страницы. По умолчанию на странице 10 объявлений
 // @ID get-advert
 // @Accept  html
 // @Produce  json
 // @Param page query int false "Page number"
-// @Param order_by query string false "Order field and order destination" Enums(price_desc, price_asc, createdat_desc, createdat_asc)
+// @Param page_size query int false "Number of adverts on a page, bounded by max_page_size"
+// @Param cursor query string false "Cursor of the next or previous page from X-Next-Cursor or X-Prev-Cursor, takes precedence over page"
+// @Param order_by query string false "Order field and order destination, distance_asc needs near" Enums(price_desc, price_asc, createdat_desc, createdat_asc, distance_asc)
+// @Param include_archived query bool false "Include archived adverts, needs the adverts:moderate permission"
+// @Param count query string false "How the total is counted" Enums(exact, estimated)
+// @Param price_min query int false "Minimal price"
+// @Param price_max query int false "Maximal price"
+// @Param created_from query string false "Created at or after, RFC 3339 timestamp or date (2021-07-01)"
+// @Param created_to query string false "Created at or before, RFC 3339 timestamp or date (the whole day)"
+// @Param has_pictures query bool false "Only adverts with (true) or without (false) pictures"
+// @Param q query string false "Text to find in the name or the description"
+// @Param category_id query int false "Category, the adverts of its subcategories are included"
+// @Param attr.name query string false "Attribute filters: attr.<name>=<value> for an equal value, attr.<name>_min and attr.<name>_max for a range of a number"
+// @Param near query string false "Point the distances are measured from: lat,lon (55.75,37.62)"
+// @Param radius_km query number false "Only adverts within the radius from near, in kilometers"
+// @Param bbox query string false "Only adverts in the box: south,west,north,east"
 // @Success 200 {object} ListMessageOk1
-// @Failure 404 {object} ListMessage404
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page"
+// @Header 200 {string} X-Prev-Cursor "Cursor of the previous page"
+// @Header 200 {string} Link "Links to the next and previous pages"
+// @Failure 400 {object} CreateMessage400 "Cursor of another order or other filters"
+// @Failure 422 {object} ValidationMessage422
 // @Failure 500 {object} ListMessage500
 // @Router /list [get]
 func (h *Handler) getList(ctx *gin.Context) {
-	//..../list?page=2&order_by=createdat_desc
-	pageStr := ctx.Query("page")
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
+	//..../list?page=2&page_size=20&order_by=createdat_desc&include_archived=true
+	//..../list?price_min=100&price_max=5000&created_from=2021-07-01&has_pictures=true&q=bike
+	//..../list?cursor=eyJvIjoi...&include_archived=true
+	//..../list?category_id=4&attr.rooms=2&attr.floor_max=5
+	//..../list?near=55.75,37.62&radius_km=5&order_by=distance_asc
+	query, err := parseListQuery(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	list, err := h.service.GetAdvertList(principal(ctx), query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	list.Links = model.ListLinks{
+		Self:  ctx.Request.URL.RequestURI(),
+		First: pageURL(ctx, 1),
+		Last:  pageURL(ctx, list.TotalPages),
+	}
+	var links []string
+	if list.NextCursor != "" {
+		list.Links.Next = cursorURL(ctx, list.NextCursor)
+		ctx.Header("X-Next-Cursor", list.NextCursor)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, list.Links.Next))
+	}
+	if list.PrevCursor != "" {
+		list.Links.Prev = cursorURL(ctx, list.PrevCursor)
+		ctx.Header("X-Prev-Cursor", list.PrevCursor)
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, list.Links.Prev))
+	}
+	if len(links) > 0 {
+		ctx.Header("Link", strings.Join(links, ", "))
+	}
+
+	ctx.JSON(http.StatusOK, list)
+
+}
+
+// cursorURL is the request URL with the page replaced by cursor.
+func cursorURL(ctx *gin.Context, cursor string) string {
+	query := ctx.Request.URL.Query()
+	query.Del("page")
+	query.Set("cursor", cursor)
+	return ctx.Request.URL.Path + "?" + query.Encode()
+}
+
+// pageURL is the request URL leading to the numbered page, the first page
+// stands for the last one of an empty list.
+func pageURL(ctx *gin.Context, page int) string {
+	if page < 1 {
 		page = 1
 	}
+	query := ctx.Request.URL.Query()
+	query.Del("cursor")
+	query.Set("page", strconv.Itoa(page))
+	return ctx.Request.URL.Path + "?" + query.Encode()
+}
 
-	orderBy := ctx.Query("order_by")
-	orderBySet := map[string]bool{
-		"price_desc": true, "price_asc": true,
-		"createdat_desc": true, "createdat_asc": true,
+// @Summary обновить объявление
+// @Tags Advert
+// @Description Полная замена полей объявления
+// @ID update-advert
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path int true "Advert ID"
+// @Param input body InputAdvert true "Advert info"
+// @Success 200 {object} StatusMessageOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 422 {object} ValidationMessage422
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id} [put]
+func (h *Handler) updateAdvert(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
 	}
-	isValid := false
-	if _, ok := orderBySet[orderBy]; ok {
-		isValid = true
+
+	var input model.Advert
+	if !bindJSON(ctx, &input) {
+		return
 	}
 
-	if orderBy == "" || !isValid {
-		orderBy = "createdat_desc"
+	if err := h.service.UpdateAdvert(principal(ctx), advertId, input); err != nil {
+		ctx.Error(err)
+		return
 	}
 
-	adverts, err := h.service.GetAdvertList(page, orderBy)
-	if err != nil {
-		SendErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	ctx.JSON(http.StatusOK, statusMessage{"ok"})
+}
+
+// @Summary частично обновить объявление
+// @Tags Advert
+// @Description Частичное обновление объявления в формате JSON merge patch (RFC 7396)
+// @ID patch-advert
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path int true "Advert ID"
+// @Param input body InputAdvert true "Changed advert fields"
+// @Success 200 {object} StatusMessageOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 422 {object} ValidationMessage422
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id} [patch]
+func (h *Handler) patchAdvert(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	patch, err := ctx.GetRawData()
+	if err != nil || !isJSONObject(patch) {
+		ctx.Error(service.NewDecodeError(err))
 		return
 	}
 
-	if len(adverts) == 0 {
-		SendErrorResponse(ctx, http.StatusNotFound, "advertisements not found")
+	if err := h.service.PatchAdvert(principal(ctx), advertId, patch); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, adverts)
+	ctx.JSON(http.StatusOK, statusMessage{"ok"})
+}
+
+// @Summary удалить объявление
+// @Tags Advert
+// @Description Удалить объявление по id
+// @ID delete-advert
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path int true "Advert ID"
+// @Success 200 {object} StatusMessageOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id} [delete]
+func (h *Handler) deleteAdvert(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAdvert(principal(ctx), advertId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, statusMessage{"ok"})
+}
+
+// @Summary архивировать объявление
+// @Tags Advert
+// @Description Скрыть объявление из выдачи без удаления
+// @ID archive-advert
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path int true "Advert ID"
+// @Success 200 {object} StatusMessageOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id}/archive [post]
+func (h *Handler) archiveAdvert(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.service.ArchiveAdvert(principal(ctx), advertId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, statusMessage{"ok"})
+}
+
+// @Summary восстановить объявление
+// @Tags Admin
+// @Description Восстановить удаленное или архивное объявление. Требуется право adverts:restore
+// @ID restore-advert
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param id path int true "Advert ID"
+// @Success 200 {object} StatusMessageOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id}/restore [post]
+func (h *Handler) restoreAdvert(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RestoreAdvert(principal(ctx), advertId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, statusMessage{"ok"})
+}
+
+type transitionInput struct {
+	Status model.AdvertStatus `json:"status" binding:"required"`
+}
+
+// @Summary изменить статус объявления
+// @Tags Advert
+// @Description Перевести объявление в другой статус. Переход из blocked в draft и любой переход в blocked выполняет только
+// @Description модератор (право adverts:moderate). Перехода в active нет: объявление публикуется только решением модерации
+// @Description (POST /moderation/{id}/decision)
+// @ID transition-advert
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param id path int true "Advert ID"
+// @Param input body InputTransition true "Target status"
+// @Success 200 {object} TransitionMessageOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 409 {object} TransitionMessage409
+// @Failure 422 {object} ValidationMessage422
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id}/transitions [post]
+func (h *Handler) transitionAdvert(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	var input transitionInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+	if !input.Status.IsValid() {
+		errs := &service.ValidationError{}
+		errs.Add("status", service.CodeInvalid, fmt.Sprintf("unknown advertisement status %q", input.Status), nil)
+		ctx.Error(errs)
+		return
+	}
+
+	if err := h.service.TransitionAdvert(principal(ctx), advertId, input.Status); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"id":     advertId,
+		"status": input.Status,
+	})
+}
+
+// isAdmin tells whether the request carries the configured admin token. An
+// empty token in the config disables the token.
+func (h *Handler) isAdmin(ctx *gin.Context) bool {
+	token := ctx.GetHeader("X-Admin-Token")
+	return h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
+func parseAdvertId(ctx *gin.Context) (int, bool) {
+	advertId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		SendErrorResponse(ctx, http.StatusBadRequest, "advertisement id must be integer")
+		return 0, false
+	}
+	return advertId, true
+}
 
+func isJSONObject(data []byte) bool {
+	var obj map[string]json.RawMessage
+	return json.Unmarshal(data, &obj) == nil && obj != nil
 }
@@ -3,14 +3,18 @@ package handler
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert/v2"
 	"github.com/golang/mock/gomock"
 	"github.com/paramonies/avito-rest-advert/internal/app/mock"
 	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
 )
 
 func TestHandler_createAdvert(t *testing.T) {
@@ -26,41 +30,111 @@ func TestHandler_createAdvert(t *testing.T) {
 	}{
 		{
 			name:      "Ok",
-			inputBody: `{"name":"name-test", "description":"desc-test", "price":1000, "pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"}`,
+			inputBody: `{"name":"name-test", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"},{"url":"avito/files/ad2"},{"url":"avito/files/ad3"}]}`,
 			inputAdvert: model.Advert{
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 			},
 			mockBehavior: func(s *mock.MockService, advert model.Advert) {
-				s.EXPECT().CreateAdvert(advert).Return(1, nil)
+				s.EXPECT().CreateAdvert(owner, advert).Return(1, model.RuleHits{}, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"id":1}`,
+			expectedResponseBody: `{"id":1,"rules":[]}`,
+		},
+		{
+			name:      "Ok with flagged rules",
+			inputBody: `{"name":"NAME TEST", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"}]}`,
+			inputAdvert: model.Advert{
+				Name:        "NAME TEST",
+				Description: "desc-test",
+				Price:       1000,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}},
+			},
+			mockBehavior: func(s *mock.MockService, advert model.Advert) {
+				s.EXPECT().CreateAdvert(owner, advert).Return(1, model.RuleHits{
+					{Rule: "caps_title", Verdict: model.VerdictFlag, Message: "name is written in capital letters"},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":1,"rules":[{"rule":"caps_title","verdict":"flag","message":"name is written in capital letters"}]}`,
+		},
+		{
+			name:      "Blocked by rules",
+			inputBody: `{"name":"casino", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"}]}`,
+			inputAdvert: model.Advert{
+				Name:        "casino",
+				Description: "desc-test",
+				Price:       1000,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}},
+			},
+			mockBehavior: func(s *mock.MockService, advert model.Advert) {
+				s.EXPECT().CreateAdvert(owner, advert).Return(0, nil, &service.BlockedError{Hits: model.RuleHits{
+					{Rule: "banned_words", Verdict: model.VerdictBlock, Message: "banned words: casino"},
+				}})
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"advertisement is blocked by content rules","rules":[{"rule":"banned_words","verdict":"block","message":"banned words: casino"}]}`,
 		},
 		{
 			name:                 "Bad input",
-			inputBody:            `{"name":"", "description":"desc-test", "price":1000, "pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"}`,
+			inputBody:            `{"name":"", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"},{"url":"avito/files/ad2"},{"url":"avito/files/ad3"}]}`,
+			inputAdvert:          model.Advert{},
+			mockBehavior:         func(s *mock.MockService, advert model.Advert) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"name","code":"required","message":"the field \"name\" is required"}]}`,
+		},
+		{
+			name:                 "Wrong field type",
+			inputBody:            `{"name":"name-test", "description":"desc-test", "price":"1000", "pictures":[{"url":"avito/files/ad1"}]}`,
+			inputAdvert:          model.Advert{},
+			mockBehavior:         func(s *mock.MockService, advert model.Advert) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"price","code":"type","message":"the field \"price\" must be of type int","params":{"type":"int"}}]}`,
+		},
+		{
+			name:                 "Malformed body",
+			inputBody:            `{"name":`,
 			inputAdvert:          model.Advert{},
 			mockBehavior:         func(s *mock.MockService, advert model.Advert) {},
 			expectedStatusCode:   400,
-			expectedResponseBody: `{"error":"invalid input body"}`,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"validation failed","errors":[{"field":"","code":"malformed","message":"invalid input body"}]}`,
+		},
+		{
+			name:      "Invalid advert",
+			inputBody: `{"name":"name-test", "description":"desc-test", "price":-1000, "pictures":[{"url":"avito/files/ad1"}]}`,
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       -1000,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}},
+			},
+			mockBehavior: func(s *mock.MockService, advert model.Advert) {
+				s.EXPECT().CreateAdvert(owner, advert).Return(0, nil, &service.ValidationError{Errors: []service.FieldError{{
+					Field:   "price",
+					Code:    service.CodeMinValue,
+					Message: `the field "price" must have a value greater than 0`,
+					Params:  map[string]interface{}{"min": 0},
+				}}})
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"price","code":"min_value","message":"the field \"price\" must have a value greater than 0","params":{"min":0}}]}`,
 		},
 		{
 			name:      "Server error",
-			inputBody: `{"name":"name-test", "description":"desc-test", "price":1000, "pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"}`,
+			inputBody: `{"name":"name-test", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"},{"url":"avito/files/ad2"},{"url":"avito/files/ad3"}]}`,
 			inputAdvert: model.Advert{
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 			},
 			mockBehavior: func(s *mock.MockService, advert model.Advert) {
-				s.EXPECT().CreateAdvert(advert).Return(0, errors.New("something went wrong"))
+				s.EXPECT().CreateAdvert(owner, advert).Return(0, nil, errors.New("something went wrong"))
 			},
 			expectedStatusCode:   500,
-			expectedResponseBody: `{"error":"something went wrong"}`,
+			expectedResponseBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`,
 		},
 	}
 
@@ -72,9 +146,10 @@ func TestHandler_createAdvert(t *testing.T) {
 			mockService := mock.NewMockService(c)
 			test.mockBehavior(mockService, test.inputAdvert)
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
 			router := gin.New()
-			router.POST("/create", handler.createAdvert)
+			router.Use(handleErrors)
+			router.POST("/create", signedIn(owner), handler.createAdvert)
 
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest("POST", "/create", bytes.NewBufferString(test.inputBody))
@@ -104,15 +179,15 @@ func TestHandler_getAdvertById(t *testing.T) {
 			inputId:     1,
 			inputFields: []string{},
 			mockBehavior: func(s *mock.MockService, advertId int, fields []string) {
-				s.EXPECT().GetAdvertById(1, []string{}).Return(model.Advert{
+				s.EXPECT().GetAdvertById(model.Principal{}, 1, []string{}).Return(model.Advert{
 					Name:        "name-test",
 					Description: "desc-test",
 					Price:       1000,
-					Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+					Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 				}, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"name":"name-test","description":"desc-test","price":1000,"pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"}`,
+			expectedResponseBody: `{"name":"name-test","description":"desc-test","price":1000,"pictures":[{"url":"avito/files/ad1","position":0,"is_main":false},{"url":"avito/files/ad2","position":0,"is_main":false},{"url":"avito/files/ad3","position":0,"is_main":false}]}`,
 		},
 		{
 			name:        "Ok with fields params",
@@ -120,15 +195,26 @@ func TestHandler_getAdvertById(t *testing.T) {
 			inputId:     1,
 			inputFields: []string{"description", "pictures"},
 			mockBehavior: func(s *mock.MockService, advertId int, fields []string) {
-				s.EXPECT().GetAdvertById(1, []string{"description", "pictures"}).Return(model.Advert{
+				s.EXPECT().GetAdvertById(model.Principal{}, 1, []string{"description", "pictures"}).Return(model.Advert{
 					Name:        "name-test",
 					Description: "desc-test",
 					Price:       1000,
-					Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+					Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 				}, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"name":"name-test","description":"desc-test","price":1000,"pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"}`,
+			expectedResponseBody: `{"name":"name-test","description":"desc-test","price":1000,"pictures":[{"url":"avito/files/ad1","position":0,"is_main":false},{"url":"avito/files/ad2","position":0,"is_main":false},{"url":"avito/files/ad3","position":0,"is_main":false}]}`,
+		},
+		{
+			name:        "Not found",
+			inputURL:    "/get/666",
+			inputId:     666,
+			inputFields: []string{},
+			mockBehavior: func(s *mock.MockService, advertId int, fields []string) {
+				s.EXPECT().GetAdvertById(model.Principal{}, 666, []string{}).Return(model.Advert{}, repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
 		},
 		{
 			name:                 "Bad input",
@@ -137,7 +223,7 @@ func TestHandler_getAdvertById(t *testing.T) {
 			inputFields:          []string{},
 			mockBehavior:         func(s *mock.MockService, advertId int, fields []string) {},
 			expectedStatusCode:   400,
-			expectedResponseBody: `{"error":"advertisement id must be integer"}`,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"advertisement id must be integer"}`,
 		},
 		{
 			name:        "Server error",
@@ -145,10 +231,10 @@ func TestHandler_getAdvertById(t *testing.T) {
 			inputId:     1,
 			inputFields: []string{},
 			mockBehavior: func(s *mock.MockService, advertId int, fields []string) {
-				s.EXPECT().GetAdvertById(1, []string{}).Return(model.Advert{}, errors.New("something went wrong"))
+				s.EXPECT().GetAdvertById(model.Principal{}, 1, []string{}).Return(model.Advert{}, errors.New("something went wrong"))
 			},
 			expectedStatusCode:   500,
-			expectedResponseBody: `{"error":"something went wrong"}`,
+			expectedResponseBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`,
 		},
 	}
 
@@ -160,8 +246,9 @@ func TestHandler_getAdvertById(t *testing.T) {
 			mockService := mock.NewMockService(c)
 			test.mockBehavior(mockService, test.inputId, test.inputFields)
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
 			router := gin.New()
+			router.Use(handleErrors)
 			router.GET("/get/:id", handler.getAdvertById)
 
 			w := httptest.NewRecorder()
@@ -176,6 +263,7 @@ func TestHandler_getAdvertById(t *testing.T) {
 
 func TestHandler_getList(t *testing.T) {
 	type mockBehaviorType func(*mock.MockService, int, string)
+	archivedAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
 	tests := []struct {
 		name                 string
 		inputURL             string
@@ -184,6 +272,7 @@ func TestHandler_getList(t *testing.T) {
 		mockBehavior         mockBehaviorType
 		expectedResponseCode int
 		expectedResponseBody string
+		expectedHeaders      map[string]string
 	}{
 		{
 			name:         "Ok",
@@ -191,26 +280,26 @@ func TestHandler_getList(t *testing.T) {
 			inputPage:    1,
 			inputOrderBy: "",
 			mockBehavior: func(s *mock.MockService, page int, orderBy string) {
-				s.EXPECT().GetAdvertList(1, "createdat_desc").Return([]model.Advert{
+				s.EXPECT().GetAdvertList(model.Principal{}, model.ListQuery{Page: 1}).Return(model.AdvertList{Page: 1, PageSize: 10, Total: 3, TotalPages: 1, Items: []model.Advert{
 					{
 						Name:     "name-test1",
 						Price:    1000,
-						Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+						Pictures: model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 					},
 					{
 						Name:     "name-test2",
 						Price:    100,
-						Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+						Pictures: model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 					},
 					{
 						Name:     "name-test3",
 						Price:    10,
-						Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+						Pictures: model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 					},
-				}, nil)
+				}}, nil)
 			},
 			expectedResponseCode: 200,
-			expectedResponseBody: `[{"name":"name-test1","price":1000,"pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"},{"name":"name-test2","price":100,"pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"},{"name":"name-test3","price":10,"pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"}]`,
+			expectedResponseBody: `{"items":[{"name":"name-test1","price":1000,"pictures":[{"url":"avito/files/ad1","position":0,"is_main":false},{"url":"avito/files/ad2","position":0,"is_main":false},{"url":"avito/files/ad3","position":0,"is_main":false}]},{"name":"name-test2","price":100,"pictures":[{"url":"avito/files/ad1","position":0,"is_main":false},{"url":"avito/files/ad2","position":0,"is_main":false},{"url":"avito/files/ad3","position":0,"is_main":false}]},{"name":"name-test3","price":10,"pictures":[{"url":"avito/files/ad1","position":0,"is_main":false},{"url":"avito/files/ad2","position":0,"is_main":false},{"url":"avito/files/ad3","position":0,"is_main":false}]}],"page":1,"page_size":10,"total":3,"total_pages":1,"links":{"self":"/list","first":"/list?page=1","last":"/list?page=1"}}`,
 		},
 		{
 			name:         "Ok with params",
@@ -218,26 +307,101 @@ func TestHandler_getList(t *testing.T) {
 			inputPage:    1,
 			inputOrderBy: "price_desc",
 			mockBehavior: func(s *mock.MockService, page int, orderBy string) {
-				s.EXPECT().GetAdvertList(1, "price_desc").Return([]model.Advert{
+				s.EXPECT().GetAdvertList(model.Principal{}, model.ListQuery{Page: 1, OrderBy: "price_desc"}).Return(model.AdvertList{Page: 1, PageSize: 10, Total: 3, TotalPages: 1, Items: []model.Advert{
 					{
 						Name:     "name-test1",
 						Price:    1000,
-						Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+						Pictures: model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 					},
 					{
 						Name:     "name-test2",
 						Price:    100,
-						Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+						Pictures: model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 					},
 					{
 						Name:     "name-test3",
 						Price:    10,
-						Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+						Pictures: model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 					},
+				}}, nil)
+			},
+			expectedResponseCode: 200,
+			expectedResponseBody: `{"items":[{"name":"name-test1","price":1000,"pictures":[{"url":"avito/files/ad1","position":0,"is_main":false},{"url":"avito/files/ad2","position":0,"is_main":false},{"url":"avito/files/ad3","position":0,"is_main":false}]},{"name":"name-test2","price":100,"pictures":[{"url":"avito/files/ad1","position":0,"is_main":false},{"url":"avito/files/ad2","position":0,"is_main":false},{"url":"avito/files/ad3","position":0,"is_main":false}]},{"name":"name-test3","price":10,"pictures":[{"url":"avito/files/ad1","position":0,"is_main":false},{"url":"avito/files/ad2","position":0,"is_main":false},{"url":"avito/files/ad3","position":0,"is_main":false}]}],"page":1,"page_size":10,"total":3,"total_pages":1,"links":{"self":"/list?page=1\u0026order_by=price_desc","first":"/list?order_by=price_desc\u0026page=1","last":"/list?order_by=price_desc\u0026page=1"}}`,
+		},
+		{
+			name:         "Ok with archived",
+			inputURL:     "/list?include_archived=true",
+			inputPage:    1,
+			inputOrderBy: "",
+			mockBehavior: func(s *mock.MockService, page int, orderBy string) {
+				s.EXPECT().GetAdvertList(model.Principal{}, model.ListQuery{Page: 1, IncludeArchived: true}).Return(model.AdvertList{Page: 1, PageSize: 10, Total: 1, TotalPages: 1, Items: []model.Advert{
+					{
+						Name:       "name-test1",
+						Price:      1000,
+						ArchivedAt: &archivedAt,
+					},
+				}}, nil)
+			},
+			expectedResponseCode: 200,
+			expectedResponseBody: `{"items":[{"name":"name-test1","price":1000,"archived-at":"2021-07-01T12:00:00Z"}],"page":1,"page_size":10,"total":1,"total_pages":1,"links":{"self":"/list?include_archived=true","first":"/list?include_archived=true\u0026page=1","last":"/list?include_archived=true\u0026page=1"}}`,
+		},
+		{
+			name:         "Ok with cursor",
+			inputURL:     "/list?page=3&cursor=c2&order_by=unknown",
+			inputPage:    3,
+			inputOrderBy: "",
+			mockBehavior: func(s *mock.MockService, page int, orderBy string) {
+				s.EXPECT().GetAdvertList(model.Principal{}, model.ListQuery{Page: 3, Cursor: "c2"}).Return(model.AdvertList{
+					Items:      []model.Advert{{Id: 12, Name: "name-test1", Price: 1000}},
+					PageSize:   10,
+					Total:      25,
+					TotalPages: 3,
+					NextCursor: "c3",
+					PrevCursor: "c1",
 				}, nil)
 			},
 			expectedResponseCode: 200,
-			expectedResponseBody: `[{"name":"name-test1","price":1000,"pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"},{"name":"name-test2","price":100,"pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"},{"name":"name-test3","price":10,"pictures":"avito/files/ad1,avito/files/ad2,avito/files/ad3"}]`,
+			expectedResponseBody: `{"items":[{"id":12,"name":"name-test1","price":1000}],"page_size":10,"total":25,"total_pages":3,"links":{"self":"/list?page=3\u0026cursor=c2\u0026order_by=unknown","first":"/list?order_by=unknown\u0026page=1","last":"/list?order_by=unknown\u0026page=3","prev":"/list?cursor=c1\u0026order_by=unknown","next":"/list?cursor=c3\u0026order_by=unknown"},"next_cursor":"c3","prev_cursor":"c1"}`,
+			expectedHeaders: map[string]string{
+				"X-Next-Cursor": "c3",
+				"X-Prev-Cursor": "c1",
+				"Link":          `</list?cursor=c3&order_by=unknown>; rel="next", </list?cursor=c1&order_by=unknown>; rel="prev"`,
+			},
+		},
+		{
+			name:         "Invalid cursor",
+			inputURL:     "/list?cursor=bad",
+			inputPage:    1,
+			inputOrderBy: "",
+			mockBehavior: func(s *mock.MockService, page int, orderBy string) {
+				errs := &service.ValidationError{}
+				errs.Add("cursor", service.CodeInvalid, "the cursor is invalid", nil)
+				s.EXPECT().GetAdvertList(model.Principal{}, model.ListQuery{Page: 1, Cursor: "bad"}).Return(model.AdvertList{}, errs)
+			},
+			expectedResponseCode: 422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"cursor","code":"invalid","message":"the cursor is invalid"}]}`,
+		},
+		{
+			name:         "Cursor of other filters",
+			inputURL:     "/list?cursor=c2&price_min=100",
+			inputPage:    1,
+			inputOrderBy: "",
+			mockBehavior: func(s *mock.MockService, page int, orderBy string) {
+				errs := &service.ValidationError{}
+				errs.Add("cursor", service.CodeMalformed, "the cursor was issued for other filters", nil)
+				s.EXPECT().GetAdvertList(model.Principal{}, gomock.Any()).Return(model.AdvertList{}, errs)
+			},
+			expectedResponseCode: 400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"validation failed","errors":[{"field":"cursor","code":"malformed","message":"the cursor was issued for other filters"}]}`,
+		},
+		{
+			name:                 "Malformed filter",
+			inputURL:             "/list?price_max=lots",
+			inputPage:            1,
+			inputOrderBy:         "",
+			mockBehavior:         func(s *mock.MockService, page int, orderBy string) {},
+			expectedResponseCode: 422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"price_max","code":"type","message":"the field \"price_max\" must be of type int","params":{"type":"int"}}]}`,
 		},
 		{
 			name:         "Server error",
@@ -245,21 +409,21 @@ func TestHandler_getList(t *testing.T) {
 			inputPage:    1,
 			inputOrderBy: "",
 			mockBehavior: func(s *mock.MockService, page int, orderBy string) {
-				s.EXPECT().GetAdvertList(1, "createdat_desc").Return([]model.Advert{}, errors.New("something went wrong"))
+				s.EXPECT().GetAdvertList(model.Principal{}, model.ListQuery{Page: 1}).Return(model.AdvertList{}, errors.New("something went wrong"))
 			},
 			expectedResponseCode: 500,
-			expectedResponseBody: `{"error":"something went wrong"}`,
+			expectedResponseBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`,
 		},
 		{
-			name:         "Records not found",
-			inputURL:     "/list?page=2",
+			name:         "Empty page",
+			inputURL:     "/list?page=2&page_size=20&count=estimated",
 			inputPage:    2,
 			inputOrderBy: "createdat_desc",
 			mockBehavior: func(s *mock.MockService, page int, orderBy string) {
-				s.EXPECT().GetAdvertList(2, "createdat_desc").Return([]model.Advert{}, nil)
+				s.EXPECT().GetAdvertList(model.Principal{}, model.ListQuery{Page: 2, PageSize: 20, Count: "estimated"}).Return(model.AdvertList{Items: []model.Advert{}, Page: 2, PageSize: 20}, nil)
 			},
-			expectedResponseCode: 404,
-			expectedResponseBody: `{"error":"advertisements not found"}`,
+			expectedResponseCode: 200,
+			expectedResponseBody: `{"items":[],"page":2,"page_size":20,"total":0,"total_pages":0,"links":{"self":"/list?page=2\u0026page_size=20\u0026count=estimated","first":"/list?count=estimated\u0026page=1\u0026page_size=20","last":"/list?count=estimated\u0026page=1\u0026page_size=20"}}`,
 		},
 	}
 
@@ -271,8 +435,9 @@ func TestHandler_getList(t *testing.T) {
 			mockService := mock.NewMockService(c)
 			test.mockBehavior(mockService, test.inputPage, test.inputOrderBy)
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
 			router := gin.New()
+			router.Use(handleErrors)
 			router.GET("/list", handler.getList)
 
 			w := httptest.NewRecorder()
@@ -281,6 +446,452 @@ func TestHandler_getList(t *testing.T) {
 
 			assert.Equal(t, w.Code, test.expectedResponseCode)
 			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, w.Header().Get(name), value)
+			}
+		})
+	}
+}
+
+func TestHandler_updateAdvert(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockService, advert model.Advert)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		inputBody            string
+		inputAdvert          model.Advert
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Ok",
+			inputURL:  "/adverts/1",
+			inputBody: `{"name":"name-test", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"}]}`,
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}},
+			},
+			mockBehavior: func(s *mock.MockService, advert model.Advert) {
+				s.EXPECT().UpdateAdvert(owner, 1, advert).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:                 "Bad id",
+			inputURL:             "/adverts/1a",
+			inputBody:            `{"name":"name-test", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"}]}`,
+			mockBehavior:         func(s *mock.MockService, advert model.Advert) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"advertisement id must be integer"}`,
+		},
+		{
+			name:                 "Bad input",
+			inputURL:             "/adverts/1",
+			inputBody:            `{"name":"", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"}]}`,
+			mockBehavior:         func(s *mock.MockService, advert model.Advert) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"name","code":"required","message":"the field \"name\" is required"}]}`,
+		},
+		{
+			name:      "Not found",
+			inputURL:  "/adverts/666",
+			inputBody: `{"name":"name-test", "description":"desc-test", "price":1000, "pictures":[{"url":"avito/files/ad1"}]}`,
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}},
+			},
+			mockBehavior: func(s *mock.MockService, advert model.Advert) {
+				s.EXPECT().UpdateAdvert(owner, 666, advert).Return(repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService, test.inputAdvert)
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.PUT("/adverts/:id", signedIn(owner), handler.updateAdvert)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", test.inputURL, bytes.NewBufferString(test.inputBody))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_patchAdvert(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockService, patch []byte)
+
+	tests := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Ok",
+			inputBody: `{"price":500}`,
+			mockBehavior: func(s *mock.MockService, patch []byte) {
+				s.EXPECT().PatchAdvert(owner, 1, patch).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:                 "Not an object",
+			inputBody:            `[{"price":500}]`,
+			mockBehavior:         func(s *mock.MockService, patch []byte) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"validation failed","errors":[{"field":"","code":"malformed","message":"invalid input body"}]}`,
+		},
+		{
+			name:                 "Invalid json",
+			inputBody:            `{"price":`,
+			mockBehavior:         func(s *mock.MockService, patch []byte) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"validation failed","errors":[{"field":"","code":"malformed","message":"invalid input body"}]}`,
+		},
+		{
+			name:      "Not found",
+			inputBody: `{"price":500}`,
+			mockBehavior: func(s *mock.MockService, patch []byte) {
+				s.EXPECT().PatchAdvert(owner, 1, patch).Return(repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
+		},
+		{
+			name:      "Server error",
+			inputBody: `{"price":500}`,
+			mockBehavior: func(s *mock.MockService, patch []byte) {
+				s.EXPECT().PatchAdvert(owner, 1, patch).Return(errors.New("something went wrong"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService, []byte(test.inputBody))
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.PATCH("/adverts/:id", signedIn(owner), handler.patchAdvert)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PATCH", "/adverts/1", bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_deleteAdvert(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockService)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Ok",
+			inputURL: "/adverts/1",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().DeleteAdvert(owner, 1).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:                 "Bad id",
+			inputURL:             "/adverts/abc",
+			mockBehavior:         func(s *mock.MockService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"advertisement id must be integer"}`,
+		},
+		{
+			name:     "Not found",
+			inputURL: "/adverts/666",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().DeleteAdvert(owner, 666).Return(repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
+		},
+		{
+			name:     "Not owner",
+			inputURL: "/adverts/2",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().DeleteAdvert(owner, 2).Return(&service.PermissionError{Permission: service.PermDeleteAnyAdverts})
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"permission \"adverts:delete_any\" is required","permission":"adverts:delete_any"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService)
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.DELETE("/adverts/:id", signedIn(owner), handler.deleteAdvert)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", test.inputURL, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_archiveAdvert(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockService)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Ok",
+			inputURL: "/adverts/1/archive",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().ArchiveAdvert(owner, 1).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:     "Not found",
+			inputURL: "/adverts/666/archive",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().ArchiveAdvert(owner, 666).Return(repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService)
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/adverts/:id/archive", signedIn(owner), handler.archiveAdvert)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", test.inputURL, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_restoreAdvert(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockService)
+
+	tests := []struct {
+		name                 string
+		configToken          string
+		inputToken           string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:        "Ok",
+			configToken: "secret",
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().RestoreAdvert(model.Principal{Role: model.RoleAdmin}, 1).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:                 "Wrong token",
+			configToken:          "secret",
+			inputToken:           "guess",
+			mockBehavior:         func(s *mock.MockService) {},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authentication required"}`,
+		},
+		{
+			name:                 "Admin endpoints disabled",
+			configToken:          "",
+			inputToken:           "",
+			mockBehavior:         func(s *mock.MockService) {},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authentication required"}`,
+		},
+		{
+			name:        "Not found",
+			configToken: "secret",
+			inputToken:  "secret",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().RestoreAdvert(model.Principal{Role: model.RoleAdmin}, 1).Return(repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService)
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, test.configToken)
+			router := gin.New()
+			router.Use(handleErrors, handler.authenticate)
+			router.POST("/adverts/:id/restore", handler.requireAuth, handler.restoreAdvert)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/adverts/1/restore", nil)
+			req.Header.Set("X-Admin-Token", test.inputToken)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_transitionAdvert(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockService)
+
+	tests := []struct {
+		name                 string
+		inputBody            string
+		inputToken           string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Ok",
+			inputBody: `{"status":"pending"}`,
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().TransitionAdvert(owner, 1, model.StatusPending).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":1,"status":"pending"}`,
+		},
+		{
+			name:       "Ok by moderator",
+			inputBody:  `{"status":"blocked"}`,
+			inputToken: "secret",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().TransitionAdvert(model.Principal{UserId: 7, Role: model.RoleAdmin}, 1, model.StatusBlocked).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":1,"status":"blocked"}`,
+		},
+		{
+			name:                 "Unknown status",
+			inputBody:            `{"status":"sold"}`,
+			mockBehavior:         func(s *mock.MockService) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"status","code":"invalid","message":"unknown advertisement status \"sold\""}]}`,
+		},
+		{
+			name:      "Illegal transition",
+			inputBody: `{"status":"active"}`,
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().TransitionAdvert(owner, 1, model.StatusActive).
+					Return(fmt.Errorf("%w: from draft to active", service.ErrTransitionNotAllowed))
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"type":"about:blank","title":"Conflict","status":409,"detail":"status transition is not allowed: from draft to active"}`,
+		},
+		{
+			name:      "Moderator required",
+			inputBody: `{"status":"blocked"}`,
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().TransitionAdvert(owner, 1, model.StatusBlocked).
+					Return(&service.PermissionError{Permission: service.PermModerateAdverts})
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"permission \"adverts:moderate\" is required","permission":"adverts:moderate"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService)
+			mockUsers := mock.NewMockUsers(c)
+			mockUsers.EXPECT().Authenticate("user-token").Return(owner, nil)
+
+			handler := NewHandler(mockService, nil, nil, nil, mockUsers, nil, nil, "secret")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/adverts/:id/transitions", handler.authenticate, handler.requireAuth, handler.transitionAdvert)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/adverts/1/transitions", bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Authorization", "Bearer user-token")
+			req.Header.Set("X-Admin-Token", test.inputToken)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
 		})
 	}
 }
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+const (
+	dateLayout      = "2006-01-02"
+	attributePrefix = "attr."
+)
+
+var listOrders = map[string]bool{
+	"price_desc": true, "price_asc": true,
+	"createdat_desc": true, "createdat_asc": true,
+	"distance_asc": true,
+}
+
+// parseListQuery reads the list parameters. The paging parameters are
+// lenient as they always were: a bad page or page size falls back to the
+// default and an unknown order to the default one, or to the order of the
+// cursor when there is one. A filter that can not be parsed is reported,
+// silently dropping it would show adverts the client filtered out.
+func parseListQuery(ctx *gin.Context) (model.ListQuery, error) {
+	query := model.ListQuery{
+		Cursor: ctx.Query("cursor"),
+		Count:  ctx.Query("count"),
+		Q:      ctx.Query("q"),
+	}
+
+	query.Page, _ = strconv.Atoi(ctx.Query("page"))
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	query.PageSize, _ = strconv.Atoi(ctx.Query("page_size"))
+	if orderBy := ctx.Query("order_by"); listOrders[orderBy] {
+		query.OrderBy = orderBy
+	}
+	query.IncludeArchived, _ = strconv.ParseBool(ctx.Query("include_archived"))
+
+	errs := &service.ValidationError{}
+	query.PriceMin = queryInt(ctx, "price_min", errs)
+	query.PriceMax = queryInt(ctx, "price_max", errs)
+	query.CreatedFrom = queryTime(ctx, "created_from", false, errs)
+	query.CreatedTo = queryTime(ctx, "created_to", true, errs)
+	query.HasPictures = queryBool(ctx, "has_pictures", errs)
+	query.CategoryId = queryInt(ctx, "category_id", errs)
+	query.Attributes = queryAttributes(ctx, errs)
+	if near := queryFloats(ctx, "near", 2, errs); near != nil {
+		query.Near = &model.GeoPoint{Lat: near[0], Lon: near[1]}
+	}
+	query.RadiusKm = queryFloat(ctx, "radius_km", errs)
+	if box := queryFloats(ctx, "bbox", 4, errs); box != nil {
+		query.Box = &model.GeoBox{South: box[0], West: box[1], North: box[2], East: box[3]}
+	}
+
+	return query, errs.Err()
+}
+
+// queryAttributes reads the attribute filters: attr.<name>=<value> for an
+// equal value and attr.<name>_min, attr.<name>_max for a range. The filters
+// are ordered by the name, so equal queries build equal SQL.
+func queryAttributes(ctx *gin.Context, errs *service.ValidationError) []model.AttributeFilter {
+	params := ctx.Request.URL.Query()
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if strings.HasPrefix(key, attributePrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var filters []model.AttributeFilter
+	filter := func(name string) *model.AttributeFilter {
+		for i := range filters {
+			if filters[i].Name == name {
+				return &filters[i]
+			}
+		}
+		filters = append(filters, model.AttributeFilter{Name: name})
+		return &filters[len(filters)-1]
+	}
+	for _, key := range keys {
+		name, value := strings.TrimPrefix(key, attributePrefix), params.Get(key)
+		bound := ""
+		if strings.HasSuffix(name, "_min") || strings.HasSuffix(name, "_max") {
+			name, bound = name[:len(name)-4], name[len(name)-3:]
+		}
+		if name == "" {
+			errs.Add(key, service.CodeInvalid, fmt.Sprintf(`the field "%s" must name an attribute`, key), nil)
+			continue
+		}
+		if bound == "" {
+			filter(name).Equal = &value
+			continue
+		}
+
+		number, ok := parseFloat(value)
+		if !ok {
+			typeError(errs, key, "number")
+			continue
+		}
+		if bound == "min" {
+			filter(name).Min = &number
+		} else {
+			filter(name).Max = &number
+		}
+	}
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Name < filters[j].Name })
+	return filters
+}
+
+func queryInt(ctx *gin.Context, name string, errs *service.ValidationError) *int {
+	value, ok := ctx.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		typeError(errs, name, "int")
+		return nil
+	}
+	return &number
+}
+
+func queryFloat(ctx *gin.Context, name string, errs *service.ValidationError) *float64 {
+	value, ok := ctx.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	number, ok := parseFloat(value)
+	if !ok {
+		typeError(errs, name, "number")
+		return nil
+	}
+	return &number
+}
+
+// queryFloats reads n comma separated numbers, e.g. the coordinates of a
+// point: near=55.75,37.62.
+func queryFloats(ctx *gin.Context, name string, n int, errs *service.ValidationError) []float64 {
+	value, ok := ctx.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	parts := strings.Split(value, ",")
+	numbers := make([]float64, len(parts))
+	for i, part := range parts {
+		number, ok := parseFloat(strings.TrimSpace(part))
+		if !ok || len(parts) != n {
+			errs.Add(name, service.CodeType, fmt.Sprintf(`the field "%s" must be %d comma separated numbers`, name, n),
+				map[string]interface{}{"type": "number", "items": n})
+			return nil
+		}
+		numbers[i] = number
+	}
+	return numbers
+}
+
+// parseFloat accepts finite numbers only.
+func parseFloat(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+func queryBool(ctx *gin.Context, name string, errs *service.ValidationError) *bool {
+	value, ok := ctx.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		typeError(errs, name, "bool")
+		return nil
+	}
+	return &flag
+}
+
+// queryTime accepts RFC 3339 timestamps and dates. A date stands for the
+// start of the day, or for its end when endOfDay is set, in UTC.
+func queryTime(ctx *gin.Context, name string, endOfDay bool, errs *service.ValidationError) *time.Time {
+	value, ok := ctx.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		typeError(errs, name, "date")
+		return nil
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Microsecond)
+	}
+	return &t
+}
+
+func typeError(errs *service.ValidationError, name, typ string) {
+	errs.Add(name, service.CodeType, fmt.Sprintf(`the field "%s" must be of type %s`, name, typ),
+		map[string]interface{}{"type": typ})
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+func TestHandler_parseListQuery(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	boolPtr := func(b bool) *bool { return &b }
+	timePtr := func(t time.Time) *time.Time { return &t }
+	stringPtr := func(s string) *string { return &s }
+	floatPtr := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name          string
+		inputURL      string
+		expectedQuery model.ListQuery
+		expectedError error
+	}{
+		{
+			name:          "Defaults",
+			inputURL:      "/list?page=-1&page_size=many&order_by=name",
+			expectedQuery: model.ListQuery{Page: 1},
+		},
+		{
+			name: "Filters",
+			inputURL: "/list?page=2&page_size=20&order_by=price_asc&price_min=100&price_max=5000" +
+				"&created_from=2021-07-01&created_to=2021-07-31&has_pictures=false&q=bike&category_id=2",
+			expectedQuery: model.ListQuery{
+				Page:        2,
+				PageSize:    20,
+				OrderBy:     "price_asc",
+				PriceMin:    intPtr(100),
+				PriceMax:    intPtr(5000),
+				CreatedFrom: timePtr(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)),
+				CreatedTo:   timePtr(time.Date(2021, 7, 31, 23, 59, 59, 999999000, time.UTC)),
+				HasPictures: boolPtr(false),
+				Q:           "bike",
+				CategoryId:  intPtr(2),
+			},
+		},
+		{
+			name:     "Attributes",
+			inputURL: "/list?attr.rooms=2&attr.mileage_max=100000&attr.mileage_min=1000.5&attr.gearbox=auto",
+			expectedQuery: model.ListQuery{
+				Page: 1,
+				Attributes: []model.AttributeFilter{
+					{Name: "gearbox", Equal: stringPtr("auto")},
+					{Name: "mileage", Min: floatPtr(1000.5), Max: floatPtr(100000)},
+					{Name: "rooms", Equal: stringPtr("2")},
+				},
+			},
+		},
+		{
+			name:     "Timestamps",
+			inputURL: "/list?created_from=2021-07-01T10:00:00Z&created_to=2021-07-01T18:00:00Z",
+			expectedQuery: model.ListQuery{
+				Page:        1,
+				CreatedFrom: timePtr(time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)),
+				CreatedTo:   timePtr(time.Date(2021, 7, 1, 18, 0, 0, 0, time.UTC)),
+			},
+		},
+		{
+			name:     "Location",
+			inputURL: "/list?near=55.75,%2037.62&radius_km=2.5&bbox=55,37,56,38&order_by=distance_asc",
+			expectedQuery: model.ListQuery{
+				Page:     1,
+				OrderBy:  "distance_asc",
+				Near:     &model.GeoPoint{Lat: 55.75, Lon: 37.62},
+				RadiusKm: floatPtr(2.5),
+				Box:      &model.GeoBox{South: 55, West: 37, North: 56, East: 38},
+			},
+		},
+		{
+			name:     "Malformed location",
+			inputURL: "/list?near=55.75&radius_km=near&bbox=55,37,56,NaN",
+			expectedError: &service.ValidationError{Errors: []service.FieldError{
+				{Field: "near", Code: service.CodeType, Message: `the field "near" must be 2 comma separated numbers`, Params: map[string]interface{}{"type": "number", "items": 2}},
+				{Field: "radius_km", Code: service.CodeType, Message: `the field "radius_km" must be of type number`, Params: map[string]interface{}{"type": "number"}},
+				{Field: "bbox", Code: service.CodeType, Message: `the field "bbox" must be 4 comma separated numbers`, Params: map[string]interface{}{"type": "number", "items": 4}},
+			}},
+		},
+		{
+			name:     "Malformed attributes",
+			inputURL: "/list?attr._max=3&attr.floor_min=low&attr.rooms=2",
+			expectedError: &service.ValidationError{Errors: []service.FieldError{
+				{Field: "attr._max", Code: service.CodeInvalid, Message: `the field "attr._max" must name an attribute`},
+				{Field: "attr.floor_min", Code: service.CodeType, Message: `the field "attr.floor_min" must be of type number`, Params: map[string]interface{}{"type": "number"}},
+			}},
+		},
+		{
+			name:     "Malformed filters",
+			inputURL: "/list?price_min=cheap&price_max=1e3&created_from=yesterday&has_pictures=some",
+			expectedError: &service.ValidationError{Errors: []service.FieldError{
+				{Field: "price_min", Code: service.CodeType, Message: `the field "price_min" must be of type int`, Params: map[string]interface{}{"type": "int"}},
+				{Field: "price_max", Code: service.CodeType, Message: `the field "price_max" must be of type int`, Params: map[string]interface{}{"type": "int"}},
+				{Field: "created_from", Code: service.CodeType, Message: `the field "created_from" must be of type date`, Params: map[string]interface{}{"type": "date"}},
+				{Field: "has_pictures", Code: service.CodeType, Message: `the field "has_pictures" must be of type bool`, Params: map[string]interface{}{"type": "bool"}},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", test.inputURL, nil)
+
+			query, err := parseListQuery(ctx)
+			assert.Equal(t, err, test.expectedError)
+			if err == nil {
+				assert.Equal(t, query, test.expectedQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+type decisionInput struct {
+	Decision   model.Decision `json:"decision" binding:"required"`
+	ReasonCode string         `json:"reason_code"`
+	Comment    string         `json:"comment"`
+}
+
+// @Summary очередь модерации
+// @Tags Moderation
+// @Description Получить самые старые объявления, ожидающие модерации, и закрепить их за модератором на время аренды.
+// @Description Закрепленные за другим модератором объявления не выдаются, пока аренда не истечет. Требуется право adverts:moderate
+// @ID moderation-queue
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param X-Moderator header string false "Moderator name, accepted with the admin token only"
+// @Param limit query int false "Number of adverts to claim, 10 by default, 50 at most"
+// @Success 200 {array} ModerationTaskOk
+// @Failure 400 {object} ModerationMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 500 {object} AdvertMessage500
+// @Router /moderation/queue [get]
+func (h *Handler) getModerationQueue(ctx *gin.Context) {
+	moderator, ok := h.getModerator(ctx)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+
+	tasks, err := h.moderation.ClaimQueue(moderator, limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tasks)
+}
+
+// @Summary решение модератора
+// @Tags Moderation
+// @Description Одобрить (approve) или отклонить (reject) закрепленное за модератором объявление.
+// @Description Одобренное объявление публикуется, отклоненное возвращается автору в черновики. Для отклонения обязателен reason_code. Требуется право adverts:moderate
+// @ID moderation-decision
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param X-Moderator header string false "Moderator name, accepted with the admin token only"
+// @Param id path int true "Advert ID"
+// @Param input body InputDecision true "Decision"
+// @Success 200 {object} ModerationDecisionOk
+// @Failure 400 {object} ModerationMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 409 {object} ModerationMessage409
+// @Failure 422 {object} ValidationMessage422
+// @Failure 500 {object} AdvertMessage500
+// @Router /moderation/{id}/decision [post]
+func (h *Handler) decideModeration(ctx *gin.Context) {
+	moderator, ok := h.getModerator(ctx)
+	if !ok {
+		return
+	}
+
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	var input decisionInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+
+	decision, err := h.moderation.Decide(model.ModerationDecision{
+		AdvertId:   advertId,
+		Moderator:  moderator,
+		Decision:   input.Decision,
+		ReasonCode: input.ReasonCode,
+		Comment:    input.Comment,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, decision)
+}
+
+// @Summary история модерации
+// @Tags Moderation
+// @Description Получить все решения модераторов по объявлению в хронологическом порядке. Требуется право adverts:moderate
+// @ID moderation-decisions
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Admin-Token header string false "Admin token"
+// @Param id path int true "Advert ID"
+// @Success 200 {array} ModerationDecisionOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 500 {object} AdvertMessage500
+// @Router /moderation/{id}/decisions [get]
+func (h *Handler) getModerationDecisions(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	decisions, err := h.moderation.GetDecisions(advertId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, decisions)
+}
+
+// getModerator returns the name of the moderator. A signed in moderator is
+// named by the user id, X-Moderator names the moderator only for the
+// requests with the admin token, so the moderators cannot act for each
+// other.
+func (h *Handler) getModerator(ctx *gin.Context) (string, bool) {
+	var moderator string
+	if h.isAdmin(ctx) {
+		moderator = strings.TrimSpace(ctx.GetHeader("X-Moderator"))
+	}
+	if user := principal(ctx); moderator == "" && user.UserId != 0 {
+		moderator = "user:" + strconv.Itoa(user.UserId)
+	}
+	if moderator == "" || len(moderator) > 100 {
+		SendErrorResponse(ctx, http.StatusBadRequest, "X-Moderator header is required")
+		return "", false
+	}
+	return moderator, true
+}
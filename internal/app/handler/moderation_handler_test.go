@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+func TestHandler_getModerationQueue(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockModeration)
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		inputToken           string
+		inputModerator       string
+		inputPrincipal       *model.Principal
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:           "Ok",
+			inputURL:       "/moderation/queue?limit=1",
+			inputToken:     "secret",
+			inputModerator: "moderator-1",
+			mockBehavior: func(s *mock.MockModeration) {
+				s.EXPECT().ClaimQueue("moderator-1", 1).Return([]model.ModerationTask{
+					{
+						AdvertId:       1,
+						Name:           "name-test",
+						Description:    "desc-test",
+						Price:          1000,
+						CreatedAt:      createdAt,
+						ClaimExpiresAt: createdAt.Add(10 * time.Minute),
+					},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"advert-id":1,"name":"name-test","description":"desc-test","price":1000,"created-at":"2021-07-01T12:00:00Z","claim-expires-at":"2021-07-01T12:10:00Z"}]`,
+		},
+		{
+			name:                 "Without moderator",
+			inputURL:             "/moderation/queue",
+			inputToken:           "secret",
+			mockBehavior:         func(s *mock.MockModeration) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"X-Moderator header is required"}`,
+		},
+		{
+			name:                 "Without admin token",
+			inputURL:             "/moderation/queue",
+			inputModerator:       "moderator-1",
+			mockBehavior:         func(s *mock.MockModeration) {},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authentication required"}`,
+		},
+		{
+			name:           "Signed in moderator",
+			inputURL:       "/moderation/queue",
+			inputPrincipal: &model.Principal{UserId: 9, Role: model.RoleModerator},
+			mockBehavior: func(s *mock.MockModeration) {
+				s.EXPECT().ClaimQueue("user:9", 0).Return([]model.ModerationTask{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:           "Signed in moderator names another one",
+			inputURL:       "/moderation/queue",
+			inputModerator: "user:10",
+			inputPrincipal: &model.Principal{UserId: 9, Role: model.RoleModerator},
+			mockBehavior: func(s *mock.MockModeration) {
+				s.EXPECT().ClaimQueue("user:9", 0).Return([]model.ModerationTask{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:                 "Signed in seller",
+			inputURL:             "/moderation/queue",
+			inputPrincipal:       &owner,
+			mockBehavior:         func(s *mock.MockModeration) {},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"permission \"adverts:moderate\" is required","permission":"adverts:moderate"}`,
+		},
+		{
+			name:           "Server error",
+			inputURL:       "/moderation/queue",
+			inputToken:     "secret",
+			inputModerator: "moderator-1",
+			mockBehavior: func(s *mock.MockModeration) {
+				s.EXPECT().ClaimQueue("moderator-1", 0).Return(nil, errors.New("something went wrong"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockModeration := mock.NewMockModeration(c)
+			test.mockBehavior(mockModeration)
+
+			handler := NewHandler(nil, nil, mockModeration, nil, nil, nil, nil, "secret")
+			router := gin.New()
+			router.Use(handleErrors, handler.authenticate)
+			if test.inputPrincipal != nil {
+				router.Use(signedIn(*test.inputPrincipal))
+			}
+			router.GET("/moderation/queue", handler.permit(service.PermModerateAdverts), handler.getModerationQueue)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.inputURL, nil)
+			req.Header.Set("X-Admin-Token", test.inputToken)
+			req.Header.Set("X-Moderator", test.inputModerator)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_decideModeration(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockModeration)
+	createdAt := time.Date(2021, 7, 1, 12, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Ok",
+			inputBody: `{"decision":"reject","reason_code":"spam"}`,
+			mockBehavior: func(s *mock.MockModeration) {
+				s.EXPECT().Decide(model.ModerationDecision{
+					AdvertId:   1,
+					Moderator:  "moderator-1",
+					Decision:   model.DecisionReject,
+					ReasonCode: "spam",
+				}).Return(model.ModerationDecision{
+					Id:         3,
+					AdvertId:   1,
+					Moderator:  "moderator-1",
+					Decision:   model.DecisionReject,
+					ReasonCode: "spam",
+					CreatedAt:  createdAt,
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":3,"advert-id":1,"moderator":"moderator-1","decision":"reject","reason-code":"spam","created-at":"2021-07-01T12:05:00Z"}`,
+		},
+		{
+			name:                 "Bad input",
+			inputBody:            `{"reason_code":"spam"}`,
+			mockBehavior:         func(s *mock.MockModeration) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"decision","code":"required","message":"the field \"decision\" is required"}]}`,
+		},
+		{
+			name:      "Not claimed",
+			inputBody: `{"decision":"approve"}`,
+			mockBehavior: func(s *mock.MockModeration) {
+				s.EXPECT().Decide(gomock.Any()).Return(model.ModerationDecision{}, repository.ErrNotClaimed)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"type":"about:blank","title":"Conflict","status":409,"detail":"advertisement is not claimed by the moderator"}`,
+		},
+		{
+			name:      "Not found",
+			inputBody: `{"decision":"approve"}`,
+			mockBehavior: func(s *mock.MockModeration) {
+				s.EXPECT().Decide(gomock.Any()).Return(model.ModerationDecision{}, repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockModeration := mock.NewMockModeration(c)
+			test.mockBehavior(mockModeration)
+
+			handler := NewHandler(nil, nil, mockModeration, nil, nil, nil, nil, "secret")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/moderation/:id/decision", handler.decideModeration)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/moderation/1/decision", bytes.NewBufferString(test.inputBody))
+			req.Header.Set("X-Admin-Token", "secret")
+			req.Header.Set("X-Moderator", "moderator-1")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_getModerationDecisions(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockModeration := mock.NewMockModeration(c)
+	mockModeration.EXPECT().GetDecisions(1).Return([]model.ModerationDecision{
+		{
+			Id:        1,
+			AdvertId:  1,
+			Moderator: "moderator-1",
+			Decision:  model.DecisionApprove,
+			CreatedAt: time.Date(2021, 7, 1, 12, 5, 0, 0, time.UTC),
+		},
+	}, nil)
+
+	handler := NewHandler(nil, nil, mockModeration, nil, nil, nil, nil, "secret")
+	router := gin.New()
+	router.Use(handleErrors)
+	router.GET("/moderation/:id/decisions", handler.getModerationDecisions)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/moderation/1/decisions", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, 200)
+	assert.Equal(t, w.Body.String(), `[{"id":1,"advert-id":1,"moderator":"moderator-1","decision":"approve","created-at":"2021-07-01T12:05:00Z"}]`)
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+// maxUploadRequestSize bounds the whole multipart request. The size of the
+// picture itself is checked by the upload service.
+const maxUploadRequestSize = 32 << 20
+
+type pictureOrderInput struct {
+	Pictures []int `json:"pictures" binding:"required"`
+}
+
+// @Summary загрузить фотографию
+// @Tags Advert
+// @Description Загрузить фотографию объявления. Фотография добавляется в конец списка, первая фотография становится главной. Принимаются jpeg, png, gif и webp
+// @ID upload-picture
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path int true "Advert ID"
+// @Param file formData file true "Picture file"
+// @Success 201 {object} PictureOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 413 {object} UploadMessage413
+// @Failure 422 {object} ValidationMessage422
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id}/pictures [post]
+func (h *Handler) uploadPicture(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxUploadRequestSize)
+	fileHeader, err := ctx.FormFile("file")
+	switch {
+	case err == http.ErrMissingFile:
+		errs := &service.ValidationError{}
+		errs.Add("file", service.CodeRequired, `the field "file" is required`, nil)
+		ctx.Error(errs)
+		return
+	case err != nil && strings.Contains(err.Error(), "request body too large"):
+		SendErrorResponse(ctx, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	case err != nil:
+		SendErrorResponse(ctx, http.StatusBadRequest, "invalid multipart form")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer file.Close()
+
+	picture, err := h.uploader.UploadPicture(principal(ctx), advertId, file)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, picture)
+}
+
+// @Summary изменить порядок фотографий
+// @Tags Advert
+// @Description Упорядочить фотографии объявления. В теле передаются id всех фотографий объявления в новом порядке
+// @ID reorder-pictures
+// @Accept  json
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path int true "Advert ID"
+// @Param input body InputPictureOrder true "Picture ids in the new order"
+// @Success 200 {array} PictureOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} AdvertMessage404
+// @Failure 422 {object} ValidationMessage422
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id}/pictures/order [put]
+func (h *Handler) reorderPictures(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	var input pictureOrderInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+
+	pictures, err := h.service.ReorderPictures(principal(ctx), advertId, input.Pictures)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pictures)
+}
+
+// @Summary сделать фотографию главной
+// @Tags Advert
+// @Description Сделать фотографию главной фотографией объявления, она показывается в списке объявлений
+// @ID set-main-picture
+// @Accept  html
+// @Produce  json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path int true "Advert ID"
+// @Param picture_id path int true "Picture ID"
+// @Success 200 {array} PictureOk
+// @Failure 400 {object} AdvertMessage400
+// @Failure 401 {object} AuthMessage401
+// @Failure 403 {object} PermissionMessage403
+// @Failure 404 {object} PictureMessage404
+// @Failure 429 {object} QuotaMessage429
+// @Failure 500 {object} AdvertMessage500
+// @Router /adverts/{id}/pictures/{picture_id}/main [post]
+func (h *Handler) setMainPicture(ctx *gin.Context) {
+	advertId, ok := parseAdvertId(ctx)
+	if !ok {
+		return
+	}
+
+	pictureId, err := strconv.Atoi(ctx.Param("picture_id"))
+	if err != nil {
+		SendErrorResponse(ctx, http.StatusBadRequest, "picture id must be integer")
+		return
+	}
+
+	pictures, err := h.service.SetMainPicture(principal(ctx), advertId, pictureId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pictures)
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+func multipartBody(t *testing.T, field string, content []byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if field != "" {
+		part, err := writer.CreateFormFile(field, "photo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func TestHandler_uploadPicture(t *testing.T) {
+	type mockBehaviorType func(u *mock.MockUploader)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		inputField           string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:       "Ok",
+			inputURL:   "/adverts/1/pictures",
+			inputField: "file",
+			mockBehavior: func(u *mock.MockUploader) {
+				u.EXPECT().UploadPicture(owner, 1, gomock.Any()).Return(model.Picture{Id: 4, URL: "http://localhost:8080/uploads/adverts/1/a.png", Position: 1}, nil)
+			},
+			expectedStatusCode:   201,
+			expectedResponseBody: `{"id":4,"url":"http://localhost:8080/uploads/adverts/1/a.png","position":1,"is_main":false}`,
+		},
+		{
+			name:                 "No file",
+			inputURL:             "/adverts/1/pictures",
+			inputField:           "",
+			mockBehavior:         func(u *mock.MockUploader) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"file","code":"required","message":"the field \"file\" is required"}]}`,
+		},
+		{
+			name:       "Too many pictures",
+			inputURL:   "/adverts/1/pictures",
+			inputField: "file",
+			mockBehavior: func(u *mock.MockUploader) {
+				errs := &service.ValidationError{}
+				errs.Add("pictures", service.CodeMaxItems, `the field "pictures" must contain no more than 3 photos`, map[string]interface{}{"max": 3})
+				u.EXPECT().UploadPicture(owner, 1, gomock.Any()).Return(model.Picture{}, errs)
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"pictures","code":"max_items","message":"the field \"pictures\" must contain no more than 3 photos","params":{"max":3}}]}`,
+		},
+		{
+			name:       "Not found",
+			inputURL:   "/adverts/666/pictures",
+			inputField: "file",
+			mockBehavior: func(u *mock.MockUploader) {
+				u.EXPECT().UploadPicture(owner, 666, gomock.Any()).Return(model.Picture{}, repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
+		},
+		{
+			name:                 "Bad id",
+			inputURL:             "/adverts/one/pictures",
+			inputField:           "file",
+			mockBehavior:         func(u *mock.MockUploader) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"advertisement id must be integer"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockUploader := mock.NewMockUploader(c)
+			test.mockBehavior(mockUploader)
+
+			handler := NewHandler(nil, nil, nil, mockUploader, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/adverts/:id/pictures", signedIn(owner), handler.uploadPicture)
+
+			body, contentType := multipartBody(t, test.inputField, []byte("png data"))
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", test.inputURL, body)
+			req.Header.Set("Content-Type", contentType)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_reorderPictures(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockService)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		inputBody            string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Ok",
+			inputURL:  "/adverts/1/pictures/order",
+			inputBody: `{"pictures":[2,1]}`,
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().ReorderPictures(owner, 1, []int{2, 1}).Return(model.Pictures{
+					{Id: 2, URL: "avito/files/ad2", Position: 0},
+					{Id: 1, URL: "avito/files/ad1", Position: 1, IsMain: true},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"id":2,"url":"avito/files/ad2","position":0,"is_main":false},{"id":1,"url":"avito/files/ad1","position":1,"is_main":true}]`,
+		},
+		{
+			name:                 "Bad input",
+			inputURL:             "/adverts/1/pictures/order",
+			inputBody:            `{"pictures":"2,1"}`,
+			mockBehavior:         func(s *mock.MockService) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"pictures","code":"type","message":"the field \"pictures\" must be of type []int","params":{"type":"[]int"}}]}`,
+		},
+		{
+			name:      "Not found",
+			inputURL:  "/adverts/666/pictures/order",
+			inputBody: `{"pictures":[2,1]}`,
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().ReorderPictures(owner, 666, []int{2, 1}).Return(nil, repository.ErrAdvertNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"advertisement not found"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService)
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.PUT("/adverts/:id/pictures/order", signedIn(owner), handler.reorderPictures)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", test.inputURL, bytes.NewBufferString(test.inputBody))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_setMainPicture(t *testing.T) {
+	type mockBehaviorType func(s *mock.MockService)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		mockBehavior         mockBehaviorType
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Ok",
+			inputURL: "/adverts/1/pictures/2/main",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().SetMainPicture(owner, 1, 2).Return(model.Pictures{
+					{Id: 1, URL: "avito/files/ad1", Position: 0},
+					{Id: 2, URL: "avito/files/ad2", Position: 1, IsMain: true},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"id":1,"url":"avito/files/ad1","position":0,"is_main":false},{"id":2,"url":"avito/files/ad2","position":1,"is_main":true}]`,
+		},
+		{
+			name:                 "Bad picture id",
+			inputURL:             "/adverts/1/pictures/main/main",
+			mockBehavior:         func(s *mock.MockService) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"picture id must be integer"}`,
+		},
+		{
+			name:     "Picture not found",
+			inputURL: "/adverts/1/pictures/666/main",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().SetMainPicture(owner, 1, 666).Return(nil, repository.ErrPictureNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"picture not found"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService)
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/adverts/:id/pictures/:picture_id/main", signedIn(owner), handler.setMainPicture)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", test.inputURL, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
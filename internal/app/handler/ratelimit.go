@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/ratelimit"
+)
+
+// limit applies the rate limits to the request, it goes after authenticate
+// so the limits of the user and the API key apply. The state of the most
+// restrictive bucket is reported in the RateLimit-* headers, a refused
+// request is answered with 429 and Retry-After. The requests are let
+// through when the store fails.
+func (h *Handler) limit(ctx *gin.Context) {
+	if h.limiter == nil {
+		ctx.Next()
+		return
+	}
+	caller := principal(ctx)
+	result, err := h.limiter.Allow(ratelimit.Request{
+		Route:  ctx.Request.Method + " " + ctx.FullPath(),
+		IP:     ctx.ClientIP(),
+		UserId: caller.UserId,
+		KeyId:  caller.KeyId,
+	})
+	if err != nil {
+		log.Printf("rate limit of %s %s: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+		ctx.Next()
+		return
+	}
+	if result.Limit == 0 {
+		ctx.Next()
+		return
+	}
+
+	ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Header("RateLimit-Reset", wholeSeconds(result.Reset))
+	if !result.Allowed {
+		ctx.Header("Retry-After", wholeSeconds(result.RetryAfter))
+		ctx.Error(ratelimit.ErrLimited)
+		ctx.Abort()
+		return
+	}
+	ctx.Next()
+}
+
+// wholeSeconds rounds the duration up, the headers carry whole seconds.
+func wholeSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("rate limit: connection refused")
+}
+
+func (failingStore) Refund(key string, limit ratelimit.Limit, now time.Time) error {
+	return errors.New("rate limit: connection refused")
+}
+
+func TestHandler_limit(t *testing.T) {
+	rules := []ratelimit.Rule{
+		{Route: "POST /create", Key: ratelimit.KeyUser, Requests: 2, PeriodSec: 60},
+	}
+
+	tests := []struct {
+		name               string
+		store              ratelimit.Store
+		principal          model.Principal
+		requests           int
+		expectedStatusCode int
+		expectedHeaders    map[string]string
+		expectedBody       string
+	}{
+		{
+			name:               "Allowed",
+			store:              ratelimit.NewMemoryStore(),
+			principal:          model.Principal{UserId: 1, Role: model.RoleSeller},
+			requests:           2,
+			expectedStatusCode: 200,
+			expectedHeaders:    map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": ""},
+			expectedBody:       `{"status":"ok"}`,
+		},
+		{
+			name:               "Limited",
+			store:              ratelimit.NewMemoryStore(),
+			principal:          model.Principal{UserId: 1, Role: model.RoleSeller},
+			requests:           3,
+			expectedStatusCode: 429,
+			expectedHeaders:    map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": "30"},
+			expectedBody:       `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"rate limit exceeded"}`,
+		},
+		{
+			name:               "No rule applies",
+			store:              ratelimit.NewMemoryStore(),
+			requests:           3,
+			expectedStatusCode: 200,
+			expectedHeaders:    map[string]string{"RateLimit-Limit": "", "Retry-After": ""},
+			expectedBody:       `{"status":"ok"}`,
+		},
+		{
+			name:               "Store failed",
+			store:              failingStore{},
+			principal:          model.Principal{UserId: 1, Role: model.RoleSeller},
+			requests:           3,
+			expectedStatusCode: 200,
+			expectedHeaders:    map[string]string{"RateLimit-Limit": "", "Retry-After": ""},
+			expectedBody:       `{"status":"ok"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := ratelimit.NewLimiter(test.store, rules)
+			handler := NewHandler(nil, nil, nil, nil, nil, nil, limiter, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/create", signedIn(test.principal), handler.limit, func(ctx *gin.Context) {
+				ctx.JSON(200, statusMessage{"ok"})
+			})
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < test.requests; i++ {
+				w = httptest.NewRecorder()
+				req := httptest.NewRequest("POST", "/create", nil)
+				router.ServeHTTP(w, req)
+			}
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			for header, value := range test.expectedHeaders {
+				assert.Equal(t, w.Header().Get(header), value)
+			}
+			assert.Equal(t, w.Body.String(), test.expectedBody)
+		})
+	}
+}
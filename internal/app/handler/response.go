@@ -1,15 +1,51 @@
 package handler
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
 
-type errorMessage struct {
-	Message string `json:"error"`
-}
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+const problemContentType = "application/problem+json"
 
 type statusMessage struct {
 	Status string `json:"status"`
 }
 
+type createMessage struct {
+	Id    int            `json:"id"`
+	Rules model.RuleHits `json:"rules"`
+}
+
+// problem is an RFC 7807 problem details object. Errors and Rules are
+// extension members filled for validation failures and blocked adverts.
+type problem struct {
+	Type   string               `json:"type"`
+	Title  string               `json:"title"`
+	Status int                  `json:"status"`
+	Detail string               `json:"detail,omitempty"`
+	Errors []service.FieldError `json:"errors,omitempty"`
+	Rules  model.RuleHits       `json:"rules,omitempty"`
+	// Permission is the permission the caller lacks
+	Permission string `json:"permission,omitempty"`
+}
+
+func newProblem(statusCode int, detail string) problem {
+	return problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+	}
+}
+
+func sendProblem(ctx *gin.Context, p problem) {
+	ctx.Header("Content-Type", problemContentType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
 func SendErrorResponse(ctx *gin.Context, statusCode int, message string) {
-	ctx.AbortWithStatusJSON(statusCode, errorMessage{message})
+	sendProblem(ctx, newProblem(statusCode, message))
 }
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+// @Summary полнотекстовый поиск объявлений
+// @Tags Advert
+// @Description Поиск по названию и описанию с учетом морфологии (русский и английский). Поддерживаются фразы в кавычках, OR и исключение слов через минус
+// @ID search-adverts
+// @Accept  html
+// @Produce  json
+// @Param q query string true "Search query, e.g. \"горный велосипед\" -детский"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Number of adverts on a page, bounded by max_page_size"
+// @Param order_by query string false "Order, relevance by default" Enums(relevance, price_desc, price_asc, createdat_desc, createdat_asc)
+// @Param count query string false "How the total is counted" Enums(exact, estimated)
+// @Param price_min query int false "Minimal price"
+// @Param price_max query int false "Maximal price"
+// @Param created_from query string false "Created at or after, RFC 3339 timestamp or date (2021-07-01)"
+// @Param created_to query string false "Created at or before, RFC 3339 timestamp or date (the whole day)"
+// @Param has_pictures query bool false "Only adverts with (true) or without (false) pictures"
+// @Param category_id query int false "Category, the adverts of its subcategories are included"
+// @Param attr.name query string false "Attribute filters: attr.<name>=<value> for an equal value, attr.<name>_min and attr.<name>_max for a range of a number"
+// @Param near query string false "Center of the radius: lat,lon (55.75,37.62)"
+// @Param radius_km query number false "Only adverts within the radius from near, in kilometers"
+// @Param bbox query string false "Only adverts in the box: south,west,north,east"
+// @Success 200 {object} SearchMessageOk
+// @Failure 422 {object} ValidationMessage422
+// @Failure 500 {object} ListMessage500
+// @Router /search [get]
+func (h *Handler) search(ctx *gin.Context) {
+	//..../search?q="горный велосипед" -детский&price_max=20000&order_by=price_asc
+	list, err := parseListQuery(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// q is the search text here, not the substring filter of the list, and
+	// archived adverts and cursors are not part of the search
+	query := model.SearchQuery{ListQuery: list, Text: list.Q}
+	query.Q, query.Cursor, query.IncludeArchived = "", "", false
+	if ctx.Query("order_by") == model.SearchRelevance {
+		query.OrderBy = model.SearchRelevance
+	}
+
+	result, err := h.service.SearchAdverts(principal(ctx), query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result.Links = model.ListLinks{
+		Self:  ctx.Request.URL.RequestURI(),
+		First: pageURL(ctx, 1),
+		Last:  pageURL(ctx, result.TotalPages),
+	}
+	if result.Page > 1 {
+		result.Links.Prev = pageURL(ctx, result.Page-1)
+	}
+	if result.Page < result.TotalPages {
+		result.Links.Next = pageURL(ctx, result.Page+1)
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// @Summary подсказки названий объявлений
+// @Tags Advert
+// @Description Дополняет набираемый текст до названий опубликованных объявлений, начиная с любого слова названия. Если ничего не найдено, исправляет опечатки и возвращает исправленный текст в did_you_mean
+// @ID suggest
+// @Accept  html
+// @Produce  json
+// @Param prefix query string true "Typed text, e.g. горный вел"
+// @Param limit query int false "Number of completions, 10 by default, at most 20"
+// @Success 200 {object} SuggestMessageOk
+// @Failure 422 {object} ValidationMessage422
+// @Failure 500 {object} ListMessage500
+// @Router /suggest [get]
+func (h *Handler) suggest(ctx *gin.Context) {
+	//..../suggest?prefix=горный вел&limit=5
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	suggestions, err := h.service.Suggest(principal(ctx), ctx.Query("prefix"), limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, suggestions)
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+func TestHandler_search(t *testing.T) {
+	type mockBehaviorType func(*mock.MockService)
+	intPtr := func(i int) *int { return &i }
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		mockBehavior         mockBehaviorType
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Ok",
+			inputURL: "/search?q=%22mountain+bike%22&price_max=20000&page=2&order_by=relevance&include_archived=true",
+			mockBehavior: func(s *mock.MockService) {
+				query := model.SearchQuery{
+					ListQuery: model.ListQuery{Page: 2, OrderBy: "relevance", PriceMax: intPtr(20000)},
+					Text:      `"mountain bike"`,
+				}
+				s.EXPECT().SearchAdverts(model.Principal{}, query).Return(model.SearchResult{
+					Items: []model.SearchHit{{
+						Advert:              model.Advert{Id: 7, Name: "Mountain bike", Price: 15000},
+						Rank:                0.5,
+						NameHeadline:        "<b>Mountain</b> <b>bike</b>",
+						DescriptionHeadline: "selling a <b>mountain</b> <b>bike</b>",
+					}},
+					Page:       2,
+					PageSize:   1,
+					Total:      3,
+					TotalPages: 3,
+				}, nil)
+			},
+			expectedResponseCode: 200,
+			expectedResponseBody: `{"items":[{"id":7,"name":"Mountain bike","price":15000,"rank":0.5,` +
+				`"name-headline":"\u003cb\u003eMountain\u003c/b\u003e \u003cb\u003ebike\u003c/b\u003e",` +
+				`"description-headline":"selling a \u003cb\u003emountain\u003c/b\u003e \u003cb\u003ebike\u003c/b\u003e"}],` +
+				`"page":2,"page_size":1,"total":3,"total_pages":3,"links":{` +
+				`"self":"/search?q=%22mountain+bike%22\u0026price_max=20000\u0026page=2\u0026order_by=relevance\u0026include_archived=true",` +
+				`"first":"/search?include_archived=true\u0026order_by=relevance\u0026page=1\u0026price_max=20000\u0026q=%22mountain+bike%22",` +
+				`"last":"/search?include_archived=true\u0026order_by=relevance\u0026page=3\u0026price_max=20000\u0026q=%22mountain+bike%22",` +
+				`"prev":"/search?include_archived=true\u0026order_by=relevance\u0026page=1\u0026price_max=20000\u0026q=%22mountain+bike%22",` +
+				`"next":"/search?include_archived=true\u0026order_by=relevance\u0026page=3\u0026price_max=20000\u0026q=%22mountain+bike%22"}}`,
+		},
+		{
+			name:     "Missing query",
+			inputURL: "/search",
+			mockBehavior: func(s *mock.MockService) {
+				errs := &service.ValidationError{}
+				errs.Add("q", service.CodeRequired, `the field "q" is required`, nil)
+				s.EXPECT().SearchAdverts(model.Principal{}, model.SearchQuery{ListQuery: model.ListQuery{Page: 1}}).Return(model.SearchResult{}, errs)
+			},
+			expectedResponseCode: 422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"q","code":"required","message":"the field \"q\" is required"}]}`,
+		},
+		{
+			name:                 "Malformed filter",
+			inputURL:             "/search?q=bike&has_pictures=maybe",
+			mockBehavior:         func(s *mock.MockService) {},
+			expectedResponseCode: 422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"has_pictures","code":"type","message":"the field \"has_pictures\" must be of type bool","params":{"type":"bool"}}]}`,
+		},
+		{
+			name:     "Server error",
+			inputURL: "/search?q=bike",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().SearchAdverts(model.Principal{}, model.SearchQuery{ListQuery: model.ListQuery{Page: 1}, Text: "bike"}).Return(model.SearchResult{}, errors.New("something went wrong"))
+			},
+			expectedResponseCode: 500,
+			expectedResponseBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService)
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.GET("/search", handler.search)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.inputURL, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedResponseCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_suggest(t *testing.T) {
+	type mockBehaviorType func(*mock.MockService)
+
+	tests := []struct {
+		name                 string
+		inputURL             string
+		mockBehavior         mockBehaviorType
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Ok",
+			inputURL: "/suggest?prefix=%D0%B3%D0%BE%D1%80%D0%BD%D1%8B%D0%B9+%D0%B2%D0%B5%D0%BB&limit=2",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().Suggest(model.Principal{}, "горный вел", 2).Return(model.Suggestions{
+					Prefix:      "горный вел",
+					Completions: []model.Completion{{Text: "Горный велосипед", Count: 3}, {Text: "Горный велосипед Stels", Count: 1}},
+				}, nil)
+			},
+			expectedResponseCode: 200,
+			expectedResponseBody: `{"prefix":"горный вел","completions":[{"text":"Горный велосипед","count":3},{"text":"Горный велосипед Stels","count":1}]}`,
+		},
+		{
+			name:     "Did you mean",
+			inputURL: "/suggest?prefix=velo&limit=many",
+			mockBehavior: func(s *mock.MockService) {
+				s.EXPECT().Suggest(model.Principal{}, "velo", 0).Return(model.Suggestions{
+					Prefix:      "velo",
+					Completions: []model.Completion{{Text: "Vello bike", Count: 1}},
+					DidYouMean:  "vello",
+				}, nil)
+			},
+			expectedResponseCode: 200,
+			expectedResponseBody: `{"prefix":"velo","completions":[{"text":"Vello bike","count":1}],"did_you_mean":"vello"}`,
+		},
+		{
+			name:     "Missing prefix",
+			inputURL: "/suggest",
+			mockBehavior: func(s *mock.MockService) {
+				errs := &service.ValidationError{}
+				errs.Add("prefix", service.CodeRequired, `the field "prefix" is required`, nil)
+				s.EXPECT().Suggest(model.Principal{}, "", 0).Return(model.Suggestions{}, errs)
+			},
+			expectedResponseCode: 422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[{"field":"prefix","code":"required","message":"the field \"prefix\" is required"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock.NewMockService(c)
+			test.mockBehavior(mockService)
+
+			handler := NewHandler(mockService, nil, nil, nil, nil, nil, nil, "")
+			router := gin.New()
+			router.Use(handleErrors)
+			router.GET("/suggest", handler.suggest)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.inputURL, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedResponseCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
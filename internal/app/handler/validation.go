@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/paramonies/avito-rest-advert/internal/app/service"
+)
+
+// bindJSON decodes the request body into obj. On failure it reports the
+// same field errors the service returns, so clients handle both alike.
+func bindJSON(ctx *gin.Context, obj interface{}) bool {
+	if err := ctx.ShouldBindJSON(obj); err != nil {
+		ctx.Error(bindingError(obj, err))
+		return false
+	}
+	return true
+}
+
+func bindingError(obj interface{}, err error) *service.ValidationError {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return service.NewDecodeError(err)
+	}
+
+	errs := &service.ValidationError{}
+	for _, fieldError := range fieldErrors {
+		field := jsonFieldName(obj, fieldError.StructField())
+		switch fieldError.Tag() {
+		case "required":
+			errs.Add(field, service.CodeRequired, fmt.Sprintf(`the field "%s" is required`, field), nil)
+		default:
+			var params map[string]interface{}
+			if fieldError.Param() != "" {
+				params = map[string]interface{}{fieldError.Tag(): fieldError.Param()}
+			}
+			errs.Add(field, fieldError.Tag(), fmt.Sprintf(`the field "%s" is invalid`, field), params)
+		}
+	}
+	return errs
+}
+
+// jsonFieldName maps a struct field to the key clients send it under.
+func jsonFieldName(obj interface{}, structField string) string {
+	objType := reflect.TypeOf(obj)
+	for objType.Kind() == reflect.Ptr {
+		objType = objType.Elem()
+	}
+
+	field, ok := objType.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return structField
+	}
+	return name
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestHandler_bindJSON(t *testing.T) {
+	type input struct {
+		Title  string `json:"title" binding:"required"`
+		Rating int    `json:"rating,omitempty" binding:"max=5"`
+		Note   string `binding:"required"`
+	}
+
+	tests := []struct {
+		name                 string
+		inputBody            string
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Ok",
+			inputBody:            `{"title":"title-test", "rating":5, "Note":"note-test"}`,
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:               "Invalid fields",
+			inputBody:          `{"rating":6}`,
+			expectedStatusCode: 422,
+			expectedResponseBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"validation failed","errors":[` +
+				`{"field":"title","code":"required","message":"the field \"title\" is required"},` +
+				`{"field":"rating","code":"max","message":"the field \"rating\" is invalid","params":{"max":"5"}},` +
+				`{"field":"Note","code":"required","message":"the field \"Note\" is required"}]}`,
+		},
+		{
+			name:                 "Empty body",
+			inputBody:            ``,
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"validation failed","errors":[{"field":"","code":"malformed","message":"invalid input body"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(handleErrors)
+			router.POST("/bind", func(ctx *gin.Context) {
+				var in input
+				if !bindJSON(ctx, &in) {
+					return
+				}
+				ctx.JSON(200, statusMessage{"ok"})
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/bind", bytes.NewBufferString(test.inputBody))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, test.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+		})
+	}
+}
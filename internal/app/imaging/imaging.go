@@ -0,0 +1,83 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // registers the gif decoder for image.Decode
+	"image/jpeg"
+	"image/png"
+)
+
+// MaxPixels protects the workers from decompression bombs: a small file
+// may declare a huge canvas that would not fit in memory once decoded.
+const MaxPixels = 50000000
+
+var (
+	ErrUnsupported = errors.New("unsupported image format")
+	ErrTooLarge    = errors.New("image dimensions are too large")
+)
+
+// Variant is a resized copy of a picture, it fits a MaxSize x MaxSize box.
+type Variant struct {
+	Name    string
+	MaxSize int
+}
+
+var Variants = []Variant{
+	{Name: "thumb", MaxSize: 160},
+	{Name: "medium", MaxSize: 640},
+	{Name: "large", MaxSize: 1280},
+}
+
+// Image is an encoded variant ready to be stored.
+type Image struct {
+	Variant     string
+	ContentType string
+	Ext         string
+	Data        []byte
+}
+
+// Generate decodes the picture and encodes every variant. The EXIF
+// orientation of jpeg pictures is applied, since re-encoding drops the
+// metadata along with it. Jpeg pictures are encoded as jpeg, others as png
+// to keep transparency. Webp can not be decoded by the standard library
+// and is reported as ErrUnsupported.
+func Generate(data []byte) ([]Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if format == "jpeg" {
+		src = Orient(src, JPEGOrientation(data))
+	}
+
+	images := make([]Image, 0, len(Variants))
+	for _, variant := range Variants {
+		resized := Fit(src, variant.MaxSize)
+
+		var buf bytes.Buffer
+		img := Image{Variant: variant.Name}
+		if format == "jpeg" {
+			img.ContentType, img.Ext = "image/jpeg", ".jpg"
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+		} else {
+			img.ContentType, img.Ext = "image/png", ".png"
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, err
+		}
+		img.Data = buf.Bytes()
+		images = append(images, img)
+	}
+	return images, nil
+}
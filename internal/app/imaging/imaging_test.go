@@ -0,0 +1,98 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestGenerate(t *testing.T) {
+	images, err := Generate(encodeJPEG(t, testImage(2000, 1000)))
+	require.NoError(t, err)
+	require.Len(t, images, len(Variants))
+
+	expectedSizes := map[string]image.Point{
+		"thumb":  {160, 80},
+		"medium": {640, 320},
+		"large":  {1280, 640},
+	}
+	for _, img := range images {
+		assert.Equal(t, "image/jpeg", img.ContentType)
+		assert.Equal(t, ".jpg", img.Ext)
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, expectedSizes[img.Variant], image.Point{config.Width, config.Height}, img.Variant)
+	}
+}
+
+func TestGenerate_PNG(t *testing.T) {
+	images, err := Generate(encodePNG(t, testImage(100, 300)))
+	require.NoError(t, err)
+
+	for _, img := range images {
+		assert.Equal(t, "image/png", img.ContentType)
+		config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		require.NoError(t, err)
+		if img.Variant == "thumb" {
+			assert.Equal(t, image.Point{53, 160}, image.Point{config.Width, config.Height})
+		} else {
+			assert.Equal(t, image.Point{100, 300}, image.Point{config.Width, config.Height}, "small images are not scaled up")
+		}
+	}
+}
+
+func TestGenerate_Orientation(t *testing.T) {
+	data := withOrientation(encodeJPEG(t, testImage(400, 200)), 6)
+
+	images, err := Generate(data)
+	require.NoError(t, err)
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(images[0].Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Point{80, 160}, image.Point{config.Width, config.Height})
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+	_, err := Generate([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "))
+	assert.Equal(t, ErrUnsupported, err)
+}
+
+func TestGenerate_TooLarge(t *testing.T) {
+	// a valid png header declaring a 10000 x 10000 canvas
+	img := image.NewGray(image.Rect(0, 0, 10000, 10000))
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	_, err := Generate(buf.Bytes())
+	assert.Equal(t, ErrTooLarge, err)
+}
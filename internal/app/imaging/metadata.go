@@ -0,0 +1,229 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/jpeg"
+)
+
+var errMalformed = errors.New("malformed image")
+
+// StripMetadata removes EXIF, XMP and text metadata (camera, GPS position,
+// comments) from jpeg, png and webp files without re-encoding them. A jpeg
+// with a non-default orientation is re-encoded upright instead, otherwise
+// it would be shown rotated once the orientation tag is gone. Other formats
+// are returned unchanged. ErrTooLarge is returned for a jpeg to re-encode
+// with more than MaxPixels pixels.
+func StripMetadata(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		if JPEGOrientation(data) > 1 {
+			return reencodeJPEG(data)
+		}
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	}
+	return data, nil
+}
+
+// JPEGOrientation reads the EXIF orientation tag, 1 (upright) is returned
+// when there is none.
+func JPEGOrientation(data []byte) int {
+	orientation := 1
+	walkJPEG(data, func(marker byte, segment, _ []byte) bool {
+		if marker != 0xE1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return true
+		}
+		if value, ok := exifOrientation(segment[6:]); ok {
+			orientation = value
+		}
+		return false
+	})
+	return orientation
+}
+
+func exifOrientation(tiff []byte) (int, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0, false
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:])), true
+		}
+	}
+	return 0, false
+}
+
+// walkJPEG calls fn for every segment before the image data with the
+// marker, the segment payload and the whole segment including the marker.
+// It stops when fn returns false. The offset of the start of scan segment
+// is returned.
+func walkJPEG(data []byte, fn func(marker byte, payload, segment []byte) bool) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, errMalformed
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 0, errMalformed
+		}
+		marker := data[pos+1]
+		if marker == 0xFF { // fill byte
+			pos++
+			continue
+		}
+		if marker == 0xDA { // start of scan, entropy coded data follows
+			return pos, nil
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) { // no payload
+			pos += 2
+			continue
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 0, errMalformed
+		}
+		if !fn(marker, data[pos+4:pos+2+length], data[pos:pos+2+length]) {
+			return pos, nil
+		}
+		pos += 2 + length
+	}
+	return 0, errMalformed
+}
+
+// stripJPEG drops the APP1 (EXIF, XMP), APP13 (IPTC) and comment segments.
+// JFIF, ICC profile and Adobe segments are kept, they affect the colors.
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+
+	sos, err := walkJPEG(data, func(marker byte, _, segment []byte) bool {
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, segment...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[sos:]...), nil
+}
+
+func reencodeJPEG(data []byte) ([]byte, error) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, Orient(img, JPEGOrientation(data)), &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripPNG drops the eXIf and text chunks.
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length // length, type, data, crc
+		if length < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+
+		switch string(data[pos+4 : pos+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt":
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out, nil
+}
+
+// stripWebP drops the EXIF and XMP chunks and clears their flags in the
+// extended header.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformed
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	pos := 12
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errMalformed
+		}
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size%2 // chunks are padded to an even size
+		if end == len(data)+1 {
+			end-- // some encoders omit the padding of the last chunk
+		}
+		if size < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+
+		switch string(data[pos : pos+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP flags
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withOrientation inserts an EXIF segment with the orientation tag right
+// after the start of image marker.
+func withOrientation(data []byte, orientation uint16) []byte {
+	exif := []byte("Exif\x00\x00MM\x00\x2A\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	exif = append(exif, entry...)
+	exif = append(exif, 0, 0, 0, 0) // no next IFD
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+	segment = append(segment, exif...)
+
+	out := append([]byte{0xFF, 0xD8}, segment...)
+	return append(out, data[2:]...)
+}
+
+func pngChunk(chunkType string, payload []byte) []byte {
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, payload...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	return append(chunk, crc...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	data := encodeJPEG(t, testImage(4, 4))
+
+	assert.Equal(t, 1, JPEGOrientation(data))
+	assert.Equal(t, 6, JPEGOrientation(withOrientation(data, 6)))
+	assert.Equal(t, 1, JPEGOrientation([]byte("not a jpeg")))
+}
+
+func TestStripMetadata_JPEG(t *testing.T) {
+	original := encodeJPEG(t, testImage(8, 4))
+	data := withOrientation(original, 1)
+	data = append(data[:2], append([]byte{0xFF, 0xFE, 0x00, 0x07, 'h', 'e', 'l', 'l', 'o'}, data[2:]...)...)
+
+	stripped, err := StripMetadata(data, "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, original, stripped)
+}
+
+func TestStripMetadata_JPEGRotated(t *testing.T) {
+	data := withOrientation(encodeJPEG(t, testImage(8, 4)), 8)
+
+	stripped, err := StripMetadata(data, "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, 1, JPEGOrientation(stripped))
+	assert.False(t, bytes.Contains(stripped, []byte("Exif")))
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(stripped))
+	require.NoError(t, err)
+	assert.Equal(t, image.Point{4, 8}, image.Point{config.Width, config.Height})
+}
+
+func TestStripMetadata_JPEGRotatedTooLarge(t *testing.T) {
+	data := withOrientation(encodeJPEG(t, testImage(8, 4)), 6)
+	// declare a 10000x10000 canvas in the start of frame segment
+	sof := bytes.Index(data, []byte{0xFF, 0xC0})
+	require.True(t, sof > 0)
+	binary.BigEndian.PutUint16(data[sof+5:], 10000)
+	binary.BigEndian.PutUint16(data[sof+7:], 10000)
+
+	_, err := StripMetadata(data, "image/jpeg")
+	assert.Equal(t, ErrTooLarge, err)
+}
+
+func TestStripMetadata_PNG(t *testing.T) {
+	original := encodePNG(t, testImage(4, 4))
+	// the text chunk goes right after IHDR (8 bytes signature + 25 bytes chunk)
+	data := append([]byte(nil), original[:33]...)
+	data = append(data, pngChunk("tEXt", []byte("Author\x00somebody"))...)
+	data = append(data, pngChunk("eXIf", []byte("MM\x00\x2A\x00\x00\x00\x08"))...)
+	data = append(data, original[33:]...)
+
+	stripped, err := StripMetadata(data, "image/png")
+	require.NoError(t, err)
+	assert.Equal(t, original, stripped)
+}
+
+func TestStripMetadata_WebP(t *testing.T) {
+	vp8x := []byte("VP8X\x0A\x00\x00\x00\x0C\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	vp8l := []byte("VP8L\x05\x00\x00\x00\x2F\x00\x00\x00\x00\x00")
+	exif := []byte("EXIF\x03\x00\x00\x00abc\x00")
+
+	build := func(chunks ...[]byte) []byte {
+		data := []byte("RIFF\x00\x00\x00\x00WEBP")
+		for _, chunk := range chunks {
+			data = append(data, chunk...)
+		}
+		binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+		return data
+	}
+
+	stripped, err := StripMetadata(build(vp8x, vp8l, exif), "image/webp")
+	require.NoError(t, err)
+
+	cleared := append([]byte(nil), vp8x...)
+	cleared[8] = 0x00
+	assert.Equal(t, build(cleared, vp8l), stripped)
+}
+
+func TestStripMetadata_Malformed(t *testing.T) {
+	for _, contentType := range []string{"image/jpeg", "image/png", "image/webp"} {
+		_, err := StripMetadata([]byte("garbage"), contentType)
+		assert.Error(t, err, contentType)
+	}
+
+	data := []byte("GIF89a")
+	stripped, err := StripMetadata(data, "image/gif")
+	assert.NoError(t, err)
+	assert.Equal(t, data, stripped)
+}
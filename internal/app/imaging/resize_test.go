@@ -0,0 +1,70 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name         string
+		width        int
+		height       int
+		size         int
+		expectedSize image.Point
+	}{
+		{name: "Landscape", width: 1000, height: 500, size: 100, expectedSize: image.Point{100, 50}},
+		{name: "Portrait", width: 300, height: 900, size: 90, expectedSize: image.Point{30, 90}},
+		{name: "Thin", width: 1000, height: 2, size: 100, expectedSize: image.Point{100, 1}},
+		{name: "Small", width: 40, height: 20, size: 100, expectedSize: image.Point{40, 20}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := Fit(image.NewRGBA(image.Rect(0, 0, test.width, test.height)), test.size)
+			assert.Equal(t, test.expectedSize, img.Bounds().Size())
+		})
+	}
+}
+
+func TestResize_Averages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 200, A: 255})
+	img.Set(1, 0, color.RGBA{R: 100, A: 255})
+	img.Set(0, 1, color.RGBA{B: 200, A: 255})
+	img.Set(1, 1, color.RGBA{B: 100, A: 255})
+
+	resized := Resize(img, 1, 1)
+	assert.Equal(t, color.RGBA{R: 75, B: 75, A: 255}, resized.RGBAAt(0, 0))
+}
+
+func TestOrient(t *testing.T) {
+	// 3 x 2 image with a marked top left corner
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	marked := color.RGBA{R: 255, A: 255}
+	img.Set(0, 0, marked)
+
+	tests := []struct {
+		orientation    int
+		expectedSize   image.Point
+		expectedMarked image.Point
+	}{
+		{orientation: 1, expectedSize: image.Point{3, 2}, expectedMarked: image.Point{0, 0}},
+		{orientation: 2, expectedSize: image.Point{3, 2}, expectedMarked: image.Point{2, 0}},
+		{orientation: 3, expectedSize: image.Point{3, 2}, expectedMarked: image.Point{2, 1}},
+		{orientation: 4, expectedSize: image.Point{3, 2}, expectedMarked: image.Point{0, 1}},
+		{orientation: 5, expectedSize: image.Point{2, 3}, expectedMarked: image.Point{0, 0}},
+		{orientation: 6, expectedSize: image.Point{2, 3}, expectedMarked: image.Point{1, 0}},
+		{orientation: 7, expectedSize: image.Point{2, 3}, expectedMarked: image.Point{1, 2}},
+		{orientation: 8, expectedSize: image.Point{2, 3}, expectedMarked: image.Point{0, 2}},
+	}
+
+	for _, test := range tests {
+		oriented := toRGBA(Orient(img, test.orientation))
+		assert.Equal(t, test.expectedSize, oriented.Bounds().Size(), "orientation %d", test.orientation)
+		assert.Equal(t, marked, oriented.RGBAAt(test.expectedMarked.X, test.expectedMarked.Y), "orientation %d", test.orientation)
+	}
+}
@@ -6,6 +6,7 @@ package mock
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/paramonies/avito-rest-advert/internal/app/model"
@@ -34,6 +35,50 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddPicture mocks base method.
+func (m *MockRepository) AddPicture(arg0 int, arg1 string, arg2 int) (model.Picture, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPicture", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.Picture)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddPicture indicates an expected call of AddPicture.
+func (mr *MockRepositoryMockRecorder) AddPicture(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPicture", reflect.TypeOf((*MockRepository)(nil).AddPicture), arg0, arg1, arg2)
+}
+
+// ArchiveAdvert mocks base method.
+func (m *MockRepository) ArchiveAdvert(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveAdvert", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ArchiveAdvert indicates an expected call of ArchiveAdvert.
+func (mr *MockRepositoryMockRecorder) ArchiveAdvert(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveAdvert", reflect.TypeOf((*MockRepository)(nil).ArchiveAdvert), arg0)
+}
+
+// CountAdverts mocks base method.
+func (m *MockRepository) CountAdverts(arg0 model.ListQuery, arg1 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountAdverts", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountAdverts indicates an expected call of CountAdverts.
+func (mr *MockRepositoryMockRecorder) CountAdverts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountAdverts", reflect.TypeOf((*MockRepository)(nil).CountAdverts), arg0, arg1)
+}
+
 // CreateAdvert mocks base method.
 func (m *MockRepository) CreateAdvert(arg0 model.Advert) (int, error) {
 	m.ctrl.T.Helper()
@@ -49,6 +94,20 @@ func (mr *MockRepositoryMockRecorder) CreateAdvert(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdvert", reflect.TypeOf((*MockRepository)(nil).CreateAdvert), arg0)
 }
 
+// DeleteAdvert mocks base method.
+func (m *MockRepository) DeleteAdvert(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAdvert", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAdvert indicates an expected call of DeleteAdvert.
+func (mr *MockRepositoryMockRecorder) DeleteAdvert(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAdvert", reflect.TypeOf((*MockRepository)(nil).DeleteAdvert), arg0)
+}
+
 // GetAdvertById mocks base method.
 func (m *MockRepository) GetAdvertById(arg0 int) (model.Advert, error) {
 	m.ctrl.T.Helper()
@@ -65,16 +124,548 @@ func (mr *MockRepositoryMockRecorder) GetAdvertById(arg0 interface{}) *gomock.Ca
 }
 
 // GetAdvertList mocks base method.
-func (m *MockRepository) GetAdvertList(arg0 int, arg1, arg2 string) ([]model.Advert, error) {
+func (m *MockRepository) GetAdvertList(arg0 model.ListQuery, arg1 model.ListPage) ([]model.Advert, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAdvertList", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetAdvertList", arg0, arg1)
 	ret0, _ := ret[0].([]model.Advert)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAdvertList indicates an expected call of GetAdvertList.
-func (mr *MockRepositoryMockRecorder) GetAdvertList(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetAdvertList(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertList", reflect.TypeOf((*MockRepository)(nil).GetAdvertList), arg0, arg1)
+}
+
+// GetAdvertOwner mocks base method.
+func (m *MockRepository) GetAdvertOwner(arg0 int) (*int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdvertOwner", arg0)
+	ret0, _ := ret[0].(*int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAdvertOwner indicates an expected call of GetAdvertOwner.
+func (mr *MockRepositoryMockRecorder) GetAdvertOwner(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertOwner", reflect.TypeOf((*MockRepository)(nil).GetAdvertOwner), arg0)
+}
+
+// GetAdvertsByOwner mocks base method.
+func (m *MockRepository) GetAdvertsByOwner(arg0 int) ([]model.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdvertsByOwner", arg0)
+	ret0, _ := ret[0].([]model.Advert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAdvertsByOwner indicates an expected call of GetAdvertsByOwner.
+func (mr *MockRepositoryMockRecorder) GetAdvertsByOwner(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertsByOwner", reflect.TypeOf((*MockRepository)(nil).GetAdvertsByOwner), arg0)
+}
+
+// GetPicturesToCheck mocks base method.
+func (m *MockRepository) GetPicturesToCheck(arg0 int, arg1 time.Time) ([]model.Picture, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPicturesToCheck", arg0, arg1)
+	ret0, _ := ret[0].([]model.Picture)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPicturesToCheck indicates an expected call of GetPicturesToCheck.
+func (mr *MockRepositoryMockRecorder) GetPicturesToCheck(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPicturesToCheck", reflect.TypeOf((*MockRepository)(nil).GetPicturesToCheck), arg0, arg1)
+}
+
+// GetSearchDocument mocks base method.
+func (m *MockRepository) GetSearchDocument(arg0 int) (model.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSearchDocument", arg0)
+	ret0, _ := ret[0].(model.Advert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSearchDocument indicates an expected call of GetSearchDocument.
+func (mr *MockRepositoryMockRecorder) GetSearchDocument(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSearchDocument", reflect.TypeOf((*MockRepository)(nil).GetSearchDocument), arg0)
+}
+
+// GetSearchDocuments mocks base method.
+func (m *MockRepository) GetSearchDocuments(arg0, arg1 int) ([]model.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSearchDocuments", arg0, arg1)
+	ret0, _ := ret[0].([]model.Advert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSearchDocuments indicates an expected call of GetSearchDocuments.
+func (mr *MockRepositoryMockRecorder) GetSearchDocuments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSearchDocuments", reflect.TypeOf((*MockRepository)(nil).GetSearchDocuments), arg0, arg1)
+}
+
+// ReorderPictures mocks base method.
+func (m *MockRepository) ReorderPictures(arg0 int, arg1 []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReorderPictures", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReorderPictures indicates an expected call of ReorderPictures.
+func (mr *MockRepositoryMockRecorder) ReorderPictures(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReorderPictures", reflect.TypeOf((*MockRepository)(nil).ReorderPictures), arg0, arg1)
+}
+
+// RestoreAdvert mocks base method.
+func (m *MockRepository) RestoreAdvert(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreAdvert", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreAdvert indicates an expected call of RestoreAdvert.
+func (mr *MockRepositoryMockRecorder) RestoreAdvert(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreAdvert", reflect.TypeOf((*MockRepository)(nil).RestoreAdvert), arg0)
+}
+
+// SetMainPicture mocks base method.
+func (m *MockRepository) SetMainPicture(arg0, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMainPicture", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMainPicture indicates an expected call of SetMainPicture.
+func (mr *MockRepositoryMockRecorder) SetMainPicture(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMainPicture", reflect.TypeOf((*MockRepository)(nil).SetMainPicture), arg0, arg1)
+}
+
+// SetPictureBroken mocks base method.
+func (m *MockRepository) SetPictureBroken(arg0 int, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPictureBroken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPictureBroken indicates an expected call of SetPictureBroken.
+func (mr *MockRepositoryMockRecorder) SetPictureBroken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPictureBroken", reflect.TypeOf((*MockRepository)(nil).SetPictureBroken), arg0, arg1)
+}
+
+// SetPictureVariants mocks base method.
+func (m *MockRepository) SetPictureVariants(arg0 int, arg1 string, arg2 model.PictureVariants) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPictureVariants", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPictureVariants indicates an expected call of SetPictureVariants.
+func (mr *MockRepositoryMockRecorder) SetPictureVariants(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPictureVariants", reflect.TypeOf((*MockRepository)(nil).SetPictureVariants), arg0, arg1, arg2)
+}
+
+// UpdateAdvert mocks base method.
+func (m *MockRepository) UpdateAdvert(arg0 int, arg1 model.Advert) (model.Pictures, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAdvert", arg0, arg1)
+	ret0, _ := ret[0].(model.Pictures)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAdvert indicates an expected call of UpdateAdvert.
+func (mr *MockRepositoryMockRecorder) UpdateAdvert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAdvert", reflect.TypeOf((*MockRepository)(nil).UpdateAdvert), arg0, arg1)
+}
+
+// UpdateAdvertStatus mocks base method.
+func (m *MockRepository) UpdateAdvertStatus(arg0 int, arg1, arg2 model.AdvertStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAdvertStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAdvertStatus indicates an expected call of UpdateAdvertStatus.
+func (mr *MockRepositoryMockRecorder) UpdateAdvertStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAdvertStatus", reflect.TypeOf((*MockRepository)(nil).UpdateAdvertStatus), arg0, arg1, arg2)
+}
+
+// MockModerationRepository is a mock of ModerationRepository interface.
+type MockModerationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockModerationRepositoryMockRecorder
+}
+
+// MockModerationRepositoryMockRecorder is the mock recorder for MockModerationRepository.
+type MockModerationRepositoryMockRecorder struct {
+	mock *MockModerationRepository
+}
+
+// NewMockModerationRepository creates a new mock instance.
+func NewMockModerationRepository(ctrl *gomock.Controller) *MockModerationRepository {
+	mock := &MockModerationRepository{ctrl: ctrl}
+	mock.recorder = &MockModerationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModerationRepository) EXPECT() *MockModerationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimPending mocks base method.
+func (m *MockModerationRepository) ClaimPending(arg0 string, arg1 int, arg2 time.Duration) ([]model.ModerationTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimPending", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.ModerationTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimPending indicates an expected call of ClaimPending.
+func (mr *MockModerationRepositoryMockRecorder) ClaimPending(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimPending", reflect.TypeOf((*MockModerationRepository)(nil).ClaimPending), arg0, arg1, arg2)
+}
+
+// GetDecisions mocks base method.
+func (m *MockModerationRepository) GetDecisions(arg0 int) ([]model.ModerationDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDecisions", arg0)
+	ret0, _ := ret[0].([]model.ModerationDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDecisions indicates an expected call of GetDecisions.
+func (mr *MockModerationRepositoryMockRecorder) GetDecisions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDecisions", reflect.TypeOf((*MockModerationRepository)(nil).GetDecisions), arg0)
+}
+
+// SaveDecision mocks base method.
+func (m *MockModerationRepository) SaveDecision(arg0 model.ModerationDecision, arg1, arg2 model.AdvertStatus) (model.ModerationDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDecision", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.ModerationDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveDecision indicates an expected call of SaveDecision.
+func (mr *MockModerationRepositoryMockRecorder) SaveDecision(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDecision", reflect.TypeOf((*MockModerationRepository)(nil).SaveDecision), arg0, arg1, arg2)
+}
+
+// MockCategoryRepository is a mock of CategoryRepository interface.
+type MockCategoryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCategoryRepositoryMockRecorder
+}
+
+// MockCategoryRepositoryMockRecorder is the mock recorder for MockCategoryRepository.
+type MockCategoryRepositoryMockRecorder struct {
+	mock *MockCategoryRepository
+}
+
+// NewMockCategoryRepository creates a new mock instance.
+func NewMockCategoryRepository(ctrl *gomock.Controller) *MockCategoryRepository {
+	mock := &MockCategoryRepository{ctrl: ctrl}
+	mock.recorder = &MockCategoryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCategoryRepository) EXPECT() *MockCategoryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateCategory mocks base method.
+func (m *MockCategoryRepository) CreateCategory(arg0 model.Category) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCategory", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCategory indicates an expected call of CreateCategory.
+func (mr *MockCategoryRepositoryMockRecorder) CreateCategory(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCategory", reflect.TypeOf((*MockCategoryRepository)(nil).CreateCategory), arg0)
+}
+
+// DeleteCategory mocks base method.
+func (m *MockCategoryRepository) DeleteCategory(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCategory", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCategory indicates an expected call of DeleteCategory.
+func (mr *MockCategoryRepositoryMockRecorder) DeleteCategory(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCategory", reflect.TypeOf((*MockCategoryRepository)(nil).DeleteCategory), arg0)
+}
+
+// GetCategories mocks base method.
+func (m *MockCategoryRepository) GetCategories() ([]model.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategories")
+	ret0, _ := ret[0].([]model.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategories indicates an expected call of GetCategories.
+func (mr *MockCategoryRepositoryMockRecorder) GetCategories() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategories", reflect.TypeOf((*MockCategoryRepository)(nil).GetCategories))
+}
+
+// GetCategory mocks base method.
+func (m *MockCategoryRepository) GetCategory(arg0 int) (model.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategory", arg0)
+	ret0, _ := ret[0].(model.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategory indicates an expected call of GetCategory.
+func (mr *MockCategoryRepositoryMockRecorder) GetCategory(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategory", reflect.TypeOf((*MockCategoryRepository)(nil).GetCategory), arg0)
+}
+
+// GetSubtree mocks base method.
+func (m *MockCategoryRepository) GetSubtree(arg0 int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubtree", arg0)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubtree indicates an expected call of GetSubtree.
+func (mr *MockCategoryRepositoryMockRecorder) GetSubtree(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubtree", reflect.TypeOf((*MockCategoryRepository)(nil).GetSubtree), arg0)
+}
+
+// UpdateCategory mocks base method.
+func (m *MockCategoryRepository) UpdateCategory(arg0 int, arg1 model.Category) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCategory", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCategory indicates an expected call of UpdateCategory.
+func (mr *MockCategoryRepositoryMockRecorder) UpdateCategory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCategory", reflect.TypeOf((*MockCategoryRepository)(nil).UpdateCategory), arg0, arg1)
+}
+
+// MockUserRepository is a mock of UserRepository interface.
+type MockUserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserRepositoryMockRecorder
+}
+
+// MockUserRepositoryMockRecorder is the mock recorder for MockUserRepository.
+type MockUserRepositoryMockRecorder struct {
+	mock *MockUserRepository
+}
+
+// NewMockUserRepository creates a new mock instance.
+func NewMockUserRepository(ctrl *gomock.Controller) *MockUserRepository {
+	mock := &MockUserRepository{ctrl: ctrl}
+	mock.recorder = &MockUserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateUser mocks base method.
+func (m *MockUserRepository) CreateUser(arg0 model.User) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUserRepositoryMockRecorder) CreateUser(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepository)(nil).CreateUser), arg0)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockUserRepository) GetUserByEmail(arg0 string) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", arg0)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockUserRepositoryMockRecorder) GetUserByEmail(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockUserRepository)(nil).GetUserByEmail), arg0)
+}
+
+// GetUserById mocks base method.
+func (m *MockUserRepository) GetUserById(arg0 int) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserById", arg0)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserById indicates an expected call of GetUserById.
+func (mr *MockUserRepositoryMockRecorder) GetUserById(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserById", reflect.TypeOf((*MockUserRepository)(nil).GetUserById), arg0)
+}
+
+// SetUserRole mocks base method.
+func (m *MockUserRepository) SetUserRole(arg0 int, arg1 model.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockUserRepositoryMockRecorder) SetUserRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockUserRepository)(nil).SetUserRole), arg0, arg1)
+}
+
+// MockAPIKeyRepository is a mock of APIKeyRepository interface.
+type MockAPIKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyRepositoryMockRecorder
+}
+
+// MockAPIKeyRepositoryMockRecorder is the mock recorder for MockAPIKeyRepository.
+type MockAPIKeyRepositoryMockRecorder struct {
+	mock *MockAPIKeyRepository
+}
+
+// NewMockAPIKeyRepository creates a new mock instance.
+func NewMockAPIKeyRepository(ctrl *gomock.Controller) *MockAPIKeyRepository {
+	mock := &MockAPIKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyRepository) EXPECT() *MockAPIKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockAPIKeyRepository) CreateAPIKey(arg0 model.APIKey) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockAPIKeyRepositoryMockRecorder) CreateAPIKey(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockAPIKeyRepository)(nil).CreateAPIKey), arg0)
+}
+
+// GetAPIKeyByHash mocks base method.
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(arg0 string) (model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", arg0)
+	ret0, _ := ret[0].(model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockAPIKeyRepositoryMockRecorder) GetAPIKeyByHash(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockAPIKeyRepository)(nil).GetAPIKeyByHash), arg0)
+}
+
+// GetAPIKeys mocks base method.
+func (m *MockAPIKeyRepository) GetAPIKeys(arg0 time.Time) ([]model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeys", arg0)
+	ret0, _ := ret[0].([]model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys.
+func (mr *MockAPIKeyRepositoryMockRecorder) GetAPIKeys(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockAPIKeyRepository)(nil).GetAPIKeys), arg0)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockAPIKeyRepository) RevokeAPIKey(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockAPIKeyRepositoryMockRecorder) RevokeAPIKey(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockAPIKeyRepository)(nil).RevokeAPIKey), arg0)
+}
+
+// UseAPIKey mocks base method.
+func (m *MockAPIKeyRepository) UseAPIKey(arg0 int, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseAPIKey indicates an expected call of UseAPIKey.
+func (mr *MockAPIKeyRepositoryMockRecorder) UseAPIKey(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertList", reflect.TypeOf((*MockRepository)(nil).GetAdvertList), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseAPIKey", reflect.TypeOf((*MockAPIKeyRepository)(nil).UseAPIKey), arg0, arg1)
 }
@@ -5,6 +5,7 @@
 package mock
 
 import (
+	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,41 +35,70 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// ArchiveAdvert mocks base method.
+func (m *MockService) ArchiveAdvert(arg0 model.Principal, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveAdvert", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ArchiveAdvert indicates an expected call of ArchiveAdvert.
+func (mr *MockServiceMockRecorder) ArchiveAdvert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveAdvert", reflect.TypeOf((*MockService)(nil).ArchiveAdvert), arg0, arg1)
+}
+
 // CreateAdvert mocks base method.
-func (m *MockService) CreateAdvert(arg0 model.Advert) (int, error) {
+func (m *MockService) CreateAdvert(arg0 model.Principal, arg1 model.Advert) (int, model.RuleHits, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAdvert", arg0)
+	ret := m.ctrl.Call(m, "CreateAdvert", arg0, arg1)
 	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(model.RuleHits)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // CreateAdvert indicates an expected call of CreateAdvert.
-func (mr *MockServiceMockRecorder) CreateAdvert(arg0 interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) CreateAdvert(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdvert", reflect.TypeOf((*MockService)(nil).CreateAdvert), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdvert", reflect.TypeOf((*MockService)(nil).CreateAdvert), arg0, arg1)
+}
+
+// DeleteAdvert mocks base method.
+func (m *MockService) DeleteAdvert(arg0 model.Principal, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAdvert", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAdvert indicates an expected call of DeleteAdvert.
+func (mr *MockServiceMockRecorder) DeleteAdvert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAdvert", reflect.TypeOf((*MockService)(nil).DeleteAdvert), arg0, arg1)
 }
 
 // GetAdvertById mocks base method.
-func (m *MockService) GetAdvertById(arg0 int, arg1 []string) (model.Advert, error) {
+func (m *MockService) GetAdvertById(arg0 model.Principal, arg1 int, arg2 []string) (model.Advert, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAdvertById", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetAdvertById", arg0, arg1, arg2)
 	ret0, _ := ret[0].(model.Advert)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAdvertById indicates an expected call of GetAdvertById.
-func (mr *MockServiceMockRecorder) GetAdvertById(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetAdvertById(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertById", reflect.TypeOf((*MockService)(nil).GetAdvertById), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertById", reflect.TypeOf((*MockService)(nil).GetAdvertById), arg0, arg1, arg2)
 }
 
 // GetAdvertList mocks base method.
-func (m *MockService) GetAdvertList(arg0 int, arg1 string) ([]model.Advert, error) {
+func (m *MockService) GetAdvertList(arg0 model.Principal, arg1 model.ListQuery) (model.AdvertList, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetAdvertList", arg0, arg1)
-	ret0, _ := ret[0].([]model.Advert)
+	ret0, _ := ret[0].(model.AdvertList)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -78,3 +108,670 @@ func (mr *MockServiceMockRecorder) GetAdvertList(arg0, arg1 interface{}) *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdvertList", reflect.TypeOf((*MockService)(nil).GetAdvertList), arg0, arg1)
 }
+
+// GetOwnAdverts mocks base method.
+func (m *MockService) GetOwnAdverts(arg0 model.Principal) ([]model.Advert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwnAdverts", arg0)
+	ret0, _ := ret[0].([]model.Advert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwnAdverts indicates an expected call of GetOwnAdverts.
+func (mr *MockServiceMockRecorder) GetOwnAdverts(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnAdverts", reflect.TypeOf((*MockService)(nil).GetOwnAdverts), arg0)
+}
+
+// PatchAdvert mocks base method.
+func (m *MockService) PatchAdvert(arg0 model.Principal, arg1 int, arg2 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchAdvert", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PatchAdvert indicates an expected call of PatchAdvert.
+func (mr *MockServiceMockRecorder) PatchAdvert(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchAdvert", reflect.TypeOf((*MockService)(nil).PatchAdvert), arg0, arg1, arg2)
+}
+
+// ReorderPictures mocks base method.
+func (m *MockService) ReorderPictures(arg0 model.Principal, arg1 int, arg2 []int) (model.Pictures, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReorderPictures", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.Pictures)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReorderPictures indicates an expected call of ReorderPictures.
+func (mr *MockServiceMockRecorder) ReorderPictures(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReorderPictures", reflect.TypeOf((*MockService)(nil).ReorderPictures), arg0, arg1, arg2)
+}
+
+// RestoreAdvert mocks base method.
+func (m *MockService) RestoreAdvert(arg0 model.Principal, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreAdvert", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreAdvert indicates an expected call of RestoreAdvert.
+func (mr *MockServiceMockRecorder) RestoreAdvert(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreAdvert", reflect.TypeOf((*MockService)(nil).RestoreAdvert), arg0, arg1)
+}
+
+// SearchAdverts mocks base method.
+func (m *MockService) SearchAdverts(arg0 model.Principal, arg1 model.SearchQuery) (model.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAdverts", arg0, arg1)
+	ret0, _ := ret[0].(model.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAdverts indicates an expected call of SearchAdverts.
+func (mr *MockServiceMockRecorder) SearchAdverts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAdverts", reflect.TypeOf((*MockService)(nil).SearchAdverts), arg0, arg1)
+}
+
+// SetMainPicture mocks base method.
+func (m *MockService) SetMainPicture(arg0 model.Principal, arg1, arg2 int) (model.Pictures, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMainPicture", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.Pictures)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetMainPicture indicates an expected call of SetMainPicture.
+func (mr *MockServiceMockRecorder) SetMainPicture(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMainPicture", reflect.TypeOf((*MockService)(nil).SetMainPicture), arg0, arg1, arg2)
+}
+
+// Suggest mocks base method.
+func (m *MockService) Suggest(arg0 model.Principal, arg1 string, arg2 int) (model.Suggestions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suggest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.Suggestions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Suggest indicates an expected call of Suggest.
+func (mr *MockServiceMockRecorder) Suggest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suggest", reflect.TypeOf((*MockService)(nil).Suggest), arg0, arg1, arg2)
+}
+
+// TransitionAdvert mocks base method.
+func (m *MockService) TransitionAdvert(arg0 model.Principal, arg1 int, arg2 model.AdvertStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionAdvert", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransitionAdvert indicates an expected call of TransitionAdvert.
+func (mr *MockServiceMockRecorder) TransitionAdvert(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionAdvert", reflect.TypeOf((*MockService)(nil).TransitionAdvert), arg0, arg1, arg2)
+}
+
+// UpdateAdvert mocks base method.
+func (m *MockService) UpdateAdvert(arg0 model.Principal, arg1 int, arg2 model.Advert) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAdvert", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAdvert indicates an expected call of UpdateAdvert.
+func (mr *MockServiceMockRecorder) UpdateAdvert(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAdvert", reflect.TypeOf((*MockService)(nil).UpdateAdvert), arg0, arg1, arg2)
+}
+
+// MockIndexUpdater is a mock of IndexUpdater interface.
+type MockIndexUpdater struct {
+	ctrl     *gomock.Controller
+	recorder *MockIndexUpdaterMockRecorder
+}
+
+// MockIndexUpdaterMockRecorder is the mock recorder for MockIndexUpdater.
+type MockIndexUpdaterMockRecorder struct {
+	mock *MockIndexUpdater
+}
+
+// NewMockIndexUpdater creates a new mock instance.
+func NewMockIndexUpdater(ctrl *gomock.Controller) *MockIndexUpdater {
+	mock := &MockIndexUpdater{ctrl: ctrl}
+	mock.recorder = &MockIndexUpdaterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIndexUpdater) EXPECT() *MockIndexUpdaterMockRecorder {
+	return m.recorder
+}
+
+// Remove mocks base method.
+func (m *MockIndexUpdater) Remove(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockIndexUpdaterMockRecorder) Remove(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockIndexUpdater)(nil).Remove), arg0)
+}
+
+// Update mocks base method.
+func (m *MockIndexUpdater) Update(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockIndexUpdaterMockRecorder) Update(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockIndexUpdater)(nil).Update), arg0)
+}
+
+// MockSearchIndex is a mock of SearchIndex interface.
+type MockSearchIndex struct {
+	ctrl     *gomock.Controller
+	recorder *MockSearchIndexMockRecorder
+}
+
+// MockSearchIndexMockRecorder is the mock recorder for MockSearchIndex.
+type MockSearchIndexMockRecorder struct {
+	mock *MockSearchIndex
+}
+
+// NewMockSearchIndex creates a new mock instance.
+func NewMockSearchIndex(ctrl *gomock.Controller) *MockSearchIndex {
+	mock := &MockSearchIndex{ctrl: ctrl}
+	mock.recorder = &MockSearchIndexMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSearchIndex) EXPECT() *MockSearchIndexMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockSearchIndex) Count(arg0 model.SearchQuery, arg1 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockSearchIndexMockRecorder) Count(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockSearchIndex)(nil).Count), arg0, arg1)
+}
+
+// Remove mocks base method.
+func (m *MockSearchIndex) Remove(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockSearchIndexMockRecorder) Remove(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockSearchIndex)(nil).Remove), arg0)
+}
+
+// Search mocks base method.
+func (m *MockSearchIndex) Search(arg0 model.SearchQuery, arg1 model.ListPage) ([]model.SearchHit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", arg0, arg1)
+	ret0, _ := ret[0].([]model.SearchHit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockSearchIndexMockRecorder) Search(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockSearchIndex)(nil).Search), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockSearchIndex) Update(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockSearchIndexMockRecorder) Update(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockSearchIndex)(nil).Update), arg0)
+}
+
+// MockSuggester is a mock of Suggester interface.
+type MockSuggester struct {
+	ctrl     *gomock.Controller
+	recorder *MockSuggesterMockRecorder
+}
+
+// MockSuggesterMockRecorder is the mock recorder for MockSuggester.
+type MockSuggesterMockRecorder struct {
+	mock *MockSuggester
+}
+
+// NewMockSuggester creates a new mock instance.
+func NewMockSuggester(ctrl *gomock.Controller) *MockSuggester {
+	mock := &MockSuggester{ctrl: ctrl}
+	mock.recorder = &MockSuggesterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSuggester) EXPECT() *MockSuggesterMockRecorder {
+	return m.recorder
+}
+
+// Suggest mocks base method.
+func (m *MockSuggester) Suggest(arg0 string, arg1 int) model.Suggestions {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suggest", arg0, arg1)
+	ret0, _ := ret[0].(model.Suggestions)
+	return ret0
+}
+
+// Suggest indicates an expected call of Suggest.
+func (mr *MockSuggesterMockRecorder) Suggest(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suggest", reflect.TypeOf((*MockSuggester)(nil).Suggest), arg0, arg1)
+}
+
+// MockCategories is a mock of Categories interface.
+type MockCategories struct {
+	ctrl     *gomock.Controller
+	recorder *MockCategoriesMockRecorder
+}
+
+// MockCategoriesMockRecorder is the mock recorder for MockCategories.
+type MockCategoriesMockRecorder struct {
+	mock *MockCategories
+}
+
+// NewMockCategories creates a new mock instance.
+func NewMockCategories(ctrl *gomock.Controller) *MockCategories {
+	mock := &MockCategories{ctrl: ctrl}
+	mock.recorder = &MockCategoriesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCategories) EXPECT() *MockCategoriesMockRecorder {
+	return m.recorder
+}
+
+// CreateCategory mocks base method.
+func (m *MockCategories) CreateCategory(arg0 model.Category) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCategory", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCategory indicates an expected call of CreateCategory.
+func (mr *MockCategoriesMockRecorder) CreateCategory(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCategory", reflect.TypeOf((*MockCategories)(nil).CreateCategory), arg0)
+}
+
+// DeleteCategory mocks base method.
+func (m *MockCategories) DeleteCategory(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCategory", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCategory indicates an expected call of DeleteCategory.
+func (mr *MockCategoriesMockRecorder) DeleteCategory(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCategory", reflect.TypeOf((*MockCategories)(nil).DeleteCategory), arg0)
+}
+
+// GetCategory mocks base method.
+func (m *MockCategories) GetCategory(arg0 int) (model.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategory", arg0)
+	ret0, _ := ret[0].(model.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategory indicates an expected call of GetCategory.
+func (mr *MockCategoriesMockRecorder) GetCategory(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategory", reflect.TypeOf((*MockCategories)(nil).GetCategory), arg0)
+}
+
+// GetCategoryTree mocks base method.
+func (m *MockCategories) GetCategoryTree() ([]model.Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryTree")
+	ret0, _ := ret[0].([]model.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryTree indicates an expected call of GetCategoryTree.
+func (mr *MockCategoriesMockRecorder) GetCategoryTree() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryTree", reflect.TypeOf((*MockCategories)(nil).GetCategoryTree))
+}
+
+// UpdateCategory mocks base method.
+func (m *MockCategories) UpdateCategory(arg0 int, arg1 model.Category) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCategory", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCategory indicates an expected call of UpdateCategory.
+func (mr *MockCategoriesMockRecorder) UpdateCategory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCategory", reflect.TypeOf((*MockCategories)(nil).UpdateCategory), arg0, arg1)
+}
+
+// MockModeration is a mock of Moderation interface.
+type MockModeration struct {
+	ctrl     *gomock.Controller
+	recorder *MockModerationMockRecorder
+}
+
+// MockModerationMockRecorder is the mock recorder for MockModeration.
+type MockModerationMockRecorder struct {
+	mock *MockModeration
+}
+
+// NewMockModeration creates a new mock instance.
+func NewMockModeration(ctrl *gomock.Controller) *MockModeration {
+	mock := &MockModeration{ctrl: ctrl}
+	mock.recorder = &MockModerationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModeration) EXPECT() *MockModerationMockRecorder {
+	return m.recorder
+}
+
+// ClaimQueue mocks base method.
+func (m *MockModeration) ClaimQueue(arg0 string, arg1 int) ([]model.ModerationTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimQueue", arg0, arg1)
+	ret0, _ := ret[0].([]model.ModerationTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimQueue indicates an expected call of ClaimQueue.
+func (mr *MockModerationMockRecorder) ClaimQueue(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimQueue", reflect.TypeOf((*MockModeration)(nil).ClaimQueue), arg0, arg1)
+}
+
+// Decide mocks base method.
+func (m *MockModeration) Decide(arg0 model.ModerationDecision) (model.ModerationDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decide", arg0)
+	ret0, _ := ret[0].(model.ModerationDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Decide indicates an expected call of Decide.
+func (mr *MockModerationMockRecorder) Decide(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decide", reflect.TypeOf((*MockModeration)(nil).Decide), arg0)
+}
+
+// GetDecisions mocks base method.
+func (m *MockModeration) GetDecisions(arg0 int) ([]model.ModerationDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDecisions", arg0)
+	ret0, _ := ret[0].([]model.ModerationDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDecisions indicates an expected call of GetDecisions.
+func (mr *MockModerationMockRecorder) GetDecisions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDecisions", reflect.TypeOf((*MockModeration)(nil).GetDecisions), arg0)
+}
+
+// MockUploader is a mock of Uploader interface.
+type MockUploader struct {
+	ctrl     *gomock.Controller
+	recorder *MockUploaderMockRecorder
+}
+
+// MockUploaderMockRecorder is the mock recorder for MockUploader.
+type MockUploaderMockRecorder struct {
+	mock *MockUploader
+}
+
+// NewMockUploader creates a new mock instance.
+func NewMockUploader(ctrl *gomock.Controller) *MockUploader {
+	mock := &MockUploader{ctrl: ctrl}
+	mock.recorder = &MockUploaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUploader) EXPECT() *MockUploaderMockRecorder {
+	return m.recorder
+}
+
+// UploadPicture mocks base method.
+func (m *MockUploader) UploadPicture(arg0 model.Principal, arg1 int, arg2 io.Reader) (model.Picture, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadPicture", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.Picture)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadPicture indicates an expected call of UploadPicture.
+func (mr *MockUploaderMockRecorder) UploadPicture(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadPicture", reflect.TypeOf((*MockUploader)(nil).UploadPicture), arg0, arg1, arg2)
+}
+
+// MockUsers is a mock of Users interface.
+type MockUsers struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsersMockRecorder
+}
+
+// MockUsersMockRecorder is the mock recorder for MockUsers.
+type MockUsersMockRecorder struct {
+	mock *MockUsers
+}
+
+// NewMockUsers creates a new mock instance.
+func NewMockUsers(ctrl *gomock.Controller) *MockUsers {
+	mock := &MockUsers{ctrl: ctrl}
+	mock.recorder = &MockUsersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUsers) EXPECT() *MockUsersMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockUsers) Authenticate(arg0 string) (model.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", arg0)
+	ret0, _ := ret[0].(model.Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockUsersMockRecorder) Authenticate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockUsers)(nil).Authenticate), arg0)
+}
+
+// Login mocks base method.
+func (m *MockUsers) Login(arg0 model.Credentials) (model.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", arg0)
+	ret0, _ := ret[0].(model.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockUsersMockRecorder) Login(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUsers)(nil).Login), arg0)
+}
+
+// Register mocks base method.
+func (m *MockUsers) Register(arg0 model.Credentials, arg1 model.Role) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", arg0, arg1)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockUsersMockRecorder) Register(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockUsers)(nil).Register), arg0, arg1)
+}
+
+// SetRole mocks base method.
+func (m *MockUsers) SetRole(arg0 int, arg1 model.Role) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRole", arg0, arg1)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockUsersMockRecorder) SetRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockUsers)(nil).SetRole), arg0, arg1)
+}
+
+// MockAPIKeys is a mock of APIKeys interface.
+type MockAPIKeys struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeysMockRecorder
+}
+
+// MockAPIKeysMockRecorder is the mock recorder for MockAPIKeys.
+type MockAPIKeysMockRecorder struct {
+	mock *MockAPIKeys
+}
+
+// NewMockAPIKeys creates a new mock instance.
+func NewMockAPIKeys(ctrl *gomock.Controller) *MockAPIKeys {
+	mock := &MockAPIKeys{ctrl: ctrl}
+	mock.recorder = &MockAPIKeysMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeys) EXPECT() *MockAPIKeysMockRecorder {
+	return m.recorder
+}
+
+// AuthenticateAPIKey mocks base method.
+func (m *MockAPIKeys) AuthenticateAPIKey(arg0 string) (model.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateAPIKey", arg0)
+	ret0, _ := ret[0].(model.Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateAPIKey indicates an expected call of AuthenticateAPIKey.
+func (mr *MockAPIKeysMockRecorder) AuthenticateAPIKey(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateAPIKey", reflect.TypeOf((*MockAPIKeys)(nil).AuthenticateAPIKey), arg0)
+}
+
+// GetAPIKeys mocks base method.
+func (m *MockAPIKeys) GetAPIKeys() ([]model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeys")
+	ret0, _ := ret[0].([]model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys.
+func (mr *MockAPIKeysMockRecorder) GetAPIKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockAPIKeys)(nil).GetAPIKeys))
+}
+
+// IssueAPIKey mocks base method.
+func (m *MockAPIKeys) IssueAPIKey(arg0 model.APIKey) (model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueAPIKey", arg0)
+	ret0, _ := ret[0].(model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueAPIKey indicates an expected call of IssueAPIKey.
+func (mr *MockAPIKeysMockRecorder) IssueAPIKey(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueAPIKey", reflect.TypeOf((*MockAPIKeys)(nil).IssueAPIKey), arg0)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockAPIKeys) RevokeAPIKey(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockAPIKeysMockRecorder) RevokeAPIKey(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockAPIKeys)(nil).RevokeAPIKey), arg0)
+}
@@ -1,9 +1,46 @@
 package model
 
+import "time"
+
+type AdvertStatus string
+
+const (
+	StatusDraft   AdvertStatus = "draft"
+	StatusPending AdvertStatus = "pending"
+	StatusActive  AdvertStatus = "active"
+	StatusExpired AdvertStatus = "expired"
+	StatusBlocked AdvertStatus = "blocked"
+)
+
+func (s AdvertStatus) IsValid() bool {
+	switch s {
+	case StatusDraft, StatusPending, StatusActive, StatusExpired, StatusBlocked:
+		return true
+	}
+	return false
+}
+
 type Advert struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description,omitempty" binding:"required"`
-	Price       int    `json:"price" binding:"required"`
-	Pictures    string `json:"pictures,omitempty" binding:"required"`
-	MainPicture string `json:"main-picture,omitempty"`
+	Id          int          `json:"id,omitempty"`
+	Name        string       `json:"name" binding:"required"`
+	Description string       `json:"description,omitempty" binding:"required"`
+	Price       int          `json:"price" binding:"required"`
+	Pictures    Pictures     `json:"pictures,omitempty" binding:"required"`
+	CategoryId  int          `json:"category-id,omitempty" db:"category_id"`
+	OwnerId     *int         `json:"owner-id,omitempty" db:"owner_id"`
+	Attributes  Attributes   `json:"attributes,omitempty"`
+	Lat         *float64     `json:"lat,omitempty"`
+	Lon         *float64     `json:"lon,omitempty"`
+	City        string       `json:"city,omitempty"`
+	Region      string       `json:"region,omitempty"`
+	MainPicture string       `json:"main-picture,omitempty" db:"main_picture"`
+	Status      AdvertStatus `json:"status,omitempty"`
+	CreatedAt   *time.Time   `json:"created-at,omitempty" db:"createdat"`
+	ArchivedAt  *time.Time   `json:"archived-at,omitempty" db:"archived_at"`
+
+	MainPictureVariants PictureVariants `json:"main-picture-variants,omitempty" db:"main_picture_variants"`
+	ScreeningFlags      RuleHits        `json:"-" db:"screening_flags"`
+
+	// DistanceKm is the distance from the point of the list query
+	DistanceKm *float64 `json:"distance-km,omitempty" db:"distance_km"`
 }
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Scope is a permission of an API key.
+type Scope string
+
+const (
+	ScopeAdvertsRead  Scope = "adverts:read"
+	ScopeAdvertsWrite Scope = "adverts:write"
+	ScopeAdmin        Scope = "admin"
+)
+
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeAdvertsRead, ScopeAdvertsWrite, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// Scopes is stored in a JSONB column.
+type Scopes []Scope
+
+func (s Scopes) Contains(scope Scope) bool {
+	for _, c := range s {
+		if c == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (s *Scopes) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for api key scopes")
+	}
+	return json.Unmarshal(data, s)
+}
+
+// APIKey is a key a partner calls the API with. Only the SHA-256 hash of the
+// key is stored, the key itself is returned once when it is issued. Prefix
+// is the start of the key to tell the keys apart. A zero DailyQuota is no
+// limit.
+type APIKey struct {
+	Id         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty" db:"-"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	OwnerId    *int       `json:"owner-id,omitempty" db:"owner_id"`
+	OwnerRole  Role       `json:"-" db:"owner_role"`
+	Scopes     Scopes     `json:"scopes"`
+	DailyQuota int        `json:"daily-quota" db:"daily_quota"`
+	UsedToday  int        `json:"used-today" db:"used_today"`
+	CreatedAt  *time.Time `json:"created-at,omitempty" db:"created_at"`
+	LastUsedAt *time.Time `json:"last-used-at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked-at,omitempty" db:"revoked_at"`
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+type AttributeType string
+
+const (
+	AttributeInt    AttributeType = "int"
+	AttributeNumber AttributeType = "number"
+	AttributeString AttributeType = "string"
+	AttributeBool   AttributeType = "bool"
+)
+
+func (t AttributeType) IsValid() bool {
+	switch t {
+	case AttributeInt, AttributeNumber, AttributeString, AttributeBool:
+		return true
+	}
+	return false
+}
+
+// IsNumeric reports whether the attribute may be bounded by a range.
+func (t AttributeType) IsNumeric() bool {
+	return t == AttributeInt || t == AttributeNumber
+}
+
+// AttributeSpec describes an attribute of the adverts of a category. Enum
+// lists the allowed values of a string attribute, Min and Max bound the
+// value of a numeric one.
+type AttributeSpec struct {
+	Name     string        `json:"name"`
+	Type     AttributeType `json:"type"`
+	Required bool          `json:"required,omitempty"`
+	Enum     []string      `json:"enum,omitempty"`
+	Min      *float64      `json:"min,omitempty"`
+	Max      *float64      `json:"max,omitempty"`
+}
+
+// AttributeSchema is the list of the attributes of a category, it is stored
+// in a JSONB column.
+type AttributeSchema []AttributeSpec
+
+func (s AttributeSchema) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (s *AttributeSchema) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return errors.New("unsupported type for attribute schema")
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return err
+	}
+	if len(*s) == 0 {
+		*s = nil
+	}
+	return nil
+}
+
+// Attributes are the values of the category attributes of an advert keyed
+// by the attribute name: strings, booleans and numbers. They are stored in
+// a JSONB column.
+type Attributes map[string]interface{}
+
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *Attributes) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return errors.New("unsupported type for attributes")
+	}
+	if err := json.Unmarshal(data, a); err != nil {
+		return err
+	}
+	if len(*a) == 0 {
+		*a = nil
+	}
+	return nil
+}
+
+// AttributeFilter narrows the list to the adverts whose attribute equals
+// Equal or lies between Min and Max, nil bounds are not applied. The list
+// is filtered without the schema, so Equal matches a string value as well
+// as a number or a boolean written the same way.
+type AttributeFilter struct {
+	Name  string
+	Equal *string
+	Min   *float64
+	Max   *float64
+}
+
+// Match tells whether the attributes pass the filter, an advert without the
+// attribute never does.
+func (f AttributeFilter) Match(attributes Attributes) bool {
+	value, ok := attributes[f.Name]
+	if !ok {
+		return false
+	}
+
+	if f.Equal != nil {
+		switch v := value.(type) {
+		case string:
+			if v != *f.Equal {
+				return false
+			}
+		case bool:
+			if *f.Equal != strconv.FormatBool(v) {
+				return false
+			}
+		default:
+			n, isNumber := numericValue(v)
+			equal, err := strconv.ParseFloat(*f.Equal, 64)
+			if !isNumber || err != nil || n != equal {
+				return false
+			}
+		}
+	}
+
+	if f.Min != nil || f.Max != nil {
+		n, ok := numericValue(value)
+		if !ok || (f.Min != nil && n < *f.Min) || (f.Max != nil && n > *f.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+// numericValue returns a numeric attribute value as float64, the values are
+// float64 when decoded from JSON and json.Number when decoded with
+// UseNumber.
+func numericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
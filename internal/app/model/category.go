@@ -0,0 +1,13 @@
+package model
+
+// Category is a node of the category tree, root categories have no parent.
+// Children are filled when the tree is returned, not when it is saved.
+// Attributes is the schema the attributes of the adverts of the category
+// are validated against, the schema is not inherited by subcategories.
+type Category struct {
+	Id         int             `json:"id"`
+	ParentId   *int            `json:"parent-id,omitempty" db:"parent_id"`
+	Name       string          `json:"name"`
+	Attributes AttributeSchema `json:"attributes,omitempty"`
+	Children   []Category      `json:"children,omitempty" db:"-"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import "math"
+
+// OrderDistance orders the list by the distance from ListQuery.Near, the
+// adverts without a location are left out then.
+const OrderDistance = "distance"
+
+// EarthRadiusKm is the radius of the earth the Postgres earthdistance
+// module assumes, distances computed outside of Postgres use it as well.
+const EarthRadiusKm = 6378.168
+
+// GeoPoint is a point given by the latitude and the longitude in degrees.
+type GeoPoint struct {
+	Lat float64
+	Lon float64
+}
+
+// DistanceKm returns the great circle distance to the point in kilometers.
+func (p GeoPoint) DistanceKm(lat, lon float64) float64 {
+	lat1, lat2 := radians(p.Lat), radians(lat)
+	dLat, dLon := lat2-lat1, radians(lon-p.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// GeoBox is a bounding box: the latitudes from South to North and the
+// longitudes from West to East. A box with West greater than East crosses
+// the 180th meridian.
+type GeoBox struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// Contains tells whether the point lies in the box, the borders included.
+func (b GeoBox) Contains(lat, lon float64) bool {
+	if lat < b.South || lat > b.North {
+		return false
+	}
+	if b.West <= b.East {
+		return lon >= b.West && lon <= b.East
+	}
+	return lon >= b.West || lon <= b.East
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,132 @@
+package model
+
+import "time"
+
+const (
+	// CountExact counts the matching adverts, CountEstimated takes the row
+	// estimate of the query planner, which is cheap but only approximate.
+	CountExact     = "exact"
+	CountEstimated = "estimated"
+)
+
+// ListQuery is what a client asks of the advert list: the page, the order
+// and the filters. Nil filters are not applied.
+type ListQuery struct {
+	Page            int
+	PageSize        int
+	Cursor          string
+	OrderBy         string
+	Count           string
+	IncludeArchived bool
+
+	PriceMin    *int
+	PriceMax    *int
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	HasPictures *bool
+	// Q is matched as a substring of the name and the description
+	Q string
+	// CategoryId selects the adverts of the category and of all its
+	// descendants, the service resolves it to the ids of the subtree in
+	// Categories
+	CategoryId *int
+	Categories []int
+	// Attributes are the filters on the category attributes ordered by
+	// the attribute name
+	Attributes []AttributeFilter
+	// Near is the point the distances are measured from, with RadiusKm
+	// only the adverts within the radius are selected
+	Near     *GeoPoint
+	RadiusKm *float64
+	Box      *GeoBox
+}
+
+// ListPage is the slice of the filtered list a repository reads: either
+// Limit adverts after Offset or, with a Cursor, after the cursor position.
+type ListPage struct {
+	Offset      int
+	Limit       int
+	OrderField  string
+	OrderDirect string
+	Cursor      *ListCursor
+}
+
+// ListCursor is a position in the advert list: the sort key value and the
+// id of the advert a page starts after. A backward cursor leads to the page
+// before that advert. Filters is a hash of the filters the position was
+// taken with, the point of a distance order among them.
+type ListCursor struct {
+	OrderBy  string `json:"o"`
+	Value    string `json:"v"`
+	Id       int    `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+	Filters  string `json:"f,omitempty"`
+}
+
+// AdvertList is one page of the advert list. Page is 0 for a page selected
+// by a cursor. The opaque cursors of the neighbouring pages are empty when
+// there is no such page.
+type AdvertList struct {
+	Items      []Advert  `json:"items"`
+	Page       int       `json:"page,omitempty"`
+	PageSize   int       `json:"page_size"`
+	Total      int       `json:"total"`
+	TotalPages int       `json:"total_pages"`
+	Links      ListLinks `json:"links"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+}
+
+type ListLinks struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+}
+
+// SearchQuery is a full-text search of the adverts narrowed by the list
+// filters. Text is a web search query: words, "quoted phrases", OR and
+// -excluded words. OrderBy is SearchRelevance or one of the list orders,
+// the relevance then orders adverts with equal keys.
+type SearchQuery struct {
+	ListQuery
+	Text string
+}
+
+const SearchRelevance = "relevance"
+
+// SearchHit is an advert found by the search with its rank and the name
+// and description fragments with the matched words highlighted.
+type SearchHit struct {
+	Advert
+	Rank                float64 `json:"rank" db:"rank"`
+	NameHeadline        string  `json:"name-headline" db:"name_headline"`
+	DescriptionHeadline string  `json:"description-headline" db:"description_headline"`
+}
+
+type SearchResult struct {
+	Items      []SearchHit `json:"items"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	Total      int         `json:"total"`
+	TotalPages int         `json:"total_pages"`
+	Links      ListLinks   `json:"links"`
+}
+
+// Suggestions are the advert names starting with a typed prefix, most
+// frequent first. DidYouMean is the prefix with misspelled words corrected,
+// it is set when nothing starts with the prefix itself, the completions are
+// those of the correction then.
+type Suggestions struct {
+	Prefix      string       `json:"prefix"`
+	Completions []Completion `json:"completions"`
+	DidYouMean  string       `json:"did_you_mean,omitempty"`
+}
+
+// Completion is an advert name with the number of public adverts with this
+// name.
+type Completion struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
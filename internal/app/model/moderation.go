@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+type Decision string
+
+const (
+	DecisionApprove Decision = "approve"
+	DecisionReject  Decision = "reject"
+)
+
+// ReasonCodes are the reasons a moderator may give for a decision.
+var ReasonCodes = map[string]bool{
+	"prohibited_goods":  true,
+	"misleading_info":   true,
+	"offensive_content": true,
+	"spam":              true,
+	"duplicate":         true,
+	"wrong_price":       true,
+	"other":             true,
+}
+
+type ModerationTask struct {
+	AdvertId       int       `json:"advert-id" db:"advert_id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	Price          int       `json:"price"`
+	Pictures       Pictures  `json:"pictures,omitempty"`
+	CreatedAt      time.Time `json:"created-at" db:"createdat"`
+	ClaimExpiresAt time.Time `json:"claim-expires-at" db:"expires_at"`
+	ScreeningFlags RuleHits  `json:"screening-flags,omitempty" db:"screening_flags"`
+}
+
+type ModerationDecision struct {
+	Id         int       `json:"id"`
+	AdvertId   int       `json:"advert-id" db:"advert_id"`
+	Moderator  string    `json:"moderator"`
+	Decision   Decision  `json:"decision"`
+	ReasonCode string    `json:"reason-code,omitempty" db:"reason_code"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created-at" db:"created_at"`
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	VariantThumb  = "thumb"
+	VariantMedium = "medium"
+	VariantLarge  = "large"
+)
+
+type Picture struct {
+	Id       int             `json:"id,omitempty"`
+	AdvertId int             `json:"-" db:"advert_id"`
+	URL      string          `json:"url"`
+	Position int             `json:"position"`
+	IsMain   bool            `json:"is_main" db:"is_main"`
+	Variants PictureVariants `json:"variants,omitempty"`
+
+	// Broken is set by the link checker when the picture can not be loaded
+	Broken bool `json:"broken,omitempty"`
+}
+
+// PictureVariants maps a variant name to the URL of the resized copy of an
+// uploaded picture. It is stored in a JSONB column and is empty until the
+// variants are generated.
+type PictureVariants map[string]string
+
+func (v PictureVariants) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (v *PictureVariants) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return errors.New("unsupported type for picture variants")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if len(*v) == 0 {
+		*v = nil
+	}
+	return nil
+}
+
+// Pictures are read from the advert_pictures table aggregated into a JSON
+// array, so they are scanned the same way as JSONB columns.
+type Pictures []Picture
+
+// Main returns the url of the main picture or an empty string.
+func (p Pictures) Main() string {
+	for _, picture := range p {
+		if picture.IsMain {
+			return picture.URL
+		}
+	}
+	return ""
+}
+
+func (p *Pictures) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for pictures")
+	}
+	return json.Unmarshal(data, p)
+}
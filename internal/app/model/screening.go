@@ -0,0 +1,52 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+type Verdict string
+
+const (
+	VerdictAllow Verdict = "allow"
+	VerdictFlag  Verdict = "flag"
+	VerdictBlock Verdict = "block"
+)
+
+// RuleHit describes a content rule that fired on an advert.
+type RuleHit struct {
+	Rule    string  `json:"rule"`
+	Verdict Verdict `json:"verdict"`
+	Message string  `json:"message"`
+}
+
+// RuleHits is stored in a JSONB column.
+type RuleHits []RuleHit
+
+func (h RuleHits) Value() (driver.Value, error) {
+	if h == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (h *RuleHits) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for screening flags")
+	}
+	return json.Unmarshal(data, h)
+}
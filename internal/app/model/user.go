@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// Role is what a user does on the service, the permissions of the roles
+// are listed by the policy of the service.
+type Role string
+
+const (
+	RoleBuyer     Role = "buyer"
+	RoleSeller    Role = "seller"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleBuyer, RoleSeller, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+type User struct {
+	Id           int        `json:"id"`
+	Email        string     `json:"email"`
+	Role         Role       `json:"role"`
+	PasswordHash string     `json:"-" db:"password_hash"`
+	CreatedAt    *time.Time `json:"created-at,omitempty" db:"created_at"`
+}
+
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Token is the bearer token a user is given on login.
+type Token struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token-type"`
+	ExpiresAt time.Time `json:"expires-at"`
+}
+
+// Principal is the caller of the service: a signed in user, the admin or
+// a caller with an API key. The zero value is an anonymous caller. A caller
+// with an API key acts with the role of the owner of the key within the
+// scopes of the key.
+type Principal struct {
+	UserId int
+	Role   Role
+	KeyId  int
+	Scopes Scopes
+}
+
+func (p Principal) Anonymous() bool {
+	return p.UserId == 0 && p.KeyId == 0 && p.Role == ""
+}
+
+// Owns tells whether the advert of the owner is the principal's one, the
+// adverts without an owner belong to nobody.
+func (p Principal) Owns(ownerId *int) bool {
+	return p.UserId != 0 && ownerId != nil && *ownerId == p.UserId
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the refilled buckets are dropped, so the
+// buckets of the clients gone do not pile up.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets of a single instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	swept   time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	limit Limit
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.swept) >= sweepInterval {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	return b.take(limit, now), nil
+}
+
+func (s *MemoryStore) Refund(key string, limit Limit, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a bucket swept in between is full already
+	if b, ok := s.buckets[key]; ok {
+		b.refund(limit, now)
+	}
+	return nil
+}
+
+// Len is the number of the buckets kept.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.full(b.limit, now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	limit := Limit{Rate: 1, Burst: 1}
+	store := NewMemoryStore()
+
+	result, err := store.Take("ip|10.0.0.1", limit, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = store.Take("ip|10.0.0.1", limit, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	result, err = store.Take("ip|10.0.0.2", limit, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, store.Len())
+
+	// the refilled buckets are dropped, the one used just now is kept
+	result, err = store.Take("ip|10.0.0.3", limit, now.Add(sweepInterval))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, store.Len())
+}
@@ -0,0 +1,290 @@
+// Package ratelimit limits the rate of the requests with token buckets. A
+// bucket holds up to Burst tokens and is refilled at a steady rate, every
+// request takes a token and a request finding the bucket empty is refused.
+// The buckets live in a Store: in memory for a single instance or in a
+// Redis compatible server shared by the instances.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+
+	KeyIP     = "ip"
+	KeyUser   = "user"
+	KeyAPIKey = "api_key"
+
+	// AnyRoute is the route of a rule applied to every route, the requests
+	// to all of them share the bucket
+	AnyRoute = "*"
+)
+
+var ErrLimited = apperror.New(apperror.ErrTooManyRequests, "rate limit exceeded")
+
+// Limit is the size of a bucket and the number of tokens it is refilled
+// with per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the state of a bucket after a request took a token. RetryAfter
+// is the time until the next token of a refused request, Reset the time
+// until the bucket is full again.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Store keeps the buckets. Take takes a token from the bucket of the key,
+// a bucket seen the first time is full. Refund gives a taken token back.
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (Result, error)
+	Refund(key string, limit Limit, now time.Time) error
+}
+
+// Config selects the store and lists the rules, a request is refused when
+// a bucket of any rule applied to it is empty.
+type Config struct {
+	Store         string `toml:"store"`
+	RedisAddr     string `toml:"redis_addr"`
+	RedisPassword string `toml:"redis_password"`
+	RedisDB       int    `toml:"redis_db"`
+	Rules         []Rule `toml:"rules"`
+}
+
+// Rule limits the requests to the route, "METHOD /path" or "/path" for
+// every method as the path is routed ("/adverts/:id"), or AnyRoute. Key
+// tells whose bucket a request takes the token from: of the client IP, of
+// the signed in user or of the API key. The requests without a user or a
+// key are not limited by the user and the api_key rules. A zero Burst is
+// the number of the requests in the period.
+type Rule struct {
+	Route     string `toml:"route"`
+	Key       string `toml:"key"`
+	Requests  int    `toml:"requests"`
+	PeriodSec int    `toml:"period_sec"`
+	Burst     int    `toml:"burst"`
+}
+
+func (c Config) Validate() error {
+	switch c.Store {
+	case "", StoreMemory:
+	case StoreRedis:
+		if c.RedisAddr == "" {
+			return errors.New("rate limit: redis_addr is required for the redis store")
+		}
+	default:
+		return fmt.Errorf("rate limit: unknown store %q", c.Store)
+	}
+	for i, rule := range c.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rate limit: rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r Rule) validate() error {
+	route := r.Route
+	if i := strings.IndexByte(route, ' '); i > 0 {
+		route = route[i+1:]
+	}
+	if route != AnyRoute && !strings.HasPrefix(route, "/") {
+		return fmt.Errorf("route %q is neither %q nor a path", r.Route, AnyRoute)
+	}
+	switch r.Key {
+	case KeyIP, KeyUser, KeyAPIKey:
+	default:
+		return fmt.Errorf("unknown key %q", r.Key)
+	}
+	if r.Requests <= 0 || r.PeriodSec <= 0 {
+		return errors.New("requests and period_sec must be positive")
+	}
+	if r.Burst < 0 {
+		return errors.New("burst must not be negative")
+	}
+	return nil
+}
+
+func (r Rule) limit() Limit {
+	burst := r.Burst
+	if burst == 0 {
+		burst = r.Requests
+	}
+	return Limit{Rate: float64(r.Requests) / float64(r.PeriodSec), Burst: burst}
+}
+
+// matches tells whether the rule applies to the route, "METHOD /path".
+func (r Rule) matches(route string) bool {
+	if r.Route == AnyRoute || r.Route == route {
+		return true
+	}
+	return strings.HasPrefix(r.Route, "/") && strings.HasSuffix(route, " "+r.Route)
+}
+
+// subject returns whose bucket the request takes the token from.
+func (r Rule) subject(req Request) (string, bool) {
+	switch r.Key {
+	case KeyUser:
+		return strconv.Itoa(req.UserId), req.UserId != 0
+	case KeyAPIKey:
+		return strconv.Itoa(req.KeyId), req.KeyId != 0
+	}
+	return req.IP, req.IP != ""
+}
+
+// NewStore builds the store selected by config.Store, the buckets are kept
+// in memory when the store is not set.
+func NewStore(config Config) (Store, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Store == StoreRedis {
+		client := NewRedisClient(config.RedisAddr, config.RedisPassword, config.RedisDB)
+		return NewRedisStore(client), nil
+	}
+	return NewMemoryStore(), nil
+}
+
+// Request is what the rules look at. Route is "METHOD /path" as the path
+// is routed, UserId and KeyId are zero for an anonymous caller.
+type Request struct {
+	Route  string
+	IP     string
+	UserId int
+	KeyId  int
+}
+
+type Limiter struct {
+	store Store
+	rules []Rule
+	now   func() time.Time
+}
+
+func NewLimiter(store Store, rules []Rule) *Limiter {
+	return &Limiter{store: store, rules: rules, now: time.Now}
+}
+
+// Allow takes a token from the bucket of every rule applied to the request.
+// When a bucket refuses the request, the tokens taken from the others are
+// given back, so a refused request does not drain them. The result is the
+// one of the refusing bucket, the longest wait of them, or of the bucket
+// with the fewest tokens left. A zero Limit of the result means no rule
+// applies to the request.
+func (l *Limiter) Allow(req Request) (Result, error) {
+	now := l.now()
+	var decision Result
+	var taken []Rule
+	var takenKeys []string
+	for _, rule := range l.rules {
+		if !rule.matches(req.Route) {
+			continue
+		}
+		subject, ok := rule.subject(req)
+		if !ok {
+			continue
+		}
+		key := rule.Route + "|" + rule.Key + "|" + subject
+		result, err := l.store.Take(key, rule.limit(), now)
+		if err != nil {
+			return Result{}, err
+		}
+		if result.Allowed {
+			taken = append(taken, rule)
+			takenKeys = append(takenKeys, key)
+		}
+		if decision.Limit == 0 || restricts(result, decision) {
+			decision = result
+		}
+	}
+
+	if decision.Limit != 0 && !decision.Allowed {
+		for i, rule := range taken {
+			if err := l.store.Refund(takenKeys[i], rule.limit(), now); err != nil {
+				return Result{}, err
+			}
+		}
+	}
+	return decision, nil
+}
+
+// restricts tells whether the result is to be reported instead of the
+// decision so far.
+func restricts(result, decision Result) bool {
+	if result.Allowed != decision.Allowed {
+		return !result.Allowed
+	}
+	if !result.Allowed {
+		return result.RetryAfter > decision.RetryAfter
+	}
+	return result.Remaining < decision.Remaining
+}
+
+// bucket is a token bucket refilled lazily when a token is taken.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	b.refill(limit, now)
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(limit, b.tokens, allowed)
+}
+
+// refund gives back a token taken by a request another bucket refused.
+func (b *bucket) refund(limit Limit, now time.Time) {
+	b.refill(limit, now)
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+1)
+}
+
+func (b *bucket) refill(limit Limit, now time.Time) {
+	if b.updated.IsZero() {
+		b.tokens = float64(limit.Burst)
+		b.updated = now
+	} else if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.updated = now
+	}
+}
+
+// full tells whether the bucket is refilled by now, such a bucket is the
+// same as a bucket never seen.
+func (b *bucket) full(limit Limit, now time.Time) bool {
+	return b.tokens+now.Sub(b.updated).Seconds()*limit.Rate >= float64(limit.Burst)
+}
+
+// newResult describes the bucket left with the tokens.
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
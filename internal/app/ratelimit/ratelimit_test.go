@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket_Take(t *testing.T) {
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	// 2 requests a second, 3 at once
+	limit := Limit{Rate: 2, Burst: 3}
+	var b bucket
+
+	for i := 2; i >= 0; i-- {
+		result := b.take(limit, now)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+	result := b.take(limit, now)
+	assert.Equal(t, Result{Limit: 3, RetryAfter: 500 * time.Millisecond, Reset: 1500 * time.Millisecond}, result)
+
+	// a token is refilled in half a second
+	result = b.take(limit, now.Add(500*time.Millisecond))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// the bucket holds the burst at most
+	result = b.take(limit, now.Add(time.Hour))
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 500 * time.Millisecond}, result)
+
+	// a clock going back does not refill the bucket
+	result = b.take(limit, now)
+	assert.Equal(t, 1, result.Remaining)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	rule := Rule{Route: "POST /create", Key: KeyUser, Requests: 10, PeriodSec: 60}
+	assert.NoError(t, Config{Rules: []Rule{rule, {Route: "*", Key: KeyIP, Requests: 1, PeriodSec: 1}}}.Validate())
+	assert.NoError(t, Config{Store: StoreRedis, RedisAddr: "localhost:6379"}.Validate())
+
+	for _, config := range []Config{
+		{Store: "memcached"},
+		{Store: StoreRedis},
+		{Rules: []Rule{{Route: "create", Key: KeyIP, Requests: 1, PeriodSec: 1}}},
+		{Rules: []Rule{{Route: "/create", Key: "email", Requests: 1, PeriodSec: 1}}},
+		{Rules: []Rule{{Route: "/create", Key: KeyIP, PeriodSec: 1}}},
+		{Rules: []Rule{{Route: "/create", Key: KeyIP, Requests: 1, PeriodSec: 1, Burst: -1}}},
+	} {
+		assert.Error(t, config.Validate(), config)
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(NewMemoryStore(), []Rule{
+		{Route: "POST /create", Key: KeyUser, Requests: 2, PeriodSec: 60},
+		{Route: "/create", Key: KeyAPIKey, Requests: 1, PeriodSec: 60},
+		{Route: "*", Key: KeyIP, Requests: 10, PeriodSec: 1},
+	})
+	limiter.now = func() time.Time { return now }
+
+	create := Request{Route: "POST /create", IP: "10.0.0.1", UserId: 7}
+	for i := 1; i >= 0; i-- {
+		result, err := limiter.Allow(create)
+		require.NoError(t, err)
+		// the user rule is the most restrictive one
+		assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: i, Reset: time.Duration(2-i) * 30 * time.Second}, result)
+	}
+	result, err := limiter.Allow(create)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 30*time.Second, result.RetryAfter)
+
+	// the other users and the other routes have their own buckets
+	result, err = limiter.Allow(Request{Route: "POST /create", IP: "10.0.0.1", UserId: 8})
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = limiter.Allow(Request{Route: "GET /list", IP: "10.0.0.1", UserId: 7})
+	require.NoError(t, err)
+	// the refused request gave its token of the ip bucket back
+	assert.Equal(t, Result{Allowed: true, Limit: 10, Remaining: 6, Reset: 400 * time.Millisecond}, result)
+
+	// the route without a method applies to every method
+	key := Request{Route: "PUT /create", IP: "10.0.0.2", KeyId: 3}
+	result, err = limiter.Allow(key)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = limiter.Allow(key)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 60*time.Second, result.RetryAfter)
+	result, err = limiter.Allow(Request{Route: "GET /list", IP: "10.0.0.2"})
+	require.NoError(t, err)
+	assert.Equal(t, 8, result.Remaining)
+
+	// no rule applies
+	result, err = NewLimiter(NewMemoryStore(), nil).Allow(create)
+	require.NoError(t, err)
+	assert.Equal(t, Result{}, result)
+}
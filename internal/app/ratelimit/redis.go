@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisPrefix is the prefix of the keys of the buckets
+const redisPrefix = "ratelimit:"
+
+// tokenBucketScript takes a token from the bucket of KEYS[1] atomically,
+// or gives one back when ARGV[4] is "refund". ARGV is the rate per second,
+// the burst and the time in milliseconds. The bucket is a hash of the
+// tokens left and the time it was refilled, it expires once it is full
+// again. The tokens are returned as a string, Redis truncates the numbers a
+// script returns to integers.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+  tokens = burst
+  updated = now
+elseif now > updated then
+  tokens = math.min(burst, tokens + (now - updated) / 1000 * rate)
+  updated = now
+end
+local allowed = 0
+if ARGV[4] == 'refund' then
+  tokens = math.min(burst, tokens + 1)
+  allowed = 1
+elseif tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', updated)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// Redis runs a command on a Redis compatible server, RedisClient is the
+// one used outside of the tests.
+type Redis interface {
+	Do(args ...interface{}) (interface{}, error)
+}
+
+// RedisStore keeps the buckets in a Redis compatible server (Redis, KeyDB,
+// Valkey), so the instances of the service share the limits. The script is
+// sent once and is run by its hash after that.
+type RedisStore struct {
+	client Redis
+	sha    string
+}
+
+func NewRedisStore(client Redis) *RedisStore {
+	sum := sha1.Sum([]byte(tokenBucketScript))
+	return &RedisStore{client: client, sha: hex.EncodeToString(sum[:])}
+}
+
+func (s *RedisStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	reply, err := s.run(key, limit, now, "take")
+	if err != nil {
+		return Result{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("rate limit: unexpected reply %v", reply)
+	}
+	allowed, ok := values[0].(int64)
+	text, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(text, 64)
+	if !ok || err != nil {
+		return Result{}, fmt.Errorf("rate limit: unexpected reply %v", reply)
+	}
+	return newResult(limit, tokens, allowed == 1), nil
+}
+
+func (s *RedisStore) Refund(key string, limit Limit, now time.Time) error {
+	_, err := s.run(key, limit, now, "refund")
+	return err
+}
+
+// run runs the script by its hash and sends it when the server does not
+// know it yet.
+func (s *RedisStore) run(key string, limit Limit, now time.Time, op string) (interface{}, error) {
+	args := []interface{}{1, redisPrefix + key, limit.Rate, limit.Burst, now.UnixNano() / int64(time.Millisecond), op}
+	reply, err := s.client.Do(append([]interface{}{"EVALSHA", s.sha}, args...)...)
+	if redisErr, ok := err.(RedisError); ok && strings.HasPrefix(string(redisErr), "NOSCRIPT") {
+		reply, err = s.client.Do(append([]interface{}{"EVAL", tokenBucketScript}, args...)...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
+	}
+	return reply, nil
+}
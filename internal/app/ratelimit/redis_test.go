@@ -0,0 +1,182 @@
+package ratelimit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redisStub is a Redis server running the token bucket script in Go. It
+// only knows the script it is sent with EVAL first.
+type redisStub struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	scripts  map[string]bool
+	buckets  map[string]*bucket
+	commands []string
+	fail     string
+}
+
+func newRedisStub(t *testing.T, password string) *redisStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stub := &redisStub{listener: listener, password: password, scripts: map[string]bool{}, buckets: map[string]*bucket{}}
+	go stub.serve()
+	t.Cleanup(func() { listener.Close() })
+	return stub
+}
+
+func (s *redisStub) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *redisStub) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		values, _ := reply.([]interface{})
+		args := make([]string, len(values))
+		for i, value := range values {
+			args[i], _ = value.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+		if args[0] == "AUTH" {
+			authed = len(args) == 2 && args[1] == s.password
+			if !authed {
+				io.WriteString(conn, "-WRONGPASS invalid username-password pair\r\n")
+				continue
+			}
+		}
+		if !authed {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		io.WriteString(conn, s.run(args))
+	}
+}
+
+func (s *redisStub) run(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, args[0])
+	if s.fail != "" {
+		return "-" + s.fail + "\r\n"
+	}
+
+	switch args[0] {
+	case "AUTH", "SELECT", "PING":
+		return "+OK\r\n"
+	case "EVAL":
+		if args[1] != tokenBucketScript {
+			return "-ERR unknown script\r\n"
+		}
+		s.scripts[NewRedisStore(nil).sha] = true
+	case "EVALSHA":
+		if !s.scripts[args[1]] {
+			return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+		}
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+
+	rate, _ := strconv.ParseFloat(args[4], 64)
+	burst, _ := strconv.Atoi(args[5])
+	ms, _ := strconv.ParseInt(args[6], 10, 64)
+	b, ok := s.buckets[args[3]]
+	if !ok {
+		b = &bucket{}
+		s.buckets[args[3]] = b
+	}
+	limit, now := Limit{Rate: rate, Burst: burst}, time.Unix(0, ms*int64(time.Millisecond))
+	allowed := 1
+	if args[7] == "refund" {
+		b.refund(limit, now)
+	} else if !b.take(limit, now).Allowed {
+		allowed = 0
+	}
+	tokens := strconv.FormatFloat(b.tokens, 'f', -1, 64)
+	return fmt.Sprintf("*2\r\n:%d\r\n$%d\r\n%s\r\n", allowed, len(tokens), tokens)
+}
+
+func (s *redisStub) addr() string {
+	return s.listener.Addr().String()
+}
+
+func TestRedisStore_Take(t *testing.T) {
+	stub := newRedisStub(t, "secret")
+	client := NewRedisClient(stub.addr(), "secret", 2)
+	defer client.Close()
+	store := NewRedisStore(client)
+
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	limit := Limit{Rate: 2, Burst: 2}
+	for i := 1; i >= 0; i-- {
+		result, err := store.Take("ip|10.0.0.1", limit, now)
+		require.NoError(t, err)
+		assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: i, Reset: time.Duration(2-i) * 500 * time.Millisecond}, result)
+	}
+	result, err := store.Take("ip|10.0.0.1", limit, now)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Limit: 2, RetryAfter: 500 * time.Millisecond, Reset: time.Second}, result)
+
+	result, err = store.Take("ip|10.0.0.1", limit, now.Add(250*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, Result{Limit: 2, RetryAfter: 250 * time.Millisecond, Reset: 750 * time.Millisecond}, result)
+
+	// a refunded token can be taken again
+	require.NoError(t, store.Refund("ip|10.0.0.1", limit, now.Add(250*time.Millisecond)))
+	result, err = store.Take("ip|10.0.0.1", limit, now.Add(250*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	// the script is sent once, the connection is reused
+	assert.Equal(t, []string{"AUTH", "SELECT", "EVALSHA", "EVAL", "EVALSHA", "EVALSHA", "EVALSHA", "EVALSHA", "EVALSHA"}, stub.commands)
+	assert.Contains(t, stub.buckets, redisPrefix+"ip|10.0.0.1")
+}
+
+func TestRedisStore_TakeErrors(t *testing.T) {
+	stub := newRedisStub(t, "secret")
+	limit := Limit{Rate: 1, Burst: 1}
+	now := time.Now()
+
+	_, err := NewRedisStore(NewRedisClient(stub.addr(), "wrong", 0)).Take("ip|10.0.0.1", limit, now)
+	assert.EqualError(t, err, "rate limit: WRONGPASS invalid username-password pair")
+
+	client := NewRedisClient(stub.addr(), "secret", 0)
+	defer client.Close()
+	stub.mu.Lock()
+	stub.fail = "ERR out of memory"
+	stub.mu.Unlock()
+	_, err = NewRedisStore(client).Take("ip|10.0.0.1", limit, now)
+	assert.EqualError(t, err, "rate limit: ERR out of memory")
+
+	stub.listener.Close()
+	client.Close()
+	_, err = NewRedisStore(client).Take("ip|10.0.0.1", limit, now)
+	assert.Error(t, err)
+}
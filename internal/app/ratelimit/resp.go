@@ -0,0 +1,199 @@
+package ratelimit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRedisTimeout = time.Second
+	DefaultRedisMaxIdle = 8
+)
+
+// RedisError is an error reply of the server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// RedisClient sends the commands to a Redis compatible server over RESP.
+// The connections are reused, a connection that failed is closed. A bulk
+// string is replied as a string, a null one as nil.
+type RedisClient struct {
+	addr     string
+	password string
+	db       int
+
+	Timeout time.Duration
+	MaxIdle int
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewRedisClient(addr, password string, db int) *RedisClient {
+	return &RedisClient{addr: addr, password: password, db: db, Timeout: DefaultRedisTimeout, MaxIdle: DefaultRedisMaxIdle}
+}
+
+func (c *RedisClient) Do(args ...interface{}) (interface{}, error) {
+	conn, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(c.Timeout, args)
+	if _, ok := err.(RedisError); err != nil && !ok {
+		conn.conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+	return reply, err
+}
+
+// Close closes the idle connections.
+func (c *RedisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, conn := range c.idle {
+		conn.conn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+func (c *RedisClient) get() (*redisConn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+	return c.dial()
+}
+
+func (c *RedisClient) put(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) >= c.MaxIdle {
+		conn.conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// dial opens a connection, signs in and selects the database.
+func (c *RedisClient) dial() (*redisConn, error) {
+	netConn, err := net.DialTimeout("tcp", c.addr, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if c.password != "" {
+		if _, err := conn.do(c.Timeout, []interface{}{"AUTH", c.password}); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := conn.do(c.Timeout, []interface{}{"SELECT", c.db}); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *redisConn) do(timeout time.Duration, args []interface{}) (interface{}, error) {
+	if timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if _, err := c.conn.Write(encodeCommand(args)); err != nil {
+		return nil, err
+	}
+	return readReply(c.reader)
+}
+
+// encodeCommand encodes the command as an array of bulk strings.
+func encodeCommand(args []interface{}) []byte {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		case int:
+			s = strconv.Itoa(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			s = fmt.Sprint(v)
+		}
+		buf = append(buf, "$"+strconv.Itoa(len(s))+"\r\n"+s+"\r\n"...)
+	}
+	return buf
+}
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply")
+	}
+	kind, text := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return text, nil
+	case '-':
+		return nil, RedisError(text)
+	case ':':
+		return strconv.ParseInt(text, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(text)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(text)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, size)
+		for i := range values {
+			value, err := readReply(r)
+			if _, ok := err.(RedisError); err != nil && !ok {
+				return nil, err
+			}
+			values[i] = value
+			if err != nil {
+				values[i] = err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
@@ -2,17 +2,54 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
 	"github.com/paramonies/avito-rest-advert/internal/app/model"
 )
 
 const (
-	ADVERTSTABLE = "adverts"
+	ADVERTSTABLE        = "adverts"
+	ADVERTPICTURESTABLE = "advert_pictures"
+
+	// Every read and write goes through these conditions, so soft deleted
+	// and archived adverts never leak to callers that did not ask for them.
+	visibleCondition           = "deleted_at IS NULL AND archived_at IS NULL"
+	notDeletedCondition        = "deleted_at IS NULL"
+	archivedOrDeletedCondition = "(deleted_at IS NOT NULL OR archived_at IS NOT NULL)"
+
+	// only active adverts are listed publicly
+	publicCondition = "status = 'active'"
 )
 
+// listOrderKeys are the columns the list can be ordered by with the types
+// their cursor values are cast to. The distance is not a column but is
+// computed from the point of the query.
+var listOrderKeys = map[string]string{
+	"price":             "integer",
+	"createdat":         "timestamptz",
+	model.OrderDistance: "float8",
+}
+
+var (
+	ErrAdvertNotFound  = apperror.New(apperror.ErrNotFound, "advertisement not found")
+	ErrPictureNotFound = apperror.New(apperror.ErrNotFound, "picture not found")
+	ErrPictureLimit    = apperror.New(apperror.ErrValidation, "advertisement has the maximum number of pictures")
+)
+
+// picturesColumn selects the pictures of the advert aliased as "a" as a JSON
+// array ordered by position.
+var picturesColumn = fmt.Sprintf(`COALESCE((SELECT json_agg(json_build_object(
+		'id', p.id, 'url', p.url, 'position', p.position, 'is_main', p.is_main, 'variants', p.variants, 'broken', p.broken)
+		ORDER BY p.position)
+	FROM %s p WHERE p.advert_id = a.id), '[]')`, ADVERTPICTURESTABLE)
+
 type AdvertRepository struct {
 	DB *sqlx.DB
 }
@@ -22,36 +59,479 @@ func NewAdvertRepository(db *sqlx.DB) *AdvertRepository {
 }
 
 func (r *AdvertRepository) CreateAdvert(advert model.Advert) (int, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return 0, dbError(err)
+	}
+	defer tx.Rollback()
+
 	var id int
-	query := fmt.Sprintf("INSERT INTO %s (name, description, price, pictures) VALUES ($1, $2, $3, $4) RETURNING id", ADVERTSTABLE)
-	row := r.DB.QueryRow(query, advert.Name, advert.Description, advert.Price, advert.Pictures)
+	query := fmt.Sprintf(`INSERT INTO %s (name, description, price, category_id, attributes, lat, lon, city, region, status, screening_flags, owner_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`, ADVERTSTABLE)
+	row := tx.QueryRow(query, advert.Name, advert.Description, advert.Price, advert.CategoryId, advert.Attributes,
+		advert.Lat, advert.Lon, advert.City, advert.Region, advert.Status, advert.ScreeningFlags, advert.OwnerId)
 	if err := row.Scan(&id); err != nil {
+		return 0, dbError(err)
+	}
+
+	if err := insertPictures(tx, id, advert.Pictures); err != nil {
 		return 0, err
 	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, dbError(err)
+	}
 	return id, nil
 }
 
 func (r *AdvertRepository) GetAdvertById(advertId int) (model.Advert, error) {
-	query := fmt.Sprintf("SELECT name, description, price, pictures FROM %s WHERE id = $1", ADVERTSTABLE)
+	query := fmt.Sprintf("SELECT a.id, a.name, a.description, a.price, %s AS pictures, a.category_id, a.attributes, a.lat, a.lon, a.city, a.region, a.status, a.owner_id, a.createdat FROM %s a WHERE a.id = $1 AND %s", picturesColumn, ADVERTSTABLE, visibleCondition)
 	row := r.DB.QueryRow(query, advertId)
 	var advert model.Advert
-	if err := row.Scan(&advert.Name, &advert.Description, &advert.Price, &advert.Pictures); err != nil {
+	if err := row.Scan(&advert.Id, &advert.Name, &advert.Description, &advert.Price, &advert.Pictures, &advert.CategoryId, &advert.Attributes,
+		&advert.Lat, &advert.Lon, &advert.City, &advert.Region, &advert.Status, &advert.OwnerId, &advert.CreatedAt); err != nil {
 		switch {
 		case err == sql.ErrNoRows:
-			return advert, errors.New("advertisement not found")
+			return advert, ErrAdvertNotFound
 		default:
-			return advert, err
+			return advert, dbError(err)
 		}
 	}
 	return advert, nil
 
 }
 
-func (r *AdvertRepository) GetAdvertList(page int, orderField string, orderDirect string) ([]model.Advert, error) {
+// GetAdvertOwner returns the owner of an advert that is not deleted, the
+// archived adverts included. The adverts created before the accounts have
+// no owner.
+func (r *AdvertRepository) GetAdvertOwner(advertId int) (*int, error) {
+	var ownerId *int
+	query := fmt.Sprintf("SELECT owner_id FROM %s WHERE id = $1 AND %s", ADVERTSTABLE, notDeletedCondition)
+	if err := r.DB.QueryRow(query, advertId).Scan(&ownerId); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAdvertNotFound
+		}
+		return nil, dbError(err)
+	}
+	return ownerId, nil
+}
+
+// GetAdvertsByOwner returns the adverts of the user in any status, the
+// archived ones included, the newest first.
+func (r *AdvertRepository) GetAdvertsByOwner(ownerId int) ([]model.Advert, error) {
+	adverts := []model.Advert{}
+	query := fmt.Sprintf(`SELECT a.id, a.name, a.price, a.category_id, a.status, a.createdat, a.archived_at,
+		COALESCE(p.variants->>'thumb', p.url, '') AS main_picture, p.variants AS main_picture_variants FROM %s a
+		LEFT JOIN %s p ON p.advert_id = a.id AND p.is_main
+		WHERE a.owner_id = $1 AND %s ORDER BY a.createdat DESC, a.id DESC`, ADVERTSTABLE, ADVERTPICTURESTABLE, notDeletedCondition)
+	if err := r.DB.Select(&adverts, query, ownerId); err != nil {
+		return nil, dbError(err)
+	}
+	return adverts, nil
+}
+
+// GetAdvertList returns up to page.Limit public adverts matching list
+// ordered by page.OrderField and the id. Without a cursor the page starts
+// at page.Offset, with a cursor it starts right after (or, for a backward
+// cursor, right before) the advert the cursor points at. Backward pages are
+// returned in reverse order.
+func (r *AdvertRepository) GetAdvertList(list model.ListQuery, page model.ListPage) ([]model.Advert, error) {
+	keyType, ok := listOrderKeys[page.OrderField]
+	if !ok {
+		return nil, fmt.Errorf("unsupported order field %q", page.OrderField)
+	}
+	orderDirect := strings.ToUpper(page.OrderDirect)
+	if orderDirect != "ASC" && orderDirect != "DESC" {
+		return nil, fmt.Errorf("unsupported order direction %q", page.OrderDirect)
+	}
+
+	filter := newListFilter(list, page.Limit)
+	orderKey, distance := "a."+page.OrderField, ""
+	if list.Near != nil {
+		distance = filter.distance(*list.Near)
+	}
+	if page.OrderField == model.OrderDistance {
+		if list.Near == nil {
+			return nil, errors.New("the list can be ordered by distance from a point only")
+		}
+		// the adverts without a location have no place in the order
+		orderKey = distance
+		filter.conditions = append(filter.conditions, "a.lat IS NOT NULL")
+	}
+
+	pagination := fmt.Sprintf("LIMIT $1 OFFSET %d", page.Offset)
+	if page.Cursor != nil {
+		if page.Cursor.Backward {
+			orderDirect = map[string]string{"ASC": "DESC", "DESC": "ASC"}[orderDirect]
+		}
+		operator := ">"
+		if orderDirect == "DESC" {
+			operator = "<"
+		}
+		filter.args = append(filter.args, page.Cursor.Value, page.Cursor.Id)
+		filter.conditions = append(filter.conditions, fmt.Sprintf("(%s, a.id) %s ($%d::%s, $%d)",
+			orderKey, operator, len(filter.args)-1, keyType, len(filter.args)))
+		pagination = "LIMIT $1"
+	}
+
+	distanceColumn := ""
+	if distance != "" {
+		distanceColumn = ", " + distance + " AS distance_km"
+	}
+
 	var adverts []model.Advert
-	query := fmt.Sprintf("SELECT name, price, pictures FROM %s  ORDER BY %s %s  LIMIT 10 OFFSET ($1-1)*10", ADVERTSTABLE, orderField, orderDirect)
-	if err := r.DB.Select(&adverts, query, page); err != nil {
-		return nil, err
+	// the list shows the thumbnail of the main picture once it is generated
+	query := fmt.Sprintf(`SELECT a.id, a.name, a.price, a.category_id, a.attributes, a.lat, a.lon, a.city, a.region, a.createdat,
+		COALESCE(p.variants->>'thumb', p.url, '') AS main_picture, p.variants AS main_picture_variants, a.archived_at%s FROM %s a
+		LEFT JOIN %s p ON p.advert_id = a.id AND p.is_main
+		WHERE %s ORDER BY %s %s, a.id %s %s`,
+		distanceColumn, ADVERTSTABLE, ADVERTPICTURESTABLE, filter.where(), orderKey, orderDirect, orderDirect, pagination)
+	if err := r.DB.Select(&adverts, query, filter.args...); err != nil {
+		return nil, dbError(err)
 	}
 	return adverts, nil
 }
+
+// CountAdverts returns the number of adverts matching list. The estimated
+// count is the row estimate the planner makes from the table statistics, it
+// costs nothing on a large table but may be off until the next ANALYZE.
+func (r *AdvertRepository) CountAdverts(list model.ListQuery, mode string) (int, error) {
+	return count(r.DB, newListFilter(list), mode)
+}
+
+func count(db *sqlx.DB, filter *listFilter, mode string) (int, error) {
+	switch mode {
+	case model.CountExact:
+		var total int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s a WHERE %s", ADVERTSTABLE, filter.where())
+		if err := db.Get(&total, query, filter.args...); err != nil {
+			return 0, dbError(err)
+		}
+		return total, nil
+	case model.CountEstimated:
+		var plan string
+		query := fmt.Sprintf("EXPLAIN (FORMAT JSON) SELECT 1 FROM %s a WHERE %s", ADVERTSTABLE, filter.where())
+		if err := db.Get(&plan, query, filter.args...); err != nil {
+			return 0, dbError(err)
+		}
+		return planRows(plan)
+	default:
+		return 0, fmt.Errorf("unsupported count mode %q", mode)
+	}
+}
+
+// planRows reads the row estimate of the top plan node from the output of
+// EXPLAIN (FORMAT JSON).
+func planRows(plan string) (int, error) {
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explain); err != nil {
+		return 0, err
+	}
+	if len(explain) == 0 {
+		return 0, errors.New("empty query plan")
+	}
+	return int(explain[0].Plan.Rows), nil
+}
+
+// UpdateAdvert replaces the advert fields and its list of pictures. The
+// stored pictures are matched by url, so the kept ones keep their id,
+// variants and link check state. The removed pictures are returned. An
+// active advert goes back to pending, its new content is not moderated yet,
+// and so does an advert flagged by the screening unless it is blocked.
+func (r *AdvertRepository) UpdateAdvert(advertId int, advert model.Advert) (model.Pictures, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`UPDATE %s SET name = $1, description = $2, price = $3, category_id = $4, attributes = $5,
+		lat = $6, lon = $7, city = $8, region = $9, screening_flags = $10,
+		status = CASE WHEN status = 'active' OR ($11 AND status <> 'blocked') THEN 'pending' ELSE status END
+		WHERE id = $12 AND %s`, ADVERTSTABLE, visibleCondition)
+	res, err := tx.Exec(query, advert.Name, advert.Description, advert.Price, advert.CategoryId, advert.Attributes,
+		advert.Lat, advert.Lon, advert.City, advert.Region, advert.ScreeningFlags, len(advert.ScreeningFlags) > 0, advertId)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	if err := checkAffected(res); err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, len(advert.Pictures))
+	positions := make([]int, len(advert.Pictures))
+	main := make([]bool, len(advert.Pictures))
+	for i, picture := range advert.Pictures {
+		urls[i], positions[i], main[i] = picture.URL, picture.Position, picture.IsMain
+	}
+
+	var removed model.Pictures
+	query = fmt.Sprintf("DELETE FROM %s WHERE advert_id = $1 AND url <> ALL($2) RETURNING url, variants", ADVERTPICTURESTABLE)
+	if err := tx.Select(&removed, query, advertId, pq.Array(urls)); err != nil {
+		return nil, dbError(err)
+	}
+
+	// reset first to satisfy the one-main-picture index, the positions are
+	// checked at the end of the statement
+	query = fmt.Sprintf("UPDATE %s SET is_main = FALSE WHERE advert_id = $1 AND is_main", ADVERTPICTURESTABLE)
+	if _, err := tx.Exec(query, advertId); err != nil {
+		return nil, dbError(err)
+	}
+	query = fmt.Sprintf(`INSERT INTO %s (advert_id, url, position, is_main)
+		SELECT $1, p.url, p.position, p.is_main FROM unnest($2::text[], $3::int[], $4::boolean[]) AS p(url, position, is_main)
+		ON CONFLICT (advert_id, url) DO UPDATE SET position = EXCLUDED.position, is_main = EXCLUDED.is_main`, ADVERTPICTURESTABLE)
+	if _, err := tx.Exec(query, advertId, pq.Array(urls), pq.Array(positions), pq.Array(main)); err != nil {
+		return nil, dbError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(err)
+	}
+	return removed, nil
+}
+
+func (r *AdvertRepository) DeleteAdvert(advertId int) error {
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = NOW() WHERE id = $1 AND %s", ADVERTSTABLE, notDeletedCondition)
+	res, err := r.DB.Exec(query, advertId)
+	if err != nil {
+		return dbError(err)
+	}
+	return checkAffected(res)
+}
+
+// UpdateAdvertStatus moves the advert from one status to another. The
+// current status is part of the condition, so a concurrent transition
+// makes the update miss and ErrAdvertNotFound is returned.
+func (r *AdvertRepository) UpdateAdvertStatus(advertId int, from, to model.AdvertStatus) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $1 WHERE id = $2 AND status = $3 AND %s", ADVERTSTABLE, visibleCondition)
+	res, err := r.DB.Exec(query, to, advertId, from)
+	if err != nil {
+		return dbError(err)
+	}
+	return checkAffected(res)
+}
+
+func (r *AdvertRepository) ArchiveAdvert(advertId int) error {
+	query := fmt.Sprintf("UPDATE %s SET archived_at = NOW() WHERE id = $1 AND %s", ADVERTSTABLE, visibleCondition)
+	res, err := r.DB.Exec(query, advertId)
+	if err != nil {
+		return dbError(err)
+	}
+	return checkAffected(res)
+}
+
+func (r *AdvertRepository) RestoreAdvert(advertId int) error {
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = NULL, archived_at = NULL WHERE id = $1 AND %s", ADVERTSTABLE, archivedOrDeletedCondition)
+	res, err := r.DB.Exec(query, advertId)
+	if err != nil {
+		return dbError(err)
+	}
+	return checkAffected(res)
+}
+
+// ReorderPictures sets the position of every picture to its index in
+// pictureIds. The ids must cover all pictures of the advert.
+func (r *AdvertRepository) ReorderPictures(advertId int, pictureIds []int) error {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return dbError(err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`UPDATE %s p SET position = o.ord - 1
+		FROM unnest($2::int[]) WITH ORDINALITY AS o(id, ord)
+		WHERE p.id = o.id AND p.advert_id = $1`, ADVERTPICTURESTABLE)
+	res, err := tx.Exec(query, advertId, pq.Array(pictureIds))
+	if err != nil {
+		return dbError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+	if affected != int64(len(pictureIds)) {
+		return ErrPictureNotFound
+	}
+
+	return dbError(tx.Commit())
+}
+
+// SetMainPicture makes the picture the main one of its advert. The previous
+// main picture is reset first to satisfy the one-main-picture index.
+func (r *AdvertRepository) SetMainPicture(advertId, pictureId int) error {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return dbError(err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("UPDATE %s SET is_main = FALSE WHERE advert_id = $1 AND is_main AND id <> $2", ADVERTPICTURESTABLE)
+	if _, err := tx.Exec(query, advertId, pictureId); err != nil {
+		return dbError(err)
+	}
+
+	query = fmt.Sprintf("UPDATE %s SET is_main = TRUE WHERE advert_id = $1 AND id = $2", ADVERTPICTURESTABLE)
+	res, err := tx.Exec(query, advertId, pictureId)
+	if err != nil {
+		return dbError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+	if affected == 0 {
+		return ErrPictureNotFound
+	}
+
+	return dbError(tx.Commit())
+}
+
+// AddPicture appends the picture after the last one of the advert. The
+// first picture of an advert becomes the main one. The advert row is locked,
+// so concurrent uploads can not exceed maxPictures. An active advert goes
+// back to pending, the new picture is not moderated yet.
+func (r *AdvertRepository) AddPicture(advertId int, url string, maxPictures int) (model.Picture, error) {
+	picture := model.Picture{URL: url}
+
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return picture, dbError(err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	query := fmt.Sprintf("SELECT id FROM %s WHERE id = $1 AND %s FOR UPDATE", ADVERTSTABLE, visibleCondition)
+	if err := tx.Get(&id, query, advertId); err != nil {
+		if err == sql.ErrNoRows {
+			return picture, ErrAdvertNotFound
+		}
+		return picture, dbError(err)
+	}
+
+	var count int
+	query = fmt.Sprintf("SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM %s WHERE advert_id = $1", ADVERTPICTURESTABLE)
+	if err := tx.QueryRow(query, advertId).Scan(&count, &picture.Position); err != nil {
+		return picture, dbError(err)
+	}
+	if count >= maxPictures {
+		return picture, ErrPictureLimit
+	}
+	picture.IsMain = count == 0
+
+	query = fmt.Sprintf("INSERT INTO %s (advert_id, url, position, is_main) VALUES ($1, $2, $3, $4) RETURNING id", ADVERTPICTURESTABLE)
+	if err := tx.QueryRow(query, advertId, picture.URL, picture.Position, picture.IsMain).Scan(&picture.Id); err != nil {
+		return picture, dbError(err)
+	}
+
+	query = fmt.Sprintf("UPDATE %s SET status = 'pending' WHERE id = $1 AND status = 'active'", ADVERTSTABLE)
+	if _, err := tx.Exec(query, advertId); err != nil {
+		return picture, dbError(err)
+	}
+
+	return picture, dbError(tx.Commit())
+}
+
+// SetPictureVariants saves the variants of the advert picture stored under
+// url. The picture is looked up by url, the one the variants were generated
+// from, so ErrPictureNotFound means it was removed in the meantime.
+func (r *AdvertRepository) SetPictureVariants(advertId int, url string, variants model.PictureVariants) error {
+	query := fmt.Sprintf("UPDATE %s SET variants = $3 WHERE advert_id = $1 AND url = $2", ADVERTPICTURESTABLE)
+	res, err := r.DB.Exec(query, advertId, url, variants)
+	if err != nil {
+		return dbError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+	if affected == 0 {
+		return ErrPictureNotFound
+	}
+	return nil
+}
+
+// GetPicturesToCheck returns up to limit pictures of not deleted adverts
+// that were never checked or were checked before checkedBefore, the least
+// recently checked first.
+func (r *AdvertRepository) GetPicturesToCheck(limit int, checkedBefore time.Time) ([]model.Picture, error) {
+	var pictures []model.Picture
+	query := fmt.Sprintf(`SELECT p.id, p.advert_id, p.url, p.position, p.is_main, p.broken FROM %s p
+		JOIN %s a ON a.id = p.advert_id AND a.deleted_at IS NULL
+		WHERE p.checked_at IS NULL OR p.checked_at < $1
+		ORDER BY p.checked_at NULLS FIRST, p.id LIMIT $2`, ADVERTPICTURESTABLE, ADVERTSTABLE)
+	if err := r.DB.Select(&pictures, query, checkedBefore, limit); err != nil {
+		return nil, dbError(err)
+	}
+	return pictures, nil
+}
+
+// SetPictureBroken records the result of a link check. When the main
+// picture is broken, the next working picture of the advert becomes main,
+// wrapping around to the first one.
+func (r *AdvertRepository) SetPictureBroken(pictureId int, broken bool) error {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return dbError(err)
+	}
+	defer tx.Rollback()
+
+	var picture model.Picture
+	query := fmt.Sprintf(`UPDATE %s SET broken = $2, checked_at = NOW() WHERE id = $1
+		RETURNING id, advert_id, position, is_main`, ADVERTPICTURESTABLE)
+	if err := tx.Get(&picture, query, pictureId, broken); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPictureNotFound
+		}
+		return dbError(err)
+	}
+
+	if broken && picture.IsMain {
+		var nextId int
+		query = fmt.Sprintf(`SELECT id FROM %s WHERE advert_id = $1 AND id <> $2 AND NOT broken
+			ORDER BY position < $3, position LIMIT 1`, ADVERTPICTURESTABLE)
+		err := tx.Get(&nextId, query, picture.AdvertId, picture.Id, picture.Position)
+		switch {
+		case err == sql.ErrNoRows:
+			// every picture is broken, the main one is left as is
+		case err != nil:
+			return dbError(err)
+		default:
+			// reset first to satisfy the one-main-picture index
+			query = fmt.Sprintf("UPDATE %s SET is_main = FALSE WHERE id = $1", ADVERTPICTURESTABLE)
+			if _, err := tx.Exec(query, picture.Id); err != nil {
+				return dbError(err)
+			}
+			query = fmt.Sprintf("UPDATE %s SET is_main = TRUE WHERE id = $1", ADVERTPICTURESTABLE)
+			if _, err := tx.Exec(query, nextId); err != nil {
+				return dbError(err)
+			}
+		}
+	}
+
+	return dbError(tx.Commit())
+}
+
+func insertPictures(tx *sqlx.Tx, advertId int, pictures model.Pictures) error {
+	query := fmt.Sprintf("INSERT INTO %s (advert_id, url, position, is_main, variants) VALUES ($1, $2, $3, $4, $5)", ADVERTPICTURESTABLE)
+	for _, picture := range pictures {
+		if _, err := tx.Exec(query, advertId, picture.URL, picture.Position, picture.IsMain, picture.Variants); err != nil {
+			return dbError(err)
+		}
+	}
+	return nil
+}
+
+func checkAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+	if affected == 0 {
+		return ErrAdvertNotFound
+	}
+	return nil
+}
@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/paramonies/avito-rest-advert/internal/app/model"
 	"github.com/stretchr/testify/assert"
 )
@@ -34,15 +37,30 @@ func TestRepository_createAdvert(t *testing.T) {
 			name: "OK",
 			mock: func() {
 				rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
+				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO adverts").
-					WithArgs("name-test", "desc-test", 1000, "avito/files/ad1,avito/files/ad2,avito/files/ad3").WillReturnRows(rows)
+					WithArgs("name-test", "desc-test", 1000, 3, "{}", nil, nil, "", "", model.StatusDraft, "[]", 7).WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO advert_pictures").
+					WithArgs(1, "avito/files/ad1", 0, true, "{}").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO advert_pictures").
+					WithArgs(1, "avito/files/ad2", 1, false, "{}").WillReturnResult(sqlmock.NewResult(2, 1))
+				mock.ExpectExec("INSERT INTO advert_pictures").
+					WithArgs(1, "avito/files/ad3", 2, false, "{}").WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectCommit()
 			},
 			input: args{
 				advert: model.Advert{
 					Name:        "name-test",
 					Description: "desc-test",
 					Price:       1000,
-					Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+					CategoryId:  3,
+					Pictures: model.Pictures{
+						{URL: "avito/files/ad1", Position: 0, IsMain: true},
+						{URL: "avito/files/ad2", Position: 1},
+						{URL: "avito/files/ad3", Position: 2},
+					},
+					Status:  model.StatusDraft,
+					OwnerId: intPtr(7),
 				},
 			},
 			want:    1,
@@ -52,15 +70,23 @@ func TestRepository_createAdvert(t *testing.T) {
 			name: "Empty Fields",
 			mock: func() {
 				rows := sqlmock.NewRows([]string{"id"})
+				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO adverts").
-					WithArgs("", "desc-test", 1000, "avito/files/ad1,avito/files/ad2,avito/files/ad3").WillReturnRows(rows)
+					WithArgs("", "desc-test", 1000, 3, "{}", nil, nil, "", "", model.StatusDraft, "[]", nil).WillReturnRows(rows)
+				mock.ExpectRollback()
 			},
 			input: args{
 				advert: model.Advert{
 					Name:        "",
 					Description: "desc-test",
 					Price:       1000,
-					Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+					CategoryId:  3,
+					Pictures: model.Pictures{
+						{URL: "avito/files/ad1", Position: 0, IsMain: true},
+						{URL: "avito/files/ad2", Position: 1},
+						{URL: "avito/files/ad3", Position: 2},
+					},
+					Status: model.StatusDraft,
 				},
 			},
 			// want:    1,
@@ -93,6 +119,7 @@ func TestRepository_getAdvertById(t *testing.T) {
 	defer mockDB.Close()
 	db := sqlx.NewDb(mockDB, "sqlmock")
 	r := NewAdvertRepository(db)
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
 
 	type args struct {
 		advertId int
@@ -108,29 +135,42 @@ func TestRepository_getAdvertById(t *testing.T) {
 		{
 			name: "Ok",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"name", "price", "description", "pictures"}).
-					AddRow("name-test", "desc-test", 1000, "avito/files/ad1,avito/files/ad2,avito/files/ad3")
+				rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "pictures", "category_id", "attributes", "lat", "lon", "city", "region", "status", "owner_id", "createdat"}).
+					AddRow(1, "name-test", "desc-test", 1000, []byte(`[{"id":1,"url":"avito/files/ad1","position":0,"is_main":true},{"id":2,"url":"avito/files/ad2","position":1,"is_main":false}]`), 3, []byte(`{"rooms":2}`), 55.75, 37.62, "Москва", "Москва", "active", 7, createdAt)
 
-				mock.ExpectQuery("SELECT name, description, price, pictures FROM adverts WHERE (.+)").
+				mock.ExpectQuery("SELECT a.id, a.name, a.description, a.price, (.+) AS pictures, a.category_id, a.attributes, a.lat, a.lon, a.city, a.region, a.status, a.owner_id, a.createdat FROM adverts a WHERE (.+)").
 					WithArgs(1).WillReturnRows(rows)
 			},
 			input: args{
 				advertId: 1,
 			},
 			want: model.Advert{
+				Id:          1,
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				CategoryId:  3,
+				Attributes:  model.Attributes{"rooms": float64(2)},
+				Lat:         floatPtr(55.75),
+				Lon:         floatPtr(37.62),
+				City:        "Москва",
+				Region:      "Москва",
+				Pictures: model.Pictures{
+					{Id: 1, URL: "avito/files/ad1", Position: 0, IsMain: true},
+					{Id: 2, URL: "avito/files/ad2", Position: 1},
+				},
+				Status:    model.StatusActive,
+				OwnerId:   intPtr(7),
+				CreatedAt: &createdAt,
 			},
 			wantErr: false,
 		},
 		{
 			name: "Not Found - wit `advertisement not found` error",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"name", "price", "description", "pictures"})
+				rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "pictures", "category_id", "attributes", "lat", "lon", "city", "region", "status", "owner_id", "createdat"})
 
-				mock.ExpectQuery("SELECT name, description, price, pictures FROM adverts WHERE (.+)").
+				mock.ExpectQuery("SELECT a.id, a.name, a.description, a.price, (.+) AS pictures, a.category_id, a.attributes, a.lat, a.lon, a.city, a.region, a.status, a.owner_id, a.createdat FROM adverts a WHERE (.+)").
 					WithArgs(666).WillReturnRows(rows)
 			},
 			input: args{
@@ -157,6 +197,68 @@ func TestRepository_getAdvertById(t *testing.T) {
 	}
 }
 
+func TestRepository_getAdvertOwner(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	mock.ExpectQuery("SELECT owner_id FROM adverts WHERE id = \\$1 AND deleted_at IS NULL").
+		WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(7))
+	mock.ExpectQuery("SELECT owner_id FROM adverts WHERE id = \\$1 AND deleted_at IS NULL").
+		WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(nil))
+	mock.ExpectQuery("SELECT owner_id FROM adverts WHERE id = \\$1 AND deleted_at IS NULL").
+		WithArgs(666).WillReturnRows(sqlmock.NewRows([]string{"owner_id"}))
+
+	ownerId, err := r.GetAdvertOwner(1)
+	assert.NoError(t, err)
+	assert.Equal(t, intPtr(7), ownerId)
+
+	ownerId, err = r.GetAdvertOwner(2)
+	assert.NoError(t, err)
+	assert.Nil(t, ownerId)
+
+	_, err = r.GetAdvertOwner(666)
+	assert.Equal(t, ErrAdvertNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_getAdvertsByOwner(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "name", "price", "category_id", "status", "createdat", "archived_at", "main_picture"}).
+		AddRow(2, "Велосипед", 5000, 3, "draft", createdAt, nil, "").
+		AddRow(1, "Самокат", 3000, 3, "active", createdAt, createdAt, "avito/files/ad1")
+	mock.ExpectQuery("SELECT (.+) FROM adverts a LEFT JOIN advert_pictures p (.+) WHERE a.owner_id = \\$1 AND deleted_at IS NULL ORDER BY a.createdat DESC, a.id DESC").
+		WithArgs(7).WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.+) FROM adverts a").
+		WithArgs(8).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	adverts, err := r.GetAdvertsByOwner(7)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Advert{
+		{Id: 2, Name: "Велосипед", Price: 5000, CategoryId: 3, Status: model.StatusDraft, CreatedAt: &createdAt},
+		{Id: 1, Name: "Самокат", Price: 3000, CategoryId: 3, Status: model.StatusActive, CreatedAt: &createdAt, ArchivedAt: &createdAt, MainPicture: "avito/files/ad1"},
+	}, adverts)
+
+	adverts, err = r.GetAdvertsByOwner(8)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Advert{}, adverts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRepository_getAdvertList(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	if err != nil {
@@ -168,9 +270,8 @@ func TestRepository_getAdvertList(t *testing.T) {
 	r := NewAdvertRepository(db)
 
 	type args struct {
-		page        int
-		orderField  string
-		orderDirect string
+		list model.ListQuery
+		page model.ListPage
 	}
 
 	tests := []struct {
@@ -183,38 +284,214 @@ func TestRepository_getAdvertList(t *testing.T) {
 		{
 			name: "Ok",
 			mock: func() {
-				rows := sqlmock.NewRows([]string{"name", "price", "pictures"}).
-					AddRow("name-test1", 1000, "avito/files/ad1,avito/files/ad2,avito/files/ad3").
-					AddRow("name-test2", 100, "avito/files/ad1,avito/files/ad2,avito/files/ad3").
-					AddRow("name-test3", 10, "avito/files/ad1,avito/files/ad2,avito/files/ad3")
+				rows := sqlmock.NewRows([]string{"name", "price", "main_picture", "main_picture_variants"}).
+					AddRow("name-test1", 1000, "avito/files/ad1_thumb.jpg", `{"thumb":"avito/files/ad1_thumb.jpg","large":"avito/files/ad1_large.jpg"}`).
+					AddRow("name-test2", 100, "avito/files/ad2", `{}`).
+					AddRow("name-test3", 10, "", nil)
 
-				mock.ExpectQuery("SELECT (.+) FROM adverts ORDER BY (.+)  LIMIT (.+) OFFSET (.+)").
-					WithArgs(1).WillReturnRows(rows)
+				mock.ExpectQuery("SELECT (.+) FROM adverts a LEFT JOIN advert_pictures p ON p.advert_id = a.id AND p.is_main " +
+					"WHERE deleted_at IS NULL AND archived_at IS NULL AND status = 'active' ORDER BY a.price DESC, a.id DESC LIMIT \\$1 OFFSET 0").
+					WithArgs(11).WillReturnRows(rows)
 			},
 			input: args{
-				page:        1,
-				orderField:  "price",
-				orderDirect: "desc",
+				page: model.ListPage{Limit: 11, OrderField: "price", OrderDirect: "desc"},
 			},
 			want: []model.Advert{
 				{
-					Name:     "name-test1",
-					Price:    1000,
-					Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+					Name:        "name-test1",
+					Price:       1000,
+					MainPicture: "avito/files/ad1_thumb.jpg",
+					MainPictureVariants: model.PictureVariants{
+						model.VariantThumb: "avito/files/ad1_thumb.jpg",
+						model.VariantLarge: "avito/files/ad1_large.jpg",
+					},
+				},
+				{
+					Name:        "name-test2",
+					Price:       100,
+					MainPicture: "avito/files/ad2",
 				},
 				{
-					Name:     "name-test2",
-					Price:    100,
-					Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+					Name:  "name-test3",
+					Price: 10,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Ok with archived",
+			mock: func() {
+				archivedAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+				rows := sqlmock.NewRows([]string{"name", "price", "main_picture", "archived_at"}).
+					AddRow("name-test1", 1000, "avito/files/ad1", archivedAt)
+
+				mock.ExpectQuery("SELECT (.+) FROM adverts a LEFT JOIN advert_pictures p ON (.+) " +
+					"WHERE deleted_at IS NULL AND status = 'active' ORDER BY (.+) LIMIT \\$1 OFFSET 10").
+					WithArgs(11).WillReturnRows(rows)
+			},
+			input: args{
+				list: model.ListQuery{IncludeArchived: true},
+				page: model.ListPage{Offset: 10, Limit: 11, OrderField: "createdat", OrderDirect: "desc"},
+			},
+			want: []model.Advert{
+				{
+					Name:        "name-test1",
+					Price:       1000,
+					MainPicture: "avito/files/ad1",
+					ArchivedAt:  timePtr(time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Ok with cursor",
+			mock: func() {
+				createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+				rows := sqlmock.NewRows([]string{"id", "name", "price", "createdat"}).
+					AddRow(4, "name-test4", 400, createdAt)
+
+				mock.ExpectQuery("SELECT (.+) FROM adverts a LEFT JOIN advert_pictures p ON (.+) "+
+					"WHERE deleted_at IS NULL AND archived_at IS NULL AND status = 'active' "+
+					"AND \\(a.createdat, a.id\\) < \\(\\$2::timestamptz, \\$3\\) ORDER BY a.createdat DESC, a.id DESC LIMIT \\$1$").
+					WithArgs(11, "2021-07-02T12:00:00Z", 5).WillReturnRows(rows)
+			},
+			input: args{
+				page: model.ListPage{
+					Limit:       11,
+					OrderField:  "createdat",
+					OrderDirect: "desc",
+					Cursor:      &model.ListCursor{OrderBy: "createdat_desc", Value: "2021-07-02T12:00:00Z", Id: 5},
 				},
+			},
+			want: []model.Advert{
 				{
-					Name:     "name-test3",
-					Price:    10,
-					Pictures: "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+					Id:        4,
+					Name:      "name-test4",
+					Price:     400,
+					CreatedAt: timePtr(time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)),
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "Ok with backward cursor",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "name", "price"}).
+					AddRow(6, "name-test6", 600)
+
+				mock.ExpectQuery("SELECT (.+) FROM adverts a LEFT JOIN advert_pictures p ON (.+) "+
+					"WHERE (.+) AND \\(a.price, a.id\\) > \\(\\$2::integer, \\$3\\) "+
+					"ORDER BY a.price ASC, a.id ASC LIMIT \\$1$").
+					WithArgs(11, "500", 5).WillReturnRows(rows)
+			},
+			input: args{
+				page: model.ListPage{
+					Limit:       11,
+					OrderField:  "price",
+					OrderDirect: "desc",
+					Cursor:      &model.ListCursor{OrderBy: "price_desc", Value: "500", Id: 5, Backward: true},
+				},
+			},
+			want: []model.Advert{
+				{
+					Id:    6,
+					Name:  "name-test6",
+					Price: 600,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Ok with filters and cursor",
+			mock: func() {
+				from := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+				rows := sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(6, "name-test6", 600)
+
+				mock.ExpectQuery("SELECT (.+) FROM adverts a LEFT JOIN advert_pictures p ON (.+) "+
+					"WHERE deleted_at IS NULL AND archived_at IS NULL AND status = 'active' AND a.price >= \\$2 AND a.createdat >= \\$3 "+
+					"AND EXISTS \\(SELECT 1 FROM advert_pictures ap WHERE ap.advert_id = a.id\\) "+
+					"AND \\(a.name ILIKE \\$4 ESCAPE '\\\\' OR a.description ILIKE \\$4 ESCAPE '\\\\'\\) "+
+					"AND \\(a.price, a.id\\) < \\(\\$5::integer, \\$6\\) ORDER BY a.price DESC, a.id DESC LIMIT \\$1$").
+					WithArgs(11, 100, from, `%50\%%`, "700", 7).WillReturnRows(rows)
+			},
+			input: args{
+				list: model.ListQuery{
+					PriceMin:    intPtr(100),
+					CreatedFrom: timePtr(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)),
+					HasPictures: boolPtr(true),
+					Q:           "50%",
+				},
+				page: model.ListPage{
+					Limit:       11,
+					OrderField:  "price",
+					OrderDirect: "desc",
+					Cursor:      &model.ListCursor{OrderBy: "price_desc", Value: "700", Id: 7},
+				},
+			},
+			want: []model.Advert{
+				{
+					Id:    6,
+					Name:  "name-test6",
+					Price: 600,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Ok by distance with radius and cursor",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "name", "price", "lat", "lon", "city", "distance_km"}).
+					AddRow(8, "name-test8", 800, 55.76, 37.64, "Москва", 1.6)
+
+				distance := "\\(earth_distance\\(ll_to_earth\\(\\$%[1]d, \\$%[2]d\\), ll_to_earth\\(a.lat, a.lon\\)\\) / 1000\\)"
+				mock.ExpectQuery("SELECT (.+), a.archived_at, "+fmt.Sprintf(distance, 5, 6)+" AS distance_km FROM adverts a "+
+					"LEFT JOIN advert_pictures p ON (.+) "+
+					"WHERE (.+) AND status = 'active' AND a.lat IS NOT NULL "+
+					"AND earth_box\\(ll_to_earth\\(\\$2, \\$3\\), \\$4\\) @> ll_to_earth\\(a.lat, a.lon\\) "+
+					"AND earth_distance\\(ll_to_earth\\(\\$2, \\$3\\), ll_to_earth\\(a.lat, a.lon\\)\\) <= \\$4 "+
+					"AND a.lat IS NOT NULL AND \\("+fmt.Sprintf(distance, 5, 6)+", a.id\\) > \\(\\$7::float8, \\$8\\) "+
+					"ORDER BY "+fmt.Sprintf(distance, 5, 6)+" ASC, a.id ASC LIMIT \\$1$").
+					WithArgs(11, 55.75, 37.62, 5000.0, 55.75, 37.62, "1.2", 3).WillReturnRows(rows)
+			},
+			input: args{
+				list: model.ListQuery{
+					Near:     &model.GeoPoint{Lat: 55.75, Lon: 37.62},
+					RadiusKm: floatPtr(5),
+				},
+				page: model.ListPage{
+					Limit:       11,
+					OrderField:  model.OrderDistance,
+					OrderDirect: "asc",
+					Cursor:      &model.ListCursor{OrderBy: "distance_asc", Value: "1.2", Id: 3},
+				},
+			},
+			want: []model.Advert{
+				{
+					Id:         8,
+					Name:       "name-test8",
+					Price:      800,
+					Lat:        floatPtr(55.76),
+					Lon:        floatPtr(37.64),
+					City:       "Москва",
+					DistanceKm: floatPtr(1.6),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Distance without a point",
+			mock:    func() {},
+			input:   args{page: model.ListPage{Limit: 11, OrderField: model.OrderDistance, OrderDirect: "asc"}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported order field",
+			mock:    func() {},
+			input:   args{page: model.ListPage{Limit: 11, OrderField: "name", OrderDirect: "desc"}},
+			want:    nil,
+			wantErr: true,
+		},
 		//make test "Not Found - wit error"
 	}
 
@@ -222,7 +499,87 @@ func TestRepository_getAdvertList(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			test.mock()
 
-			got, err := r.GetAdvertList(test.input.page, test.input.orderField, test.input.orderDirect)
+			got, err := r.GetAdvertList(test.input.list, test.input.page)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_countAdverts(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		list    model.ListQuery
+		mode    string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "Exact",
+			mock: func() {
+				mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM adverts a WHERE deleted_at IS NULL AND archived_at IS NULL AND status = 'active'").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+			},
+			mode: model.CountExact,
+			want: 42,
+		},
+		{
+			name: "Exact with filters",
+			mock: func() {
+				mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM adverts a WHERE (.+) AND a.price <= \\$1 AND \\(a.name ILIKE \\$2 (.+)").
+					WithArgs(500, "%bike%").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+			},
+			list: model.ListQuery{PriceMax: intPtr(500), Q: "bike"},
+			mode: model.CountExact,
+			want: 3,
+		},
+		{
+			name: "Estimated",
+			mock: func() {
+				plan := `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "adverts", "Plan Rows": 1250, "Plan Width": 4}}]`
+				mock.ExpectQuery("EXPLAIN \\(FORMAT JSON\\) SELECT 1 FROM adverts a WHERE deleted_at IS NULL AND status = 'active'").
+					WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(plan))
+			},
+			list: model.ListQuery{IncludeArchived: true},
+			mode: model.CountEstimated,
+			want: 1250,
+		},
+		{
+			name: "Broken plan",
+			mock: func() {
+				mock.ExpectQuery("EXPLAIN (.+)").WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(`[]`))
+			},
+			mode:    model.CountEstimated,
+			wantErr: true,
+		},
+		{
+			name:    "Unknown mode",
+			mock:    func() {},
+			mode:    "approximate",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			got, err := r.CountAdverts(test.list, test.mode)
 			if test.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -233,3 +590,572 @@ func TestRepository_getAdvertList(t *testing.T) {
 		})
 	}
 }
+
+func TestRepository_updateAdvert(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	advert := model.Advert{
+		Name:        "name-test",
+		Description: "desc-test",
+		Price:       1000,
+		CategoryId:  3,
+		Pictures:    model.Pictures{{URL: "avito/files/ad1", Position: 0, IsMain: true}},
+		ScreeningFlags: model.RuleHits{
+			{Rule: "caps_title", Verdict: model.VerdictFlag, Message: "name is written in capital letters"},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		mock            func()
+		inputId         int
+		expectedRemoved model.Pictures
+		expectedErr     error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE adverts SET (.+) status = CASE WHEN status = 'active' OR \(\$11 AND status <> 'blocked'\) THEN 'pending' ELSE status END\s+WHERE id = (.+)`).
+					WithArgs("name-test", "desc-test", 1000, 3, "{}", nil, nil, "", "", `[{"rule":"caps_title","verdict":"flag","message":"name is written in capital letters"}]`, true, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`DELETE FROM advert_pictures WHERE advert_id = (.+) AND url <> ALL\(\$2\) RETURNING url, variants`).
+					WithArgs(1, pq.Array([]string{"avito/files/ad1"})).WillReturnRows(sqlmock.NewRows([]string{"url", "variants"}).
+					AddRow("avito/files/ad2", `{"thumb":"avito/files/ad2_thumb.jpg"}`))
+				mock.ExpectExec("UPDATE advert_pictures SET is_main = FALSE WHERE advert_id = (.+) AND is_main").
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				// the kept pictures are updated in place, their variants and link check state stay
+				mock.ExpectExec(`INSERT INTO advert_pictures \(advert_id, url, position, is_main\)\s+SELECT (.+) FROM unnest(.+)\s+ON CONFLICT \(advert_id, url\) DO UPDATE SET position = EXCLUDED.position, is_main = EXCLUDED.is_main`).
+					WithArgs(1, pq.Array([]string{"avito/files/ad1"}), pq.Array([]int{0}), pq.Array([]bool{true})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			inputId: 1,
+			expectedRemoved: model.Pictures{
+				{URL: "avito/files/ad2", Variants: model.PictureVariants{"thumb": "avito/files/ad2_thumb.jpg"}},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE adverts SET (.+) WHERE id = (.+)").
+					WithArgs("name-test", "desc-test", 1000, 3, "{}", nil, nil, "", "", `[{"rule":"caps_title","verdict":"flag","message":"name is written in capital letters"}]`, true, 666).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			inputId:     666,
+			expectedErr: ErrAdvertNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			removed, err := r.UpdateAdvert(test.inputId, advert)
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedRemoved, removed)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_deleteAdvert(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	tests := []struct {
+		name        string
+		mock        func()
+		inputId     int
+		expectedErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectExec("UPDATE adverts SET deleted_at = NOW\\(\\) WHERE id = (.+) AND deleted_at IS NULL").
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			inputId:     1,
+			expectedErr: nil,
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectExec("UPDATE adverts SET deleted_at = NOW\\(\\) WHERE id = (.+) AND deleted_at IS NULL").
+					WithArgs(666).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			inputId:     666,
+			expectedErr: ErrAdvertNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			err := r.DeleteAdvert(test.inputId)
+			assert.Equal(t, test.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_archiveAdvert(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	mock.ExpectExec("UPDATE adverts SET archived_at = NOW\\(\\) WHERE id = (.+) AND deleted_at IS NULL AND archived_at IS NULL").
+		WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE adverts SET archived_at = NOW\\(\\) WHERE id = (.+) AND deleted_at IS NULL AND archived_at IS NULL").
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, r.ArchiveAdvert(1))
+	assert.Equal(t, ErrAdvertNotFound, r.ArchiveAdvert(2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_restoreAdvert(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	mock.ExpectExec("UPDATE adverts SET deleted_at = NULL, archived_at = NULL WHERE id = (.+) AND \\(deleted_at IS NOT NULL OR archived_at IS NOT NULL\\)").
+		WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE adverts SET deleted_at = NULL, archived_at = NULL WHERE id = (.+)").
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, r.RestoreAdvert(1))
+	assert.Equal(t, ErrAdvertNotFound, r.RestoreAdvert(2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_updateAdvertStatus(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	mock.ExpectExec("UPDATE adverts SET status = (.+) WHERE id = (.+) AND status = (.+) AND deleted_at IS NULL AND archived_at IS NULL").
+		WithArgs(model.StatusPending, 1, model.StatusDraft).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE adverts SET status = (.+) WHERE id = (.+) AND status = (.+)").
+		WithArgs(model.StatusPending, 2, model.StatusDraft).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, r.UpdateAdvertStatus(1, model.StatusDraft, model.StatusPending))
+	assert.Equal(t, ErrAdvertNotFound, r.UpdateAdvertStatus(2, model.StatusDraft, model.StatusPending))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestRepository_reorderPictures(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	tests := []struct {
+		name        string
+		mock        func()
+		inputIds    []int
+		expectedErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE advert_pictures p SET position = (.+) FROM unnest(.+) WHERE p.id = o.id AND p.advert_id = (.+)").
+					WithArgs(1, pq.Array([]int{3, 1, 2})).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			},
+			inputIds:    []int{3, 1, 2},
+			expectedErr: nil,
+		},
+		{
+			name: "Picture of another advert",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE advert_pictures p SET position = (.+)").
+					WithArgs(1, pq.Array([]int{3, 7})).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectRollback()
+			},
+			inputIds:    []int{3, 7},
+			expectedErr: ErrPictureNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			err := r.ReorderPictures(1, test.inputIds)
+			assert.Equal(t, test.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_setMainPicture(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	tests := []struct {
+		name        string
+		mock        func()
+		inputId     int
+		expectedErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE advert_pictures SET is_main = FALSE WHERE advert_id = (.+) AND is_main AND id <> (.+)").
+					WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE advert_pictures SET is_main = TRUE WHERE advert_id = (.+) AND id = (.+)").
+					WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			inputId:     2,
+			expectedErr: nil,
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE advert_pictures SET is_main = FALSE (.+)").
+					WithArgs(1, 666).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE advert_pictures SET is_main = TRUE (.+)").
+					WithArgs(1, 666).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			inputId:     666,
+			expectedErr: ErrPictureNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			err := r.SetMainPicture(1, test.inputId)
+			assert.Equal(t, test.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_addPicture(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	tests := []struct {
+		name            string
+		mock            func()
+		expectedPicture model.Picture
+		expectedErr     error
+	}{
+		{
+			name: "First picture",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM adverts WHERE id = (.+) FOR UPDATE").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("SELECT COUNT(.+), COALESCE(.+) FROM advert_pictures WHERE advert_id = (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count", "position"}).AddRow(0, 0))
+				mock.ExpectQuery("INSERT INTO advert_pictures (.+) RETURNING id").
+					WithArgs(1, "http://localhost:8080/uploads/a.png", 0, true).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+				mock.ExpectExec("UPDATE adverts SET status = 'pending' WHERE id = (.+) AND status = 'active'").
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			expectedPicture: model.Picture{Id: 5, URL: "http://localhost:8080/uploads/a.png", Position: 0, IsMain: true},
+			expectedErr:     nil,
+		},
+		{
+			name: "Appended picture",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM adverts (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("SELECT COUNT(.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count", "position"}).AddRow(2, 2))
+				mock.ExpectQuery("INSERT INTO advert_pictures (.+)").
+					WithArgs(1, "http://localhost:8080/uploads/a.png", 2, false).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				mock.ExpectExec("UPDATE adverts SET status = 'pending' (.+)").
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			expectedPicture: model.Picture{Id: 7, URL: "http://localhost:8080/uploads/a.png", Position: 2, IsMain: false},
+			expectedErr:     nil,
+		},
+		{
+			name: "Active advert goes back to pending",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM adverts (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("SELECT COUNT(.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count", "position"}).AddRow(1, 1))
+				mock.ExpectQuery("INSERT INTO advert_pictures (.+)").
+					WithArgs(1, "http://localhost:8080/uploads/a.png", 1, false).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+				mock.ExpectExec("UPDATE adverts SET status = 'pending' WHERE id = (.+) AND status = 'active'").
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedPicture: model.Picture{Id: 8, URL: "http://localhost:8080/uploads/a.png", Position: 1, IsMain: false},
+			expectedErr:     nil,
+		},
+		{
+			name: "Limit reached",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM adverts (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("SELECT COUNT(.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count", "position"}).AddRow(3, 3))
+				mock.ExpectRollback()
+			},
+			expectedPicture: model.Picture{URL: "http://localhost:8080/uploads/a.png", Position: 3},
+			expectedErr:     ErrPictureLimit,
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM adverts (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+			},
+			expectedPicture: model.Picture{URL: "http://localhost:8080/uploads/a.png"},
+			expectedErr:     ErrAdvertNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			picture, err := r.AddPicture(1, "http://localhost:8080/uploads/a.png", 3)
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedPicture, picture)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_setPictureVariants(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	variants := model.PictureVariants{model.VariantThumb: "uploads/a_thumb.jpg"}
+
+	tests := []struct {
+		name        string
+		mock        func()
+		expectedErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectExec("UPDATE advert_pictures SET variants = (.+) WHERE advert_id = (.+) AND url = (.+)").
+					WithArgs(1, "uploads/a.jpg", `{"thumb":"uploads/a_thumb.jpg"}`).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "Picture removed",
+			mock: func() {
+				mock.ExpectExec("UPDATE advert_pictures SET variants = (.+)").
+					WithArgs(1, "uploads/a.jpg", `{"thumb":"uploads/a_thumb.jpg"}`).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedErr: ErrPictureNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			err := r.SetPictureVariants(1, "uploads/a.jpg", variants)
+			assert.Equal(t, test.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_getPicturesToCheck(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	checkedBefore := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "advert_id", "url", "position", "is_main", "broken"}).
+		AddRow(1, 1, "https://avito.ru/ad1.jpg", 0, true, false).
+		AddRow(4, 2, "https://avito.ru/ad4.jpg", 1, false, true)
+	mock.ExpectQuery("SELECT (.+) FROM advert_pictures p JOIN adverts a ON a.id = p.advert_id AND a.deleted_at IS NULL "+
+		"WHERE p.checked_at IS NULL OR p.checked_at < (.+) ORDER BY p.checked_at NULLS FIRST, p.id LIMIT (.+)").
+		WithArgs(checkedBefore, 10).WillReturnRows(rows)
+
+	pictures, err := r.GetPicturesToCheck(10, checkedBefore)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Picture{
+		{Id: 1, AdvertId: 1, URL: "https://avito.ru/ad1.jpg", Position: 0, IsMain: true},
+		{Id: 4, AdvertId: 2, URL: "https://avito.ru/ad4.jpg", Position: 1, Broken: true},
+	}, pictures)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_setPictureBroken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	tests := []struct {
+		name        string
+		mock        func()
+		inputBroken bool
+		expectedErr error
+	}{
+		{
+			name: "Working picture",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE advert_pictures SET broken = (.+), checked_at = NOW(.+) WHERE id = (.+) RETURNING (.+)").
+					WithArgs(2, false).WillReturnRows(sqlmock.NewRows([]string{"id", "advert_id", "position", "is_main"}).AddRow(2, 1, 1, true))
+				mock.ExpectCommit()
+			},
+			inputBroken: false,
+			expectedErr: nil,
+		},
+		{
+			name: "Broken main picture",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE advert_pictures SET broken = (.+)").
+					WithArgs(2, true).WillReturnRows(sqlmock.NewRows([]string{"id", "advert_id", "position", "is_main"}).AddRow(2, 1, 1, true))
+				mock.ExpectQuery("SELECT id FROM advert_pictures WHERE advert_id = (.+) AND id <> (.+) AND NOT broken ORDER BY position < (.+), position LIMIT 1").
+					WithArgs(1, 2, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec("UPDATE advert_pictures SET is_main = FALSE WHERE id = (.+)").
+					WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE advert_pictures SET is_main = TRUE WHERE id = (.+)").
+					WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			inputBroken: true,
+			expectedErr: nil,
+		},
+		{
+			name: "All pictures broken",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE advert_pictures SET broken = (.+)").
+					WithArgs(2, true).WillReturnRows(sqlmock.NewRows([]string{"id", "advert_id", "position", "is_main"}).AddRow(2, 1, 1, true))
+				mock.ExpectQuery("SELECT id FROM advert_pictures (.+)").
+					WithArgs(1, 2, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectCommit()
+			},
+			inputBroken: true,
+			expectedErr: nil,
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE advert_pictures SET broken = (.+)").
+					WithArgs(2, true).WillReturnRows(sqlmock.NewRows([]string{"id", "advert_id", "position", "is_main"}))
+				mock.ExpectRollback()
+			},
+			inputBroken: true,
+			expectedErr: ErrPictureNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			err := r.SetPictureBroken(2, test.inputBroken)
+			assert.Equal(t, test.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+const (
+	APIKEYSTABLE     = "api_keys"
+	APIKEYUSAGETABLE = "api_key_usage"
+)
+
+var ErrAPIKeyNotFound = apperror.New(apperror.ErrNotFound, "api key not found")
+
+type APIKeyPostgres struct {
+	DB *sqlx.DB
+}
+
+func NewAPIKeyPostgres(db *sqlx.DB) *APIKeyPostgres {
+	return &APIKeyPostgres{DB: db}
+}
+
+func (r *APIKeyPostgres) CreateAPIKey(key model.APIKey) (int, error) {
+	var id int
+	query := fmt.Sprintf(`INSERT INTO %s (name, prefix, key_hash, owner_id, scopes, daily_quota)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`, APIKEYSTABLE)
+	err := r.DB.QueryRow(query, key.Name, key.Prefix, key.KeyHash, key.OwnerId, key.Scopes, key.DailyQuota).Scan(&id)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	return id, nil
+}
+
+// GetAPIKeys returns all the keys, the revoked ones included, with the
+// requests made on the day.
+func (r *APIKeyPostgres) GetAPIKeys(day time.Time) ([]model.APIKey, error) {
+	keys := []model.APIKey{}
+	query := fmt.Sprintf(`SELECT k.id, k.name, k.prefix, k.owner_id, k.scopes, k.daily_quota,
+			COALESCE(u.requests, 0) AS used_today, k.created_at, k.last_used_at, k.revoked_at
+		FROM %s k LEFT JOIN %s u ON u.key_id = k.id AND u.day = $1
+		ORDER BY k.id`, APIKEYSTABLE, APIKEYUSAGETABLE)
+	if err := r.DB.Select(&keys, query, day.Format("2006-01-02")); err != nil {
+		return nil, dbError(err)
+	}
+	return keys, nil
+}
+
+// GetAPIKeyByHash finds a key that is not revoked together with the role of
+// its owner.
+func (r *APIKeyPostgres) GetAPIKeyByHash(hash string) (model.APIKey, error) {
+	var key model.APIKey
+	query := fmt.Sprintf(`SELECT k.id, k.name, k.prefix, k.key_hash, k.owner_id, COALESCE(u.role, '') AS owner_role,
+		k.scopes, k.daily_quota, k.created_at, k.last_used_at
+		FROM %s k LEFT JOIN %s u ON u.id = k.owner_id WHERE k.key_hash = $1 AND k.revoked_at IS NULL`, APIKEYSTABLE, USERSTABLE)
+	if err := r.DB.Get(&key, query, hash); err != nil {
+		if err == sql.ErrNoRows {
+			return key, ErrAPIKeyNotFound
+		}
+		return key, dbError(err)
+	}
+	return key, nil
+}
+
+// RevokeAPIKey revokes a key, a key revoked before is not found.
+func (r *APIKeyPostgres) RevokeAPIKey(keyId int) error {
+	query := fmt.Sprintf("UPDATE %s SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", APIKEYSTABLE)
+	res, err := r.DB.Exec(query, keyId)
+	if err != nil {
+		return dbError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+	if affected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// UseAPIKey records a request made with the key at the time and returns the
+// number of the requests made with it on the UTC day of the time.
+func (r *APIKeyPostgres) UseAPIKey(keyId int, at time.Time) (int, error) {
+	var requests int
+	query := fmt.Sprintf(`WITH used AS (UPDATE %s SET last_used_at = $2 WHERE id = $1)
+		INSERT INTO %s (key_id, day, requests) VALUES ($1, $3, 1)
+		ON CONFLICT (key_id, day) DO UPDATE SET requests = %[2]s.requests + 1
+		RETURNING requests`, APIKEYSTABLE, APIKEYUSAGETABLE)
+	if err := r.DB.QueryRow(query, keyId, at, at.UTC().Format("2006-01-02")).Scan(&requests); err != nil {
+		return 0, dbError(err)
+	}
+	return requests, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAPIKeyMock(t *testing.T) (*APIKeyPostgres, sqlmock.Sqlmock, func()) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+	return NewAPIKeyPostgres(sqlx.NewDb(mockDB, "sqlmock")), mock, func() { mockDB.Close() }
+}
+
+func TestAPIKeyRepository_createAPIKey(t *testing.T) {
+	r, mock, done := newAPIKeyMock(t)
+	defer done()
+
+	owner := 7
+	mock.ExpectQuery("INSERT INTO api_keys \\(name, prefix, key_hash, owner_id, scopes, daily_quota\\)").
+		WithArgs("feed", "ak_abcd", "hash", &owner, `["adverts:read","adverts:write"]`, 1000).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	id, err := r.CreateAPIKey(model.APIKey{
+		Name: "feed", Prefix: "ak_abcd", KeyHash: "hash", OwnerId: &owner,
+		Scopes: model.Scopes{model.ScopeAdvertsRead, model.ScopeAdvertsWrite}, DailyQuota: 1000,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_getAPIKeys(t *testing.T) {
+	r, mock, done := newAPIKeyMock(t)
+	defer done()
+
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "name", "prefix", "owner_id", "scopes", "daily_quota", "used_today", "created_at", "last_used_at", "revoked_at"}
+	mock.ExpectQuery("SELECT (.+) FROM api_keys k LEFT JOIN api_key_usage u ON u.key_id = k.id AND u.day = \\$1 ORDER BY k.id").
+		WithArgs("2021-07-02").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(3, "feed", "ak_abcd", 7, []byte(`["admin"]`), 0, 12, createdAt, createdAt, nil))
+	mock.ExpectQuery("SELECT (.+) FROM api_keys").WithArgs("2021-07-02").WillReturnRows(sqlmock.NewRows(columns))
+
+	keys, err := r.GetAPIKeys(time.Date(2021, 7, 2, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	owner := 7
+	assert.Equal(t, []model.APIKey{{
+		Id: 3, Name: "feed", Prefix: "ak_abcd", OwnerId: &owner, Scopes: model.Scopes{model.ScopeAdmin},
+		UsedToday: 12, CreatedAt: &createdAt, LastUsedAt: &createdAt,
+	}}, keys)
+
+	keys, err = r.GetAPIKeys(time.Date(2021, 7, 2, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []model.APIKey{}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_getAPIKeyByHash(t *testing.T) {
+	r, mock, done := newAPIKeyMock(t)
+	defer done()
+
+	columns := []string{"id", "name", "prefix", "key_hash", "owner_id", "owner_role", "scopes", "daily_quota", "created_at", "last_used_at"}
+	mock.ExpectQuery("SELECT (.+) FROM api_keys k LEFT JOIN users u ON u.id = k.owner_id WHERE k.key_hash = \\$1 AND k.revoked_at IS NULL").
+		WithArgs("hash").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(3, "feed", "ak_abcd", "hash", 7, "seller", []byte(`["adverts:read"]`), 100, nil, nil))
+	mock.ExpectQuery("SELECT (.+) FROM api_keys k LEFT JOIN users u").
+		WithArgs("revoked").WillReturnRows(sqlmock.NewRows(columns))
+
+	key, err := r.GetAPIKeyByHash("hash")
+	assert.NoError(t, err)
+	ownerId := 7
+	assert.Equal(t, model.APIKey{Id: 3, Name: "feed", Prefix: "ak_abcd", KeyHash: "hash", OwnerId: &ownerId,
+		OwnerRole: model.RoleSeller, Scopes: model.Scopes{model.ScopeAdvertsRead}, DailyQuota: 100}, key)
+
+	_, err = r.GetAPIKeyByHash("revoked")
+	assert.Equal(t, ErrAPIKeyNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_revokeAPIKey(t *testing.T) {
+	r, mock, done := newAPIKeyMock(t)
+	defer done()
+
+	mock.ExpectExec("UPDATE api_keys SET revoked_at = NOW\\(\\) WHERE id = \\$1 AND revoked_at IS NULL").
+		WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE api_keys SET revoked_at").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, r.RevokeAPIKey(3))
+	assert.Equal(t, ErrAPIKeyNotFound, r.RevokeAPIKey(3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_useAPIKey(t *testing.T) {
+	r, mock, done := newAPIKeyMock(t)
+	defer done()
+
+	// the day of the usage is the UTC one
+	at := time.Date(2021, 7, 2, 1, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	mock.ExpectQuery("WITH used AS \\(UPDATE api_keys SET last_used_at = \\$2 WHERE id = \\$1\\) "+
+		"INSERT INTO api_key_usage \\(key_id, day, requests\\) VALUES \\(\\$1, \\$3, 1\\) "+
+		"ON CONFLICT \\(key_id, day\\) DO UPDATE SET requests = api_key_usage.requests \\+ 1 RETURNING requests").
+		WithArgs(3, at, "2021-07-01").WillReturnRows(sqlmock.NewRows([]string{"requests"}).AddRow(5))
+
+	requests, err := r.UseAPIKey(3, at)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, requests)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
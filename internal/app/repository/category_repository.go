@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+const CATEGORIESTABLE = "categories"
+
+var (
+	ErrCategoryNotFound = apperror.New(apperror.ErrNotFound, "category not found")
+	ErrCategoryExists   = apperror.New(apperror.ErrConflict, "the parent already has a category with this name")
+	ErrCategoryInUse    = apperror.New(apperror.ErrConflict, "category has subcategories or advertisements")
+)
+
+// subtreeQuery selects the ids of the category $1 and of all its
+// descendants, the category itself comes first.
+var subtreeQuery = fmt.Sprintf(`WITH RECURSIVE subtree (id, depth) AS (
+		SELECT id, 0 FROM %[1]s WHERE id = $1
+		UNION ALL
+		SELECT c.id, s.depth + 1 FROM %[1]s c JOIN subtree s ON c.parent_id = s.id
+	)
+	SELECT id FROM subtree ORDER BY depth, id`, CATEGORIESTABLE)
+
+type CategoryPostgres struct {
+	DB *sqlx.DB
+}
+
+func NewCategoryPostgres(db *sqlx.DB) *CategoryPostgres {
+	return &CategoryPostgres{DB: db}
+}
+
+func (r *CategoryPostgres) CreateCategory(category model.Category) (int, error) {
+	var id int
+	query := fmt.Sprintf("INSERT INTO %s (parent_id, name, attributes) VALUES ($1, $2, $3) RETURNING id", CATEGORIESTABLE)
+	if err := r.DB.QueryRow(query, category.ParentId, category.Name, category.Attributes).Scan(&id); err != nil {
+		return 0, categoryError(err)
+	}
+	return id, nil
+}
+
+func (r *CategoryPostgres) GetCategory(categoryId int) (model.Category, error) {
+	var category model.Category
+	query := fmt.Sprintf("SELECT id, parent_id, name, attributes FROM %s WHERE id = $1", CATEGORIESTABLE)
+	if err := r.DB.Get(&category, query, categoryId); err != nil {
+		if err == sql.ErrNoRows {
+			return category, ErrCategoryNotFound
+		}
+		return category, dbError(err)
+	}
+	return category, nil
+}
+
+// GetCategories returns all the categories ordered by the name, the tree is
+// assembled by the caller.
+func (r *CategoryPostgres) GetCategories() ([]model.Category, error) {
+	var categories []model.Category
+	query := fmt.Sprintf("SELECT id, parent_id, name, attributes FROM %s ORDER BY name, id", CATEGORIESTABLE)
+	if err := r.DB.Select(&categories, query); err != nil {
+		return nil, dbError(err)
+	}
+	return categories, nil
+}
+
+// GetSubtree returns the id of the category followed by the ids of its
+// descendants, the result is empty when there is no such category.
+func (r *CategoryPostgres) GetSubtree(categoryId int) ([]int, error) {
+	var ids []int
+	if err := r.DB.Select(&ids, subtreeQuery, categoryId); err != nil {
+		return nil, dbError(err)
+	}
+	return ids, nil
+}
+
+func (r *CategoryPostgres) UpdateCategory(categoryId int, category model.Category) error {
+	query := fmt.Sprintf("UPDATE %s SET parent_id = $1, name = $2, attributes = $3 WHERE id = $4", CATEGORIESTABLE)
+	res, err := r.DB.Exec(query, category.ParentId, category.Name, category.Attributes, categoryId)
+	if err != nil {
+		return categoryError(err)
+	}
+	return checkCategoryAffected(res)
+}
+
+// DeleteCategory deletes a category without subcategories and adverts, the
+// soft deleted adverts still keep the category.
+func (r *CategoryPostgres) DeleteCategory(categoryId int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", CATEGORIESTABLE)
+	res, err := r.DB.Exec(query, categoryId)
+	if err != nil {
+		return categoryError(err)
+	}
+	return checkCategoryAffected(res)
+}
+
+func checkCategoryAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+	if affected == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// categoryError tells the violated constraints of the categories apart.
+func categoryError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrCategoryExists
+		case "foreign_key_violation":
+			// an unknown parent is checked by the service, a category still
+			// referenced by children or adverts remains
+			return ErrCategoryInUse
+		}
+	}
+	return dbError(err)
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryRepository_createCategory(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewCategoryPostgres(db)
+
+	mock.ExpectQuery("INSERT INTO categories \\(parent_id, name, attributes\\) VALUES").
+		WithArgs(nil, "Транспорт", "[]").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO categories \\(parent_id, name, attributes\\) VALUES").
+		WithArgs(1, "Квартиры", `[{"name":"rooms","type":"int","required":true}]`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("INSERT INTO categories \\(parent_id, name, attributes\\) VALUES").
+		WithArgs(1, "квартиры", "[]").WillReturnError(&pq.Error{Code: "23505"})
+
+	id, err := r.CreateCategory(model.Category{Name: "Транспорт"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	id, err = r.CreateCategory(model.Category{ParentId: intPtr(1), Name: "Квартиры", Attributes: model.AttributeSchema{
+		{Name: "rooms", Type: model.AttributeInt, Required: true},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, id)
+
+	_, err = r.CreateCategory(model.Category{ParentId: intPtr(1), Name: "квартиры"})
+	assert.Equal(t, ErrCategoryExists, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryRepository_getCategory(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewCategoryPostgres(db)
+
+	mock.ExpectQuery("SELECT id, parent_id, name, attributes FROM categories WHERE id = \\$1").
+		WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "name", "attributes"}).
+		AddRow(2, 1, "Велосипеды", []byte(`[{"name":"wheel","type":"number","min":12,"max":29}]`)))
+	mock.ExpectQuery("SELECT id, parent_id, name, attributes FROM categories WHERE id = \\$1").
+		WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "name", "attributes"}))
+	mock.ExpectQuery("SELECT id, parent_id, name, attributes FROM categories ORDER BY name, id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "name", "attributes"}).
+			AddRow(2, 1, "Велосипеды", []byte(`[]`)).
+			AddRow(1, nil, "Транспорт", []byte(`[]`)))
+
+	category, err := r.GetCategory(2)
+	assert.NoError(t, err)
+	min, max := 12.0, 29.0
+	assert.Equal(t, model.Category{Id: 2, ParentId: intPtr(1), Name: "Велосипеды", Attributes: model.AttributeSchema{
+		{Name: "wheel", Type: model.AttributeNumber, Min: &min, Max: &max},
+	}}, category)
+
+	_, err = r.GetCategory(7)
+	assert.Equal(t, ErrCategoryNotFound, err)
+
+	categories, err := r.GetCategories()
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Category{
+		{Id: 2, ParentId: intPtr(1), Name: "Велосипеды"},
+		{Id: 1, Name: "Транспорт"},
+	}, categories)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryRepository_getSubtree(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewCategoryPostgres(db)
+
+	mock.ExpectQuery("WITH RECURSIVE subtree (.+) FROM categories WHERE id = \\$1 UNION ALL (.+) JOIN subtree s ON c.parent_id = s.id").
+		WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(4).AddRow(5))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ids, err := r.GetSubtree(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 4, 5}, ids)
+
+	ids, err = r.GetSubtree(7)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryRepository_updateDeleteCategory(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewCategoryPostgres(db)
+
+	lost := &pq.Error{Code: "08006"}
+	tests := []struct {
+		name    string
+		mock    func()
+		run     func() error
+		wantErr error
+	}{
+		{
+			name: "Update",
+			mock: func() {
+				mock.ExpectExec("UPDATE categories SET parent_id = \\$1, name = \\$2, attributes = \\$3 WHERE id = \\$4").
+					WithArgs(3, "Велосипеды", "[]", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			run: func() error {
+				return r.UpdateCategory(2, model.Category{ParentId: intPtr(3), Name: "Велосипеды"})
+			},
+		},
+		{
+			name: "Update not found",
+			mock: func() {
+				mock.ExpectExec("UPDATE categories").
+					WithArgs(nil, "Велосипеды", "[]", 7).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			run:     func() error { return r.UpdateCategory(7, model.Category{Name: "Велосипеды"}) },
+			wantErr: ErrCategoryNotFound,
+		},
+		{
+			name: "Delete",
+			mock: func() {
+				mock.ExpectExec("DELETE FROM categories WHERE id = \\$1").
+					WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			run: func() error { return r.DeleteCategory(4) },
+		},
+		{
+			name: "Delete in use",
+			mock: func() {
+				mock.ExpectExec("DELETE FROM categories WHERE id = \\$1").
+					WithArgs(1).WillReturnError(&pq.Error{Code: "23503"})
+			},
+			run:     func() error { return r.DeleteCategory(1) },
+			wantErr: ErrCategoryInUse,
+		},
+		{
+			name: "Delete on a lost connection",
+			mock: func() {
+				mock.ExpectExec("DELETE FROM categories WHERE id = \\$1").
+					WithArgs(1).WillReturnError(lost)
+			},
+			run:     func() error { return r.DeleteCategory(1) },
+			wantErr: dbError(lost),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			assert.Equal(t, tt.wantErr, tt.run())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+)
+
+// dbError classifies a database error: lost connections and an overloaded
+// or restarting server make the service unavailable, a duplicate key
+// conflicts with the stored data, a missing reference, a null or a value out
+// of a check constraint fails the validation. Anything else is returned as
+// is.
+func dbError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return apperror.Wrap(apperror.ErrUnavailable, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return apperror.Wrap(apperror.ErrUnavailable, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		// connection exception, insufficient resources, operator intervention
+		case "08", "53", "57":
+			return apperror.Wrap(apperror.ErrUnavailable, err)
+		}
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return apperror.Wrap(apperror.ErrConflict, err)
+		case "foreign_key_violation", "not_null_violation", "check_violation":
+			return apperror.Wrap(apperror.ErrValidation, err)
+		}
+	}
+
+	return err
+}
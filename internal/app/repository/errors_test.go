@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_dbError(t *testing.T) {
+	tests := []struct {
+		name         string
+		inputError   error
+		expectedKind error
+	}{
+		{
+			name:         "Bad connection",
+			inputError:   driver.ErrBadConn,
+			expectedKind: apperror.ErrUnavailable,
+		},
+		{
+			name:         "Network error",
+			inputError:   &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			expectedKind: apperror.ErrUnavailable,
+		},
+		{
+			name:         "Server shutdown",
+			inputError:   &pq.Error{Code: "57P01", Message: "terminating connection due to administrator command"},
+			expectedKind: apperror.ErrUnavailable,
+		},
+		{
+			name:         "Unique violation",
+			inputError:   &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"},
+			expectedKind: apperror.ErrConflict,
+		},
+		{
+			name:         "Foreign key violation",
+			inputError:   &pq.Error{Code: "23503", Message: "insert or update on table \"adverts\" violates foreign key constraint \"adverts_category_id_fkey\""},
+			expectedKind: apperror.ErrValidation,
+		},
+		{
+			name:         "Check violation",
+			inputError:   &pq.Error{Code: "23514", Message: "new row for relation \"adverts\" violates check constraint \"adverts_price_check\""},
+			expectedKind: apperror.ErrValidation,
+		},
+		{
+			name:         "Exclusion violation",
+			inputError:   &pq.Error{Code: "23P01", Message: "conflicting key value violates exclusion constraint"},
+			expectedKind: nil,
+		},
+		{
+			name:         "Syntax error",
+			inputError:   &pq.Error{Code: "42601", Message: "syntax error"},
+			expectedKind: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := dbError(test.inputError)
+			assert.Equal(t, test.expectedKind, apperror.Kind(err))
+			assert.True(t, errors.Is(err, test.inputError))
+		})
+	}
+
+	assert.Nil(t, dbError(nil))
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+// listFilter collects the WHERE conditions of the advert list. Values are
+// always passed as query arguments, only placeholders end up in the SQL.
+type listFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+// newListFilter builds the conditions of query after the arguments the
+// caller has already placed.
+func newListFilter(query model.ListQuery, args ...interface{}) *listFilter {
+	f := &listFilter{args: args}
+	if query.IncludeArchived {
+		f.conditions = append(f.conditions, notDeletedCondition)
+	} else {
+		f.conditions = append(f.conditions, visibleCondition)
+	}
+	f.conditions = append(f.conditions, publicCondition)
+
+	if query.PriceMin != nil {
+		f.add("a.price >= %s", *query.PriceMin)
+	}
+	if query.PriceMax != nil {
+		f.add("a.price <= %s", *query.PriceMax)
+	}
+	if query.CreatedFrom != nil {
+		f.add("a.createdat >= %s", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		f.add("a.createdat <= %s", *query.CreatedTo)
+	}
+	if query.HasPictures != nil {
+		exists := fmt.Sprintf("EXISTS (SELECT 1 FROM %s ap WHERE ap.advert_id = a.id)", ADVERTPICTURESTABLE)
+		if !*query.HasPictures {
+			exists = "NOT " + exists
+		}
+		f.conditions = append(f.conditions, exists)
+	}
+	if query.Categories != nil {
+		f.add("a.category_id = ANY(%s)", pq.Array(query.Categories))
+	}
+	for _, attribute := range query.Attributes {
+		f.addAttribute(attribute)
+	}
+	if query.Near != nil && query.RadiusKm != nil {
+		// earth_box is a cube around the circle, the GiST index of the
+		// location finds the adverts in it, the distance cuts the corners
+		f.add("a.lat IS NOT NULL AND earth_box(ll_to_earth(%[1]s, %[2]s), %[3]s) @> ll_to_earth(a.lat, a.lon) "+
+			"AND earth_distance(ll_to_earth(%[1]s, %[2]s), ll_to_earth(a.lat, a.lon)) <= %[3]s",
+			query.Near.Lat, query.Near.Lon, *query.RadiusKm*1000)
+	}
+	if box := query.Box; box != nil {
+		if box.West <= box.East {
+			f.add("a.lat BETWEEN %s AND %s AND a.lon BETWEEN %s AND %s", box.South, box.North, box.West, box.East)
+		} else {
+			f.add("a.lat BETWEEN %s AND %s AND (a.lon >= %s OR a.lon <= %s)", box.South, box.North, box.West, box.East)
+		}
+	}
+	if query.Q != "" {
+		f.add(`(a.name ILIKE %[1]s ESCAPE '\' OR a.description ILIKE %[1]s ESCAPE '\')`, "%"+escapeLike(query.Q)+"%")
+	}
+	return f
+}
+
+// add appends a condition with its arguments, the verbs in condition are
+// replaced by the placeholders of the arguments in order.
+func (f *listFilter) add(condition string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i, arg := range args {
+		f.args = append(f.args, arg)
+		placeholders[i] = fmt.Sprintf("$%d", len(f.args))
+	}
+	f.conditions = append(f.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+// numericAttribute is the value of the attribute %[1]s when it is a
+// number and NULL otherwise, so a range never fails on a string value.
+const numericAttribute = "(CASE WHEN jsonb_typeof(a.attributes->%[1]s::text) = 'number' THEN (a.attributes->>%[1]s::text)::numeric END)"
+
+// addAttribute appends the conditions of an attribute filter. The equality
+// is a containment of every JSON value the filter value may stand for, the
+// GIN index of the attributes serves it.
+func (f *listFilter) addAttribute(attribute model.AttributeFilter) {
+	if attribute.Equal != nil {
+		var alternatives []string
+		for _, value := range attributeValues(*attribute.Equal) {
+			document, _ := json.Marshal(map[string]interface{}{attribute.Name: value})
+			f.args = append(f.args, string(document))
+			alternatives = append(alternatives, fmt.Sprintf("a.attributes @> $%d", len(f.args)))
+		}
+		f.conditions = append(f.conditions, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	if attribute.Min != nil {
+		f.add(numericAttribute+" >= %[2]s", attribute.Name, *attribute.Min)
+	}
+	if attribute.Max != nil {
+		f.add(numericAttribute+" <= %[2]s", attribute.Name, *attribute.Max)
+	}
+}
+
+// attributeValues returns the string and, when it reads as one, the number
+// or the boolean a filter value is.
+func attributeValues(value string) []interface{} {
+	values := []interface{}{value}
+	if n, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+		values = append(values, n)
+	} else if value == "true" || value == "false" {
+		values = append(values, value == "true")
+	}
+	return values
+}
+
+// distance returns the distance of the advert from the point in kilometers
+// as the earthdistance module measures it.
+func (f *listFilter) distance(point model.GeoPoint) string {
+	f.args = append(f.args, point.Lat, point.Lon)
+	return fmt.Sprintf("(earth_distance(ll_to_earth($%d, $%d), ll_to_earth(a.lat, a.lon)) / 1000)", len(f.args)-1, len(f.args))
+}
+
+func (f *listFilter) where() string {
+	return strings.Join(f.conditions, " AND ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes the wildcards of a LIKE pattern match literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
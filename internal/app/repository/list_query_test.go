@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_newListFilter(t *testing.T) {
+	from := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 7, 31, 0, 0, 0, 0, time.UTC)
+
+	filter := newListFilter(model.ListQuery{IncludeArchived: true})
+	assert.Equal(t, "deleted_at IS NULL AND status = 'active'", filter.where())
+	assert.Empty(t, filter.args)
+
+	// placeholders continue after the arguments placed by the caller
+	filter = newListFilter(model.ListQuery{
+		PriceMin:    intPtr(10),
+		PriceMax:    intPtr(20),
+		CreatedFrom: &from,
+		CreatedTo:   &to,
+		HasPictures: boolPtr(false),
+		CategoryId:  intPtr(2),
+		Categories:  []int{2, 4, 5},
+		Q:           "a_b",
+	}, 11)
+	assert.Equal(t, "deleted_at IS NULL AND archived_at IS NULL AND status = 'active' AND a.price >= $2 AND a.price <= $3 "+
+		"AND a.createdat >= $4 AND a.createdat <= $5 "+
+		"AND NOT EXISTS (SELECT 1 FROM advert_pictures ap WHERE ap.advert_id = a.id) "+
+		"AND a.category_id = ANY($6) "+
+		`AND (a.name ILIKE $7 ESCAPE '\' OR a.description ILIKE $7 ESCAPE '\')`, filter.where())
+	assert.Equal(t, []interface{}{11, 10, 20, from, to, pq.Array([]int{2, 4, 5}), `%a\_b%`}, filter.args)
+}
+
+func TestRepository_attributeFilter(t *testing.T) {
+	rooms, gearbox, min, max := "2", "auto", 1000.0, 100000.0
+
+	filter := newListFilter(model.ListQuery{Attributes: []model.AttributeFilter{
+		{Name: "gearbox", Equal: &gearbox},
+		{Name: "mileage", Min: &min, Max: &max},
+		{Name: "rooms", Equal: &rooms},
+	}}, 11)
+	mileage := "(CASE WHEN jsonb_typeof(a.attributes->%[1]s::text) = 'number' THEN (a.attributes->>%[1]s::text)::numeric END)"
+	assert.Equal(t, "deleted_at IS NULL AND archived_at IS NULL AND status = 'active' "+
+		"AND (a.attributes @> $2) "+
+		"AND "+fmt.Sprintf(mileage, "$3")+" >= $4 "+
+		"AND "+fmt.Sprintf(mileage, "$5")+" <= $6 "+
+		"AND (a.attributes @> $7 OR a.attributes @> $8)", filter.where())
+	assert.Equal(t, []interface{}{11, `{"gearbox":"auto"}`, "mileage", min, "mileage", max, `{"rooms":"2"}`, `{"rooms":2}`}, filter.args)
+}
+
+func TestRepository_geoFilter(t *testing.T) {
+	filter := newListFilter(model.ListQuery{
+		Near:     &model.GeoPoint{Lat: 55.75, Lon: 37.62},
+		RadiusKm: floatPtr(2.5),
+		Box:      &model.GeoBox{South: 55, West: 37, North: 56, East: 38},
+	})
+	assert.Equal(t, "deleted_at IS NULL AND archived_at IS NULL AND status = 'active' "+
+		"AND a.lat IS NOT NULL AND earth_box(ll_to_earth($1, $2), $3) @> ll_to_earth(a.lat, a.lon) "+
+		"AND earth_distance(ll_to_earth($1, $2), ll_to_earth(a.lat, a.lon)) <= $3 "+
+		"AND a.lat BETWEEN $4 AND $5 AND a.lon BETWEEN $6 AND $7", filter.where())
+	assert.Equal(t, []interface{}{55.75, 37.62, 2500.0, 55.0, 56.0, 37.0, 38.0}, filter.args)
+
+	// the point alone does not filter, a box may cross the 180th meridian
+	filter = newListFilter(model.ListQuery{
+		Near: &model.GeoPoint{Lat: 55.75, Lon: 37.62},
+		Box:  &model.GeoBox{South: 60, West: 170, North: 70, East: -170},
+	})
+	assert.Equal(t, "deleted_at IS NULL AND archived_at IS NULL AND status = 'active' "+
+		"AND a.lat BETWEEN $1 AND $2 AND (a.lon >= $3 OR a.lon <= $4)", filter.where())
+	assert.Equal(t, []interface{}{60.0, 70.0, 170.0, -170.0}, filter.args)
+}
+
+func TestRepository_attributeValues(t *testing.T) {
+	assert.Equal(t, []interface{}{"auto"}, attributeValues("auto"))
+	assert.Equal(t, []interface{}{"1.5", 1.5}, attributeValues("1.5"))
+	assert.Equal(t, []interface{}{"true", true}, attributeValues("true"))
+	assert.Equal(t, []interface{}{"NaN"}, attributeValues("NaN"))
+}
+
+func TestRepository_escapeLike(t *testing.T) {
+	assert.Equal(t, "bike", escapeLike("bike"))
+	assert.Equal(t, `100\% \_new\_ c:\\dir`, escapeLike(`100% _new_ c:\dir`))
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+const (
+	MODERATIONCLAIMSTABLE    = "moderation_claims"
+	MODERATIONDECISIONSTABLE = "moderation_decisions"
+)
+
+var (
+	ErrNotClaimed     = apperror.New(apperror.ErrConflict, "advertisement is not claimed by the moderator")
+	ErrStatusConflict = apperror.New(apperror.ErrConflict, "advertisement status has been changed")
+)
+
+type ModerationPostgres struct {
+	DB *sqlx.DB
+}
+
+func NewModerationPostgres(db *sqlx.DB) *ModerationPostgres {
+	return &ModerationPostgres{DB: db}
+}
+
+// ClaimPending leases up to limit of the oldest pending adverts to the
+// moderator. Adverts leased to somebody else are skipped until the lease
+// expires, the moderator's own leases are extended.
+func (r *ModerationPostgres) ClaimPending(moderator string, limit int, lease time.Duration) ([]model.ModerationTask, error) {
+	var tasks []model.ModerationTask
+	query := fmt.Sprintf(`WITH candidates AS (
+		SELECT a.id FROM %[1]s a
+		LEFT JOIN %[2]s c ON c.advert_id = a.id
+		WHERE a.status = $4 AND %[3]s
+			AND (c.advert_id IS NULL OR c.expires_at < NOW() OR c.moderator = $1)
+		ORDER BY a.createdAt, a.id
+		LIMIT $2
+		FOR UPDATE OF a SKIP LOCKED
+	), claimed AS (
+		INSERT INTO %[2]s (advert_id, moderator, expires_at)
+		SELECT id, $1, NOW() + $3 * INTERVAL '1 second' FROM candidates
+		ON CONFLICT (advert_id) DO UPDATE SET moderator = EXCLUDED.moderator, expires_at = EXCLUDED.expires_at
+		WHERE %[2]s.expires_at < NOW() OR %[2]s.moderator = EXCLUDED.moderator
+		RETURNING advert_id, expires_at
+	)
+	SELECT a.id AS advert_id, a.name, a.description, a.price, %[4]s AS pictures, a.createdAt, claimed.expires_at, a.screening_flags
+	FROM claimed JOIN %[1]s a ON a.id = claimed.advert_id
+	ORDER BY a.createdAt, a.id`, ADVERTSTABLE, MODERATIONCLAIMSTABLE, visibleCondition, picturesColumn)
+
+	if err := r.DB.Select(&tasks, query, moderator, limit, int(lease.Seconds()), model.StatusPending); err != nil {
+		return nil, dbError(err)
+	}
+	return tasks, nil
+}
+
+// SaveDecision checks that the advert is leased to the decision's
+// moderator and is still in the from status, moves it to the to status,
+// records the decision and releases the lease in one transaction.
+func (r *ModerationPostgres) SaveDecision(decision model.ModerationDecision, from, to model.AdvertStatus) (model.ModerationDecision, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return decision, dbError(err)
+	}
+	defer tx.Rollback()
+
+	var status model.AdvertStatus
+	var moderator sql.NullString
+	query := fmt.Sprintf(`SELECT a.status, c.moderator FROM %s a
+		LEFT JOIN %s c ON c.advert_id = a.id AND c.expires_at > NOW()
+		WHERE a.id = $1 AND %s
+		FOR UPDATE OF a`, ADVERTSTABLE, MODERATIONCLAIMSTABLE, visibleCondition)
+	if err := tx.QueryRow(query, decision.AdvertId).Scan(&status, &moderator); err != nil {
+		if err == sql.ErrNoRows {
+			return decision, ErrAdvertNotFound
+		}
+		return decision, dbError(err)
+	}
+
+	if !moderator.Valid || moderator.String != decision.Moderator {
+		return decision, ErrNotClaimed
+	}
+	if status != from {
+		return decision, ErrStatusConflict
+	}
+
+	query = fmt.Sprintf("UPDATE %s SET status = $1 WHERE id = $2", ADVERTSTABLE)
+	if _, err := tx.Exec(query, to, decision.AdvertId); err != nil {
+		return decision, dbError(err)
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (advert_id, moderator, decision, reason_code, comment)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`, MODERATIONDECISIONSTABLE)
+	row := tx.QueryRow(query, decision.AdvertId, decision.Moderator, decision.Decision, decision.ReasonCode, decision.Comment)
+	if err := row.Scan(&decision.Id, &decision.CreatedAt); err != nil {
+		return decision, dbError(err)
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE advert_id = $1", MODERATIONCLAIMSTABLE)
+	if _, err := tx.Exec(query, decision.AdvertId); err != nil {
+		return decision, dbError(err)
+	}
+
+	return decision, dbError(tx.Commit())
+}
+
+func (r *ModerationPostgres) GetDecisions(advertId int) ([]model.ModerationDecision, error) {
+	decisions := make([]model.ModerationDecision, 0)
+	query := fmt.Sprintf(`SELECT id, advert_id, moderator, decision, reason_code, comment, created_at
+		FROM %s WHERE advert_id = $1 ORDER BY created_at, id`, MODERATIONDECISIONSTABLE)
+	if err := r.DB.Select(&decisions, query, advertId); err != nil {
+		return nil, dbError(err)
+	}
+	return decisions, nil
+}
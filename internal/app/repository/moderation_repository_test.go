@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModerationRepository_claimPending(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewModerationPostgres(db)
+
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(10 * time.Minute)
+
+	rows := sqlmock.NewRows([]string{"advert_id", "name", "description", "price", "pictures", "createdat", "expires_at", "screening_flags"}).
+		AddRow(1, "name-test", "desc-test", 1000, []byte(`[{"id":1,"url":"avito/files/ad1","position":0,"is_main":true}]`), createdAt, expiresAt, []byte(`[{"rule":"caps_title","verdict":"flag","message":"name is written in capital letters"}]`))
+	mock.ExpectQuery("WITH candidates AS (.+) FOR UPDATE OF a SKIP LOCKED (.+) INSERT INTO moderation_claims (.+) ON CONFLICT").
+		WithArgs("moderator-1", 10, 600, model.StatusPending).WillReturnRows(rows)
+
+	got, err := r.ClaimPending("moderator-1", 10, 10*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ModerationTask{
+		{
+			AdvertId:       1,
+			Name:           "name-test",
+			Description:    "desc-test",
+			Price:          1000,
+			Pictures:       model.Pictures{{Id: 1, URL: "avito/files/ad1", Position: 0, IsMain: true}},
+			CreatedAt:      createdAt,
+			ClaimExpiresAt: expiresAt,
+			ScreeningFlags: model.RuleHits{
+				{Rule: "caps_title", Verdict: model.VerdictFlag, Message: "name is written in capital letters"},
+			},
+		},
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestModerationRepository_saveDecision(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewModerationPostgres(db)
+
+	decidedAt := time.Date(2021, 7, 1, 12, 5, 0, 0, time.UTC)
+	decision := model.ModerationDecision{
+		AdvertId:   1,
+		Moderator:  "moderator-1",
+		Decision:   model.DecisionReject,
+		ReasonCode: "spam",
+	}
+
+	tests := []struct {
+		name        string
+		mock        func()
+		want        model.ModerationDecision
+		expectedErr error
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT a.status, c.moderator FROM adverts a (.+) FOR UPDATE OF a").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"status", "moderator"}).AddRow("pending", "moderator-1"))
+				mock.ExpectExec("UPDATE adverts SET status = (.+) WHERE id = (.+)").
+					WithArgs(model.StatusDraft, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO moderation_decisions (.+) RETURNING id, created_at").
+					WithArgs(1, "moderator-1", model.DecisionReject, "spam", "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, decidedAt))
+				mock.ExpectExec("DELETE FROM moderation_claims WHERE advert_id = (.+)").
+					WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: model.ModerationDecision{
+				Id:         7,
+				AdvertId:   1,
+				Moderator:  "moderator-1",
+				Decision:   model.DecisionReject,
+				ReasonCode: "spam",
+				CreatedAt:  decidedAt,
+			},
+		},
+		{
+			name: "Claimed by another moderator",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT a.status, c.moderator FROM adverts a (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"status", "moderator"}).AddRow("pending", "moderator-2"))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNotClaimed,
+		},
+		{
+			name: "Claim expired",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT a.status, c.moderator FROM adverts a (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"status", "moderator"}).AddRow("pending", nil))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrNotClaimed,
+		},
+		{
+			name: "Status changed",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT a.status, c.moderator FROM adverts a (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"status", "moderator"}).AddRow("draft", "moderator-1"))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrStatusConflict,
+		},
+		{
+			name: "Not found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT a.status, c.moderator FROM adverts a (.+)").
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"status", "moderator"}))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrAdvertNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			got, err := r.SaveDecision(decision, model.StatusPending, model.StatusDraft)
+			assert.Equal(t, test.expectedErr, err)
+			if test.expectedErr == nil {
+				assert.Equal(t, test.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestModerationRepository_getDecisions(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewModerationPostgres(db)
+
+	decidedAt := time.Date(2021, 7, 1, 12, 5, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "advert_id", "moderator", "decision", "reason_code", "comment", "created_at"}).
+		AddRow(1, 1, "moderator-1", "reject", "spam", "", decidedAt).
+		AddRow(2, 1, "moderator-2", "approve", "", "fixed", decidedAt.Add(time.Hour))
+	mock.ExpectQuery("SELECT (.+) FROM moderation_decisions WHERE advert_id = (.+) ORDER BY created_at, id").
+		WithArgs(1).WillReturnRows(rows)
+
+	got, err := r.GetDecisions(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ModerationDecision{
+		{Id: 1, AdvertId: 1, Moderator: "moderator-1", Decision: model.DecisionReject, ReasonCode: "spam", CreatedAt: decidedAt},
+		{Id: 2, AdvertId: 1, Moderator: "moderator-2", Decision: model.DecisionApprove, Comment: "fixed", CreatedAt: decidedAt.Add(time.Hour)},
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
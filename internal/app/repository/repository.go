@@ -1,9 +1,59 @@
 package repository
 
-import "github.com/paramonies/avito-rest-advert/internal/app/model"
+import (
+	"time"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
 
 type Repository interface {
 	CreateAdvert(model.Advert) (int, error)
 	GetAdvertById(int) (model.Advert, error)
-	GetAdvertList(int, string, string) ([]model.Advert, error)
+	GetAdvertOwner(int) (*int, error)
+	GetAdvertsByOwner(int) ([]model.Advert, error)
+	GetAdvertList(model.ListQuery, model.ListPage) ([]model.Advert, error)
+	CountAdverts(model.ListQuery, string) (int, error)
+	GetSearchDocument(int) (model.Advert, error)
+	GetSearchDocuments(int, int) ([]model.Advert, error)
+	UpdateAdvert(int, model.Advert) (model.Pictures, error)
+	DeleteAdvert(int) error
+	ArchiveAdvert(int) error
+	RestoreAdvert(int) error
+	UpdateAdvertStatus(int, model.AdvertStatus, model.AdvertStatus) error
+	ReorderPictures(int, []int) error
+	SetMainPicture(int, int) error
+	AddPicture(int, string, int) (model.Picture, error)
+	SetPictureVariants(int, string, model.PictureVariants) error
+	GetPicturesToCheck(int, time.Time) ([]model.Picture, error)
+	SetPictureBroken(int, bool) error
+}
+
+type ModerationRepository interface {
+	ClaimPending(string, int, time.Duration) ([]model.ModerationTask, error)
+	SaveDecision(model.ModerationDecision, model.AdvertStatus, model.AdvertStatus) (model.ModerationDecision, error)
+	GetDecisions(int) ([]model.ModerationDecision, error)
+}
+
+type CategoryRepository interface {
+	CreateCategory(model.Category) (int, error)
+	GetCategory(int) (model.Category, error)
+	GetCategories() ([]model.Category, error)
+	GetSubtree(int) ([]int, error)
+	UpdateCategory(int, model.Category) error
+	DeleteCategory(int) error
+}
+
+type UserRepository interface {
+	CreateUser(model.User) (int, error)
+	GetUserById(int) (model.User, error)
+	GetUserByEmail(string) (model.User, error)
+	SetUserRole(int, model.Role) error
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(model.APIKey) (int, error)
+	GetAPIKeys(time.Time) ([]model.APIKey, error)
+	GetAPIKeyByHash(string) (model.APIKey, error)
+	RevokeAPIKey(int) error
+	UseAPIKey(int, time.Time) (int, error)
 }
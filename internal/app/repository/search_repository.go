@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+// searchConfig is the text search configuration of adverts.search_vector,
+// queries and headlines must be parsed with the same one.
+const searchConfig = "russian"
+
+// ts_headline wraps the matches in private use characters, which can not
+// be confused with the text. The text is escaped afterwards and the markers
+// become <b> tags, so headlines are safe to show as HTML.
+const (
+	headlineStart = "\ue000"
+	headlineStop  = "\ue001"
+)
+
+var headlineMarkers = strings.NewReplacer(headlineStart, "<b>", headlineStop, "</b>")
+
+// headlineOptions of the name: the whole name is returned; of the
+// description: a couple of short fragments around the matches.
+var (
+	nameHeadlineOptions        = fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", headlineStart, headlineStop)
+	descriptionHeadlineOptions = fmt.Sprintf(`StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=" ... "`, headlineStart, headlineStop)
+)
+
+// SearchPostgres is the search index kept by Postgres itself: the search
+// vector is a generated column of the adverts table, so the index is always
+// up to date and Update and Remove have nothing to do.
+type SearchPostgres struct {
+	DB *sqlx.DB
+}
+
+func NewSearchPostgres(db *sqlx.DB) *SearchPostgres {
+	return &SearchPostgres{DB: db}
+}
+
+func (r *SearchPostgres) Update(advertId int) error {
+	return nil
+}
+
+func (r *SearchPostgres) Remove(advertId int) error {
+	return nil
+}
+
+// Search returns a page of the public adverts matching the web search query
+// search.Text and the list filters. The headlines are computed for the
+// adverts of the page only, ts_headline reads the whole text and is too
+// slow to run for every match.
+func (r *SearchPostgres) Search(search model.SearchQuery, page model.ListPage) ([]model.SearchHit, error) {
+	innerOrder, err := searchOrder(page, "a.", "")
+	if err != nil {
+		return nil, err
+	}
+	outerOrder, _ := searchOrder(page, "s.", "s.")
+
+	filter := newListFilter(search.ListQuery, page.Limit, search.Text)
+	filter.conditions = append(filter.conditions, "a.search_vector @@ q")
+
+	var hits []model.SearchHit
+	query := fmt.Sprintf(`SELECT a.id, a.name, a.price, a.category_id, a.attributes, a.lat, a.lon, a.city, a.region, a.createdat,
+		COALESCE(p.variants->>'thumb', p.url, '') AS main_picture, p.variants AS main_picture_variants, a.archived_at, s.rank,
+		ts_headline('%[1]s', a.name, q, '%[2]s') AS name_headline,
+		ts_headline('%[1]s', COALESCE(a.description, ''), q, '%[9]s') AS description_headline
+		FROM (
+			SELECT a.id, a.price, a.createdat, ts_rank_cd(a.search_vector, q) AS rank
+			FROM %[3]s a, websearch_to_tsquery('%[1]s', $2) q
+			WHERE %[5]s ORDER BY %[6]s LIMIT $1 OFFSET %[8]d
+		) s
+		JOIN %[3]s a ON a.id = s.id
+		CROSS JOIN websearch_to_tsquery('%[1]s', $2) q
+		LEFT JOIN %[4]s p ON p.advert_id = a.id AND p.is_main
+		ORDER BY %[7]s`,
+		searchConfig, nameHeadlineOptions, ADVERTSTABLE, ADVERTPICTURESTABLE, filter.where(), innerOrder, outerOrder, page.Offset,
+		descriptionHeadlineOptions)
+	if err := r.DB.Select(&hits, query, filter.args...); err != nil {
+		return nil, dbError(err)
+	}
+
+	for i := range hits {
+		hits[i].NameHeadline = highlight(hits[i].NameHeadline)
+		hits[i].DescriptionHeadline = highlight(hits[i].DescriptionHeadline)
+	}
+	return hits, nil
+}
+
+// searchDocumentQuery selects the public adverts with the fields an
+// external search index keeps.
+var searchDocumentQuery = fmt.Sprintf(`SELECT a.id, a.name, a.description, a.price, a.category_id, a.attributes, a.lat, a.lon, a.city, a.region, a.createdat,
+	COALESCE(p.variants->>'thumb', p.url, '') AS main_picture, p.variants AS main_picture_variants FROM %s a
+	LEFT JOIN %s p ON p.advert_id = a.id AND p.is_main
+	WHERE %s AND %s`, ADVERTSTABLE, ADVERTPICTURESTABLE, visibleCondition, publicCondition)
+
+// GetSearchDocument returns the advert to index, ErrAdvertNotFound when it
+// is not public.
+func (r *AdvertRepository) GetSearchDocument(advertId int) (model.Advert, error) {
+	var advert model.Advert
+	if err := r.DB.Get(&advert, searchDocumentQuery+" AND a.id = $1", advertId); err != nil {
+		if err == sql.ErrNoRows {
+			return advert, ErrAdvertNotFound
+		}
+		return advert, dbError(err)
+	}
+	return advert, nil
+}
+
+// GetSearchDocuments returns up to limit public adverts with ids greater
+// than afterId ordered by id, so an index can be built batch by batch.
+func (r *AdvertRepository) GetSearchDocuments(afterId, limit int) ([]model.Advert, error) {
+	var adverts []model.Advert
+	if err := r.DB.Select(&adverts, searchDocumentQuery+" AND a.id > $1 ORDER BY a.id LIMIT $2", afterId, limit); err != nil {
+		return nil, dbError(err)
+	}
+	return adverts, nil
+}
+
+// highlight escapes a headline and turns the markers into <b> tags.
+func highlight(headline string) string {
+	return headlineMarkers.Replace(html.EscapeString(headline))
+}
+
+// Count returns the number of adverts Search finds, see CountAdverts for
+// the modes.
+func (r *SearchPostgres) Count(search model.SearchQuery, mode string) (int, error) {
+	filter := newListFilter(search.ListQuery)
+	filter.add(fmt.Sprintf("a.search_vector @@ websearch_to_tsquery('%s', %%s)", searchConfig), search.Text)
+	return count(r.DB, filter, mode)
+}
+
+// searchOrder is the ORDER BY of the search: by the rank or by a list
+// order with the rank breaking the ties, the id keeps the order stable.
+// keys and rank qualify the columns.
+func searchOrder(page model.ListPage, keys, rank string) (string, error) {
+	direct := strings.ToUpper(page.OrderDirect)
+	if page.OrderField == model.SearchRelevance {
+		return fmt.Sprintf("%[1]srank DESC, %[2]sid DESC", rank, keys), nil
+	}
+	if _, ok := listOrderKeys[page.OrderField]; !ok || page.OrderField == model.OrderDistance {
+		return "", fmt.Errorf("unsupported order field %q", page.OrderField)
+	}
+	if direct != "ASC" && direct != "DESC" {
+		return "", fmt.Errorf("unsupported order direction %q", page.OrderDirect)
+	}
+	return fmt.Sprintf("%[1]s%[2]s %[3]s, %[4]srank DESC, %[1]sid %[3]s", keys, page.OrderField, direct, rank), nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchRepository_search(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewSearchPostgres(db)
+
+	type args struct {
+		search model.SearchQuery
+		page   model.ListPage
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		input   args
+		want    []model.SearchHit
+		wantErr bool
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "name", "price", "main_picture", "rank", "name_headline", "description_headline"}).
+					AddRow(3, "Горный велосипед", 15000, "", 0.6, "\ue000Горный\ue001 \ue000велосипед\ue001", "Продаю <\ue000горный\ue001> \ue000велосипед\ue001 & шлем")
+
+				mock.ExpectQuery("SELECT (.+) ts_headline\\('russian', a.name, q, 'StartSel=\ue000, StopSel=\ue001, HighlightAll=true'\\) AS name_headline, (.+) FROM \\( "+
+					"SELECT a.id, a.price, a.createdat, ts_rank_cd\\(a.search_vector, q\\) AS rank "+
+					"FROM adverts a, websearch_to_tsquery\\('russian', \\$2\\) q "+
+					"WHERE deleted_at IS NULL AND archived_at IS NULL AND status = 'active' AND a.price <= \\$3 AND a.search_vector @@ q "+
+					"ORDER BY rank DESC, a.id DESC LIMIT \\$1 OFFSET 10 \\) s "+
+					"JOIN adverts a ON a.id = s.id CROSS JOIN websearch_to_tsquery\\('russian', \\$2\\) q (.+) "+
+					"ORDER BY s.rank DESC, s.id DESC$").
+					WithArgs(11, `"горный велосипед"`, 20000).WillReturnRows(rows)
+			},
+			input: args{
+				search: model.SearchQuery{ListQuery: model.ListQuery{PriceMax: intPtr(20000)}, Text: `"горный велосипед"`},
+				page:   model.ListPage{Offset: 10, Limit: 11, OrderField: model.SearchRelevance},
+			},
+			want: []model.SearchHit{{
+				Advert:              model.Advert{Id: 3, Name: "Горный велосипед", Price: 15000},
+				Rank:                0.6,
+				NameHeadline:        "<b>Горный</b> <b>велосипед</b>",
+				DescriptionHeadline: "Продаю &lt;<b>горный</b>&gt; <b>велосипед</b> &amp; шлем",
+			}},
+		},
+		{
+			name: "Ordered by price",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "name", "price", "rank"}).AddRow(3, "bike", 100, 0.1)
+
+				mock.ExpectQuery("SELECT (.+) ORDER BY a.price ASC, rank DESC, a.id ASC LIMIT \\$1 OFFSET 0 (.+) "+
+					"ORDER BY s.price ASC, s.rank DESC, s.id ASC$").
+					WithArgs(11, "bike").WillReturnRows(rows)
+			},
+			input: args{
+				search: model.SearchQuery{Text: "bike"},
+				page:   model.ListPage{Limit: 11, OrderField: "price", OrderDirect: "asc"},
+			},
+			want: []model.SearchHit{{Advert: model.Advert{Id: 3, Name: "bike", Price: 100}, Rank: 0.1}},
+		},
+		{
+			name: "Database error",
+			mock: func() {
+				mock.ExpectQuery("SELECT (.+)").WillReturnError(errors.New("connection reset"))
+			},
+			input: args{
+				search: model.SearchQuery{Text: "bike"},
+				page:   model.ListPage{Limit: 11, OrderField: model.SearchRelevance},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported order field",
+			mock:    func() {},
+			input:   args{search: model.SearchQuery{Text: "bike"}, page: model.ListPage{Limit: 11, OrderField: "name", OrderDirect: "asc"}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+
+			got, err := r.Search(test.input.search, test.input.page)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSearchRepository_count(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewSearchPostgres(db)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM adverts a WHERE (.+) AND a.search_vector @@ websearch_to_tsquery\\('russian', \\$1\\)$").
+		WithArgs("bike -kids").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	total, err := r.Count(model.SearchQuery{Text: "bike -kids"}, model.CountExact)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_getSearchDocument(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "main_picture"}).
+		AddRow(3, "bike", "mountain bike", 100, "https://avito.ru/ad3.jpg")
+	mock.ExpectQuery("SELECT (.+) FROM adverts a LEFT JOIN advert_pictures p ON p.advert_id = a.id AND p.is_main " +
+		"WHERE deleted_at IS NULL AND archived_at IS NULL AND status = 'active' AND a.id = \\$1$").
+		WithArgs(3).WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.+) AND a.id = \\$1$").WithArgs(4).WillReturnError(sql.ErrNoRows)
+
+	advert, err := r.GetSearchDocument(3)
+	assert.NoError(t, err)
+	assert.Equal(t, model.Advert{Id: 3, Name: "bike", Description: "mountain bike", Price: 100, MainPicture: "https://avito.ru/ad3.jpg"}, advert)
+
+	_, err = r.GetSearchDocument(4)
+	assert.Equal(t, ErrAdvertNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_getSearchDocuments(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewAdvertRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "price"}).
+		AddRow(11, "bike", "mountain bike", 100).
+		AddRow(12, "helmet", "red helmet", 20)
+	mock.ExpectQuery("SELECT (.+) WHERE deleted_at IS NULL AND archived_at IS NULL AND status = 'active' "+
+		"AND a.id > \\$1 ORDER BY a.id LIMIT \\$2$").
+		WithArgs(10, 2).WillReturnRows(rows)
+
+	adverts, err := r.GetSearchDocuments(10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Advert{
+		{Id: 11, Name: "bike", Description: "mountain bike", Price: 100},
+		{Id: 12, Name: "helmet", Description: "red helmet", Price: 20},
+	}, adverts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchRepository_highlight(t *testing.T) {
+	assert.Equal(t, "<b>bike</b> &lt;script&gt;", highlight("\ue000bike\ue001 <script>"))
+}
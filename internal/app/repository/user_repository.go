@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+const USERSTABLE = "users"
+
+var (
+	ErrUserNotFound = apperror.New(apperror.ErrNotFound, "user not found")
+	ErrUserExists   = apperror.New(apperror.ErrConflict, "a user with this email already exists")
+)
+
+type UserPostgres struct {
+	DB *sqlx.DB
+}
+
+func NewUserPostgres(db *sqlx.DB) *UserPostgres {
+	return &UserPostgres{DB: db}
+}
+
+func (r *UserPostgres) CreateUser(user model.User) (int, error) {
+	var id int
+	query := fmt.Sprintf("INSERT INTO %s (email, password_hash, role) VALUES ($1, $2, $3) RETURNING id", USERSTABLE)
+	if err := r.DB.QueryRow(query, user.Email, user.PasswordHash, user.Role).Scan(&id); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return 0, ErrUserExists
+		}
+		return 0, dbError(err)
+	}
+	return id, nil
+}
+
+func (r *UserPostgres) GetUserById(userId int) (model.User, error) {
+	var user model.User
+	query := fmt.Sprintf("SELECT id, email, password_hash, role, created_at FROM %s WHERE id = $1", USERSTABLE)
+	if err := r.DB.Get(&user, query, userId); err != nil {
+		if err == sql.ErrNoRows {
+			return user, ErrUserNotFound
+		}
+		return user, dbError(err)
+	}
+	return user, nil
+}
+
+// GetUserByEmail finds the user regardless of the case of the email.
+func (r *UserPostgres) GetUserByEmail(email string) (model.User, error) {
+	var user model.User
+	query := fmt.Sprintf("SELECT id, email, password_hash, role, created_at FROM %s WHERE lower(email) = lower($1)", USERSTABLE)
+	if err := r.DB.Get(&user, query, email); err != nil {
+		if err == sql.ErrNoRows {
+			return user, ErrUserNotFound
+		}
+		return user, dbError(err)
+	}
+	return user, nil
+}
+
+func (r *UserPostgres) SetUserRole(userId int, role model.Role) error {
+	query := fmt.Sprintf("UPDATE %s SET role = $1 WHERE id = $2", USERSTABLE)
+	res, err := r.DB.Exec(query, role, userId)
+	if err != nil {
+		return dbError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepository_createUser(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewUserPostgres(db)
+
+	mock.ExpectQuery("INSERT INTO users \\(email, password_hash, role\\) VALUES").
+		WithArgs("ivan@example.com", "hash", model.RoleSeller).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO users \\(email, password_hash, role\\) VALUES").
+		WithArgs("ivan@example.com", "hash", model.RoleSeller).WillReturnError(&pq.Error{Code: "23505"})
+
+	id, err := r.CreateUser(model.User{Email: "ivan@example.com", PasswordHash: "hash", Role: model.RoleSeller})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	_, err = r.CreateUser(model.User{Email: "ivan@example.com", PasswordHash: "hash", Role: model.RoleSeller})
+	assert.Equal(t, ErrUserExists, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_getUserByEmail(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewUserPostgres(db)
+
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, email, password_hash, role, created_at FROM users WHERE lower\\(email\\) = lower\\(\\$1\\)").
+		WithArgs("Ivan@example.com").WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash", "role", "created_at"}).
+		AddRow(1, "ivan@example.com", "hash", "seller", createdAt))
+	mock.ExpectQuery("SELECT id, email, password_hash, role, created_at FROM users").
+		WithArgs("nobody@example.com").WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash", "role", "created_at"}))
+
+	user, err := r.GetUserByEmail("Ivan@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, model.User{Id: 1, Email: "ivan@example.com", PasswordHash: "hash", Role: model.RoleSeller, CreatedAt: &createdAt}, user)
+
+	_, err = r.GetUserByEmail("nobody@example.com")
+	assert.Equal(t, ErrUserNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_getUserById(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewUserPostgres(db)
+
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, email, password_hash, role, created_at FROM users WHERE id = \\$1").
+		WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash", "role", "created_at"}).
+		AddRow(1, "ivan@example.com", "hash", "seller", createdAt))
+	mock.ExpectQuery("SELECT id, email, password_hash, role, created_at FROM users WHERE id = \\$1").
+		WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash", "role", "created_at"}))
+
+	user, err := r.GetUserById(1)
+	assert.NoError(t, err)
+	assert.Equal(t, model.User{Id: 1, Email: "ivan@example.com", PasswordHash: "hash", Role: model.RoleSeller, CreatedAt: &createdAt}, user)
+
+	_, err = r.GetUserById(2)
+	assert.Equal(t, ErrUserNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_setUserRole(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' while opening a stub database connection", err)
+	}
+
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	r := NewUserPostgres(db)
+
+	mock.ExpectExec("UPDATE users SET role = \\$1 WHERE id = \\$2").
+		WithArgs(model.RoleModerator, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users SET role").
+		WithArgs(model.RoleModerator, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, r.SetUserRole(1, model.RoleModerator))
+	assert.Equal(t, ErrUserNotFound, r.SetUserRole(2, model.RoleModerator))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,88 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+// Fragments of the description headline, the same as the Postgres search
+// asks ts_headline for.
+const (
+	maxFragments      = 2
+	fragmentWords     = 20
+	wordsBeforeMatch  = 5
+	fragmentDelimiter = " ... "
+)
+
+// nameHeadline returns the escaped name with the words of terms in <b> tags.
+func nameHeadline(name string, terms map[string]bool) string {
+	return mark(name, words(name), terms)
+}
+
+// descriptionHeadline returns up to maxFragments fragments of the
+// description around the matched words, or its beginning when no word
+// matches.
+func descriptionHeadline(description string, terms map[string]bool) string {
+	all := words(description)
+	if len(all) == 0 {
+		return html.EscapeString(description)
+	}
+
+	var fragments []string
+	end := 0
+	for i, t := range all {
+		if len(fragments) == maxFragments {
+			break
+		}
+		if i < end || t.stop || !terms[t.term] {
+			continue
+		}
+		start := i - wordsBeforeMatch
+		if start < end {
+			start = end
+		}
+		end = start + fragmentWords
+		if end > len(all) {
+			end = len(all)
+		}
+		fragments = append(fragments, fragment(description, all[start:end], terms))
+	}
+
+	if len(fragments) == 0 {
+		if len(all) > fragmentWords {
+			all = all[:fragmentWords]
+		}
+		return fragment(description, all, terms)
+	}
+	return strings.Join(fragments, fragmentDelimiter)
+}
+
+// fragment marks the part of text from the first to the last of tokens.
+func fragment(text string, tokens []token, terms map[string]bool) string {
+	first, last := tokens[0].start, tokens[len(tokens)-1].end
+	shifted := make([]token, len(tokens))
+	for i, t := range tokens {
+		t.start -= first
+		t.end -= first
+		shifted[i] = t
+	}
+	return mark(text[first:last], shifted, terms)
+}
+
+// mark escapes text and wraps the tokens of terms in <b> tags.
+func mark(text string, tokens []token, terms map[string]bool) string {
+	var sb strings.Builder
+	pos := 0
+	for _, t := range tokens {
+		if t.stop || !terms[t.term] {
+			continue
+		}
+		sb.WriteString(html.EscapeString(text[pos:t.start]))
+		sb.WriteString("<b>")
+		sb.WriteString(html.EscapeString(text[t.start:t.end]))
+		sb.WriteString("</b>")
+		pos = t.end
+	}
+	sb.WriteString(html.EscapeString(text[pos:]))
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameHeadline(t *testing.T) {
+	terms := makeSet("велосипед", "bike")
+	assert.Equal(t, "<b>Велосипеды</b> &amp; <b>bikes</b> &lt;script&gt;", nameHeadline("Велосипеды & bikes <script>", terms))
+	assert.Equal(t, "и &lt;b&gt;", nameHeadline("и <b>", makeSet("и")))
+}
+
+func TestDescriptionHeadline(t *testing.T) {
+	terms := makeSet("велосипед")
+	words := func(from, to int) string {
+		var w []string
+		for i := from; i <= to; i++ {
+			w = append(w, "слово")
+		}
+		return strings.Join(w, " ")
+	}
+
+	tests := []struct {
+		name        string
+		description string
+		expected    string
+	}{
+		{
+			name:        "Short",
+			description: "Продаю велосипед & шлем",
+			expected:    "Продаю <b>велосипед</b> &amp; шлем",
+		},
+		{
+			name:        "No match",
+			description: words(1, 25),
+			expected:    words(1, 20),
+		},
+		{
+			name:        "Fragments",
+			description: words(1, 10) + " велосипед " + words(1, 30) + " велосипеды " + words(1, 30) + " велосипед",
+			expected: words(1, 5) + " <b>велосипед</b> " + words(1, 14) + " ... " +
+				words(1, 5) + " <b>велосипеды</b> " + words(1, 14),
+		},
+		{
+			name:        "Empty",
+			description: "",
+			expected:    "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, descriptionHeadline(test.description, terms))
+		})
+	}
+}
@@ -0,0 +1,414 @@
+// Package search is an in-memory full-text index of the public adverts, an
+// alternative to the Postgres search for installations that want to take
+// the load off the database. The index is built from the adverts table at
+// startup and kept up to date by the services that change adverts.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+const (
+	IndexPostgres = "postgres"
+	IndexMemory   = "memory"
+
+	DefaultBatchSize = 500
+)
+
+// BM25 parameters. A word of the name counts as nameWeight words of the
+// description, as the weights A and B of the Postgres search vector.
+const (
+	bm25K1     = 1.2
+	bm25B      = 0.75
+	nameWeight = 2
+)
+
+type Config struct {
+	Index     string `toml:"index"`
+	BatchSize int    `toml:"batch_size"`
+}
+
+func (c Config) Validate() error {
+	switch c.Index {
+	case "", IndexPostgres, IndexMemory:
+	default:
+		return fmt.Errorf("search: unknown index %q", c.Index)
+	}
+	if c.BatchSize < 0 {
+		return errors.New("search: batch_size must not be negative")
+	}
+	return nil
+}
+
+// Source loads the public adverts with the fields the index keeps.
+// GetSearchDocument returns an error of the not found kind for an advert
+// that is not public, GetSearchDocuments returns up to limit adverts with
+// ids greater than the given one ordered by id.
+type Source interface {
+	GetSearchDocument(int) (model.Advert, error)
+	GetSearchDocuments(int, int) ([]model.Advert, error)
+}
+
+// MemoryIndex is an inverted index of the public adverts ranked by BM25.
+// The search text is matched word by word: every word must occur in the
+// advert, words prefixed with "-" must not.
+type MemoryIndex struct {
+	source    Source
+	batchSize int
+
+	mu    sync.RWMutex
+	index *index
+}
+
+func NewMemoryIndex(source Source, batchSize int) *MemoryIndex {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &MemoryIndex{source: source, batchSize: batchSize, index: newIndex()}
+}
+
+// Rebuild loads all public adverts into a new index and replaces the
+// current one. Updates made while the index is rebuilt may be lost, it is
+// meant to run at startup.
+func (x *MemoryIndex) Rebuild() error {
+	fresh := newIndex()
+	afterId := 0
+	for {
+		adverts, err := x.source.GetSearchDocuments(afterId, x.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, advert := range adverts {
+			fresh.add(advert)
+			afterId = advert.Id
+		}
+		if len(adverts) < x.batchSize {
+			break
+		}
+	}
+
+	x.mu.Lock()
+	x.index = fresh
+	x.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of indexed adverts.
+func (x *MemoryIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.index.docs)
+}
+
+// Update reloads the advert from the source, an advert that is no longer
+// public is removed from the index.
+func (x *MemoryIndex) Update(advertId int) error {
+	advert, err := x.source.GetSearchDocument(advertId)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return x.Remove(advertId)
+	}
+	if err != nil {
+		return err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.index.remove(advertId)
+	x.index.add(advert)
+	return nil
+}
+
+func (x *MemoryIndex) Remove(advertId int) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.index.remove(advertId)
+	return nil
+}
+
+// Search returns a page of the adverts matching search.Text and the list
+// filters ordered as the Postgres search orders them.
+func (x *MemoryIndex) Search(search model.SearchQuery, page model.ListPage) ([]model.SearchHit, error) {
+	less, err := searchOrder(page)
+	if err != nil {
+		return nil, err
+	}
+	include, exclude := parseQuery(search.Text)
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	matches := x.index.match(search.ListQuery, include, exclude)
+	sort.Slice(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+
+	if page.Offset >= len(matches) {
+		return nil, nil
+	}
+	matches = matches[page.Offset:]
+	if len(matches) > page.Limit {
+		matches = matches[:page.Limit]
+	}
+
+	terms := makeSet(include...)
+	hits := make([]model.SearchHit, 0, len(matches))
+	for _, m := range matches {
+		advert := m.doc.advert
+		hits = append(hits, model.SearchHit{
+			Advert: model.Advert{
+				Id:                  advert.Id,
+				Name:                advert.Name,
+				Price:               advert.Price,
+				CategoryId:          advert.CategoryId,
+				Attributes:          advert.Attributes,
+				Lat:                 advert.Lat,
+				Lon:                 advert.Lon,
+				City:                advert.City,
+				Region:              advert.Region,
+				CreatedAt:           advert.CreatedAt,
+				MainPicture:         advert.MainPicture,
+				MainPictureVariants: advert.MainPictureVariants,
+			},
+			Rank:                m.rank,
+			NameHeadline:        nameHeadline(advert.Name, terms),
+			DescriptionHeadline: descriptionHeadline(advert.Description, terms),
+		})
+	}
+	return hits, nil
+}
+
+// Count returns the number of adverts Search finds. Counting the index is
+// cheap, so the estimated mode is exact as well.
+func (x *MemoryIndex) Count(search model.SearchQuery, mode string) (int, error) {
+	if mode != model.CountExact && mode != model.CountEstimated {
+		return 0, fmt.Errorf("unsupported count mode %q", mode)
+	}
+	include, exclude := parseQuery(search.Text)
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.index.match(search.ListQuery, include, exclude)), nil
+}
+
+// parseQuery returns the terms of the words that must and must not occur.
+// Quotes and operators of the web search syntax are read as plain words.
+func parseQuery(text string) (include, exclude []string) {
+	for _, word := range strings.Fields(text) {
+		negated := strings.HasPrefix(word, "-")
+		for _, t := range tokenize(word) {
+			if negated {
+				exclude = append(exclude, t.term)
+			} else {
+				include = append(include, t.term)
+			}
+		}
+	}
+	return include, exclude
+}
+
+type document struct {
+	advert model.Advert
+	terms  map[string]float64
+	length float64
+}
+
+type index struct {
+	docs     map[int]*document
+	postings map[string]map[int]*document
+	length   float64
+}
+
+func newIndex() *index {
+	return &index{docs: make(map[int]*document), postings: make(map[string]map[int]*document)}
+}
+
+func (ix *index) add(advert model.Advert) {
+	doc := &document{advert: advert, terms: make(map[string]float64)}
+	for _, t := range tokenize(advert.Name) {
+		doc.terms[t.term] += nameWeight
+		doc.length += nameWeight
+	}
+	for _, t := range tokenize(advert.Description) {
+		doc.terms[t.term]++
+		doc.length++
+	}
+
+	ix.docs[advert.Id] = doc
+	ix.length += doc.length
+	for term := range doc.terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[int]*document)
+		}
+		ix.postings[term][advert.Id] = doc
+	}
+}
+
+func (ix *index) remove(advertId int) {
+	doc, ok := ix.docs[advertId]
+	if !ok {
+		return
+	}
+	delete(ix.docs, advertId)
+	ix.length -= doc.length
+	for term := range doc.terms {
+		delete(ix.postings[term], advertId)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+}
+
+type match struct {
+	doc  *document
+	rank float64
+}
+
+// match returns the ranked documents containing all include terms and none
+// of the exclude terms that pass the list filters.
+func (ix *index) match(list model.ListQuery, include, exclude []string) []match {
+	if len(include) == 0 {
+		return nil
+	}
+	// the rarest term has the fewest candidates
+	rarest := ix.postings[include[0]]
+	for _, term := range include[1:] {
+		if len(ix.postings[term]) < len(rarest) {
+			rarest = ix.postings[term]
+		}
+	}
+
+	var matches []match
+	avgLength := ix.length / float64(len(ix.docs))
+candidates:
+	for _, doc := range rarest {
+		for _, term := range include {
+			if doc.terms[term] == 0 {
+				continue candidates
+			}
+		}
+		for _, term := range exclude {
+			if doc.terms[term] > 0 {
+				continue candidates
+			}
+		}
+		if !filter(list, doc.advert) {
+			continue
+		}
+		matches = append(matches, match{doc: doc, rank: ix.bm25(doc, include, avgLength)})
+	}
+	return matches
+}
+
+func (ix *index) bm25(doc *document, terms []string, avgLength float64) float64 {
+	n := float64(len(ix.docs))
+	rank := 0.0
+	for _, term := range terms {
+		df := float64(len(ix.postings[term]))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		tf := doc.terms[term]
+		rank += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*doc.length/avgLength))
+	}
+	return rank
+}
+
+// filter applies the list filters the search supports, the substring
+// filter q and archived adverts are not used by the search.
+func filter(list model.ListQuery, advert model.Advert) bool {
+	if list.PriceMin != nil && advert.Price < *list.PriceMin {
+		return false
+	}
+	if list.PriceMax != nil && advert.Price > *list.PriceMax {
+		return false
+	}
+	if list.CreatedFrom != nil && (advert.CreatedAt == nil || advert.CreatedAt.Before(*list.CreatedFrom)) {
+		return false
+	}
+	if list.CreatedTo != nil && (advert.CreatedAt == nil || advert.CreatedAt.After(*list.CreatedTo)) {
+		return false
+	}
+	if list.HasPictures != nil && *list.HasPictures != (advert.MainPicture != "") {
+		return false
+	}
+	if list.Categories != nil && !containsInt(list.Categories, advert.CategoryId) {
+		return false
+	}
+	for _, attribute := range list.Attributes {
+		if !attribute.Match(advert.Attributes) {
+			return false
+		}
+	}
+	located := advert.Lat != nil && advert.Lon != nil
+	if list.Near != nil && list.RadiusKm != nil && (!located || list.Near.DistanceKm(*advert.Lat, *advert.Lon) > *list.RadiusKm) {
+		return false
+	}
+	if list.Box != nil && (!located || !list.Box.Contains(*advert.Lat, *advert.Lon)) {
+		return false
+	}
+	return true
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// searchOrder returns the order of the page: by the rank or by a list
+// order with the rank breaking the ties, the id keeps the order stable.
+func searchOrder(page model.ListPage) (func(a, b match) bool, error) {
+	if page.OrderField == model.SearchRelevance {
+		return func(a, b match) bool {
+			if a.rank != b.rank {
+				return a.rank > b.rank
+			}
+			return a.doc.advert.Id > b.doc.advert.Id
+		}, nil
+	}
+
+	var compare func(a, b model.Advert) int
+	switch page.OrderField {
+	case "price":
+		compare = func(a, b model.Advert) int { return a.Price - b.Price }
+	case "createdat":
+		compare = func(a, b model.Advert) int {
+			switch {
+			case a.CreatedAt == nil || b.CreatedAt == nil || a.CreatedAt.Equal(*b.CreatedAt):
+				return 0
+			case a.CreatedAt.Before(*b.CreatedAt):
+				return -1
+			}
+			return 1
+		}
+	default:
+		return nil, fmt.Errorf("unsupported order field %q", page.OrderField)
+	}
+
+	var sign int
+	switch strings.ToUpper(page.OrderDirect) {
+	case "ASC":
+		sign = 1
+	case "DESC":
+		sign = -1
+	default:
+		return nil, fmt.Errorf("unsupported order direction %q", page.OrderDirect)
+	}
+
+	return func(a, b match) bool {
+		if c := compare(a.doc.advert, b.doc.advert); c != 0 {
+			return c*sign < 0
+		}
+		if a.rank != b.rank {
+			return a.rank > b.rank
+		}
+		return (a.doc.advert.Id-b.doc.advert.Id)*sign < 0
+	}, nil
+}
@@ -0,0 +1,312 @@
+package search
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource keeps the public adverts by id.
+type fakeSource struct {
+	adverts map[int]model.Advert
+	err     error
+}
+
+func (s *fakeSource) GetSearchDocument(advertId int) (model.Advert, error) {
+	advert, ok := s.adverts[advertId]
+	if !ok {
+		return advert, repository.ErrAdvertNotFound
+	}
+	return advert, s.err
+}
+
+func (s *fakeSource) GetSearchDocuments(afterId, limit int) ([]model.Advert, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	var adverts []model.Advert
+	for id := afterId + 1; len(adverts) < limit && id <= 100; id++ {
+		if advert, ok := s.adverts[id]; ok {
+			adverts = append(adverts, advert)
+		}
+	}
+	return adverts, nil
+}
+
+func testIndex(t *testing.T) (*MemoryIndex, *fakeSource) {
+	day := func(d int) *time.Time {
+		date := time.Date(2021, 7, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	source := &fakeSource{adverts: map[int]model.Advert{
+		1: {Id: 1, Name: "Горный велосипед", Description: "Продаю горный велосипед в хорошем состоянии", Price: 15000, CategoryId: 5, CreatedAt: day(1), MainPicture: "https://avito.ru/1.jpg",
+			Attributes: model.Attributes{"wheel": 27.5, "brakes": "disc", "suspension": true}, Lat: floatPtr(55.7558), Lon: floatPtr(37.6173)},
+		2: {Id: 2, Name: "Детский велосипед", Description: "Велосипед для детей, велосипеды подойдут от 5 лет", Price: 5000, CategoryId: 6, CreatedAt: day(2),
+			Attributes: model.Attributes{"wheel": 20.0, "brakes": "rim"}, Lat: floatPtr(59.9386), Lon: floatPtr(30.3141)},
+		3: {Id: 3, Name: "Шлем", Description: "Шлем для горного велосипеда", Price: 2000, CategoryId: 7, CreatedAt: day(3)},
+		4: {Id: 4, Name: "Mountain bikes", Description: "Two used bikes", Price: 30000, CreatedAt: day(4)},
+	}}
+
+	index := NewMemoryIndex(source, 2)
+	require.NoError(t, index.Rebuild())
+	return index, source
+}
+
+func hitIds(hits []model.SearchHit) []int {
+	ids := []int{}
+	for _, hit := range hits {
+		ids = append(ids, hit.Id)
+	}
+	return ids
+}
+
+func TestMemoryIndex_Search(t *testing.T) {
+	index, _ := testIndex(t)
+	assert.Equal(t, 4, index.Len())
+
+	tests := []struct {
+		name        string
+		search      model.SearchQuery
+		page        model.ListPage
+		expectedIds []int
+		expectError bool
+	}{
+		{
+			name:        "Relevance",
+			search:      model.SearchQuery{Text: "велосипеды"},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{2, 1, 3},
+		},
+		{
+			name:        "All words must match",
+			search:      model.SearchQuery{Text: "горный велосипед"},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{1, 3},
+		},
+		{
+			name:        "Excluded word",
+			search:      model.SearchQuery{Text: "велосипед -шлем"},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{2, 1},
+		},
+		{
+			name:        "English",
+			search:      model.SearchQuery{Text: "Bike"},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{4},
+		},
+		{
+			name:        "Price order",
+			search:      model.SearchQuery{Text: "велосипед"},
+			page:        model.ListPage{Limit: 10, OrderField: "price", OrderDirect: "asc"},
+			expectedIds: []int{3, 2, 1},
+		},
+		{
+			name:        "Created order with offset",
+			search:      model.SearchQuery{Text: "велосипед"},
+			page:        model.ListPage{Offset: 1, Limit: 1, OrderField: "createdat", OrderDirect: "desc"},
+			expectedIds: []int{2},
+		},
+		{
+			name: "Filters",
+			search: model.SearchQuery{
+				ListQuery: model.ListQuery{PriceMin: intPtr(3000), HasPictures: boolPtr(false)},
+				Text:      "велосипед",
+			},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{2},
+		},
+		{
+			name: "Categories",
+			search: model.SearchQuery{
+				ListQuery: model.ListQuery{CategoryId: intPtr(2), Categories: []int{2, 5, 6}},
+				Text:      "велосипед",
+			},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{2, 1},
+		},
+		{
+			name: "Attributes",
+			search: model.SearchQuery{
+				ListQuery: model.ListQuery{Attributes: []model.AttributeFilter{
+					{Name: "brakes", Equal: stringPtr("disc")},
+					{Name: "wheel", Min: floatPtr(26)},
+				}},
+				Text: "велосипед",
+			},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{1},
+		},
+		{
+			name: "Attributes written as strings",
+			search: model.SearchQuery{
+				ListQuery: model.ListQuery{Attributes: []model.AttributeFilter{
+					{Name: "wheel", Equal: stringPtr("20")},
+				}},
+				Text: "велосипед",
+			},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{2},
+		},
+		{
+			name: "Missing attribute",
+			search: model.SearchQuery{
+				ListQuery: model.ListQuery{Attributes: []model.AttributeFilter{
+					{Name: "suspension", Equal: stringPtr("true")},
+				}},
+				Text: "велосипед",
+			},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{1},
+		},
+		{
+			name: "Radius",
+			search: model.SearchQuery{
+				ListQuery: model.ListQuery{Near: &model.GeoPoint{Lat: 55.7, Lon: 37.6}, RadiusKm: floatPtr(10)},
+				Text:      "велосипед",
+			},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{1},
+		},
+		{
+			name: "Bounding box",
+			search: model.SearchQuery{
+				ListQuery: model.ListQuery{Box: &model.GeoBox{South: 59, West: 29, North: 61, East: 31}},
+				Text:      "велосипед",
+			},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{2},
+		},
+		{
+			name:        "Only stop words",
+			search:      model.SearchQuery{Text: "и в на"},
+			page:        model.ListPage{Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{},
+		},
+		{
+			name:        "Page past the end",
+			search:      model.SearchQuery{Text: "велосипед"},
+			page:        model.ListPage{Offset: 10, Limit: 10, OrderField: model.SearchRelevance},
+			expectedIds: []int{},
+		},
+		{
+			name:        "Unsupported order field",
+			search:      model.SearchQuery{Text: "велосипед"},
+			page:        model.ListPage{Limit: 10, OrderField: "name", OrderDirect: "asc"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hits, err := index.Search(test.search, test.page)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedIds, hitIds(hits))
+		})
+	}
+}
+
+func TestGeoPoint_DistanceKm(t *testing.T) {
+	moscow := model.GeoPoint{Lat: 55.7558, Lon: 37.6173}
+	assert.InDelta(t, 634.5, moscow.DistanceKm(59.9386, 30.3141), 1)
+	assert.Equal(t, 0.0, moscow.DistanceKm(55.7558, 37.6173))
+
+	box := model.GeoBox{South: 60, West: 170, North: 70, East: -170}
+	assert.True(t, box.Contains(65, 179))
+	assert.True(t, box.Contains(65, -175))
+	assert.False(t, box.Contains(65, 0))
+	assert.False(t, box.Contains(59, 179))
+}
+
+func TestMemoryIndex_SearchHit(t *testing.T) {
+	index, _ := testIndex(t)
+
+	hits, err := index.Search(model.SearchQuery{Text: "горный"}, model.ListPage{Limit: 1, OrderField: model.SearchRelevance})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, 1, hits[0].Id)
+	assert.Equal(t, "", hits[0].Description)
+	assert.Equal(t, "https://avito.ru/1.jpg", hits[0].MainPicture)
+	assert.Greater(t, hits[0].Rank, 0.0)
+	assert.Equal(t, "<b>Горный</b> велосипед", hits[0].NameHeadline)
+	assert.Equal(t, "Продаю <b>горный</b> велосипед в хорошем состоянии", hits[0].DescriptionHeadline)
+}
+
+func TestMemoryIndex_Count(t *testing.T) {
+	index, _ := testIndex(t)
+
+	total, err := index.Count(model.SearchQuery{Text: "велосипед"}, model.CountEstimated)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	_, err = index.Count(model.SearchQuery{Text: "велосипед"}, "roughly")
+	assert.Error(t, err)
+}
+
+func TestMemoryIndex_Update(t *testing.T) {
+	index, source := testIndex(t)
+	search := func(text string) []int {
+		hits, err := index.Search(model.SearchQuery{Text: text}, model.ListPage{Limit: 10, OrderField: model.SearchRelevance})
+		require.NoError(t, err)
+		return hitIds(hits)
+	}
+
+	source.adverts[5] = model.Advert{Id: 5, Name: "Самокат", Description: "Электрический самокат"}
+	assert.NoError(t, index.Update(5))
+	assert.Equal(t, []int{5}, search("самокаты"))
+
+	advert := source.adverts[1]
+	advert.Name, advert.Description = "Самокат", "Самокат для взрослых"
+	source.adverts[1] = advert
+	assert.NoError(t, index.Update(1))
+	assert.Equal(t, []int{2, 3}, search("велосипед"))
+	assert.Equal(t, []int{5, 1}, search("самокат"))
+
+	// the advert is no longer public
+	delete(source.adverts, 5)
+	assert.NoError(t, index.Update(5))
+	assert.Equal(t, []int{1}, search("самокат"))
+
+	assert.NoError(t, index.Remove(1))
+	assert.NoError(t, index.Remove(1))
+	assert.Equal(t, []int{}, search("самокат"))
+	assert.Equal(t, 3, index.Len())
+
+	source.adverts[2] = model.Advert{Id: 2}
+	source.err = errors.New("connection reset")
+	assert.Error(t, index.Update(2))
+	assert.Error(t, index.Rebuild())
+	assert.Equal(t, 3, index.Len())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{Index: IndexMemory, BatchSize: 100}.Validate())
+	assert.Error(t, Config{Index: "elastic"}.Validate())
+	assert.Error(t, Config{BatchSize: -1}.Validate())
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func stringPtr(v string) *string {
+	return &v
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,175 @@
+package search
+
+import "sort"
+
+// minCorrectedLength is the shortest word that is corrected, shorter words
+// have too many neighbours to guess from.
+const minCorrectedLength = 3
+
+// vocabulary holds the words of the names with the number of adverts they
+// occur in and a trigram index to find the words similar to a misspelled
+// one.
+type vocabulary struct {
+	counts map[string]int
+	grams  map[string]map[string]bool
+}
+
+func newVocabulary() *vocabulary {
+	return &vocabulary{counts: make(map[string]int), grams: make(map[string]map[string]bool)}
+}
+
+// add changes the count of the word by delta, a word with no adverts left
+// is removed.
+func (v *vocabulary) add(word string, delta int) {
+	count, known := v.counts[word]
+	if count+delta <= 0 {
+		if known {
+			delete(v.counts, word)
+			for _, gram := range trigrams(word) {
+				delete(v.grams[gram], word)
+				if len(v.grams[gram]) == 0 {
+					delete(v.grams, gram)
+				}
+			}
+		}
+		return
+	}
+
+	if !known {
+		for _, gram := range trigrams(word) {
+			if v.grams[gram] == nil {
+				v.grams[gram] = make(map[string]bool)
+			}
+			v.grams[gram][word] = true
+		}
+	}
+	v.counts[word] = count + delta
+}
+
+// correct returns the known word closest to word by the edit distance, the
+// most frequent of the equally close ones. With prefix set the word may be
+// unfinished and is compared with the beginnings of the known words. An
+// empty string is returned when no word is close enough.
+func (v *vocabulary) correct(word string, prefix bool) string {
+	runes := []rune(word)
+	if len(runes) < minCorrectedLength {
+		return ""
+	}
+	maxDistance := 1
+	if len(runes) > 5 {
+		maxDistance = 2
+	}
+
+	// an edit changes at most three trigrams, the candidates with fewer
+	// shared trigrams are too far away; the last trigram of an unfinished
+	// word is not expected to match
+	grams := trigrams(word)
+	minShared := len(grams) - 3*maxDistance
+	if prefix {
+		minShared--
+	}
+	shared := make(map[string]int)
+	for _, gram := range grams {
+		for candidate := range v.grams[gram] {
+			shared[candidate]++
+		}
+	}
+
+	best, bestDistance, bestCount := "", maxDistance+1, 0
+	for candidate, n := range shared {
+		if n < minShared {
+			continue
+		}
+		distance := v.distance(runes, []rune(candidate), prefix, maxDistance)
+		if distance > maxDistance {
+			continue
+		}
+		count := v.counts[candidate]
+		if distance < bestDistance || distance == bestDistance && (count > bestCount || count == bestCount && candidate < best) {
+			best, bestDistance, bestCount = candidate, distance, count
+		}
+	}
+	return best
+}
+
+// distance is the edit distance of the word to the candidate or, for a
+// prefix, to the closest beginning of the candidate.
+func (v *vocabulary) distance(word, candidate []rune, prefix bool, maxDistance int) int {
+	if !prefix {
+		if abs(len(word)-len(candidate)) > maxDistance {
+			return maxDistance + 1
+		}
+		return editDistance(word, candidate)
+	}
+
+	distance := maxDistance + 1
+	for n := len(word) - maxDistance; n <= len(word)+maxDistance && n <= len(candidate); n++ {
+		if n < 1 {
+			continue
+		}
+		if d := editDistance(word, candidate[:n]); d < distance {
+			distance = d
+		}
+	}
+	return distance
+}
+
+// trigrams returns the distinct three letter sequences of the word padded
+// with a space on both sides.
+func trigrams(word string) []string {
+	runes := append(append([]rune{' '}, []rune(word)...), ' ')
+	seen := make(map[string]bool)
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if !seen[gram] {
+			seen[gram] = true
+			grams = append(grams, gram)
+		}
+	}
+	sort.Strings(grams)
+	return grams
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and transpositions of adjacent letters.
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
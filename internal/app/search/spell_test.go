@@ -0,0 +1,64 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"велосипед", "велосипед", 0},
+		{"велосипед", "велсоипед", 1},
+		{"велосипед", "велосипеды", 1},
+		{"велосипед", "веласипет", 2},
+		{"bike", "", 4},
+		{"", "", 0},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, editDistance([]rune(test.a), []rune(test.b)), test.a+"/"+test.b)
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	assert.Equal(t, []string{" ко", "кот", "от "}, trigrams("кот"))
+	assert.Equal(t, []string{" ab", "ab "}, trigrams("ab"))
+	assert.Equal(t, []string{" аа", "аа ", "ааа"}, trigrams("аааа"))
+}
+
+func TestVocabulary(t *testing.T) {
+	v := newVocabulary()
+	v.add("велосипед", 3)
+	v.add("велосипеды", 1)
+	v.add("самокат", 1)
+	v.add("кот", 1)
+
+	tests := []struct {
+		name     string
+		word     string
+		prefix   bool
+		expected string
+	}{
+		{name: "Transposition", word: "велсоипед", expected: "велосипед"},
+		{name: "Two typos", word: "веласипет", expected: "велосипед"},
+		{name: "The most frequent of equals", word: "велосипедв", expected: "велосипед"},
+		{name: "Too far", word: "вертолет", expected: ""},
+		{name: "Short word", word: "ко", expected: ""},
+		{name: "Unfinished word", word: "самак", prefix: true, expected: "самокат"},
+		{name: "Unfinished word is compared whole", word: "самак", expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, v.correct(test.word, test.prefix))
+		})
+	}
+
+	v.add("самокат", -1)
+	v.add("кот", -5)
+	assert.Equal(t, "", v.correct("самак", true))
+	assert.NotContains(t, v.counts, "кот")
+	assert.NotContains(t, v.grams, " ко")
+}
@@ -0,0 +1,249 @@
+package search
+
+import "strings"
+
+// The Russian stemmer is the Snowball algorithm, the one behind the
+// "russian" configuration of Postgres, so both indexes match the same word
+// forms. Endings of the same group are tried longest first.
+var (
+	perfectiveGerund1 = []string{"вшись", "вши", "в"}
+	perfectiveGerund2 = []string{"ившись", "ывшись", "ивши", "ывши", "ив", "ыв"}
+
+	adjectiveEndings = []string{"ими", "ыми", "его", "ого", "ему", "ому", "ее", "ие", "ые", "ое", "ей", "ий", "ый",
+		"ой", "ем", "им", "ым", "ом", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	participle1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	participle2 = []string{"ивш", "ывш", "ующ"}
+
+	reflexiveEndings = []string{"ся", "сь"}
+
+	verb1 = []string{"ете", "йте", "ешь", "нно", "ла", "на", "ли", "ем", "ло", "но", "ет", "ют", "ны", "ть", "й", "л", "н"}
+	verb2 = []string{"ейте", "уйте", "ила", "ыла", "ена", "ите", "или", "ыли", "ило", "ыло", "ено", "ует", "уют",
+		"ены", "ить", "ыть", "ишь", "ей", "уй", "ил", "ыл", "им", "ым", "ен", "ят", "ит", "ыт", "ую", "ю"}
+
+	nounEndings = []string{"иями", "ями", "ами", "ией", "иям", "ием", "иях", "ев", "ов", "ие", "ье", "еи", "ии", "ей",
+		"ой", "ий", "ям", "ем", "ам", "ом", "ах", "ях", "ию", "ью", "ия", "ья", "а", "е", "и", "й", "о", "у", "ы", "ь",
+		"ю", "я"}
+
+	superlativeEndings  = []string{"ейше", "ейш"}
+	derivationalEndings = []string{"ость", "ост"}
+)
+
+func isRussianVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// stemRussian removes the inflectional and a few derivational endings of a
+// lower case Russian word.
+func stemRussian(word string) string {
+	w := []rune(word)
+	rv, r2 := russianRegions(w)
+
+	if n := ending(w, rv, perfectiveGerund1, perfectiveGerund2); n > 0 {
+		w = w[:len(w)-n]
+	} else {
+		if n := ending(w, rv, nil, reflexiveEndings); n > 0 {
+			w = w[:len(w)-n]
+		}
+		if n := ending(w, rv, nil, adjectiveEndings); n > 0 {
+			w = w[:len(w)-n]
+			if n := ending(w, rv, participle1, participle2); n > 0 {
+				w = w[:len(w)-n]
+			}
+		} else if n := ending(w, rv, verb1, verb2); n > 0 {
+			w = w[:len(w)-n]
+		} else if n := ending(w, rv, nil, nounEndings); n > 0 {
+			w = w[:len(w)-n]
+		}
+	}
+
+	if n := ending(w, rv, nil, []string{"и"}); n > 0 {
+		w = w[:len(w)-n]
+	}
+
+	if n := ending(w, r2, nil, derivationalEndings); n > 0 {
+		w = w[:len(w)-n]
+	}
+
+	if n := ending(w, rv, nil, superlativeEndings); n > 0 {
+		w = w[:len(w)-n]
+	}
+	if ending(w, rv, nil, []string{"нн"}) > 0 {
+		w = w[:len(w)-1]
+	} else if ending(w, rv, nil, []string{"ь"}) > 0 {
+		w = w[:len(w)-1]
+	}
+	return string(w)
+}
+
+// russianRegions returns the start of RV, the part after the first vowel,
+// and of R2, the part after the second vowel followed by a consonant.
+func russianRegions(w []rune) (rv, r2 int) {
+	rv = len(w)
+	for i, r := range w {
+		if isRussianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r1 := afterVowelConsonant(w, 0, isRussianVowel)
+	return rv, afterVowelConsonant(w, r1, isRussianVowel)
+}
+
+// afterVowelConsonant returns the position after the first consonant that
+// follows a vowel in w[from:].
+func afterVowelConsonant(w []rune, from int, vowel func(rune) bool) int {
+	for i := from + 1; i < len(w); i++ {
+		if !vowel(w[i]) && vowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// ending returns the length in runes of the longest ending of w that starts
+// at region or later. Endings of group1 count only after "а" or "я".
+func ending(w []rune, region int, group1, group2 []string) int {
+	longest := 0
+	for _, e := range group1 {
+		n := len([]rune(e))
+		if n > longest && hasEnding(w, region, e) && len(w)-n-1 >= region {
+			if prev := w[len(w)-n-1]; prev == 'а' || prev == 'я' {
+				longest = n
+			}
+		}
+	}
+	for _, e := range group2 {
+		if n := len([]rune(e)); n > longest && hasEnding(w, region, e) {
+			longest = n
+		}
+	}
+	return longest
+}
+
+func hasEnding(w []rune, region int, e string) bool {
+	suffix := []rune(e)
+	start := len(w) - len(suffix)
+	return start >= region && string(w[start:]) == e
+}
+
+// The English stemmer runs the steps of Porter2 that remove inflections:
+// plurals, past tenses, gerunds and the final e. Advert texts are short and
+// mostly Russian, the derivational steps would rather merge unrelated words
+// than help.
+
+func isEnglishVowel(r rune) bool {
+	return strings.ContainsRune("aeiouy", r)
+}
+
+// stemEnglish removes the inflectional endings of a lower case English word.
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+
+	// y at the start or after a vowel is a consonant, it is marked upper case
+	w := []rune(word)
+	for i, r := range w {
+		if r == 'y' && (i == 0 || isEnglishVowel(w[i-1])) {
+			w[i] = 'Y'
+		}
+	}
+	r1 := afterVowelConsonant(w, 0, isEnglishVowel)
+	for _, prefix := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(word, prefix) {
+			r1 = len(prefix)
+		}
+	}
+	r2 := afterVowelConsonant(w, r1, isEnglishVowel)
+
+	s := string(w)
+	switch {
+	case strings.HasSuffix(s, "sses"):
+		s = s[:len(s)-2]
+	case strings.HasSuffix(s, "ied"), strings.HasSuffix(s, "ies"):
+		if len(s) > 4 {
+			s = s[:len(s)-2]
+		} else {
+			s = s[:len(s)-1]
+		}
+	case strings.HasSuffix(s, "us"), strings.HasSuffix(s, "ss"):
+	case strings.HasSuffix(s, "s"):
+		if strings.IndexAny(s[:len(s)-2], "aeiouy") >= 0 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	s = stemEnglishStep1b(s, r1)
+
+	// step 1c: a final y after a consonant, not the first letter, is i
+	if n := len(s); n > 2 && (s[n-1] == 'y' || s[n-1] == 'Y') && !isEnglishVowel(rune(s[n-2])) {
+		s = s[:n-1] + "i"
+	}
+
+	// step 5: the final e is dropped unless it keeps a short syllable long
+	if n := len(s); s[n-1] == 'e' && (n-1 >= r2 || n-1 >= r1 && !endsShortSyllable(s[:n-1])) {
+		s = s[:n-1]
+	}
+	return strings.ToLower(s)
+}
+
+func stemEnglishStep1b(s string, r1 int) string {
+	for _, suffix := range []string{"eedly", "eed"} {
+		if strings.HasSuffix(s, suffix) {
+			if len(s)-len(suffix) >= r1 {
+				return s[:len(s)-len(suffix)] + "ee"
+			}
+			return s
+		}
+	}
+
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed"} {
+		if !strings.HasSuffix(s, suffix) {
+			continue
+		}
+		stem := s[:len(s)-len(suffix)]
+		if strings.IndexAny(stem, "aeiouy") < 0 {
+			return s
+		}
+		switch {
+		case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+			return stem + "e"
+		case hasDoubleEnding(stem):
+			return stem[:len(stem)-1]
+		case isShortEnglishWord(stem, r1):
+			return stem + "e"
+		}
+		return stem
+	}
+	return s
+}
+
+func hasDoubleEnding(s string) bool {
+	for _, double := range []string{"bb", "dd", "ff", "gg", "mm", "nn", "pp", "rr", "tt"} {
+		if strings.HasSuffix(s, double) {
+			return true
+		}
+	}
+	return false
+}
+
+// isShortEnglishWord tells if R1 of s is empty and s ends in a short
+// syllable.
+func isShortEnglishWord(s string, r1 int) bool {
+	return r1 >= len(s) && endsShortSyllable(s)
+}
+
+// endsShortSyllable tells if s ends in a consonant, a vowel and a consonant
+// other than w, x or Y, or is a vowel and a consonant.
+func endsShortSyllable(s string) bool {
+	n := len(s)
+	switch {
+	case n == 2:
+		return isEnglishVowel(rune(s[0])) && !isEnglishVowel(rune(s[1]))
+	case n > 2:
+		last := rune(s[n-1])
+		return !isEnglishVowel(rune(s[n-3])) && isEnglishVowel(rune(s[n-2])) &&
+			!isEnglishVowel(last) && last != 'w' && last != 'x' && last != 'Y'
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStemRussian(t *testing.T) {
+	tests := map[string]string{
+		"велосипед":   "велосипед",
+		"велосипеды":  "велосипед",
+		"велосипедов": "велосипед",
+		"горный":      "горн",
+		"горного":     "горн",
+		"красивая":    "красив",
+		"продается":   "прода",
+		"продаю":      "прода",
+		"состоянии":   "состоян",
+		"новейший":    "нов",
+		"радость":     "радост",
+		"кот":         "кот",
+	}
+	for word, expected := range tests {
+		assert.Equal(t, expected, stemRussian(word), word)
+	}
+}
+
+func TestStemEnglish(t *testing.T) {
+	tests := map[string]string{
+		"bikes":    "bike",
+		"running":  "run",
+		"hopped":   "hop",
+		"hoping":   "hope",
+		"boxes":    "box",
+		"classes":  "class",
+		"caresses": "caress",
+		"ponies":   "poni",
+		"ties":     "tie",
+		"cried":    "cri",
+		"used":     "use",
+		"agreed":   "agre",
+		"happy":    "happi",
+		"yellow":   "yellow",
+		"tv":       "tv",
+	}
+	for word, expected := range tests {
+		assert.Equal(t, expected, stemEnglish(word), word)
+	}
+}
+
+func TestStem(t *testing.T) {
+	assert.Equal(t, "велосипед", stem("велосипеды"))
+	assert.Equal(t, "bike", stem("bikes"))
+	assert.Equal(t, "iphone12", stem("iphone12"))
+	assert.Equal(t, "xboxы", stem("xboxы"))
+}
@@ -0,0 +1,239 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+const (
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 20
+)
+
+// Suggester completes typed prefixes to the names of the public adverts and
+// corrects misspelled words. It is kept in memory: built from the adverts
+// table at startup and updated by the same calls as the search index, so a
+// new advert is suggested once it is published.
+type Suggester struct {
+	source    Source
+	batchSize int
+
+	mu    sync.RWMutex
+	names *names
+}
+
+func NewSuggester(source Source, batchSize int) *Suggester {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Suggester{source: source, batchSize: batchSize, names: newNames()}
+}
+
+// Rebuild loads the names of all public adverts and replaces the current
+// ones, see MemoryIndex.Rebuild.
+func (s *Suggester) Rebuild() error {
+	fresh := newNames()
+	afterId := 0
+	for {
+		adverts, err := s.source.GetSearchDocuments(afterId, s.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, advert := range adverts {
+			fresh.add(advert.Id, advert.Name, false)
+			afterId = advert.Id
+		}
+		if len(adverts) < s.batchSize {
+			break
+		}
+	}
+	fresh.completions.rank()
+
+	s.mu.Lock()
+	s.names = fresh
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of distinct names.
+func (s *Suggester) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.names.byKey)
+}
+
+// Update reloads the name of the advert, the name of an advert that is no
+// longer public is removed.
+func (s *Suggester) Update(advertId int) error {
+	advert, err := s.source.GetSearchDocument(advertId)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return s.Remove(advertId)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.names.remove(advertId)
+	s.names.add(advertId, advert.Name, true)
+	return nil
+}
+
+func (s *Suggester) Remove(advertId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.names.remove(advertId)
+	return nil
+}
+
+// Suggest returns up to limit names starting with prefix at a word
+// boundary. When there are none, the misspelled words of the prefix are
+// corrected and the names of the correction are returned.
+func (s *Suggester) Suggest(prefix string, limit int) model.Suggestions {
+	if limit < 1 {
+		limit = DefaultSuggestLimit
+	}
+	suggestions := model.Suggestions{Prefix: prefix, Completions: []model.Completion{}}
+	key := normalizePrefix(prefix)
+	if key == "" {
+		return suggestions
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	top := s.names.completions.lookup(key)
+	if len(top) == 0 {
+		if corrected := s.names.correct(key); corrected != "" {
+			suggestions.DidYouMean = strings.TrimSpace(corrected)
+			top = s.names.completions.lookup(corrected)
+		}
+	}
+	for i, c := range top {
+		if i == limit {
+			break
+		}
+		suggestions.Completions = append(suggestions.Completions, model.Completion{Text: c.text, Count: c.count})
+	}
+	return suggestions
+}
+
+// names are the distinct normalized names of the public adverts.
+type names struct {
+	byAdvert    map[int]string
+	byKey       map[string]*completion
+	completions *trie
+	words       *vocabulary
+}
+
+func newNames() *names {
+	return &names{
+		byAdvert:    make(map[int]string),
+		byKey:       make(map[string]*completion),
+		completions: newTrie(MaxSuggestLimit),
+		words:       newVocabulary(),
+	}
+}
+
+// add counts the name of the advert, the first spelling of a name is the
+// one suggested. With update unset the trie is ranked later.
+func (n *names) add(advertId int, name string, update bool) {
+	key := normalizeName(name)
+	if key == "" {
+		return
+	}
+	n.byAdvert[advertId] = key
+	for _, word := range strings.Fields(key) {
+		n.words.add(word, 1)
+	}
+
+	c, ok := n.byKey[key]
+	if !ok {
+		c = &completion{key: key, text: strings.Join(strings.Fields(name), " ")}
+		n.byKey[key] = c
+	}
+	c.count++
+	if !ok {
+		n.completions.add(c, update)
+	} else if update {
+		n.completions.changed(c)
+	}
+}
+
+func (n *names) remove(advertId int) {
+	key, ok := n.byAdvert[advertId]
+	if !ok {
+		return
+	}
+	delete(n.byAdvert, advertId)
+	for _, word := range strings.Fields(key) {
+		n.words.add(word, -1)
+	}
+
+	c := n.byKey[key]
+	c.count--
+	if c.count > 0 {
+		n.completions.changed(c)
+		return
+	}
+	delete(n.byKey, key)
+	n.completions.remove(c)
+}
+
+// correct replaces the unknown words of the normalized prefix with the
+// closest known ones, the last word may be unfinished unless the prefix
+// ends with a space. An empty string is returned when there is nothing to
+// correct.
+func (n *names) correct(key string) string {
+	words := strings.Fields(key)
+	finished := strings.HasSuffix(key, " ")
+	changed := false
+	for i, word := range words {
+		prefix := i == len(words)-1 && !finished
+		if prefix && n.completions.hasPrefix(word) || !prefix && n.words.counts[word] > 0 {
+			continue
+		}
+		if corrected := n.words.correct(word, prefix); corrected != "" {
+			words[i] = corrected
+			changed = true
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	corrected := strings.Join(words, " ")
+	if finished {
+		corrected += " "
+	}
+	return corrected
+}
+
+// normalizeName lower cases the name, spells ё as е and keeps its words of
+// letters and digits separated by single spaces.
+func normalizeName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.ReplaceAll(strings.Join(fields, " "), "ё", "е")
+}
+
+// normalizePrefix normalizes a typed prefix as a name keeping a trailing
+// space: a prefix ending with a separator has its last word finished.
+func normalizePrefix(prefix string) string {
+	key := normalizeName(prefix)
+	if key == "" {
+		return ""
+	}
+	last := []rune(prefix)[len([]rune(prefix))-1]
+	if !unicode.IsLetter(last) && !unicode.IsDigit(last) {
+		key += " "
+	}
+	return key
+}
@@ -0,0 +1,209 @@
+package search
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSuggester(t *testing.T) (*Suggester, *fakeSource) {
+	source := &fakeSource{adverts: map[int]model.Advert{
+		1: {Id: 1, Name: "Горный велосипед"},
+		2: {Id: 2, Name: "Детский велосипед"},
+		3: {Id: 3, Name: "детский  велосипед!"},
+		4: {Id: 4, Name: "Шлем"},
+		5: {Id: 5, Name: "Ёлка"},
+		6: {Id: 6, Name: "Mountain bike"},
+	}}
+
+	suggester := NewSuggester(source, 2)
+	require.NoError(t, suggester.Rebuild())
+	return suggester, source
+}
+
+func completionTexts(suggestions model.Suggestions) []string {
+	texts := []string{}
+	for _, c := range suggestions.Completions {
+		texts = append(texts, c.Text)
+	}
+	return texts
+}
+
+func TestSuggester_Suggest(t *testing.T) {
+	suggester, _ := testSuggester(t)
+	assert.Equal(t, 5, suggester.Len())
+
+	tests := []struct {
+		name               string
+		prefix             string
+		limit              int
+		expectedTexts      []string
+		expectedDidYouMean string
+	}{
+		{name: "Name start", prefix: "дет", expectedTexts: []string{"Детский велосипед"}},
+		{name: "Word start", prefix: "Вел", expectedTexts: []string{"Детский велосипед", "Горный велосипед"}},
+		{name: "Several words", prefix: "горный  вел", expectedTexts: []string{"Горный велосипед"}},
+		{name: "Finished word", prefix: "горн ", expectedTexts: []string{}},
+		{name: "Yo", prefix: "елк", expectedTexts: []string{"Ёлка"}},
+		{name: "Limit", prefix: "вел", limit: 1, expectedTexts: []string{"Детский велосипед"}},
+		{name: "Not a word start", prefix: "ский", expectedTexts: []string{}},
+		{name: "Empty", prefix: " ,", expectedTexts: []string{}},
+		{
+			name:               "Did you mean",
+			prefix:             "горнй велсоипед",
+			expectedTexts:      []string{"Горный велосипед"},
+			expectedDidYouMean: "горный велосипед",
+		},
+		{
+			name:               "Did you mean unfinished",
+			prefix:             "mountian bi",
+			expectedTexts:      []string{"Mountain bike"},
+			expectedDidYouMean: "mountain bi",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			suggestions := suggester.Suggest(test.prefix, test.limit)
+			assert.Equal(t, test.prefix, suggestions.Prefix)
+			assert.Equal(t, test.expectedTexts, completionTexts(suggestions))
+			assert.Equal(t, test.expectedDidYouMean, suggestions.DidYouMean)
+		})
+	}
+
+	suggestions := suggester.Suggest("велосипед", 0)
+	assert.Equal(t, []model.Completion{
+		{Text: "Детский велосипед", Count: 2},
+		{Text: "Горный велосипед", Count: 1},
+	}, suggestions.Completions)
+}
+
+func TestSuggester_Update(t *testing.T) {
+	suggester, source := testSuggester(t)
+
+	source.adverts[7] = model.Advert{Id: 7, Name: "Горный велосипед"}
+	source.adverts[8] = model.Advert{Id: 8, Name: "Горный велосипед"}
+	require.NoError(t, suggester.Update(7))
+	require.NoError(t, suggester.Update(8))
+	assert.Equal(t, []string{"Горный велосипед", "Детский велосипед"}, completionTexts(suggester.Suggest("вел", 0)))
+
+	// renamed
+	source.adverts[2] = model.Advert{Id: 2, Name: "Самокат"}
+	require.NoError(t, suggester.Update(2))
+	assert.Equal(t, []model.Completion{{Text: "Самокат", Count: 1}}, suggester.Suggest("сам", 0).Completions)
+	assert.Equal(t, []model.Completion{
+		{Text: "Горный велосипед", Count: 3},
+		{Text: "Детский велосипед", Count: 1},
+	}, suggester.Suggest("вел", 0).Completions)
+
+	// no longer public
+	delete(source.adverts, 3)
+	require.NoError(t, suggester.Update(3))
+	require.NoError(t, suggester.Remove(5))
+	assert.Equal(t, []string{"Горный велосипед"}, completionTexts(suggester.Suggest("вел", 0)))
+	assert.Equal(t, []string{}, completionTexts(suggester.Suggest("елк", 0)))
+	assert.Equal(t, "", suggester.Suggest("детск", 0).DidYouMean)
+	assert.Equal(t, 4, suggester.Len())
+
+	source.err = errors.New("some error")
+	assert.Error(t, suggester.Update(1))
+	assert.Error(t, suggester.Rebuild())
+	assert.Equal(t, 4, suggester.Len())
+}
+
+// benchmarkNames returns n generated names of one to four words.
+func benchmarkNames(n int) []string {
+	random := rand.New(rand.NewSource(1))
+	letters := []rune("абвгдежзийклмнопрстуфхцчшщыэюя")
+	vocabulary := make([]string, 5000)
+	for i := range vocabulary {
+		word := make([]rune, 3+random.Intn(8))
+		for j := range word {
+			word[j] = letters[random.Intn(len(letters))]
+		}
+		vocabulary[i] = string(word)
+	}
+
+	names := make([]string, n)
+	for i := range names {
+		name := vocabulary[random.Intn(len(vocabulary))]
+		for words := random.Intn(4); words > 0; words-- {
+			name += " " + vocabulary[random.Intn(len(vocabulary))]
+		}
+		names[i] = name
+	}
+	return names
+}
+
+func benchmarkSuggester(b *testing.B, names []string) (*Suggester, *fakeSource) {
+	source := &fakeSource{adverts: make(map[int]model.Advert, len(names))}
+	suggester := NewSuggester(source, DefaultBatchSize)
+	for i, name := range names {
+		source.adverts[i+1] = model.Advert{Id: i + 1, Name: name}
+		suggester.names.add(i+1, name, false)
+	}
+	suggester.names.completions.rank()
+	return suggester, source
+}
+
+// reportPercentiles reports the 50th and 99th percentile of the durations
+// in milliseconds.
+func reportPercentiles(b *testing.B, durations []time.Duration) {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentile := func(p float64) float64 {
+		return float64(durations[int(float64(len(durations)-1)*p)]) / float64(time.Millisecond)
+	}
+	b.ReportMetric(percentile(0.50), "p50-ms")
+	b.ReportMetric(percentile(0.99), "p99-ms")
+}
+
+func BenchmarkSuggester_Suggest(b *testing.B) {
+	names := benchmarkNames(100000)
+	suggester, _ := benchmarkSuggester(b, names)
+
+	// per keystroke prefixes of the names, a typo in every tenth one
+	random := rand.New(rand.NewSource(2))
+	prefixes := make([]string, 1000)
+	for i := range prefixes {
+		name := []rune(names[random.Intn(len(names))])
+		prefix := name[:1+random.Intn(len(name))]
+		if i%10 == 0 && len(prefix) > 4 {
+			prefix[2], prefix[3] = prefix[3], prefix[2]
+		}
+		prefixes[i] = string(prefix)
+	}
+
+	durations := make([]time.Duration, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		suggester.Suggest(prefixes[i%len(prefixes)], DefaultSuggestLimit)
+		durations[i] = time.Since(start)
+	}
+	b.StopTimer()
+	reportPercentiles(b, durations)
+}
+
+func BenchmarkSuggester_Update(b *testing.B) {
+	names := benchmarkNames(100000)
+	suggester, source := benchmarkSuggester(b, names)
+
+	durations := make([]time.Duration, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := i%len(names) + 1
+		source.adverts[id] = model.Advert{Id: id, Name: names[(i*7919)%len(names)]}
+		start := time.Now()
+		if err := suggester.Update(id); err != nil {
+			b.Fatal(err)
+		}
+		durations[i] = time.Since(start)
+	}
+	b.StopTimer()
+	reportPercentiles(b, durations)
+}
@@ -0,0 +1,107 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token is a word of a text: the normalized term and the byte offsets of the
+// word in the text, headlines use the offsets to mark the matches.
+type token struct {
+	term       string
+	start, end int
+	stop       bool
+}
+
+// stopWords are left out of the index as the Postgres text search
+// configurations do: they occur in nearly every advert.
+var stopWords = makeSet(
+	"и", "в", "во", "не", "что", "он", "на", "я", "с", "со", "как", "а", "то", "все", "она", "так", "его",
+	"но", "да", "ты", "к", "у", "же", "вы", "за", "бы", "по", "только", "ее", "мне", "было", "вот", "от",
+	"меня", "еще", "нет", "о", "из", "ему", "теперь", "когда", "даже", "ну", "ли", "если", "уже", "или",
+	"ни", "быть", "был", "него", "до", "вас", "нибудь", "уж", "вам", "там", "потом", "себя", "ей", "может",
+	"они", "тут", "где", "есть", "надо", "ней", "для", "мы", "тебя", "их", "чем", "была", "сам", "чтоб",
+	"без", "будто", "чего", "раз", "тоже", "себе", "под", "будет", "ж", "тогда", "кто", "этот", "того",
+	"потому", "этого", "какой", "ним", "здесь", "этом", "один", "почти", "мой", "тем", "чтобы", "нее",
+	"были", "куда", "зачем", "всех", "можно", "при", "об", "хоть", "после", "над", "больше", "тот",
+	"через", "эти", "нас", "про", "всего", "них", "какая", "много", "разве", "эту", "моя", "свою", "этой",
+	"перед", "лучше", "чуть", "том", "такой", "им", "более", "всегда", "конечно", "всю", "между",
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if", "in", "into", "is", "it", "no",
+	"not", "of", "on", "or", "such", "that", "the", "their", "then", "there", "these", "they", "this",
+	"to", "was", "will", "with",
+)
+
+func makeSet(items ...string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, word := range items {
+		set[word] = true
+	}
+	return set
+}
+
+// tokenize returns the terms of text the index keeps, stop words are
+// skipped.
+func tokenize(text string) []token {
+	var tokens []token
+	for _, t := range words(text) {
+		if !t.stop {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// words splits text into words of letters and digits, lower cases them,
+// spells ё as е and stems them. Stop words are marked and kept unstemmed.
+func words(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+func appendToken(tokens []token, text string, start, end int) []token {
+	word := strings.ReplaceAll(strings.ToLower(text[start:end]), "ё", "е")
+	if stopWords[word] {
+		return append(tokens, token{term: word, start: start, end: end, stop: true})
+	}
+	return append(tokens, token{term: stem(word), start: start, end: end})
+}
+
+// stem picks the stemmer by the alphabet of the word, words mixing the
+// alphabets or holding digits (model names, sizes) are kept as they are.
+func stem(word string) string {
+	cyrillic, latin := false, false
+	for _, r := range word {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic = true
+		case r < utf8.RuneSelf && unicode.IsLetter(r):
+			latin = true
+		default:
+			return word
+		}
+	}
+	switch {
+	case cyrillic && !latin:
+		return stemRussian(word)
+	case latin && !cyrillic:
+		return stemEnglish(word)
+	}
+	return word
+}
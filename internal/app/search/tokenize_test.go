@@ -0,0 +1,29 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	text := "Продаётся горный Велосипед, и шлем (new bikes)!"
+	assert.Equal(t, []token{
+		{term: "прода", start: 0, end: 18},
+		{term: "горн", start: 19, end: 31},
+		{term: "велосипед", start: 32, end: 50},
+		{term: "шлем", start: 55, end: 63},
+		{term: "new", start: 65, end: 68},
+		{term: "bike", start: 69, end: 74},
+	}, tokenize(text))
+	assert.Equal(t, "Велосипед", text[32:50])
+}
+
+func TestWords(t *testing.T) {
+	assert.Equal(t, []token{
+		{term: "шлем", start: 0, end: 8},
+		{term: "и", start: 9, end: 11, stop: true},
+		{term: "bike", start: 12, end: 16},
+	}, words("шлем и bike"))
+	assert.Nil(t, words(" -- "))
+}
@@ -0,0 +1,203 @@
+package search
+
+import "sort"
+
+// completion is a distinct normalized advert name, count is the number of
+// public adverts with the name.
+type completion struct {
+	key   string
+	text  string
+	count int
+}
+
+func (c *completion) less(other *completion) bool {
+	if c.count != other.count {
+		return c.count > other.count
+	}
+	return c.key < other.key
+}
+
+// trie maps the prefixes of the names to their most frequent completions.
+// A name is added once for each of its words, so a prefix matches from the
+// start of any word. Every node keeps its top completions, a lookup costs
+// the length of the prefix only.
+type trie struct {
+	root *trieNode
+	size int
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	// the completions whose path ends here
+	ends []*completion
+	top  []*completion
+}
+
+func newTrie(size int) *trie {
+	return &trie{root: &trieNode{}, size: size}
+}
+
+// paths returns the keys the completion is added under: the name from the
+// start of each word.
+func paths(key string) []string {
+	var keys []string
+	runes := []rune(key)
+	for i := range runes {
+		if i == 0 || runes[i-1] == ' ' {
+			keys = append(keys, string(runes[i:]))
+		}
+	}
+	return keys
+}
+
+// add inserts the completion, with update unset the top lists are left for
+// a later call to rank.
+func (t *trie) add(c *completion, update bool) {
+	for _, path := range paths(c.key) {
+		nodes := t.walk(path, true)
+		last := nodes[len(nodes)-1]
+		if !containsCompletion(last.ends, c) {
+			last.ends = append(last.ends, c)
+		}
+		if update {
+			rankPath(nodes, t.size)
+		}
+	}
+}
+
+// changed updates the top lists after the count of the completion changed.
+func (t *trie) changed(c *completion) {
+	for _, path := range paths(c.key) {
+		rankPath(t.walk(path, false), t.size)
+	}
+}
+
+func (t *trie) remove(c *completion) {
+	for _, path := range paths(c.key) {
+		nodes := t.walk(path, false)
+		if len(nodes) != len([]rune(path))+1 {
+			continue
+		}
+		last := nodes[len(nodes)-1]
+		for i, end := range last.ends {
+			if end == c {
+				last.ends = append(last.ends[:i], last.ends[i+1:]...)
+				break
+			}
+		}
+
+		// drop the nodes left empty, bottom up
+		runes := []rune(path)
+		for i := len(nodes) - 1; i > 0; i-- {
+			if len(nodes[i].children) > 0 || len(nodes[i].ends) > 0 {
+				break
+			}
+			delete(nodes[i-1].children, runes[i-1])
+			nodes = nodes[:i]
+		}
+		rankPath(nodes, t.size)
+	}
+}
+
+// lookup returns the top completions of the prefix.
+func (t *trie) lookup(prefix string) []*completion {
+	node := t.root
+	for _, r := range prefix {
+		node = node.children[r]
+		if node == nil {
+			return nil
+		}
+	}
+	return node.top
+}
+
+// hasPrefix tells if a word of the names starts with prefix.
+func (t *trie) hasPrefix(prefix string) bool {
+	return len(t.lookup(prefix)) > 0
+}
+
+// walk returns the nodes along path starting with the root. Missing nodes
+// are created with create set, otherwise the nodes found so far are
+// returned.
+func (t *trie) walk(path string, create bool) []*trieNode {
+	nodes := []*trieNode{t.root}
+	node := t.root
+	for _, r := range path {
+		next := node.children[r]
+		if next == nil {
+			if !create {
+				break
+			}
+			if node.children == nil {
+				node.children = make(map[rune]*trieNode)
+			}
+			next = &trieNode{}
+			node.children[r] = next
+		}
+		nodes = append(nodes, next)
+		node = next
+	}
+	return nodes
+}
+
+// rank recomputes the top lists of the whole trie.
+func (t *trie) rank() {
+	var visit func(node *trieNode)
+	visit = func(node *trieNode) {
+		for _, child := range node.children {
+			visit(child)
+		}
+		node.rank(t.size)
+	}
+	visit(t.root)
+}
+
+// rankPath recomputes the top lists of the nodes of a path from the bottom,
+// the lists of the other children are up to date.
+func rankPath(nodes []*trieNode, size int) {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		nodes[i].rank(size)
+	}
+}
+
+// rank merges the completions ending at the node with the top lists of the
+// children. The lists are sorted, a child is left at its first completion
+// that does not fit.
+func (n *trieNode) rank(size int) {
+	top := make([]*completion, 0, size)
+	insert := func(c *completion) bool {
+		if len(top) == size && !c.less(top[size-1]) {
+			return false
+		}
+		if containsCompletion(top, c) {
+			return true
+		}
+		i := sort.Search(len(top), func(i int) bool { return c.less(top[i]) })
+		if len(top) < size {
+			top = append(top, nil)
+		}
+		copy(top[i+1:], top[i:])
+		top[i] = c
+		return true
+	}
+	for _, c := range n.ends {
+		insert(c)
+	}
+	for _, child := range n.children {
+		for _, c := range child.top {
+			if !insert(c) {
+				break
+			}
+		}
+	}
+	n.top = top
+}
+
+func containsCompletion(list []*completion, c *completion) bool {
+	for _, item := range list {
+		if item == c {
+			return true
+		}
+	}
+	return false
+}
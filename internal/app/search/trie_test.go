@@ -0,0 +1,63 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lookupKeys(t *trie, prefix string) []string {
+	keys := []string{}
+	for _, c := range t.lookup(prefix) {
+		keys = append(keys, c.key)
+	}
+	return keys
+}
+
+func TestPaths(t *testing.T) {
+	assert.Equal(t, []string{"горный велосипед", "велосипед"}, paths("горный велосипед"))
+	assert.Equal(t, []string{"шлем"}, paths("шлем"))
+}
+
+func TestTrie(t *testing.T) {
+	tr := newTrie(2)
+	bike := &completion{key: "горный велосипед", count: 1}
+	kids := &completion{key: "детский велосипед", count: 3}
+	hill := &completion{key: "горка", count: 2}
+	tr.add(bike, true)
+	tr.add(kids, true)
+	tr.add(hill, true)
+
+	assert.Equal(t, []string{"детский велосипед", "горный велосипед"}, lookupKeys(tr, "вел"))
+	assert.Equal(t, []string{"горка", "горный велосипед"}, lookupKeys(tr, "гор"))
+	assert.Equal(t, []string{"горный велосипед"}, lookupKeys(tr, "горный "))
+	// the root keeps the top two only
+	assert.Equal(t, []string{"детский велосипед", "горка"}, lookupKeys(tr, ""))
+	assert.Equal(t, []string{}, lookupKeys(tr, "самокат"))
+	assert.True(t, tr.hasPrefix("дет"))
+	assert.False(t, tr.hasPrefix("ски"))
+
+	bike.count = 5
+	tr.changed(bike)
+	assert.Equal(t, []string{"горный велосипед", "детский велосипед"}, lookupKeys(tr, ""))
+
+	tr.remove(bike)
+	assert.Equal(t, []string{"детский велосипед"}, lookupKeys(tr, "вел"))
+	assert.Equal(t, []string{"горка"}, lookupKeys(tr, "гор"))
+	assert.Nil(t, tr.root.children['г'].children['о'].children['р'].children['н'])
+
+	tr.remove(kids)
+	tr.remove(hill)
+	assert.Empty(t, tr.root.children)
+	assert.Empty(t, tr.root.top)
+}
+
+func TestTrie_rank(t *testing.T) {
+	tr := newTrie(10)
+	tr.add(&completion{key: "шлем", count: 1}, false)
+	tr.add(&completion{key: "красный шлем", count: 2}, false)
+	assert.Equal(t, []string{}, lookupKeys(tr, "шл"))
+
+	tr.rank()
+	assert.Equal(t, []string{"красный шлем", "шлем"}, lookupKeys(tr, "шл"))
+}
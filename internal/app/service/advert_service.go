@@ -1,101 +1,471 @@
 package service
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/paramonies/avito-rest-advert/internal/app/model"
 	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/storage"
 )
 
+const defaultListOrder = "createdat_desc"
+
 type AdvertService struct {
-	repo repository.Repository
+	repo       repository.Repository
+	categories repository.CategoryRepository
+	index      SearchIndex
+	suggester  Suggester
+	store      storage.BlobStore
+	links      LinkPolicy
+	list       ListConfig
+	cursors    cursorCodec
+	rules      []ContentRule
 }
 
-func NewAdvertService(repo repository.Repository) *AdvertService {
-	return &AdvertService{repo: repo}
+// NewAdvertService creates the service, store keeps the uploaded pictures
+// and may be nil, then the files of the removed pictures are left as is.
+func NewAdvertService(repo repository.Repository, categories repository.CategoryRepository, index SearchIndex, suggester Suggester, store storage.BlobStore, links LinkPolicy, list ListConfig, rules ...ContentRule) *AdvertService {
+	return &AdvertService{repo: repo, categories: categories, index: index, suggester: suggester, store: store, links: links, list: list.withDefaults(), cursors: newCursorCodec(list.CursorSecret), rules: rules}
 }
 
-func (s *AdvertService) CreateAdvert(advert model.Advert) (int, error) {
-	if err := validate(advert); err != nil {
-		return 0, err
+// CreateAdvert saves a draft owned by the principal, the adverts the admin
+// creates without signing in have no owner.
+func (s *AdvertService) CreateAdvert(principal model.Principal, advert model.Advert) (int, model.RuleHits, error) {
+	if err := Authorize(principal, PermCreateAdverts); err != nil {
+		return 0, nil, err
+	}
+	if err := s.validate(&advert); err != nil {
+		return 0, nil, err
+	}
+	advert.Pictures = normalizePictures(advert.Pictures)
+
+	if err := s.screen(&advert); err != nil {
+		return 0, nil, err
 	}
 
-	return s.repo.CreateAdvert(advert)
+	// a flagged advert waits for a moderator
+	advert.Status = model.StatusDraft
+	if len(advert.ScreeningFlags) > 0 {
+		advert.Status = model.StatusPending
+	}
+	advert.OwnerId = nil
+	if principal.UserId != 0 {
+		advert.OwnerId = &principal.UserId
+	}
+	id, err := s.repo.CreateAdvert(advert)
+	if err != nil {
+		return 0, nil, err
+	}
+	updateIndex(s.index, id)
+	return id, advert.ScreeningFlags, nil
 }
 
-func (s *AdvertService) GetAdvertById(advertId int, fields []string) (model.Advert, error) {
+func (s *AdvertService) GetAdvertById(principal model.Principal, advertId int, fields []string) (model.Advert, error) {
+	if err := Authorize(principal, PermReadAdverts); err != nil {
+		return model.Advert{}, err
+	}
 	advert, err := s.repo.GetAdvertById(advertId)
 	if err != nil {
 		return advert, err
 	}
+	// only the active adverts are public, the others are shown to their
+	// owner and to the moderators and do not exist for everyone else
+	if advert.Status != model.StatusActive && !principal.Owns(advert.OwnerId) && !can(principal, PermModerateAdverts) {
+		return model.Advert{}, repository.ErrAdvertNotFound
+	}
 
 	return checkFields(advert, fields), nil
 }
 
-func (s *AdvertService) GetAdvertList(page int, orderBy string) ([]model.Advert, error) {
+// GetOwnAdverts returns the adverts of the signed in user in any status.
+func (s *AdvertService) GetOwnAdverts(principal model.Principal) ([]model.Advert, error) {
+	if err := Authorize(principal, PermReadOwnAdverts); err != nil {
+		return nil, err
+	}
+	if principal.UserId == 0 {
+		return nil, ErrAuthRequired
+	}
+	return s.repo.GetAdvertsByOwner(principal.UserId)
+}
+
+// GetAdvertList returns a page of the filtered list selected either by the
+// page number or by a cursor from a previous response. A cursor keeps the
+// order it was issued for, the order may be empty then, and is refused with
+// other filters. The page size and the count mode may be left empty for the
+// configured defaults.
+func (s *AdvertService) GetAdvertList(principal model.Principal, query model.ListQuery) (model.AdvertList, error) {
+	if err := Authorize(principal, PermReadAdverts); err != nil {
+		return model.AdvertList{}, err
+	}
+	// the archived adverts are listed to the moderators only
+	if query.IncludeArchived {
+		if err := Authorize(principal, PermModerateAdverts); err != nil {
+			return model.AdvertList{}, err
+		}
+	}
+	if err := validateListQuery(query); err != nil {
+		return model.AdvertList{}, err
+	}
+	if err := s.resolveCategory(&query); err != nil {
+		return model.AdvertList{}, err
+	}
+	if query.Count == "" {
+		query.Count = s.list.CountMode
+	}
+	page, pageSize, orderBy := query.Page, s.list.pageSize(query.PageSize), query.OrderBy
+	if page < 1 {
+		page = 1
+	}
+
+	filters := filterHash(query)
+	var position *model.ListCursor
+	if query.Cursor != "" {
+		decoded, err := s.cursors.decode(query.Cursor)
+		if err != nil {
+			return model.AdvertList{}, cursorError("the cursor is invalid")
+		}
+		if orderBy != "" && orderBy != decoded.OrderBy {
+			return model.AdvertList{}, cursorMismatchError(fmt.Sprintf("the cursor was issued for order_by %s", decoded.OrderBy))
+		}
+		if decoded.Filters != filters {
+			return model.AdvertList{}, cursorMismatchError("the cursor was issued for other filters")
+		}
+		orderBy = decoded.OrderBy
+		position = &decoded
+	}
+	if orderBy == "" {
+		orderBy = defaultListOrder
+	}
+
 	order := strings.Split(orderBy, "_")
 	orderField, orderDirect := order[0], order[1]
+	if orderField == model.OrderDistance && query.Near == nil {
+		errs := &ValidationError{}
+		errs.Add("near", CodeRequired, `the field "near" is required to order by distance`, nil)
+		return model.AdvertList{}, errs
+	}
 
-	adverts, err := s.repo.GetAdvertList(page, orderField, orderDirect)
+	offset := 0
+	if position == nil {
+		offset = (page - 1) * pageSize
+	}
+	// one advert more than a page tells whether the list goes on
+	adverts, err := s.repo.GetAdvertList(query, model.ListPage{
+		Offset:      offset,
+		Limit:       pageSize + 1,
+		OrderField:  orderField,
+		OrderDirect: orderDirect,
+		Cursor:      position,
+	})
 	if err != nil {
-		return nil, err
+		return model.AdvertList{}, err
 	}
 
-	for i, advert := range adverts {
-		if advert.Pictures != "" {
-			advert.MainPicture = strings.Split(advert.Pictures, ",")[0]
-		} else {
-			advert.MainPicture = ""
+	more := len(adverts) > pageSize
+	if more {
+		adverts = adverts[:pageSize]
+	}
+	backward := position != nil && position.Backward
+	if backward {
+		for i, j := 0, len(adverts)-1; i < j; i, j = i+1, j-1 {
+			adverts[i], adverts[j] = adverts[j], adverts[i]
 		}
+	}
+	if adverts == nil {
+		adverts = []model.Advert{}
+	}
 
-		advert.Pictures = ""
-		adverts[i] = advert
+	list := model.AdvertList{Items: adverts, PageSize: pageSize}
+	if position == nil {
+		list.Page = page
+	}
+	count := func() (int, error) { return s.repo.CountAdverts(query, query.Count) }
+	if list.Total, err = listTotal(list.Page, offset, len(adverts), more, count); err != nil {
+		return model.AdvertList{}, err
 	}
+	list.TotalPages = (list.Total + pageSize - 1) / pageSize
 
-	return adverts, nil
+	if len(adverts) == 0 {
+		return list, nil
+	}
+	if backward || more {
+		list.NextCursor = s.cursors.encode(listCursor(orderBy, orderField, filters, adverts[len(adverts)-1], false))
+	}
+	if (backward && more) || (!backward && (position != nil || page > 1)) {
+		list.PrevCursor = s.cursors.encode(listCursor(orderBy, orderField, filters, adverts[0], true))
+	}
+	return list, nil
 }
 
-func validate(advert model.Advert) error {
-	var messageErrors []string
-	if utf8.RuneCountInString(advert.Name) > 200 {
-		messageErrors = append(messageErrors, `length of the field "name" should not exceed 200`)
+func (s *AdvertService) UpdateAdvert(principal model.Principal, advertId int, advert model.Advert) error {
+	if err := s.authorize(principal, advertId, PermUpdateOwnAdverts, PermUpdateAnyAdverts); err != nil {
+		return err
+	}
+	if err := s.validate(&advert); err != nil {
+		return err
 	}
+	advert.Pictures = normalizePictures(advert.Pictures)
 
-	if utf8.RuneCountInString(advert.Description) > 1000 {
-		messageErrors = append(messageErrors, `length of the field "description" should not exceed 1000`)
+	if err := s.screen(&advert); err != nil {
+		return err
 	}
 
-	if advert.Price < 0 {
-		messageErrors = append(messageErrors, `the field "price" must have a value greater than 0`)
+	removed, err := s.repo.UpdateAdvert(advertId, advert)
+	if err != nil {
+		return err
 	}
+	updateIndex(s.index, advertId)
+	s.deletePictureFiles(advertId, removed)
+	return nil
+}
 
-	if advert.Pictures != "" {
-		pictures := strings.Split(advert.Pictures, ",")
-		if len(pictures) > 3 {
-			messageErrors = append(messageErrors, `the field "pictures" must contain no more than 3 photos`)
-		}
+// PatchAdvert applies a JSON merge patch (RFC 7396) to the stored advert
+// and saves the result if it still passes validation.
+func (s *AdvertService) PatchAdvert(principal model.Principal, advertId int, patch []byte) error {
+	advert, err := s.repo.GetAdvertById(advertId)
+	if err != nil {
+		return err
+	}
+	if err := authorizeAdvert(principal, PermUpdateOwnAdverts, PermUpdateAnyAdverts, advert.OwnerId); err != nil {
+		return err
 	}
 
-	if len(messageErrors) > 0 {
-		err := strings.Join(messageErrors, ", ")
-		return errors.New(err)
+	advert, err = applyMergePatch(advert, patch)
+	if err != nil {
+		return err
 	}
 
+	if err := s.validate(&advert); err != nil {
+		return err
+	}
+	advert.Pictures = normalizePictures(advert.Pictures)
+
+	if err := s.screen(&advert); err != nil {
+		return err
+	}
+
+	removed, err := s.repo.UpdateAdvert(advertId, advert)
+	if err != nil {
+		return err
+	}
+	updateIndex(s.index, advertId)
+	s.deletePictureFiles(advertId, removed)
 	return nil
 }
 
-func checkFields(advert model.Advert, fields []string) model.Advert {
-	if advert.Pictures != "" {
-		advert.MainPicture = strings.Split(advert.Pictures, ",")[0]
+func (s *AdvertService) DeleteAdvert(principal model.Principal, advertId int) error {
+	if err := s.authorize(principal, advertId, PermDeleteOwnAdverts, PermDeleteAnyAdverts); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAdvert(advertId); err != nil {
+		return err
+	}
+	removeFromIndex(s.index, advertId)
+	return nil
+}
+
+// TransitionAdvert changes the status of the advert. The moderator changes
+// need the moderate permission, the author ones the permission to update
+// the advert.
+func (s *AdvertService) TransitionAdvert(principal model.Principal, advertId int, to model.AdvertStatus) error {
+	advert, err := s.repo.GetAdvertById(advertId)
+	if err != nil {
+		return err
+	}
+
+	moderatorOnly, err := checkTransition(advert.Status, to)
+	if err != nil {
+		return err
+	}
+	if moderatorOnly {
+		err = Authorize(principal, PermModerateAdverts)
 	} else {
-		advert.MainPicture = ""
+		err = authorizeAdvert(principal, PermUpdateOwnAdverts, PermUpdateAnyAdverts, advert.OwnerId)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.UpdateAdvertStatus(advertId, advert.Status, to)
+	if errors.Is(err, repository.ErrAdvertNotFound) {
+		return fmt.Errorf("%w: advertisement status has been changed concurrently", ErrTransitionNotAllowed)
+	}
+	if err != nil {
+		return err
+	}
+	updateIndex(s.index, advertId)
+	return nil
+}
+
+func (s *AdvertService) ArchiveAdvert(principal model.Principal, advertId int) error {
+	if err := s.authorize(principal, advertId, PermDeleteOwnAdverts, PermDeleteAnyAdverts); err != nil {
+		return err
+	}
+	if err := s.repo.ArchiveAdvert(advertId); err != nil {
+		return err
+	}
+	removeFromIndex(s.index, advertId)
+	return nil
+}
+
+func (s *AdvertService) RestoreAdvert(principal model.Principal, advertId int) error {
+	if err := Authorize(principal, PermRestoreAdverts); err != nil {
+		return err
+	}
+	if err := s.repo.RestoreAdvert(advertId); err != nil {
+		return err
+	}
+	updateIndex(s.index, advertId)
+	return nil
+}
+
+// authorize checks the own or the any permission of the principal to change
+// the advert, the owner is not loaded when the any permission is given.
+func (s *AdvertService) authorize(principal model.Principal, advertId int, own, any Permission) error {
+	if can(principal, any) {
+		return nil
+	}
+	ownerId, err := s.repo.GetAdvertOwner(advertId)
+	if err != nil {
+		return err
+	}
+	return authorizeAdvert(principal, own, any, ownerId)
+}
+
+// screen runs the content rules over the advert and stores the rules that
+// fired in its screening flags, so moderators can see them in the queue. An
+// advert with flags is saved as pending, the repository sends an edited one
+// back to pending.
+func (s *AdvertService) screen(advert *model.Advert) error {
+	verdict, hits := screen(s.rules, *advert)
+	if verdict == model.VerdictBlock {
+		return &BlockedError{Hits: hits}
+	}
+
+	advert.ScreeningFlags = hits
+	return nil
+}
+
+func listCursor(orderBy, orderField, filters string, advert model.Advert, backward bool) model.ListCursor {
+	cursor := model.ListCursor{OrderBy: orderBy, Id: advert.Id, Backward: backward, Filters: filters}
+	switch orderField {
+	case "price":
+		cursor.Value = strconv.Itoa(advert.Price)
+	case "createdat":
+		if advert.CreatedAt != nil {
+			cursor.Value = advert.CreatedAt.Format(time.RFC3339Nano)
+		}
+	case model.OrderDistance:
+		if advert.DistanceKm != nil {
+			cursor.Value = strconv.FormatFloat(*advert.DistanceKm, 'g', -1, 64)
+		}
+	}
+	return cursor
+}
+
+func cursorError(message string) error {
+	errs := &ValidationError{}
+	errs.Add("cursor", CodeInvalid, message, nil)
+	return errs
+}
+
+// cursorMismatchError refuses a valid cursor used with another order or
+// other filters than it was issued for, the request is malformed then.
+func cursorMismatchError(message string) error {
+	errs := &ValidationError{}
+	errs.Add("cursor", CodeMalformed, message, nil)
+	return errs
+}
+
+// validate checks the fields of the advert and then the attributes against
+// the schema of its category. The attributes are replaced with the
+// normalized values.
+func (s *AdvertService) validate(advert *model.Advert) error {
+	if err := validate(*advert, s.links); err != nil {
+		return err
+	}
+
+	errs := &ValidationError{}
+	category, err := s.categories.GetCategory(advert.CategoryId)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		errs.Add("category-id", CodeInvalid, fmt.Sprintf("category %d does not exist", advert.CategoryId), nil)
+		return errs
+	}
+	if err != nil {
+		return err
+	}
+
+	advert.Attributes = validateAttributes(category.Attributes, advert.Attributes, errs)
+	return errs.Err()
+}
+
+// resolveCategory replaces the category of the list filter with the ids of
+// its subtree.
+func (s *AdvertService) resolveCategory(query *model.ListQuery) error {
+	if query.CategoryId == nil {
+		return nil
+	}
+	subtree, err := s.categories.GetSubtree(*query.CategoryId)
+	if err != nil {
+		return err
+	}
+	if len(subtree) == 0 {
+		errs := &ValidationError{}
+		errs.Add("category_id", CodeInvalid, fmt.Sprintf("category %d does not exist", *query.CategoryId), nil)
+		return errs
+	}
+	query.Categories = subtree
+	return nil
+}
+
+func validate(advert model.Advert, links LinkPolicy) error {
+	errs := &ValidationError{}
+	if strings.TrimSpace(advert.Name) == "" {
+		errs.Add("name", CodeRequired, `the field "name" is required`, nil)
+	}
+
+	if strings.TrimSpace(advert.Description) == "" {
+		errs.Add("description", CodeRequired, `the field "description" is required`, nil)
+	}
+
+	if utf8.RuneCountInString(advert.Name) > 200 {
+		errs.Add("name", CodeMaxLength, `length of the field "name" should not exceed 200`,
+			map[string]interface{}{"max": 200})
 	}
 
+	if utf8.RuneCountInString(advert.Description) > 1000 {
+		errs.Add("description", CodeMaxLength, `length of the field "description" should not exceed 1000`,
+			map[string]interface{}{"max": 1000})
+	}
+
+	if advert.CategoryId <= 0 {
+		errs.Add("category-id", CodeRequired, `the field "category-id" is required`, nil)
+	}
+
+	if advert.Price < 0 {
+		errs.Add("price", CodeMinValue, `the field "price" must have a value greater than 0`,
+			map[string]interface{}{"min": 0})
+	}
+
+	validateLocation(advert, errs)
+	validatePictures(advert.Pictures, links, errs)
+
+	return errs.Err()
+}
+
+func checkFields(advert model.Advert, fields []string) model.Advert {
+	advert.MainPicture = advert.Pictures.Main()
+
 	if len(fields) == 0 {
 		advert.Description = ""
-		advert.Pictures = ""
+		advert.Pictures = nil
 		return advert
 	}
 
@@ -109,13 +479,77 @@ func checkFields(advert model.Advert, fields []string) model.Advert {
 	}
 
 	if contains(fields, "description") {
-		advert.Pictures = ""
+		advert.Pictures = nil
 		return advert
 	}
 
 	return advert
 }
 
+func applyMergePatch(advert model.Advert, patch []byte) (model.Advert, error) {
+	var patchDoc interface{}
+	if err := decodeJSON(patch, &patchDoc); err != nil {
+		return advert, NewDecodeError(err)
+	}
+	if _, ok := patchDoc.(map[string]interface{}); !ok {
+		return advert, &ValidationError{Errors: []FieldError{{
+			Code:    CodeMalformed,
+			Message: "merge patch document must be a JSON object",
+		}}}
+	}
+
+	original, err := json.Marshal(advert)
+	if err != nil {
+		return advert, err
+	}
+
+	var targetDoc interface{}
+	if err := decodeJSON(original, &targetDoc); err != nil {
+		return advert, err
+	}
+
+	merged, err := json.Marshal(mergePatch(targetDoc, patchDoc))
+	if err != nil {
+		return advert, err
+	}
+
+	var patched model.Advert
+	if err := decodeJSON(merged, &patched); err != nil {
+		return advert, NewDecodeError(err)
+	}
+	patched.MainPicture = ""
+
+	return patched, nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
 func contains(s []string, el string) bool {
 	for _, v := range s {
 		if v == el {
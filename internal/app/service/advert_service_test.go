@@ -1,6 +1,7 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/paramonies/avito-rest-advert/internal/app/mock"
 	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
 )
 
 func TestService_CreateAdvert(t *testing.T) {
@@ -16,8 +18,11 @@ func TestService_CreateAdvert(t *testing.T) {
 	tests := []struct {
 		name           string
 		inputAdvert    model.Advert
+		inputRules     []ContentRule
+		inputPrincipal *model.Principal
 		mockBehavior   mockBehaviortype
 		expectedResult int
+		expectedHits   model.RuleHits
 		expectedError  error
 	}{
 		{
@@ -26,12 +31,22 @@ func TestService_CreateAdvert(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				CategoryId:  3,
+				Pictures:    model.Pictures{{URL: "avito/files/ad2", Position: 5}, {URL: "avito/files/ad1", Position: 1}, {URL: "avito/files/ad3", Position: 7}},
 			},
 			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
+				advert.Status = model.StatusDraft
+				advert.OwnerId = intPtr(7)
+				advert.ScreeningFlags = model.RuleHits{}
+				advert.Pictures = model.Pictures{
+					{URL: "avito/files/ad1", Position: 0, IsMain: true},
+					{URL: "avito/files/ad2", Position: 1},
+					{URL: "avito/files/ad3", Position: 2},
+				}
 				r.EXPECT().CreateAdvert(advert).Return(1, nil)
 			},
 			expectedResult: 1,
+			expectedHits:   model.RuleHits{},
 			expectedError:  nil,
 		},
 		{
@@ -40,12 +55,101 @@ func TestService_CreateAdvert(t *testing.T) {
 				Name:        strings.Repeat("t", 201),
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				CategoryId:  3,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 			},
 			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
 			},
 			expectedResult: 0,
-			expectedError:  errors.New(`length of the field "name" should not exceed 200`),
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "name",
+				Code:    CodeMaxLength,
+				Message: `length of the field "name" should not exceed 200`,
+				Params:  map[string]interface{}{"max": 200},
+			}}},
+		},
+		{
+			name: "Flagged by content rule",
+			inputAdvert: model.Advert{
+				Name:        "SELL BIKE",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+			},
+			inputRules: []ContentRule{NewCapsTitleRule(5, model.VerdictFlag)},
+			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
+				advert.Status = model.StatusPending
+				advert.OwnerId = intPtr(7)
+				advert.ScreeningFlags = model.RuleHits{
+					{Rule: "caps_title", Verdict: model.VerdictFlag, Message: "name is written in capital letters"},
+				}
+				r.EXPECT().CreateAdvert(advert).Return(1, nil)
+			},
+			expectedResult: 1,
+			expectedHits: model.RuleHits{
+				{Rule: "caps_title", Verdict: model.VerdictFlag, Message: "name is written in capital letters"},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Blocked by content rule",
+			inputAdvert: model.Advert{
+				Name:        "casino chips",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+			},
+			inputRules:     []ContentRule{NewBannedWordsRule([]string{"Casino"}, model.VerdictBlock)},
+			mockBehavior:   func(r *mock.MockRepository, advert model.Advert) {},
+			expectedResult: 0,
+			expectedError: &BlockedError{Hits: model.RuleHits{
+				{Rule: "banned_words", Verdict: model.VerdictBlock, Message: "banned words: casino"},
+			}},
+		},
+		{
+			name: "Anonymous",
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+			},
+			inputPrincipal: &model.Principal{},
+			mockBehavior:   func(r *mock.MockRepository, advert model.Advert) {},
+			expectedResult: 0,
+			expectedError:  ErrAuthRequired,
+		},
+		{
+			name: "Buyer",
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+			},
+			inputPrincipal: &model.Principal{UserId: 7, Role: model.RoleBuyer},
+			mockBehavior:   func(r *mock.MockRepository, advert model.Advert) {},
+			expectedResult: 0,
+			expectedError:  &PermissionError{Permission: PermCreateAdverts},
+		},
+		{
+			name: "Admin without account",
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+				OwnerId:     intPtr(8),
+			},
+			inputPrincipal: &admin,
+			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
+				advert.Status = model.StatusDraft
+				advert.OwnerId = nil
+				advert.ScreeningFlags = model.RuleHits{}
+				r.EXPECT().CreateAdvert(advert).Return(2, nil)
+			},
+			expectedResult: 2,
+			expectedHits:   model.RuleHits{},
 		},
 	}
 
@@ -57,14 +161,19 @@ func TestService_CreateAdvert(t *testing.T) {
 			mockRepository := mock.NewMockRepository(c)
 			test.mockBehavior(mockRepository, test.inputAdvert)
 
-			service := NewAdvertService(mockRepository)
+			service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{}, test.inputRules...)
 
-			resultId, resultError := service.CreateAdvert(test.inputAdvert)
+			principal := owner
+			if test.inputPrincipal != nil {
+				principal = *test.inputPrincipal
+			}
+			resultId, resultHits, resultError := service.CreateAdvert(principal, test.inputAdvert)
 
 			// t.Logf("!!! %s\nexpected: %v %v\ngot: %v %v", test.name, test.expectedResult, test.expectedError, resultId, resultError)
 
 			assert.Equal(t, resultError, test.expectedError)
 			assert.Equal(t, resultId, test.expectedResult)
+			assert.Equal(t, resultHits, test.expectedHits)
 
 		})
 	}
@@ -82,7 +191,8 @@ func TestService_validate(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				CategoryId:  3,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 			},
 			expectedResult: nil,
 		},
@@ -92,9 +202,15 @@ func TestService_validate(t *testing.T) {
 				Name:        strings.Repeat("t", 201),
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				CategoryId:  3,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 			},
-			expectedResult: errors.New(`length of the field "name" should not exceed 200`),
+			expectedResult: &ValidationError{Errors: []FieldError{{
+				Field:   "name",
+				Code:    CodeMaxLength,
+				Message: `length of the field "name" should not exceed 200`,
+				Params:  map[string]interface{}{"max": 200},
+			}}},
 		},
 		{
 			name: "Advert.Description error",
@@ -102,9 +218,15 @@ func TestService_validate(t *testing.T) {
 				Name:        "name-test",
 				Description: strings.Repeat("t", 1001),
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				CategoryId:  3,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 			},
-			expectedResult: errors.New(`length of the field "description" should not exceed 1000`),
+			expectedResult: &ValidationError{Errors: []FieldError{{
+				Field:   "description",
+				Code:    CodeMaxLength,
+				Message: `length of the field "description" should not exceed 1000`,
+				Params:  map[string]interface{}{"max": 1000},
+			}}},
 		},
 		{
 			name: "Advert.Price error",
@@ -112,9 +234,15 @@ func TestService_validate(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       -1,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				CategoryId:  3,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}},
 			},
-			expectedResult: errors.New(`the field "price" must have a value greater than 0`),
+			expectedResult: &ValidationError{Errors: []FieldError{{
+				Field:   "price",
+				Code:    CodeMinValue,
+				Message: `the field "price" must have a value greater than 0`,
+				Params:  map[string]interface{}{"min": 0},
+			}}},
 		},
 		{
 			name: "Advert.Pictures error",
@@ -122,15 +250,21 @@ func TestService_validate(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3,avito/files/ad4",
+				CategoryId:  3,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}, {URL: "avito/files/ad3"}, {URL: "avito/files/ad4"}},
 			},
-			expectedResult: errors.New(`the field "pictures" must contain no more than 3 photos`),
+			expectedResult: &ValidationError{Errors: []FieldError{{
+				Field:   "pictures",
+				Code:    CodeMaxItems,
+				Message: `the field "pictures" must contain no more than 3 photos`,
+				Params:  map[string]interface{}{"max": 3},
+			}}},
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			result := validate(test.inputAdvert)
+			result := validate(test.inputAdvert, LinkPolicy{})
 			t.Logf("!!! %s expected: %v\ngot: %v", test.name, test.expectedResult, result)
 			assert.Equal(t, result, test.expectedResult)
 		})
@@ -150,14 +284,14 @@ func TestService_checkFields(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				Pictures:    model.Pictures{{URL: "avito/files/ad1", IsMain: true}, {URL: "avito/files/ad2", Position: 1}, {URL: "avito/files/ad3", Position: 2}},
 			},
 			inputFields: []string{},
 			expectedResult: model.Advert{
 				Name:        "name-test",
 				Description: "",
 				Price:       1000,
-				Pictures:    "",
+				Pictures:    nil,
 				MainPicture: "avito/files/ad1",
 			},
 		},
@@ -167,14 +301,14 @@ func TestService_checkFields(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "",
+				Pictures:    nil,
 			},
 			inputFields: []string{},
 			expectedResult: model.Advert{
 				Name:        "name-test",
 				Description: "",
 				Price:       1000,
-				Pictures:    "",
+				Pictures:    nil,
 				MainPicture: "",
 			},
 		},
@@ -184,14 +318,14 @@ func TestService_checkFields(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				Pictures:    model.Pictures{{URL: "avito/files/ad1", IsMain: true}, {URL: "avito/files/ad2", Position: 1}, {URL: "avito/files/ad3", Position: 2}},
 			},
 			inputFields: []string{"description", "pictures"},
 			expectedResult: model.Advert{
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				Pictures:    model.Pictures{{URL: "avito/files/ad1", IsMain: true}, {URL: "avito/files/ad2", Position: 1}, {URL: "avito/files/ad3", Position: 2}},
 				MainPicture: "avito/files/ad1",
 			},
 		},
@@ -201,14 +335,14 @@ func TestService_checkFields(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				Pictures:    model.Pictures{{URL: "avito/files/ad1", IsMain: true}, {URL: "avito/files/ad2", Position: 1}, {URL: "avito/files/ad3", Position: 2}},
 			},
 			inputFields: []string{"pictures"},
 			expectedResult: model.Advert{
 				Name:        "name-test",
 				Description: "",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				Pictures:    model.Pictures{{URL: "avito/files/ad1", IsMain: true}, {URL: "avito/files/ad2", Position: 1}, {URL: "avito/files/ad3", Position: 2}},
 				MainPicture: "avito/files/ad1",
 			},
 		},
@@ -218,14 +352,14 @@ func TestService_checkFields(t *testing.T) {
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "avito/files/ad1,avito/files/ad2,avito/files/ad3",
+				Pictures:    model.Pictures{{URL: "avito/files/ad1", IsMain: true}, {URL: "avito/files/ad2", Position: 1}, {URL: "avito/files/ad3", Position: 2}},
 			},
 			inputFields: []string{"description"},
 			expectedResult: model.Advert{
 				Name:        "name-test",
 				Description: "desc-test",
 				Price:       1000,
-				Pictures:    "",
+				Pictures:    nil,
 				MainPicture: "avito/files/ad1",
 			},
 		},
@@ -239,3 +373,654 @@ func TestService_checkFields(t *testing.T) {
 		})
 	}
 }
+
+func TestService_UpdateAdvert(t *testing.T) {
+	type mockBehaviortype func(*mock.MockRepository, model.Advert)
+	tests := []struct {
+		name          string
+		inputAdvert   model.Advert
+		mockBehavior  mockBehaviortype
+		expectedError error
+	}{
+		{
+			name: "OK",
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+				Pictures:    model.Pictures{{URL: "avito/files/ad1"}},
+			},
+			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
+				advert.ScreeningFlags = model.RuleHits{}
+				advert.Pictures = model.Pictures{{URL: "avito/files/ad1", IsMain: true}}
+				r.EXPECT().GetAdvertOwner(1).Return(intPtr(7), nil)
+				r.EXPECT().UpdateAdvert(1, advert).Return(nil, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Input model.Advert with invalid Price field",
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       -1,
+				CategoryId:  3,
+			},
+			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
+				r.EXPECT().GetAdvertOwner(1).Return(intPtr(7), nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "price",
+				Code:    CodeMinValue,
+				Message: `the field "price" must have a value greater than 0`,
+				Params:  map[string]interface{}{"min": 0},
+			}}},
+		},
+		{
+			name: "Not owner",
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+			},
+			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
+				r.EXPECT().GetAdvertOwner(1).Return(intPtr(8), nil)
+			},
+			expectedError: &PermissionError{Permission: PermUpdateAnyAdverts},
+		},
+		{
+			name: "Advert without owner",
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+			},
+			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
+				r.EXPECT().GetAdvertOwner(1).Return(nil, nil)
+			},
+			expectedError: &PermissionError{Permission: PermUpdateAnyAdverts},
+		},
+		{
+			name: "Not found",
+			inputAdvert: model.Advert{
+				Name:        "name-test",
+				Description: "desc-test",
+				Price:       1000,
+				CategoryId:  3,
+			},
+			mockBehavior: func(r *mock.MockRepository, advert model.Advert) {
+				r.EXPECT().GetAdvertOwner(1).Return(nil, repository.ErrAdvertNotFound)
+			},
+			expectedError: repository.ErrAdvertNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockRepository(c)
+			test.mockBehavior(mockRepository, test.inputAdvert)
+
+			service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{})
+
+			resultError := service.UpdateAdvert(owner, 1, test.inputAdvert)
+			assert.Equal(t, resultError, test.expectedError)
+		})
+	}
+}
+
+func TestService_PatchAdvert(t *testing.T) {
+	stored := model.Advert{
+		Name:        "name-test",
+		Description: "desc-test",
+		Price:       1000,
+		CategoryId:  3,
+		OwnerId:     intPtr(7),
+		Pictures:    model.Pictures{{URL: "avito/files/ad1"}, {URL: "avito/files/ad2"}},
+	}
+
+	type mockBehaviortype func(*mock.MockRepository)
+	tests := []struct {
+		name          string
+		inputPatch    string
+		mockBehavior  mockBehaviortype
+		expectedError error
+	}{
+		{
+			name:       "OK",
+			inputPatch: `{"price":500,"pictures":null}`,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(stored, nil)
+				r.EXPECT().UpdateAdvert(1, model.Advert{
+					Name:           "name-test",
+					Description:    "desc-test",
+					Price:          500,
+					CategoryId:     3,
+					OwnerId:        intPtr(7),
+					ScreeningFlags: model.RuleHits{},
+				}).Return(nil, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Not owner",
+			inputPatch: `{"price":500}`,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Name: "name-test", OwnerId: intPtr(8)}, nil)
+			},
+			expectedError: &PermissionError{Permission: PermUpdateAnyAdverts},
+		},
+		{
+			name:       "Not found",
+			inputPatch: `{"price":500}`,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{}, repository.ErrAdvertNotFound)
+			},
+			expectedError: repository.ErrAdvertNotFound,
+		},
+		{
+			name:       "Patch removes required field",
+			inputPatch: `{"name":null}`,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(stored, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "name",
+				Code:    CodeRequired,
+				Message: `the field "name" is required`,
+			}}},
+		},
+		{
+			name:       "Patch has wrong field type",
+			inputPatch: `{"price":"cheap"}`,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(stored, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "price",
+				Code:    CodeType,
+				Message: `the field "price" must be of type int`,
+				Params:  map[string]interface{}{"type": "int"},
+			}}},
+		},
+		{
+			name:       "Patch is not an object",
+			inputPatch: `"name"`,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(stored, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Code:    CodeMalformed,
+				Message: "merge patch document must be a JSON object",
+			}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockRepository(c)
+			test.mockBehavior(mockRepository)
+
+			service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{})
+
+			resultError := service.PatchAdvert(owner, 1, []byte(test.inputPatch))
+			assert.Equal(t, resultError, test.expectedError)
+		})
+	}
+}
+
+func TestService_mergePatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		inputTarget    string
+		inputPatch     string
+		expectedResult string
+	}{
+		{name: "Replace member", inputTarget: `{"a":"b"}`, inputPatch: `{"a":"c"}`, expectedResult: `{"a":"c"}`},
+		{name: "Add member", inputTarget: `{"a":"b"}`, inputPatch: `{"b":"c"}`, expectedResult: `{"a":"b","b":"c"}`},
+		{name: "Remove member", inputTarget: `{"a":"b","b":"c"}`, inputPatch: `{"a":null}`, expectedResult: `{"b":"c"}`},
+		{name: "Replace array", inputTarget: `{"a":["b"]}`, inputPatch: `{"a":["c","d"]}`, expectedResult: `{"a":["c","d"]}`},
+		{name: "Nested object", inputTarget: `{"a":{"b":"c"}}`, inputPatch: `{"a":{"b":"d","c":null}}`, expectedResult: `{"a":{"b":"d"}}`},
+		{name: "Non-object target", inputTarget: `["c"]`, inputPatch: `{"a":"b"}`, expectedResult: `{"a":"b"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var target, patch interface{}
+			_ = json.Unmarshal([]byte(test.inputTarget), &target)
+			_ = json.Unmarshal([]byte(test.inputPatch), &patch)
+
+			result, _ := json.Marshal(mergePatch(target, patch))
+			assert.Equal(t, string(result), test.expectedResult)
+		})
+	}
+}
+
+func TestService_GetAdvertList(t *testing.T) {
+	codec := newCursorCodec("secret")
+	noFilters := filterHash(model.ListQuery{})
+	near := &model.GeoPoint{Lat: 55.75, Lon: 37.62}
+	nearFilters := filterHash(model.ListQuery{Near: near})
+	intPtr := func(i int) *int { return &i }
+	boolPtr := func(b bool) *bool { return &b }
+	adverts := func(from, to int) []model.Advert {
+		list := []model.Advert{}
+		for id := from; id <= to; id++ {
+			list = append(list, model.Advert{Id: id, Price: id * 100})
+		}
+		return list
+	}
+
+	type mockBehaviorType func(*mock.MockRepository)
+	tests := []struct {
+		name           string
+		inputQuery     model.ListQuery
+		inputPrincipal model.Principal
+		mockBehavior   mockBehaviorType
+		expectedIds    []int
+		expectedTotal  int
+		expectedNext   *model.ListCursor
+		expectedPrev   *model.ListCursor
+		expectedError  error
+	}{
+		{
+			name:       "First page",
+			inputQuery: model.ListQuery{Page: 1},
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertList(gomock.Any(), model.ListPage{Offset: 0, Limit: 11, OrderField: "createdat", OrderDirect: "desc", Cursor: nil}).Return(adverts(1, 11), nil)
+				r.EXPECT().CountAdverts(gomock.Any(), "exact").Return(25, nil)
+			},
+			expectedIds:   []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			expectedTotal: 25,
+			expectedNext:  &model.ListCursor{OrderBy: "createdat_desc", Id: 10, Filters: noFilters},
+		},
+		{
+			name:       "Last page by number",
+			inputQuery: model.ListQuery{Page: 2, OrderBy: "price_asc"},
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertList(gomock.Any(), model.ListPage{Offset: 10, Limit: 11, OrderField: "price", OrderDirect: "asc", Cursor: nil}).Return(adverts(11, 12), nil)
+			},
+			expectedIds:   []int{11, 12},
+			expectedTotal: 12,
+			expectedPrev:  &model.ListCursor{OrderBy: "price_asc", Value: "1100", Id: 11, Backward: true, Filters: noFilters},
+		},
+		{
+			name:       "Forward cursor",
+			inputQuery: model.ListQuery{Page: 1, Cursor: codec.encode(model.ListCursor{OrderBy: "price_desc", Value: "500", Id: 5, Filters: noFilters}), Count: "estimated"},
+			mockBehavior: func(r *mock.MockRepository) {
+				cursor := &model.ListCursor{OrderBy: "price_desc", Value: "500", Id: 5, Filters: noFilters}
+				r.EXPECT().GetAdvertList(gomock.Any(), model.ListPage{Offset: 0, Limit: 11, OrderField: "price", OrderDirect: "desc", Cursor: cursor}).Return(adverts(6, 16), nil)
+				r.EXPECT().CountAdverts(gomock.Any(), "estimated").Return(40, nil)
+			},
+			expectedIds:   []int{6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+			expectedTotal: 40,
+			expectedNext:  &model.ListCursor{OrderBy: "price_desc", Value: "1500", Id: 15, Filters: noFilters},
+			expectedPrev:  &model.ListCursor{OrderBy: "price_desc", Value: "600", Id: 6, Backward: true, Filters: noFilters},
+		},
+		{
+			name:       "Backward cursor to the first page",
+			inputQuery: model.ListQuery{Page: 1, Cursor: codec.encode(model.ListCursor{OrderBy: "price_desc", Value: "400", Id: 4, Backward: true, Filters: noFilters})},
+			mockBehavior: func(r *mock.MockRepository) {
+				cursor := &model.ListCursor{OrderBy: "price_desc", Value: "400", Id: 4, Backward: true, Filters: noFilters}
+				r.EXPECT().GetAdvertList(gomock.Any(), model.ListPage{Offset: 0, Limit: 11, OrderField: "price", OrderDirect: "desc", Cursor: cursor}).Return([]model.Advert{{Id: 3, Price: 300}, {Id: 2, Price: 200}}, nil)
+				r.EXPECT().CountAdverts(gomock.Any(), "exact").Return(12, nil)
+			},
+			expectedIds:   []int{2, 3},
+			expectedTotal: 12,
+			expectedNext:  &model.ListCursor{OrderBy: "price_desc", Value: "300", Id: 3, Filters: noFilters},
+		},
+		{
+			name:       "Estimate below the adverts seen",
+			inputQuery: model.ListQuery{Page: 2, Count: "estimated"},
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertList(gomock.Any(), model.ListPage{Offset: 10, Limit: 11, OrderField: "createdat", OrderDirect: "desc", Cursor: nil}).Return(adverts(11, 21), nil)
+				r.EXPECT().CountAdverts(gomock.Any(), "estimated").Return(15, nil)
+			},
+			expectedIds:   []int{11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+			expectedTotal: 21,
+			expectedNext:  &model.ListCursor{OrderBy: "createdat_desc", Id: 20, Filters: noFilters},
+			expectedPrev:  &model.ListCursor{OrderBy: "createdat_desc", Id: 11, Backward: true, Filters: noFilters},
+		},
+		{
+			name:          "Invalid count",
+			inputQuery:    model.ListQuery{Page: 1, Count: "approximate"},
+			mockBehavior:  func(r *mock.MockRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{{Field: "count", Code: CodeInvalid, Message: "the count must be exact or estimated"}}},
+		},
+		{
+			name:       "Filters",
+			inputQuery: model.ListQuery{Page: 1, PageSize: 500, PriceMin: intPtr(100), HasPictures: boolPtr(true), Q: "bike"},
+			mockBehavior: func(r *mock.MockRepository) {
+				query := model.ListQuery{Page: 1, PageSize: 500, Count: "exact", PriceMin: intPtr(100), HasPictures: boolPtr(true), Q: "bike"}
+				r.EXPECT().GetAdvertList(query, model.ListPage{Limit: 101, OrderField: "createdat", OrderDirect: "desc"}).Return(adverts(1, 3), nil)
+			},
+			expectedIds:   []int{1, 2, 3},
+			expectedTotal: 3,
+		},
+		{
+			name:          "Reversed price range",
+			inputQuery:    model.ListQuery{Page: 1, PriceMin: intPtr(500), PriceMax: intPtr(100)},
+			mockBehavior:  func(r *mock.MockRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{{Field: "price_max", Code: CodeInvalid, Message: `the field "price_max" must not be less than "price_min"`}}},
+		},
+		{
+			name:          "Invalid cursor",
+			inputQuery:    model.ListQuery{Page: 1, Cursor: "forged"},
+			mockBehavior:  func(r *mock.MockRepository) {},
+			expectedError: cursorError("the cursor is invalid"),
+		},
+		{
+			name:          "Cursor of another order",
+			inputQuery:    model.ListQuery{Page: 1, Cursor: codec.encode(model.ListCursor{OrderBy: "price_desc", Value: "500", Id: 5, Filters: noFilters}), OrderBy: "createdat_desc"},
+			mockBehavior:  func(r *mock.MockRepository) {},
+			expectedError: cursorMismatchError("the cursor was issued for order_by price_desc"),
+		},
+		{
+			name:       "Distance cursor",
+			inputQuery: model.ListQuery{Page: 1, Near: near, Cursor: codec.encode(model.ListCursor{OrderBy: "distance_asc", Value: "1.5", Id: 5, Filters: nearFilters})},
+			mockBehavior: func(r *mock.MockRepository) {
+				cursor := &model.ListCursor{OrderBy: "distance_asc", Value: "1.5", Id: 5, Filters: nearFilters}
+				r.EXPECT().GetAdvertList(gomock.Any(), model.ListPage{Limit: 11, OrderField: "distance", OrderDirect: "asc", Cursor: cursor}).Return(adverts(6, 7), nil)
+				r.EXPECT().CountAdverts(gomock.Any(), "exact").Return(7, nil)
+			},
+			expectedIds:   []int{6, 7},
+			expectedTotal: 7,
+			expectedPrev:  &model.ListCursor{OrderBy: "distance_asc", Id: 6, Backward: true, Filters: nearFilters},
+		},
+		{
+			name:          "Cursor of another point",
+			inputQuery:    model.ListQuery{Page: 1, Near: &model.GeoPoint{Lat: 59.94, Lon: 30.31}, Cursor: codec.encode(model.ListCursor{OrderBy: "distance_asc", Value: "1.5", Id: 5, Filters: nearFilters})},
+			mockBehavior:  func(r *mock.MockRepository) {},
+			expectedError: cursorMismatchError("the cursor was issued for other filters"),
+		},
+		{
+			name:          "Cursor of other filters",
+			inputQuery:    model.ListQuery{Page: 1, PriceMin: intPtr(100), Cursor: codec.encode(model.ListCursor{OrderBy: "price_desc", Value: "500", Id: 5, Filters: noFilters})},
+			mockBehavior:  func(r *mock.MockRepository) {},
+			expectedError: cursorMismatchError("the cursor was issued for other filters"),
+		},
+		{
+			name:          "Archived by anonymous",
+			inputQuery:    model.ListQuery{Page: 1, IncludeArchived: true},
+			mockBehavior:  func(r *mock.MockRepository) {},
+			expectedError: ErrAuthRequired,
+		},
+		{
+			name:           "Archived by seller",
+			inputQuery:     model.ListQuery{Page: 1, IncludeArchived: true},
+			inputPrincipal: owner,
+			mockBehavior:   func(r *mock.MockRepository) {},
+			expectedError:  &PermissionError{Permission: PermModerateAdverts},
+		},
+		{
+			name:           "Archived by moderator",
+			inputQuery:     model.ListQuery{Page: 1, IncludeArchived: true},
+			inputPrincipal: moderator,
+			mockBehavior: func(r *mock.MockRepository) {
+				query := model.ListQuery{Page: 1, Count: "exact", IncludeArchived: true}
+				r.EXPECT().GetAdvertList(query, model.ListPage{Limit: 11, OrderField: "createdat", OrderDirect: "desc"}).Return(adverts(1, 2), nil)
+			},
+			expectedIds:   []int{1, 2},
+			expectedTotal: 2,
+		},
+		{
+			name:       "Empty",
+			inputQuery: model.ListQuery{Page: 5},
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertList(gomock.Any(), model.ListPage{Offset: 40, Limit: 11, OrderField: "createdat", OrderDirect: "desc", Cursor: nil}).Return(nil, nil)
+				r.EXPECT().CountAdverts(gomock.Any(), "exact").Return(12, nil)
+			},
+			expectedIds:   []int{},
+			expectedTotal: 12,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockRepository(c)
+			test.mockBehavior(mockRepository)
+
+			service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{CursorSecret: "secret"})
+			list, err := service.GetAdvertList(test.inputPrincipal, test.inputQuery)
+			assert.Equal(t, err, test.expectedError)
+			if err != nil {
+				return
+			}
+
+			ids := []int{}
+			for _, advert := range list.Items {
+				ids = append(ids, advert.Id)
+			}
+			assert.Equal(t, ids, test.expectedIds)
+			assert.Equal(t, list.Total, test.expectedTotal)
+			assert.Equal(t, list.TotalPages, (test.expectedTotal+9)/10)
+			assert.Equal(t, decodeOrNil(codec, list.NextCursor), test.expectedNext)
+			assert.Equal(t, decodeOrNil(codec, list.PrevCursor), test.expectedPrev)
+		})
+	}
+}
+
+func decodeOrNil(codec cursorCodec, token string) *model.ListCursor {
+	if token == "" {
+		return nil
+	}
+	cursor, err := codec.decode(token)
+	if err != nil {
+		return nil
+	}
+	return &cursor
+}
+
+func TestService_GetAdvertById(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockRepository.EXPECT().GetAdvertById(1).Return(model.Advert{Name: "name-test", Status: model.StatusActive, OwnerId: intPtr(8)}, nil)
+	mockRepository.EXPECT().GetAdvertById(2).Return(model.Advert{Name: "name-test", Status: model.StatusPending, OwnerId: intPtr(7)}, nil).Times(4)
+
+	service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{})
+
+	advert, err := service.GetAdvertById(model.Principal{}, 1, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, advert.Name, "name-test")
+	// the adverts not active yet are hidden from everyone but the owner and
+	// the moderators
+	_, err = service.GetAdvertById(model.Principal{}, 2, nil)
+	assert.Equal(t, err, repository.ErrAdvertNotFound)
+	_, err = service.GetAdvertById(model.Principal{UserId: 8, Role: model.RoleSeller}, 2, nil)
+	assert.Equal(t, err, repository.ErrAdvertNotFound)
+	advert, err = service.GetAdvertById(owner, 2, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, advert.Status, model.StatusPending)
+	_, err = service.GetAdvertById(moderator, 2, nil)
+	assert.Equal(t, err, nil)
+}
+
+func TestService_DeleteAdvert(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockRepository.EXPECT().GetAdvertOwner(1).Return(nil, repository.ErrAdvertNotFound)
+	mockRepository.EXPECT().GetAdvertOwner(2).Return(intPtr(7), nil)
+	mockRepository.EXPECT().DeleteAdvert(2).Return(nil)
+	mockRepository.EXPECT().GetAdvertOwner(3).Return(intPtr(8), nil).Times(2)
+	mockRepository.EXPECT().DeleteAdvert(3).Return(nil)
+	mockIndex := mock.NewMockSearchIndex(c)
+	mockIndex.EXPECT().Remove(2).Return(nil)
+	mockIndex.EXPECT().Remove(3).Return(nil)
+
+	service := NewAdvertService(mockRepository, existingCategories(c), mockIndex, nil, nil, LinkPolicy{}, ListConfig{})
+
+	assert.Equal(t, service.DeleteAdvert(owner, 1), repository.ErrAdvertNotFound)
+	assert.Equal(t, service.DeleteAdvert(owner, 2), nil)
+	assert.Equal(t, service.DeleteAdvert(owner, 3), &PermissionError{Permission: PermDeleteAnyAdverts})
+	// the owner is not loaded for the admin
+	assert.Equal(t, service.DeleteAdvert(admin, 3), nil)
+	// the moderators block the adverts instead of deleting them
+	assert.Equal(t, service.DeleteAdvert(model.Principal{UserId: 9, Role: model.RoleModerator}, 3),
+		&PermissionError{Permission: PermDeleteAnyAdverts})
+}
+
+func TestService_ArchiveRestoreAdvert(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockRepository.EXPECT().GetAdvertOwner(1).Return(intPtr(7), nil)
+	mockRepository.EXPECT().ArchiveAdvert(1).Return(nil)
+	mockRepository.EXPECT().RestoreAdvert(1).Return(repository.ErrAdvertNotFound)
+	mockIndex := mock.NewMockSearchIndex(c)
+	mockIndex.EXPECT().Remove(1).Return(nil)
+
+	service := NewAdvertService(mockRepository, existingCategories(c), mockIndex, nil, nil, LinkPolicy{}, ListConfig{})
+
+	assert.Equal(t, service.ArchiveAdvert(owner, 1), nil)
+	assert.Equal(t, service.RestoreAdvert(owner, 1), &PermissionError{Permission: PermRestoreAdverts})
+	assert.Equal(t, service.RestoreAdvert(admin, 1), repository.ErrAdvertNotFound)
+}
+
+func TestService_TransitionAdvert(t *testing.T) {
+	type mockBehaviortype func(*mock.MockRepository)
+	tests := []struct {
+		name           string
+		inputStatus    model.AdvertStatus
+		inputPrincipal model.Principal
+		mockBehavior   mockBehaviortype
+		expectedError  error
+	}{
+		{
+			name:        "OK",
+			inputStatus: model.StatusPending,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusDraft, OwnerId: intPtr(7)}, nil)
+				r.EXPECT().UpdateAdvertStatus(1, model.StatusDraft, model.StatusPending).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:           "OK by moderator",
+			inputStatus:    model.StatusBlocked,
+			inputPrincipal: moderator,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusActive, OwnerId: intPtr(7)}, nil)
+				r.EXPECT().UpdateAdvertStatus(1, model.StatusActive, model.StatusBlocked).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:        "Illegal transition",
+			inputStatus: model.StatusActive,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusDraft, OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: ErrTransitionNotAllowed,
+		},
+		{
+			name:        "Moderator transition",
+			inputStatus: model.StatusDraft,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusBlocked, OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: &PermissionError{Permission: PermModerateAdverts},
+		},
+		{
+			// the adverts are approved through the moderation queue only
+			name:           "Approval by moderator",
+			inputStatus:    model.StatusActive,
+			inputPrincipal: moderator,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusPending, OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: ErrTransitionNotAllowed,
+		},
+		{
+			name:        "Concurrent transition",
+			inputStatus: model.StatusPending,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusDraft, OwnerId: intPtr(7)}, nil)
+				r.EXPECT().UpdateAdvertStatus(1, model.StatusDraft, model.StatusPending).Return(repository.ErrAdvertNotFound)
+			},
+			expectedError: ErrTransitionNotAllowed,
+		},
+		{
+			name:        "Not found",
+			inputStatus: model.StatusPending,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{}, repository.ErrAdvertNotFound)
+			},
+			expectedError: repository.ErrAdvertNotFound,
+		},
+		{
+			name:        "Not owner",
+			inputStatus: model.StatusPending,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusDraft}, nil)
+			},
+			expectedError: &PermissionError{Permission: PermUpdateAnyAdverts},
+		},
+		{
+			name:           "Moderator of an advert without owner",
+			inputStatus:    model.StatusBlocked,
+			inputPrincipal: moderator,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusPending}, nil)
+				r.EXPECT().UpdateAdvertStatus(1, model.StatusPending, model.StatusBlocked).Return(nil)
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockRepository(c)
+			test.mockBehavior(mockRepository)
+			mockIndex := mock.NewMockSearchIndex(c)
+			if test.expectedError == nil {
+				mockIndex.EXPECT().Update(1).Return(nil)
+			}
+
+			service := NewAdvertService(mockRepository, existingCategories(c), mockIndex, nil, nil, LinkPolicy{}, ListConfig{})
+
+			principal := owner
+			if test.inputPrincipal.Role != "" {
+				principal = test.inputPrincipal
+			}
+			resultError := service.TransitionAdvert(principal, 1, test.inputStatus)
+			if test.expectedError == nil {
+				assert.Equal(t, resultError, nil)
+			} else {
+				assert.Equal(t, errors.Is(resultError, test.expectedError), true)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// existingCategories returns a category repository where every category
+// exists.
+func existingCategories(c *gomock.Controller) *mock.MockCategoryRepository {
+	categories := mock.NewMockCategoryRepository(c)
+	categories.EXPECT().GetCategory(gomock.Any()).DoAndReturn(func(categoryId int) (model.Category, error) {
+		return model.Category{Id: categoryId, Name: "category-test"}, nil
+	}).AnyTimes()
+	return categories
+}
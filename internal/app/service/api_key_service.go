@@ -0,0 +1,170 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+const (
+	// apiKeyPrefix starts every key, so a leaked key is easy to recognize
+	apiKeyPrefix = "ak_"
+	// apiKeyShownLength is the length of the start of a key shown in the
+	// list of the keys
+	apiKeyShownLength = len(apiKeyPrefix) + 8
+	maxAPIKeyName     = 100
+)
+
+var (
+	ErrInvalidAPIKey = apperror.New(apperror.ErrUnauthorized, "invalid or revoked api key")
+	ErrQuotaExceeded = apperror.New(apperror.ErrTooManyRequests, "daily quota of the api key is exceeded")
+)
+
+type APIKeyService struct {
+	repo  repository.APIKeyRepository
+	users repository.UserRepository
+	now   func() time.Time
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, users repository.UserRepository) *APIKeyService {
+	return &APIKeyService{repo: repo, users: users, now: time.Now}
+}
+
+// IssueAPIKey creates a key with the name, owner, scopes and daily quota of
+// the input. The returned key carries the key itself, it is not shown again.
+func (s *APIKeyService) IssueAPIKey(input model.APIKey) (model.APIKey, error) {
+	key := model.APIKey{
+		Name:       strings.TrimSpace(input.Name),
+		OwnerId:    input.OwnerId,
+		Scopes:     uniqueScopes(input.Scopes),
+		DailyQuota: input.DailyQuota,
+	}
+	if err := s.validateAPIKey(key); err != nil {
+		return model.APIKey{}, err
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return model.APIKey{}, err
+	}
+	key.Key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+	key.Prefix = key.Key[:apiKeyShownLength]
+	key.KeyHash = hashAPIKey(key.Key)
+
+	id, err := s.repo.CreateAPIKey(key)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	key.Id = id
+	return key, nil
+}
+
+// GetAPIKeys returns all the keys with the requests made today (UTC).
+func (s *APIKeyService) GetAPIKeys() ([]model.APIKey, error) {
+	return s.repo.GetAPIKeys(s.now().UTC())
+}
+
+func (s *APIKeyService) RevokeAPIKey(keyId int) error {
+	return s.repo.RevokeAPIKey(keyId)
+}
+
+// AuthenticateAPIKey returns the principal of the key: the owner of the key
+// with the role of the owner limited to the scopes of the key. The request
+// is counted and refused once the daily quota is used up.
+func (s *APIKeyService) AuthenticateAPIKey(secret string) (model.Principal, error) {
+	key, err := s.repo.GetAPIKeyByHash(hashAPIKey(secret))
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return model.Principal{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return model.Principal{}, err
+	}
+
+	requests, err := s.repo.UseAPIKey(key.Id, s.now())
+	if err != nil {
+		return model.Principal{}, err
+	}
+	if key.DailyQuota > 0 && requests > key.DailyQuota {
+		return model.Principal{}, ErrQuotaExceeded
+	}
+
+	principal := model.Principal{KeyId: key.Id, Role: key.OwnerRole, Scopes: key.Scopes}
+	if key.OwnerId != nil {
+		principal.UserId = *key.OwnerId
+	}
+	return principal, nil
+}
+
+// validateAPIKey checks the key before it is issued. A key changing adverts
+// needs an owner, the adverts it creates belong to the owner. A key with the
+// admin scope needs an admin owner, the scope adds nothing to the role.
+func (s *APIKeyService) validateAPIKey(key model.APIKey) error {
+	errs := &ValidationError{}
+	switch {
+	case key.Name == "":
+		errs.Add("name", CodeRequired, `the field "name" is required`, nil)
+	case len(key.Name) > maxAPIKeyName:
+		errs.Add("name", CodeMaxLength, fmt.Sprintf(`length of the field "name" should not exceed %d`, maxAPIKeyName),
+			map[string]interface{}{"max": maxAPIKeyName})
+	}
+
+	if len(key.Scopes) == 0 {
+		errs.Add("scopes", CodeRequired, `the field "scopes" is required`, nil)
+	}
+	for _, scope := range key.Scopes {
+		if !scope.Valid() {
+			errs.Add("scopes", CodeInvalid, fmt.Sprintf("unknown scope %q", scope),
+				map[string]interface{}{"allowed": []model.Scope{model.ScopeAdvertsRead, model.ScopeAdvertsWrite, model.ScopeAdmin}})
+		}
+	}
+
+	if key.DailyQuota < 0 {
+		errs.Add("daily-quota", CodeMinValue, `the field "daily-quota" should not be negative`, map[string]interface{}{"min": 0})
+	}
+
+	if key.OwnerId == nil {
+		for _, scope := range []model.Scope{model.ScopeAdvertsWrite, model.ScopeAdmin} {
+			if key.Scopes.Contains(scope) {
+				errs.Add("owner-id", CodeRequired, fmt.Sprintf(`the field "owner-id" is required for the scope %q`, scope), nil)
+				break
+			}
+		}
+	} else {
+		owner, err := s.users.GetUserById(*key.OwnerId)
+		switch {
+		case errors.Is(err, repository.ErrUserNotFound):
+			errs.Add("owner-id", CodeInvalid, "the owner does not exist", nil)
+		case err != nil:
+			return err
+		case key.Scopes.Contains(model.ScopeAdmin) && owner.Role != model.RoleAdmin:
+			errs.Add("scopes", CodeInvalid, `the scope "admin" is allowed for an owner with the role "admin" only`, nil)
+		}
+	}
+	return errs.Err()
+}
+
+func uniqueScopes(scopes model.Scopes) model.Scopes {
+	var unique model.Scopes
+	for _, scope := range scopes {
+		if !unique.Contains(scope) {
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
+
+// hashAPIKey is the SHA-256 of the key, the keys are random and long enough
+// not to need a slow hash.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+func TestAPIKeyService_IssueAPIKey(t *testing.T) {
+	type mockBehaviortype func(*mock.MockAPIKeyRepository, *mock.MockUserRepository)
+	tests := []struct {
+		name          string
+		input         model.APIKey
+		mockBehavior  mockBehaviortype
+		expectedError error
+	}{
+		{
+			name:  "OK",
+			input: model.APIKey{Name: " feed ", OwnerId: intPtr(7), Scopes: model.Scopes{model.ScopeAdvertsWrite, model.ScopeAdvertsRead, model.ScopeAdvertsWrite}, DailyQuota: 1000},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {
+				u.EXPECT().GetUserById(7).Return(model.User{Id: 7}, nil)
+				r.EXPECT().CreateAPIKey(gomock.Any()).DoAndReturn(func(key model.APIKey) (int, error) {
+					assert.Equal(t, key.Name, "feed")
+					assert.Equal(t, key.Scopes, model.Scopes{model.ScopeAdvertsWrite, model.ScopeAdvertsRead})
+					assert.Equal(t, key.KeyHash, hashAPIKey(key.Key))
+					assert.Equal(t, strings.HasPrefix(key.Key, key.Prefix), true)
+					return 3, nil
+				})
+			},
+		},
+		{
+			name:  "Read only key without owner",
+			input: model.APIKey{Name: "search partner", Scopes: model.Scopes{model.ScopeAdvertsRead}},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {
+				r.EXPECT().CreateAPIKey(gomock.Any()).Return(4, nil)
+			},
+		},
+		{
+			name:         "Writing key without owner",
+			input:        model.APIKey{Name: "feed", Scopes: model.Scopes{model.ScopeAdvertsWrite}},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "owner-id", Code: CodeRequired, Message: `the field "owner-id" is required for the scope "adverts:write"`},
+			}},
+		},
+		{
+			name:  "Unknown owner",
+			input: model.APIKey{Name: "feed", OwnerId: intPtr(8), Scopes: model.Scopes{model.ScopeAdvertsWrite}},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {
+				u.EXPECT().GetUserById(8).Return(model.User{}, repository.ErrUserNotFound)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "owner-id", Code: CodeInvalid, Message: "the owner does not exist"},
+			}},
+		},
+		{
+			name:  "Admin key",
+			input: model.APIKey{Name: "backoffice", OwnerId: intPtr(1), Scopes: model.Scopes{model.ScopeAdmin}},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {
+				u.EXPECT().GetUserById(1).Return(model.User{Id: 1, Role: model.RoleAdmin}, nil)
+				r.EXPECT().CreateAPIKey(gomock.Any()).Return(5, nil)
+			},
+		},
+		{
+			name:  "Admin key of a seller",
+			input: model.APIKey{Name: "backoffice", OwnerId: intPtr(7), Scopes: model.Scopes{model.ScopeAdmin}},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {
+				u.EXPECT().GetUserById(7).Return(model.User{Id: 7, Role: model.RoleSeller}, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "scopes", Code: CodeInvalid, Message: `the scope "admin" is allowed for an owner with the role "admin" only`},
+			}},
+		},
+		{
+			name:         "Admin key without owner",
+			input:        model.APIKey{Name: "backoffice", Scopes: model.Scopes{model.ScopeAdmin}},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "owner-id", Code: CodeRequired, Message: `the field "owner-id" is required for the scope "admin"`},
+			}},
+		},
+		{
+			name:         "Invalid fields",
+			input:        model.APIKey{Scopes: model.Scopes{"adverts:delete"}, DailyQuota: -1},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "name", Code: CodeRequired, Message: `the field "name" is required`},
+				{Field: "scopes", Code: CodeInvalid, Message: `unknown scope "adverts:delete"`,
+					Params: map[string]interface{}{"allowed": []model.Scope{model.ScopeAdvertsRead, model.ScopeAdvertsWrite, model.ScopeAdmin}}},
+				{Field: "daily-quota", Code: CodeMinValue, Message: `the field "daily-quota" should not be negative`,
+					Params: map[string]interface{}{"min": 0}},
+			}},
+		},
+		{
+			name:         "No scopes",
+			input:        model.APIKey{Name: "feed"},
+			mockBehavior: func(r *mock.MockAPIKeyRepository, u *mock.MockUserRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "scopes", Code: CodeRequired, Message: `the field "scopes" is required`},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockAPIKeyRepository(c)
+			mockUsers := mock.NewMockUserRepository(c)
+			test.mockBehavior(mockRepository, mockUsers)
+
+			service := NewAPIKeyService(mockRepository, mockUsers)
+			key, err := service.IssueAPIKey(test.input)
+			assert.Equal(t, err, test.expectedError)
+			if test.expectedError == nil {
+				assert.Equal(t, strings.HasPrefix(key.Key, apiKeyPrefix), true)
+				assert.Equal(t, len(key.Prefix), apiKeyShownLength)
+				assert.NotEqual(t, key.Id, 0)
+			}
+		})
+	}
+}
+
+func TestAPIKeyService_AuthenticateAPIKey(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockRepository := mock.NewMockAPIKeyRepository(c)
+	feed := model.APIKey{Id: 3, OwnerId: intPtr(7), OwnerRole: model.RoleSeller, Scopes: model.Scopes{model.ScopeAdvertsWrite}, DailyQuota: 2}
+	mockRepository.EXPECT().GetAPIKeyByHash(hashAPIKey("ak_feed")).Return(feed, nil).Times(3)
+	gomock.InOrder(
+		mockRepository.EXPECT().UseAPIKey(3, now).Return(1, nil),
+		mockRepository.EXPECT().UseAPIKey(3, now).Return(2, nil),
+		mockRepository.EXPECT().UseAPIKey(3, now).Return(3, nil),
+	)
+	admin := model.APIKey{Id: 4, OwnerId: intPtr(1), OwnerRole: model.RoleAdmin, Scopes: model.Scopes{model.ScopeAdmin}}
+	mockRepository.EXPECT().GetAPIKeyByHash(hashAPIKey("ak_admin")).Return(admin, nil)
+	mockRepository.EXPECT().UseAPIKey(4, now).Return(100, nil)
+	// issued before the owner was demoted, the scope does not restore the role
+	demoted := model.APIKey{Id: 5, OwnerId: intPtr(7), OwnerRole: model.RoleSeller, Scopes: model.Scopes{model.ScopeAdmin}}
+	mockRepository.EXPECT().GetAPIKeyByHash(hashAPIKey("ak_demoted")).Return(demoted, nil)
+	mockRepository.EXPECT().UseAPIKey(5, now).Return(1, nil)
+	mockRepository.EXPECT().GetAPIKeyByHash(hashAPIKey("ak_revoked")).Return(model.APIKey{}, repository.ErrAPIKeyNotFound)
+
+	service := NewAPIKeyService(mockRepository, nil)
+	service.now = func() time.Time { return now }
+
+	// the quota of two requests a day is used up by the third one
+	for i := 0; i < 2; i++ {
+		principal, err := service.AuthenticateAPIKey("ak_feed")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, principal, model.Principal{UserId: 7, Role: model.RoleSeller, KeyId: 3, Scopes: model.Scopes{model.ScopeAdvertsWrite}})
+	}
+	_, err := service.AuthenticateAPIKey("ak_feed")
+	assert.Equal(t, err, ErrQuotaExceeded)
+
+	// the admin key has no quota
+	principal, err := service.AuthenticateAPIKey("ak_admin")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, principal, model.Principal{UserId: 1, Role: model.RoleAdmin, KeyId: 4, Scopes: model.Scopes{model.ScopeAdmin}})
+
+	principal, err = service.AuthenticateAPIKey("ak_demoted")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, principal, model.Principal{UserId: 7, Role: model.RoleSeller, KeyId: 5, Scopes: model.Scopes{model.ScopeAdmin}})
+	assert.Equal(t, can(principal, PermManageUsers), false)
+
+	_, err = service.AuthenticateAPIKey("ak_revoked")
+	assert.Equal(t, err, ErrInvalidAPIKey)
+}
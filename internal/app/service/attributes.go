@@ -0,0 +1,185 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+const (
+	maxAttributeName  = 50
+	maxAttributeValue = 200
+)
+
+// attributeName is the form of attribute names, they end up in query
+// parameters as attr.<name>
+var attributeName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validateSchema checks the attribute schema of a category. The names must
+// not end with the _min and _max suffixes of the range filters.
+func validateSchema(schema model.AttributeSchema, errs *ValidationError) {
+	names := make(map[string]bool, len(schema))
+	for i, spec := range schema {
+		field := fmt.Sprintf("attributes[%d].name", i)
+		switch {
+		case spec.Name == "":
+			errs.Add(field, CodeRequired, fmt.Sprintf(`the field "%s" is required`, field), nil)
+		case len(spec.Name) > maxAttributeName:
+			errs.Add(field, CodeMaxLength, fmt.Sprintf(`length of the field "%s" should not exceed %d`, field, maxAttributeName),
+				map[string]interface{}{"max": maxAttributeName})
+		case !attributeName.MatchString(spec.Name) || strings.HasSuffix(spec.Name, "_min") || strings.HasSuffix(spec.Name, "_max"):
+			errs.Add(field, CodeInvalid, "an attribute name consists of lowercase latin letters, digits and underscores, "+
+				"starts with a letter and does not end with _min or _max", nil)
+		case names[spec.Name]:
+			errs.Add(field, CodeInvalid, fmt.Sprintf("the attribute %q is defined twice", spec.Name), nil)
+		}
+		names[spec.Name] = true
+
+		field = fmt.Sprintf("attributes[%d].type", i)
+		if !spec.Type.IsValid() {
+			errs.Add(field, CodeInvalid, fmt.Sprintf("the attribute type must be one of: %s, %s, %s, %s",
+				model.AttributeInt, model.AttributeNumber, model.AttributeString, model.AttributeBool), nil)
+		}
+
+		field = fmt.Sprintf("attributes[%d].enum", i)
+		if spec.Enum != nil && spec.Type.IsValid() && spec.Type != model.AttributeString {
+			errs.Add(field, CodeInvalid, "only a string attribute can have enum values", nil)
+		}
+		values := make(map[string]bool, len(spec.Enum))
+		for _, value := range spec.Enum {
+			if value == "" || values[value] {
+				errs.Add(field, CodeInvalid, "the enum values must be unique and not empty", nil)
+				break
+			}
+			values[value] = true
+		}
+
+		if (spec.Min != nil || spec.Max != nil) && spec.Type.IsValid() && !spec.Type.IsNumeric() {
+			errs.Add(fmt.Sprintf("attributes[%d]", i), CodeInvalid, "only a numeric attribute can have min and max", nil)
+		} else if spec.Min != nil && spec.Max != nil && *spec.Min > *spec.Max {
+			errs.Add(fmt.Sprintf("attributes[%d].max", i), CodeInvalid, "the max must not be less than the min", nil)
+		}
+	}
+}
+
+// validateAttributes checks the attributes of an advert against the schema
+// of its category and returns them with the numbers of the integer
+// attributes converted to int64 and of the others to float64. Attributes
+// the schema does not define are rejected.
+func validateAttributes(schema model.AttributeSchema, attributes model.Attributes, errs *ValidationError) model.Attributes {
+	specs := make(map[string]model.AttributeSpec, len(schema))
+	for _, spec := range schema {
+		specs[spec.Name] = spec
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var valid model.Attributes
+	for _, name := range names {
+		value := attributes[name]
+		field := "attributes." + name
+		spec, ok := specs[name]
+		if !ok {
+			errs.Add(field, CodeInvalid, fmt.Sprintf("the category has no attribute %q", name), nil)
+			continue
+		}
+		if value, ok := checkAttribute(field, spec, value, errs); ok {
+			if valid == nil {
+				valid = make(model.Attributes, len(attributes))
+			}
+			valid[name] = value
+		}
+	}
+
+	for _, spec := range schema {
+		if spec.Required && attributes[spec.Name] == nil {
+			field := "attributes." + spec.Name
+			errs.Add(field, CodeRequired, fmt.Sprintf(`the field "%s" is required`, field), nil)
+		}
+	}
+	return valid
+}
+
+// checkAttribute checks one value, a nil value is the same as a missing
+// one and is dropped.
+func checkAttribute(field string, spec model.AttributeSpec, value interface{}, errs *ValidationError) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	switch spec.Type {
+	case model.AttributeString:
+		s, ok := value.(string)
+		if !ok {
+			attributeTypeError(errs, field, spec.Type)
+			return nil, false
+		}
+		if utf8.RuneCountInString(s) > maxAttributeValue {
+			errs.Add(field, CodeMaxLength, fmt.Sprintf(`length of the field "%s" should not exceed %d`, field, maxAttributeValue),
+				map[string]interface{}{"max": maxAttributeValue})
+			return nil, false
+		}
+		if spec.Enum != nil && !contains(spec.Enum, s) {
+			errs.Add(field, CodeInvalid, fmt.Sprintf(`the field "%s" must be one of: %s`, field, strings.Join(spec.Enum, ", ")),
+				map[string]interface{}{"enum": spec.Enum})
+			return nil, false
+		}
+		return s, true
+
+	case model.AttributeBool:
+		b, ok := value.(bool)
+		if !ok {
+			attributeTypeError(errs, field, spec.Type)
+			return nil, false
+		}
+		return b, true
+	}
+
+	n, ok := attributeNumber(value)
+	if !ok || (spec.Type == model.AttributeInt && n != math.Trunc(n)) {
+		attributeTypeError(errs, field, spec.Type)
+		return nil, false
+	}
+	if spec.Min != nil && n < *spec.Min {
+		errs.Add(field, CodeMinValue, fmt.Sprintf(`the field "%s" must not be less than %g`, field, *spec.Min),
+			map[string]interface{}{"min": *spec.Min})
+		return nil, false
+	}
+	if spec.Max != nil && n > *spec.Max {
+		errs.Add(field, CodeMaxValue, fmt.Sprintf(`the field "%s" must not be greater than %g`, field, *spec.Max),
+			map[string]interface{}{"max": *spec.Max})
+		return nil, false
+	}
+	if spec.Type == model.AttributeInt {
+		return int64(n), true
+	}
+	return n, true
+}
+
+// attributeNumber reads a decoded JSON number: float64 from a request body,
+// json.Number from a merge patch.
+func attributeNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func attributeTypeError(errs *ValidationError, field string, typ model.AttributeType) {
+	errs.Add(field, CodeType, fmt.Sprintf(`the field "%s" must be of type %s`, field, typ),
+		map[string]interface{}{"type": string(typ)})
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+func TestService_validateSchema(t *testing.T) {
+	min, max := 1.0, 20.0
+
+	errs := &ValidationError{}
+	validateSchema(model.AttributeSchema{
+		{Name: "rooms", Type: model.AttributeInt, Required: true, Min: &min, Max: &max},
+		{Name: "area", Type: model.AttributeNumber},
+		{Name: "house", Type: model.AttributeString, Enum: []string{"brick", "panel"}},
+		{Name: "balcony", Type: model.AttributeBool},
+	}, errs)
+	assert.Equal(t, errs.Err(), nil)
+
+	errs = &ValidationError{}
+	validateSchema(model.AttributeSchema{
+		{Name: "Rooms", Type: model.AttributeInt},
+		{Name: "floor_max", Type: model.AttributeInt},
+		{Name: "area", Type: "float", Min: &max, Max: &min},
+		{Name: "area", Type: model.AttributeBool, Enum: []string{"yes"}, Min: &min},
+		{Name: "house", Type: model.AttributeString, Enum: []string{"brick", "brick"}},
+		{Name: strings.Repeat("a", maxAttributeName+1), Type: model.AttributeInt},
+		{Type: model.AttributeInt, Min: &max, Max: &min},
+	}, errs)
+	nameMessage := "an attribute name consists of lowercase latin letters, digits and underscores, starts with a letter and does not end with _min or _max"
+	assert.Equal(t, errs.Err(), &ValidationError{Errors: []FieldError{
+		{Field: "attributes[0].name", Code: CodeInvalid, Message: nameMessage},
+		{Field: "attributes[1].name", Code: CodeInvalid, Message: nameMessage},
+		{Field: "attributes[2].type", Code: CodeInvalid, Message: "the attribute type must be one of: int, number, string, bool"},
+		{Field: "attributes[2].max", Code: CodeInvalid, Message: "the max must not be less than the min"},
+		{Field: "attributes[3].name", Code: CodeInvalid, Message: `the attribute "area" is defined twice`},
+		{Field: "attributes[3].enum", Code: CodeInvalid, Message: "only a string attribute can have enum values"},
+		{Field: "attributes[3]", Code: CodeInvalid, Message: "only a numeric attribute can have min and max"},
+		{Field: "attributes[4].enum", Code: CodeInvalid, Message: "the enum values must be unique and not empty"},
+		{Field: "attributes[5].name", Code: CodeMaxLength, Message: `length of the field "attributes[5].name" should not exceed 50`, Params: map[string]interface{}{"max": maxAttributeName}},
+		{Field: "attributes[6].name", Code: CodeRequired, Message: `the field "attributes[6].name" is required`},
+		{Field: "attributes[6].max", Code: CodeInvalid, Message: "the max must not be less than the min"},
+	}})
+}
+
+func TestService_validateAttributes(t *testing.T) {
+	min, max := 1.0, 20.0
+	schema := model.AttributeSchema{
+		{Name: "rooms", Type: model.AttributeInt, Required: true, Min: &min, Max: &max},
+		{Name: "area", Type: model.AttributeNumber},
+		{Name: "house", Type: model.AttributeString, Enum: []string{"brick", "panel"}},
+		{Name: "balcony", Type: model.AttributeBool},
+	}
+
+	tests := []struct {
+		name               string
+		inputAttributes    model.Attributes
+		expectedAttributes model.Attributes
+		expectedErrors     []FieldError
+	}{
+		{
+			name:               "Valid",
+			inputAttributes:    model.Attributes{"rooms": 2.0, "area": json.Number("54.5"), "house": "brick", "balcony": true},
+			expectedAttributes: model.Attributes{"rooms": int64(2), "area": 54.5, "house": "brick", "balcony": true},
+		},
+		{
+			name:               "Null is missing",
+			inputAttributes:    model.Attributes{"rooms": json.Number("3"), "area": nil},
+			expectedAttributes: model.Attributes{"rooms": int64(3)},
+		},
+		{
+			name:            "Required",
+			inputAttributes: model.Attributes{"rooms": nil},
+			expectedErrors: []FieldError{
+				{Field: "attributes.rooms", Code: CodeRequired, Message: `the field "attributes.rooms" is required`},
+			},
+		},
+		{
+			name:            "Invalid",
+			inputAttributes: model.Attributes{"rooms": 2.5, "area": "big", "house": "wood", "balcony": "yes", "garage": true},
+			expectedErrors: []FieldError{
+				{Field: "attributes.area", Code: CodeType, Message: `the field "attributes.area" must be of type number`, Params: map[string]interface{}{"type": "number"}},
+				{Field: "attributes.balcony", Code: CodeType, Message: `the field "attributes.balcony" must be of type bool`, Params: map[string]interface{}{"type": "bool"}},
+				{Field: "attributes.garage", Code: CodeInvalid, Message: `the category has no attribute "garage"`},
+				{Field: "attributes.house", Code: CodeInvalid, Message: `the field "attributes.house" must be one of: brick, panel`, Params: map[string]interface{}{"enum": []string{"brick", "panel"}}},
+				{Field: "attributes.rooms", Code: CodeType, Message: `the field "attributes.rooms" must be of type int`, Params: map[string]interface{}{"type": "int"}},
+			},
+		},
+		{
+			name:            "Out of range",
+			inputAttributes: model.Attributes{"rooms": 21.0, "house": strings.Repeat("я", maxAttributeValue+1)},
+			expectedErrors: []FieldError{
+				{Field: "attributes.house", Code: CodeMaxLength, Message: `length of the field "attributes.house" should not exceed 200`, Params: map[string]interface{}{"max": maxAttributeValue}},
+				{Field: "attributes.rooms", Code: CodeMaxValue, Message: `the field "attributes.rooms" must not be greater than 20`, Params: map[string]interface{}{"max": max}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := &ValidationError{}
+			attributes := validateAttributes(schema, test.inputAttributes, errs)
+			assert.Equal(t, errs.Errors, test.expectedErrors)
+			if test.expectedErrors == nil {
+				assert.Equal(t, attributes, test.expectedAttributes)
+			}
+		})
+	}
+}
+
+func TestService_advertAttributes(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockCategories := mock.NewMockCategoryRepository(c)
+	service := NewAdvertService(mockRepository, mockCategories, nil, nil, nil, LinkPolicy{}, ListConfig{})
+	min := 0.0
+	flats := model.Category{Id: 4, Name: "Квартиры", Attributes: model.AttributeSchema{
+		{Name: "rooms", Type: model.AttributeInt, Required: true, Min: &min},
+	}}
+
+	// the numbers are stored normalized
+	advert := model.Advert{Name: "name-test", Description: "desc-test", Price: 1000, CategoryId: 4, Attributes: model.Attributes{"rooms": 2.0}}
+	mockCategories.EXPECT().GetCategory(4).Return(flats, nil).Times(3)
+	mockRepository.EXPECT().CreateAdvert(gomock.Any()).DoAndReturn(func(advert model.Advert) (int, error) {
+		assert.Equal(t, advert.Attributes, model.Attributes{"rooms": int64(2)})
+		return 1, nil
+	})
+	id, _, err := service.CreateAdvert(admin, advert)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, id, 1)
+
+	advert.Attributes = nil
+	err = service.UpdateAdvert(admin, 1, advert)
+	assert.Equal(t, err, &ValidationError{Errors: []FieldError{
+		{Field: "attributes.rooms", Code: CodeRequired, Message: `the field "attributes.rooms" is required`},
+	}})
+
+	// a merge patch decodes the numbers as json.Number
+	mockRepository.EXPECT().GetAdvertById(1).Return(model.Advert{Name: "name-test", Description: "desc-test", Price: 1000, CategoryId: 4,
+		Attributes: model.Attributes{"rooms": 2.0}}, nil)
+	mockRepository.EXPECT().UpdateAdvert(1, gomock.Any()).DoAndReturn(func(_ int, advert model.Advert) (model.Pictures, error) {
+		assert.Equal(t, advert.Attributes, model.Attributes{"rooms": int64(3)})
+		return nil, nil
+	})
+	assert.Equal(t, service.PatchAdvert(admin, 1, []byte(`{"attributes":{"rooms":3}}`)), nil)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+// maxCategoryName is the length of categories.name
+const maxCategoryName = 100
+
+type CategoryService struct {
+	repo repository.CategoryRepository
+}
+
+func NewCategoryService(repo repository.CategoryRepository) *CategoryService {
+	return &CategoryService{repo: repo}
+}
+
+// GetCategoryTree returns the root categories with their descendants, the
+// children of a category are ordered by the name.
+func (s *CategoryService) GetCategoryTree() ([]model.Category, error) {
+	categories, err := s.repo.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(categories, nil), nil
+}
+
+// GetCategory returns the category with its descendants.
+func (s *CategoryService) GetCategory(categoryId int) (model.Category, error) {
+	category, err := s.repo.GetCategory(categoryId)
+	if err != nil {
+		return category, err
+	}
+	categories, err := s.repo.GetCategories()
+	if err != nil {
+		return category, err
+	}
+	category.Children = buildTree(categories, &category.Id)
+	return category, nil
+}
+
+func (s *CategoryService) CreateCategory(category model.Category) (int, error) {
+	category.Name = strings.TrimSpace(category.Name)
+	if err := s.validate(0, category); err != nil {
+		return 0, err
+	}
+	return s.repo.CreateCategory(category)
+}
+
+// UpdateCategory renames the category or moves it to another parent, a
+// category can not be moved into its own subtree.
+func (s *CategoryService) UpdateCategory(categoryId int, category model.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if _, err := s.repo.GetCategory(categoryId); err != nil {
+		return err
+	}
+	if err := s.validate(categoryId, category); err != nil {
+		return err
+	}
+	return s.repo.UpdateCategory(categoryId, category)
+}
+
+// DeleteCategory deletes a leaf category without adverts.
+func (s *CategoryService) DeleteCategory(categoryId int) error {
+	return s.repo.DeleteCategory(categoryId)
+}
+
+// validate checks the name, the attribute schema and the parent of the
+// category, categoryId is 0 for a new category. The adverts already in the
+// category are checked against a changed schema when they are saved next.
+func (s *CategoryService) validate(categoryId int, category model.Category) error {
+	errs := &ValidationError{}
+	if category.Name == "" {
+		errs.Add("name", CodeRequired, `the field "name" is required`, nil)
+	}
+	if utf8.RuneCountInString(category.Name) > maxCategoryName {
+		errs.Add("name", CodeMaxLength, `length of the field "name" should not exceed 100`,
+			map[string]interface{}{"max": maxCategoryName})
+	}
+
+	validateSchema(category.Attributes, errs)
+
+	if category.ParentId != nil {
+		switch err := s.checkParent(categoryId, *category.ParentId); {
+		case errors.Is(err, repository.ErrCategoryNotFound):
+			errs.Add("parent-id", CodeInvalid, "the parent category does not exist", nil)
+		case errors.Is(err, errCategoryCycle):
+			errs.Add("parent-id", CodeInvalid, "a category can not be moved into its own subtree", nil)
+		case err != nil:
+			return err
+		}
+	}
+
+	return errs.Err()
+}
+
+var errCategoryCycle = errors.New("category cycle")
+
+// checkParent makes sure the parent exists and, for an existing category,
+// is not the category itself or one of its descendants.
+func (s *CategoryService) checkParent(categoryId, parentId int) error {
+	if _, err := s.repo.GetCategory(parentId); err != nil {
+		return err
+	}
+	if categoryId == 0 {
+		return nil
+	}
+
+	subtree, err := s.repo.GetSubtree(categoryId)
+	if err != nil {
+		return err
+	}
+	for _, id := range subtree {
+		if id == parentId {
+			return errCategoryCycle
+		}
+	}
+	return nil
+}
+
+// buildTree returns the children of the parent, the roots for a nil parent,
+// with their own children filled in. The order of categories is kept.
+func buildTree(categories []model.Category, parentId *int) []model.Category {
+	children := make(map[int][]model.Category)
+	var roots []model.Category
+	for _, category := range categories {
+		if category.ParentId == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentId] = append(children[*category.ParentId], category)
+		}
+	}
+
+	var fill func(level []model.Category) []model.Category
+	fill = func(level []model.Category) []model.Category {
+		for i := range level {
+			level[i].Children = fill(children[level[i].Id])
+		}
+		return level
+	}
+
+	if parentId != nil {
+		return fill(children[*parentId])
+	}
+	if roots == nil {
+		roots = []model.Category{}
+	}
+	return fill(roots)
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+func testCategories() []model.Category {
+	intPtr := func(i int) *int { return &i }
+	return []model.Category{
+		{Id: 4, ParentId: intPtr(2), Name: "BMX"},
+		{Id: 2, ParentId: intPtr(1), Name: "Велосипеды"},
+		{Id: 5, ParentId: intPtr(2), Name: "Горные"},
+		{Id: 3, Name: "Недвижимость"},
+		{Id: 6, ParentId: intPtr(3), Name: "Квартиры"},
+		{Id: 1, Name: "Транспорт"},
+	}
+}
+
+func TestCategoryService_GetCategoryTree(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockCategoryRepository(c)
+	mockRepository.EXPECT().GetCategories().Return(testCategories(), nil)
+	mockRepository.EXPECT().GetCategories().Return(nil, nil)
+
+	tree, err := NewCategoryService(mockRepository).GetCategoryTree()
+	assert.Equal(t, err, nil)
+	intPtr := func(i int) *int { return &i }
+	assert.Equal(t, tree, []model.Category{
+		{Id: 3, Name: "Недвижимость", Children: []model.Category{
+			{Id: 6, ParentId: intPtr(3), Name: "Квартиры"},
+		}},
+		{Id: 1, Name: "Транспорт", Children: []model.Category{
+			{Id: 2, ParentId: intPtr(1), Name: "Велосипеды", Children: []model.Category{
+				{Id: 4, ParentId: intPtr(2), Name: "BMX"},
+				{Id: 5, ParentId: intPtr(2), Name: "Горные"},
+			}},
+		}},
+	})
+
+	tree, err = NewCategoryService(mockRepository).GetCategoryTree()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, tree, []model.Category{})
+}
+
+func TestCategoryService_GetCategory(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	intPtr := func(i int) *int { return &i }
+	mockRepository := mock.NewMockCategoryRepository(c)
+	mockRepository.EXPECT().GetCategory(2).Return(model.Category{Id: 2, ParentId: intPtr(1), Name: "Велосипеды"}, nil)
+	mockRepository.EXPECT().GetCategories().Return(testCategories(), nil)
+	mockRepository.EXPECT().GetCategory(7).Return(model.Category{}, repository.ErrCategoryNotFound)
+
+	service := NewCategoryService(mockRepository)
+	category, err := service.GetCategory(2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, category, model.Category{Id: 2, ParentId: intPtr(1), Name: "Велосипеды", Children: []model.Category{
+		{Id: 4, ParentId: intPtr(2), Name: "BMX"},
+		{Id: 5, ParentId: intPtr(2), Name: "Горные"},
+	}})
+
+	_, err = service.GetCategory(7)
+	assert.Equal(t, err, repository.ErrCategoryNotFound)
+}
+
+func TestCategoryService_CreateCategory(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	type mockBehaviorType func(*mock.MockCategoryRepository)
+	tests := []struct {
+		name           string
+		inputCategory  model.Category
+		mockBehavior   mockBehaviorType
+		expectedResult int
+		expectedError  error
+	}{
+		{
+			name:          "Root",
+			inputCategory: model.Category{Name: " Транспорт "},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().CreateCategory(model.Category{Name: "Транспорт"}).Return(1, nil)
+			},
+			expectedResult: 1,
+		},
+		{
+			name:          "Child",
+			inputCategory: model.Category{ParentId: intPtr(1), Name: "Велосипеды"},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().GetCategory(1).Return(model.Category{Id: 1, Name: "Транспорт"}, nil)
+				r.EXPECT().CreateCategory(model.Category{ParentId: intPtr(1), Name: "Велосипеды"}).Return(2, nil)
+			},
+			expectedResult: 2,
+		},
+		{
+			name:          "Invalid",
+			inputCategory: model.Category{ParentId: intPtr(7), Name: strings.Repeat("т", 101)},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().GetCategory(7).Return(model.Category{}, repository.ErrCategoryNotFound)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{
+					Field:   "name",
+					Code:    CodeMaxLength,
+					Message: `length of the field "name" should not exceed 100`,
+					Params:  map[string]interface{}{"max": 100},
+				},
+				{Field: "parent-id", Code: CodeInvalid, Message: "the parent category does not exist"},
+			}},
+		},
+		{
+			name:          "Blank name",
+			inputCategory: model.Category{Name: "  "},
+			mockBehavior:  func(r *mock.MockCategoryRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "name", Code: CodeRequired, Message: `the field "name" is required`},
+			}},
+		},
+		{
+			name: "Invalid attributes",
+			inputCategory: model.Category{Name: "Квартиры", Attributes: model.AttributeSchema{
+				{Name: "rooms", Type: model.AttributeInt},
+				{Name: "rooms", Type: model.AttributeString},
+			}},
+			mockBehavior: func(r *mock.MockCategoryRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "attributes[1].name", Code: CodeInvalid, Message: `the attribute "rooms" is defined twice`},
+			}},
+		},
+		{
+			name:          "Duplicate",
+			inputCategory: model.Category{Name: "Транспорт"},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().CreateCategory(model.Category{Name: "Транспорт"}).Return(0, repository.ErrCategoryExists)
+			},
+			expectedError: repository.ErrCategoryExists,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockCategoryRepository(c)
+			test.mockBehavior(mockRepository)
+
+			id, err := NewCategoryService(mockRepository).CreateCategory(test.inputCategory)
+			assert.Equal(t, err, test.expectedError)
+			assert.Equal(t, id, test.expectedResult)
+		})
+	}
+}
+
+func TestCategoryService_UpdateCategory(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	type mockBehaviorType func(*mock.MockCategoryRepository)
+	tests := []struct {
+		name          string
+		inputCategory model.Category
+		mockBehavior  mockBehaviorType
+		expectedError error
+	}{
+		{
+			name:          "Move",
+			inputCategory: model.Category{ParentId: intPtr(3), Name: "Велосипеды"},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().GetCategory(2).Return(model.Category{Id: 2, ParentId: intPtr(1), Name: "Велосипеды"}, nil)
+				r.EXPECT().GetCategory(3).Return(model.Category{Id: 3, Name: "Спорт"}, nil)
+				r.EXPECT().GetSubtree(2).Return([]int{2, 4, 5}, nil)
+				r.EXPECT().UpdateCategory(2, model.Category{ParentId: intPtr(3), Name: "Велосипеды"}).Return(nil)
+			},
+		},
+		{
+			name:          "Make root",
+			inputCategory: model.Category{Name: "Велосипеды"},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().GetCategory(2).Return(model.Category{Id: 2, ParentId: intPtr(1), Name: "Велосипеды"}, nil)
+				r.EXPECT().UpdateCategory(2, model.Category{Name: "Велосипеды"}).Return(nil)
+			},
+		},
+		{
+			name:          "Into own subtree",
+			inputCategory: model.Category{ParentId: intPtr(5), Name: "Велосипеды"},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().GetCategory(2).Return(model.Category{Id: 2, ParentId: intPtr(1), Name: "Велосипеды"}, nil)
+				r.EXPECT().GetCategory(5).Return(model.Category{Id: 5, ParentId: intPtr(2), Name: "Горные"}, nil)
+				r.EXPECT().GetSubtree(2).Return([]int{2, 4, 5}, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "parent-id", Code: CodeInvalid, Message: "a category can not be moved into its own subtree"},
+			}},
+		},
+		{
+			name:          "Into itself",
+			inputCategory: model.Category{ParentId: intPtr(2), Name: "Велосипеды"},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().GetCategory(2).Return(model.Category{Id: 2, ParentId: intPtr(1), Name: "Велосипеды"}, nil).Times(2)
+				r.EXPECT().GetSubtree(2).Return([]int{2, 4, 5}, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "parent-id", Code: CodeInvalid, Message: "a category can not be moved into its own subtree"},
+			}},
+		},
+		{
+			name:          "Not found",
+			inputCategory: model.Category{Name: "Велосипеды"},
+			mockBehavior: func(r *mock.MockCategoryRepository) {
+				r.EXPECT().GetCategory(2).Return(model.Category{}, repository.ErrCategoryNotFound)
+			},
+			expectedError: repository.ErrCategoryNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockCategoryRepository(c)
+			test.mockBehavior(mockRepository)
+
+			err := NewCategoryService(mockRepository).UpdateCategory(2, test.inputCategory)
+			assert.Equal(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestCategoryService_DeleteCategory(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockCategoryRepository(c)
+	mockRepository.EXPECT().DeleteCategory(1).Return(repository.ErrCategoryInUse)
+	mockRepository.EXPECT().DeleteCategory(4).Return(nil)
+
+	service := NewCategoryService(mockRepository)
+	assert.Equal(t, service.DeleteCategory(1), repository.ErrCategoryInUse)
+	assert.Equal(t, service.DeleteCategory(4), nil)
+}
+
+func TestService_advertCategory(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockCategories := mock.NewMockCategoryRepository(c)
+	service := NewAdvertService(mockRepository, mockCategories, nil, nil, nil, LinkPolicy{}, ListConfig{})
+	advert := model.Advert{Name: "name-test", Description: "desc-test", Price: 1000, CategoryId: 7}
+
+	mockCategories.EXPECT().GetCategory(7).Return(model.Category{}, repository.ErrCategoryNotFound)
+	_, _, err := service.CreateAdvert(admin, advert)
+	assert.Equal(t, err, &ValidationError{Errors: []FieldError{
+		{Field: "category-id", Code: CodeInvalid, Message: "category 7 does not exist"},
+	}})
+
+	advert.CategoryId = 0
+	_, _, err = service.CreateAdvert(admin, advert)
+	assert.Equal(t, err, &ValidationError{Errors: []FieldError{
+		{Field: "category-id", Code: CodeRequired, Message: `the field "category-id" is required`},
+	}})
+
+	failure := errors.New("some error")
+	mockCategories.EXPECT().GetCategory(2).Return(model.Category{}, failure)
+	advert.CategoryId = 2
+	assert.Equal(t, service.UpdateAdvert(admin, 1, advert), failure)
+
+	// the list is narrowed to the subtree of the category
+	categoryId := 2
+	mockCategories.EXPECT().GetSubtree(2).Return([]int{2, 4, 5}, nil)
+	mockRepository.EXPECT().GetAdvertList(model.ListQuery{Page: 1, Count: "exact", CategoryId: &categoryId, Categories: []int{2, 4, 5}}, gomock.Any()).
+		Return([]model.Advert{{Id: 1, CategoryId: 4}}, nil)
+	list, err := service.GetAdvertList(model.Principal{}, model.ListQuery{Page: 1, CategoryId: &categoryId})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, list.Total, 1)
+
+	unknown := 7
+	mockCategories.EXPECT().GetSubtree(7).Return(nil, nil)
+	_, err = service.GetAdvertList(model.Principal{}, model.ListQuery{Page: 1, CategoryId: &unknown})
+	assert.Equal(t, err, &ValidationError{Errors: []FieldError{
+		{Field: "category_id", Code: CodeInvalid, Message: "category 7 does not exist"},
+	}})
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursorCodec turns list cursors into opaque tokens. The tokens are signed,
+// so clients can not forge a position, e.g. an id they should not see.
+type cursorCodec struct {
+	secret []byte
+}
+
+func newCursorCodec(secret string) cursorCodec {
+	if secret != "" {
+		return cursorCodec{secret: []byte(secret)}
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		panic(err)
+	}
+	return cursorCodec{secret: random}
+}
+
+func (c cursorCodec) encode(cursor model.ListCursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+func (c cursorCodec) decode(token string) (model.ListCursor, error) {
+	var cursor model.ListCursor
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return cursor, errInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, c.sign(payload)) {
+		return cursor, errInvalidCursor
+	}
+
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return cursor, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+func (c cursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// filterHash is a short hash of the normalized filters of the query, the
+// page, the order and the count mode left out. A position is only valid
+// with the filters it was taken with: with other filters or another point
+// of a distance order the next page would skip or repeat adverts.
+func filterHash(query model.ListQuery) string {
+	filters := query
+	filters.Page, filters.PageSize, filters.Cursor, filters.OrderBy, filters.Count = 0, 0, "", "", ""
+	filters.Categories = nil
+	if len(filters.Attributes) == 0 {
+		filters.Attributes = nil
+	}
+	if filters.CreatedFrom != nil {
+		from := filters.CreatedFrom.UTC()
+		filters.CreatedFrom = &from
+	}
+	if filters.CreatedTo != nil {
+		to := filters.CreatedTo.UTC()
+		filters.CreatedTo = &to
+	}
+
+	payload, _ := json.Marshal(filters)
+	sum := sha256.Sum256(payload)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
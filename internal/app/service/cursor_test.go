@@ -0,0 +1,35 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := newCursorCodec("secret")
+	cursor := model.ListCursor{OrderBy: "price_desc", Value: "1000", Id: 7, Backward: true}
+
+	token := codec.encode(cursor)
+	decoded, err := codec.decode(token)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, decoded, cursor)
+
+	// another secret, a changed payload and garbage are rejected
+	_, err = newCursorCodec("other").decode(token)
+	assert.Equal(t, err, errInvalidCursor)
+
+	forged := codec.encode(model.ListCursor{OrderBy: "price_desc", Value: "1000", Id: 8})
+	_, err = codec.decode(strings.Split(forged, ".")[0] + "." + strings.Split(token, ".")[1])
+	assert.Equal(t, err, errInvalidCursor)
+
+	for _, token := range []string{"", "abc", "a.b.c", "!!.!!"} {
+		_, err = codec.decode(token)
+		assert.Equal(t, err, errInvalidCursor)
+	}
+
+	// without a secret a random one is used
+	assert.NotEqual(t, newCursorCodec("").encode(cursor), newCursorCodec("").encode(cursor))
+}
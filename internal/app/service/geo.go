@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+// maxPlaceName is the length of adverts.city and adverts.region
+const maxPlaceName = 100
+
+// maxRadiusKm is half of the equator, a larger radius selects everything
+const maxRadiusKm = 20000
+
+// validateLocation checks the location of an advert: the coordinates are
+// optional but go together.
+func validateLocation(advert model.Advert, errs *ValidationError) {
+	switch {
+	case advert.Lat != nil && advert.Lon == nil:
+		errs.Add("lon", CodeRequired, `the field "lon" is required with "lat"`, nil)
+	case advert.Lat == nil && advert.Lon != nil:
+		errs.Add("lat", CodeRequired, `the field "lat" is required with "lon"`, nil)
+	}
+	if advert.Lat != nil && !validLat(*advert.Lat) {
+		errs.Add("lat", CodeInvalid, `the field "lat" must be between -90 and 90`, map[string]interface{}{"min": -90, "max": 90})
+	}
+	if advert.Lon != nil && !validLon(*advert.Lon) {
+		errs.Add("lon", CodeInvalid, `the field "lon" must be between -180 and 180`, map[string]interface{}{"min": -180, "max": 180})
+	}
+
+	if utf8.RuneCountInString(advert.City) > maxPlaceName {
+		errs.Add("city", CodeMaxLength, fmt.Sprintf(`length of the field "city" should not exceed %d`, maxPlaceName),
+			map[string]interface{}{"max": maxPlaceName})
+	}
+	if utf8.RuneCountInString(advert.Region) > maxPlaceName {
+		errs.Add("region", CodeMaxLength, fmt.Sprintf(`length of the field "region" should not exceed %d`, maxPlaceName),
+			map[string]interface{}{"max": maxPlaceName})
+	}
+}
+
+// validateGeoQuery checks the point, the radius and the bounding box of
+// the list filters. The radius is measured from the point, so it needs one.
+func validateGeoQuery(query model.ListQuery, errs *ValidationError) {
+	if query.Near != nil && !(validLat(query.Near.Lat) && validLon(query.Near.Lon)) {
+		errs.Add("near", CodeInvalid, `the latitude of the field "near" must be between -90 and 90, the longitude between -180 and 180`, nil)
+	}
+	if query.RadiusKm != nil {
+		if query.Near == nil {
+			errs.Add("near", CodeRequired, `the field "near" is required with "radius_km"`, nil)
+		}
+		if *query.RadiusKm <= 0 || *query.RadiusKm > maxRadiusKm {
+			errs.Add("radius_km", CodeInvalid, fmt.Sprintf(`the field "radius_km" must be greater than 0 and not greater than %d`, maxRadiusKm),
+				map[string]interface{}{"max": maxRadiusKm})
+		}
+	}
+
+	if box := query.Box; box != nil {
+		switch {
+		case !(validLat(box.South) && validLat(box.North) && validLon(box.West) && validLon(box.East)):
+			errs.Add("bbox", CodeInvalid, `the latitudes of the field "bbox" must be between -90 and 90, the longitudes between -180 and 180`, nil)
+		case box.South > box.North:
+			errs.Add("bbox", CodeInvalid, `the south latitude of the field "bbox" must not be greater than the north one`, nil)
+		}
+	}
+}
+
+func validLat(lat float64) bool {
+	return lat >= -90 && lat <= 90
+}
+
+func validLon(lon float64) bool {
+	return lon >= -180 && lon <= 180
+}
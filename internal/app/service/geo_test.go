@@ -0,0 +1,89 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+func TestService_validateLocation(t *testing.T) {
+	floatPtr := func(f float64) *float64 { return &f }
+
+	errs := &ValidationError{}
+	validateLocation(model.Advert{Lat: floatPtr(55.75), Lon: floatPtr(37.62), City: "Москва", Region: "Москва"}, errs)
+	validateLocation(model.Advert{}, errs)
+	assert.Equal(t, errs.Err(), nil)
+
+	validateLocation(model.Advert{Lat: floatPtr(91), City: strings.Repeat("я", maxPlaceName+1)}, errs)
+	validateLocation(model.Advert{Lon: floatPtr(-181)}, errs)
+	assert.Equal(t, errs.Errors, []FieldError{
+		{Field: "lon", Code: CodeRequired, Message: `the field "lon" is required with "lat"`},
+		{Field: "lat", Code: CodeInvalid, Message: `the field "lat" must be between -90 and 90`, Params: map[string]interface{}{"min": -90, "max": 90}},
+		{Field: "city", Code: CodeMaxLength, Message: `length of the field "city" should not exceed 100`, Params: map[string]interface{}{"max": maxPlaceName}},
+		{Field: "lat", Code: CodeRequired, Message: `the field "lat" is required with "lon"`},
+		{Field: "lon", Code: CodeInvalid, Message: `the field "lon" must be between -180 and 180`, Params: map[string]interface{}{"min": -180, "max": 180}},
+	})
+}
+
+func TestService_validateGeoQuery(t *testing.T) {
+	radius := func(r float64) *float64 { return &r }
+
+	errs := &ValidationError{}
+	validateGeoQuery(model.ListQuery{
+		Near:     &model.GeoPoint{Lat: 55.75, Lon: 37.62},
+		RadiusKm: radius(5),
+		Box:      &model.GeoBox{South: 60, West: 170, North: 70, East: -170},
+	}, errs)
+	assert.Equal(t, errs.Err(), nil)
+
+	validateGeoQuery(model.ListQuery{Near: &model.GeoPoint{Lat: 37.62, Lon: 190}, RadiusKm: radius(0)}, errs)
+	validateGeoQuery(model.ListQuery{RadiusKm: radius(5), Box: &model.GeoBox{South: 56, West: 37, North: 55, East: 38}}, errs)
+	validateGeoQuery(model.ListQuery{Box: &model.GeoBox{South: -91, West: 37, North: 55, East: 38}}, errs)
+	assert.Equal(t, errs.Errors, []FieldError{
+		{Field: "near", Code: CodeInvalid, Message: `the latitude of the field "near" must be between -90 and 90, the longitude between -180 and 180`},
+		{Field: "radius_km", Code: CodeInvalid, Message: `the field "radius_km" must be greater than 0 and not greater than 20000`, Params: map[string]interface{}{"max": maxRadiusKm}},
+		{Field: "near", Code: CodeRequired, Message: `the field "near" is required with "radius_km"`},
+		{Field: "bbox", Code: CodeInvalid, Message: `the south latitude of the field "bbox" must not be greater than the north one`},
+		{Field: "bbox", Code: CodeInvalid, Message: `the latitudes of the field "bbox" must be between -90 and 90, the longitudes between -180 and 180`},
+	})
+}
+
+func TestService_listByDistance(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{PageSize: 2})
+
+	_, err := service.GetAdvertList(model.Principal{}, model.ListQuery{Page: 1, OrderBy: "distance_asc"})
+	assert.Equal(t, err, &ValidationError{Errors: []FieldError{
+		{Field: "near", Code: CodeRequired, Message: `the field "near" is required to order by distance`},
+	}})
+
+	// the cursor keeps the distance of the last advert on the page and the
+	// point it was measured from
+	near := &model.GeoPoint{Lat: 55.75, Lon: 37.62}
+	distance := func(d float64) *float64 { return &d }
+	mockRepository.EXPECT().GetAdvertList(gomock.Any(), model.ListPage{Limit: 3, OrderField: "distance", OrderDirect: "asc"}).
+		Return([]model.Advert{{Id: 4, DistanceKm: distance(0.5)}, {Id: 2, DistanceKm: distance(1.25)}, {Id: 7, DistanceKm: distance(3)}}, nil)
+	mockRepository.EXPECT().CountAdverts(gomock.Any(), model.CountExact).Return(3, nil)
+	list, err := service.GetAdvertList(model.Principal{}, model.ListQuery{Page: 1, OrderBy: "distance_asc", Near: near})
+	assert.Equal(t, err, nil)
+
+	cursor, err := service.cursors.decode(list.NextCursor)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cursor, model.ListCursor{OrderBy: "distance_asc", Value: "1.25", Id: 2, Filters: filterHash(model.ListQuery{Near: near})})
+
+	_, err = service.GetAdvertList(model.Principal{}, model.ListQuery{Cursor: list.NextCursor, Near: &model.GeoPoint{Lat: 55.76, Lon: 37.62}})
+	assert.Equal(t, err, cursorMismatchError("the cursor was issued for other filters"))
+
+	// the search has no distance order
+	_, err = service.SearchAdverts(model.Principal{}, model.SearchQuery{ListQuery: model.ListQuery{OrderBy: "distance_asc", Near: near}, Text: "велосипед"})
+	assert.Equal(t, err, &ValidationError{Errors: []FieldError{
+		{Field: "order_by", Code: CodeInvalid, Message: "the search can not be ordered by distance"},
+	}})
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+const DefaultLinkCheckBatchSize = 100
+
+type linkState int
+
+const (
+	linkOK linkState = iota
+	linkBroken
+	// the check failed for a reason that may go away, e.g. the server is
+	// overloaded, the previous state is kept
+	linkUnknown
+)
+
+// errInternalAddress refuses the connections to the addresses of the
+// internal network, the links are given by the advert authors.
+var errInternalAddress = errors.New("the address is not public")
+
+// reservedNetworks are the ranges that do not lead to a public server
+// besides the loopback, link-local, multicast and unspecified addresses
+// net.IP reports itself: the private ranges (net.IP.IsPrivate needs Go
+// 1.17), "this network", carrier-grade NAT, the IETF protocol assignments,
+// benchmarking, documentation, the reserved and broadcast addresses, the
+// IPv4-compatible, NAT64 and discard IPv6 prefixes.
+var reservedNetworks = parseNetworks(
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7",
+	"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4",
+	"192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32",
+	"::/96", "64:ff9b::/96", "64:ff9b:1::/48", "100::/64",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// publicAddress reports whether the address may belong to a public server.
+// An IPv4-mapped IPv6 address is checked as the IPv4 one.
+func publicAddress(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewLinkCheckClient creates the client of the checker. It connects to the
+// public addresses only, the address is checked once the host is resolved,
+// so neither a link nor a redirect reaches the internal network.
+func NewLinkCheckClient(timeout time.Duration) *http.Client {
+	return newLinkCheckClient(timeout, publicAddress)
+}
+
+func newLinkCheckClient(timeout time.Duration, allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return fmt.Errorf("%s: %w", host, errInternalAddress)
+			}
+			return nil
+		},
+	}
+	// no proxy, the dialer has to see the address of the picture server
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     time.Minute,
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// LinkChecker periodically requests the stored picture links and marks the
+// pictures that can not be loaded as broken. A broken main picture is
+// replaced by the next working one.
+type LinkChecker struct {
+	repo      repository.Repository
+	index     SearchIndex
+	client    *http.Client
+	interval  time.Duration
+	batchSize int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewLinkChecker creates the checker, index may be nil. The index is updated
+// when a picture turns broken or working, the main picture may move then.
+func NewLinkChecker(repo repository.Repository, index SearchIndex, client *http.Client, interval time.Duration, batchSize int) *LinkChecker {
+	if batchSize <= 0 {
+		batchSize = DefaultLinkCheckBatchSize
+	}
+	return &LinkChecker{
+		repo:      repo,
+		index:     index,
+		client:    client,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start checks the links every interval until Stop is called.
+func (c *LinkChecker) Start() {
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := c.CheckDue(); err != nil {
+				log.Printf("picture link check failed: %s", err.Error())
+			}
+
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (c *LinkChecker) Stop() {
+	close(c.stop)
+	<-c.done
+}
+
+// CheckDue checks the pictures that were not checked during the last
+// interval and returns how many were checked.
+func (c *LinkChecker) CheckDue() (int, error) {
+	checked := 0
+	for {
+		pictures, err := c.repo.GetPicturesToCheck(c.batchSize, time.Now().Add(-c.interval))
+		if err != nil {
+			return checked, err
+		}
+
+		for _, picture := range pictures {
+			broken := picture.Broken
+			switch c.check(picture.URL) {
+			case linkOK:
+				broken = false
+			case linkBroken:
+				broken = true
+			}
+
+			err := c.repo.SetPictureBroken(picture.Id, broken)
+			if err != nil && !errors.Is(err, repository.ErrPictureNotFound) {
+				return checked, err
+			}
+			if err == nil && broken != picture.Broken {
+				updateIndex(c.index, picture.AdvertId)
+			}
+			checked++
+		}
+
+		if len(pictures) < c.batchSize {
+			return checked, nil
+		}
+		select {
+		case <-c.stop:
+			return checked, nil
+		default:
+		}
+	}
+}
+
+// check requests the link with HEAD, falling back to GET for servers that do
+// not support it. Links that are not http(s) URLs can not be checked and
+// are considered working, the ones the client refuses to connect to keep
+// their state.
+func (c *LinkChecker) check(link string) linkState {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return linkOK
+	}
+
+	status, err := c.request(http.MethodHead, link)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.request(http.MethodGet, link)
+	}
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return linkBroken
+		}
+		return linkUnknown
+	}
+
+	switch {
+	case status < 400:
+		return linkOK
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests, status >= 500:
+		return linkUnknown
+	default:
+		return linkBroken
+	}
+}
+
+func (c *LinkChecker) request(method, link string) (int, error) {
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
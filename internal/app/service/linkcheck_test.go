@@ -0,0 +1,169 @@
+package service
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+func newPictureServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok.jpg", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/moved.jpg", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok.jpg", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/no-head.jpg", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/forbidden.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("/overloaded.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestLinkChecker_check(t *testing.T) {
+	server := newPictureServer()
+	defer server.Close()
+
+	checker := NewLinkChecker(nil, nil, server.Client(), time.Hour, 0)
+
+	tests := []struct {
+		name          string
+		inputURL      string
+		expectedState linkState
+	}{
+		{name: "Ok", inputURL: server.URL + "/ok.jpg", expectedState: linkOK},
+		{name: "Redirect", inputURL: server.URL + "/moved.jpg", expectedState: linkOK},
+		{name: "HEAD not allowed", inputURL: server.URL + "/no-head.jpg", expectedState: linkOK},
+		{name: "Not found", inputURL: server.URL + "/missing.jpg", expectedState: linkBroken},
+		{name: "Forbidden", inputURL: server.URL + "/forbidden.jpg", expectedState: linkBroken},
+		{name: "Server overloaded", inputURL: server.URL + "/overloaded.jpg", expectedState: linkUnknown},
+		{name: "Not an http link", inputURL: "avito/files/ad1", expectedState: linkOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, checker.check(test.inputURL), test.expectedState)
+		})
+	}
+}
+
+func TestLinkChecker_checkInternalAddress(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/to-private.jpg":
+			http.Redirect(w, r, "http://10.0.0.1/ok.jpg", http.StatusFound)
+		case "/to-metadata.jpg":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	checker := NewLinkChecker(nil, nil, NewLinkCheckClient(time.Second), time.Hour, 0)
+	for _, link := range []string{
+		server.URL + "/ok.jpg",
+		"http://localhost:" + server.URL[strings.LastIndex(server.URL, ":")+1:] + "/ok.jpg",
+		"http://10.1.2.3/ok.jpg",
+		"http://172.16.0.1/ok.jpg",
+		"http://192.168.1.1/ok.jpg",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0/ok.jpg",
+		"http://[::1]/ok.jpg",
+		"http://[fe80::1]/ok.jpg",
+	} {
+		assert.Equal(t, checker.check(link), linkUnknown)
+	}
+	assert.Equal(t, requests, 0)
+
+	// the picture server itself is allowed, the redirects to the internal
+	// addresses are not followed
+	allowLoopback := func(ip net.IP) bool { return ip.Equal(net.IPv4(127, 0, 0, 1)) }
+	checker = NewLinkChecker(nil, nil, newLinkCheckClient(time.Second, allowLoopback), time.Hour, 0)
+	assert.Equal(t, checker.check(server.URL+"/ok.jpg"), linkOK)
+	assert.Equal(t, checker.check(server.URL+"/to-private.jpg"), linkUnknown)
+	assert.Equal(t, checker.check(server.URL+"/to-metadata.jpg"), linkUnknown)
+	assert.Equal(t, requests, 3)
+}
+
+func TestPublicAddress(t *testing.T) {
+	assert.Equal(t, publicAddress(net.ParseIP("93.184.216.34")), true)
+	assert.Equal(t, publicAddress(net.ParseIP("2606:2800:220:1::")), true)
+	assert.Equal(t, publicAddress(net.ParseIP("172.32.0.1")), true)
+	assert.Equal(t, publicAddress(net.ParseIP("172.31.255.255")), false)
+	assert.Equal(t, publicAddress(net.ParseIP("::ffff:127.0.0.1")), false)
+	assert.Equal(t, publicAddress(net.ParseIP("fd00::1")), false)
+
+	for _, address := range []string{
+		"100.64.0.1", "198.18.0.1", "192.0.0.8", "240.0.0.1", "255.255.255.255",
+		"224.0.0.1", "239.255.255.250", "ff02::1", "169.254.169.254", "fe80::1",
+		"::ffff:10.0.0.1", "::ffff:100.64.0.1", "::ffff:169.254.169.254", "::127.0.0.1", "64:ff9b::a00:1",
+		"0.0.0.0", "::",
+	} {
+		if publicAddress(net.ParseIP(address)) {
+			t.Errorf("%s is taken for a public address", address)
+		}
+	}
+}
+
+func TestLinkChecker_CheckDue(t *testing.T) {
+	server := newPictureServer()
+	defer server.Close()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockRepository.EXPECT().GetPicturesToCheck(2, gomock.Any()).Return([]model.Picture{
+		{Id: 1, AdvertId: 1, URL: server.URL + "/missing.jpg", IsMain: true},
+		{Id: 2, AdvertId: 1, URL: server.URL + "/ok.jpg", Broken: true},
+	}, nil)
+	mockRepository.EXPECT().SetPictureBroken(1, true).Return(nil)
+	mockRepository.EXPECT().SetPictureBroken(2, false).Return(repository.ErrPictureNotFound)
+	mockRepository.EXPECT().GetPicturesToCheck(2, gomock.Any()).Return([]model.Picture{
+		{Id: 3, AdvertId: 2, URL: server.URL + "/overloaded.jpg", Broken: true},
+	}, nil)
+	mockRepository.EXPECT().SetPictureBroken(3, true).Return(nil)
+
+	checker := NewLinkChecker(mockRepository, nil, server.Client(), time.Hour, 2)
+
+	checked, err := checker.CheckDue()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, checked, 3)
+}
+
+func TestLinkChecker_StartStop(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	checked := make(chan struct{}, 1)
+	mockRepository := mock.NewMockRepository(c)
+	mockRepository.EXPECT().GetPicturesToCheck(DefaultLinkCheckBatchSize, gomock.Any()).
+		DoAndReturn(func(int, time.Time) ([]model.Picture, error) {
+			select {
+			case checked <- struct{}{}:
+			default:
+			}
+			return nil, nil
+		}).MinTimes(1)
+
+	checker := NewLinkChecker(mockRepository, nil, http.DefaultClient, time.Hour, 0)
+	checker.Start()
+	<-checked
+	checker.Stop()
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type LinkConfig struct {
+	Schemes []string `toml:"schemes"`
+	Hosts   []string `toml:"hosts"`
+
+	CheckIntervalSec int `toml:"check_interval_sec"`
+	CheckTimeoutSec  int `toml:"check_timeout_sec"`
+	CheckBatchSize   int `toml:"check_batch_size"`
+}
+
+// LinkPolicy restricts the picture links clients may submit. An empty
+// scheme or host list allows any scheme or host. A host starting with "*."
+// also allows its subdomains.
+type LinkPolicy struct {
+	Schemes []string
+	Hosts   []string
+}
+
+func NewLinkPolicy(config LinkConfig, ownHosts ...string) LinkPolicy {
+	policy := LinkPolicy{}
+	for _, scheme := range config.Schemes {
+		policy.Schemes = append(policy.Schemes, strings.ToLower(scheme))
+	}
+	if len(config.Hosts) > 0 {
+		// links to the pictures uploaded to the service are always allowed
+		for _, host := range append(config.Hosts, ownHosts...) {
+			if host != "" {
+				policy.Hosts = append(policy.Hosts, strings.ToLower(host))
+			}
+		}
+	}
+	return policy
+}
+
+func (p LinkPolicy) check(field, link string, errs *ValidationError) {
+	if len(p.Schemes) == 0 && len(p.Hosts) == 0 {
+		return
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		errs.Add(field, CodeInvalid, fmt.Sprintf(`the field "%s" must be an absolute URL`, field), nil)
+		return
+	}
+
+	if len(p.Schemes) > 0 && !contains(p.Schemes, strings.ToLower(u.Scheme)) {
+		errs.Add(field, CodeURLScheme, fmt.Sprintf(`the scheme of the field "%s" must be one of: %s`, field, strings.Join(p.Schemes, ", ")),
+			map[string]interface{}{"allowed": p.Schemes})
+		return
+	}
+
+	if len(p.Hosts) > 0 && !p.hostAllowed(strings.ToLower(u.Hostname())) {
+		errs.Add(field, CodeURLHost, fmt.Sprintf(`the host of the field "%s" is not allowed`, field),
+			map[string]interface{}{"allowed": p.Hosts})
+	}
+}
+
+func (p LinkPolicy) hostAllowed(host string) bool {
+	for _, allowed := range p.Hosts {
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestLinkPolicy_check(t *testing.T) {
+	policy := NewLinkPolicy(LinkConfig{
+		Schemes: []string{"HTTPS"},
+		Hosts:   []string{"avito.ru", "*.avito-st.ru"},
+	}, "localhost")
+
+	tests := []struct {
+		name           string
+		inputPolicy    LinkPolicy
+		inputURL       string
+		expectedErrors []FieldError
+	}{
+		{
+			name:        "Allowed host",
+			inputPolicy: policy,
+			inputURL:    "https://avito.ru/files/ad1.jpg",
+		},
+		{
+			name:        "Allowed subdomain",
+			inputPolicy: policy,
+			inputURL:    "https://00.img.AVITO-ST.ru/image/1/ad1.jpg",
+		},
+		{
+			name:        "Own storage",
+			inputPolicy: policy,
+			inputURL:    "https://localhost:8080/uploads/adverts/1/a.png",
+		},
+		{
+			name:        "Relative link",
+			inputPolicy: policy,
+			inputURL:    "avito/files/ad1",
+			expectedErrors: []FieldError{
+				{Field: "pictures[0].url", Code: CodeInvalid, Message: `the field "pictures[0].url" must be an absolute URL`},
+			},
+		},
+		{
+			name:        "Scheme not allowed",
+			inputPolicy: policy,
+			inputURL:    "ftp://avito.ru/files/ad1.jpg",
+			expectedErrors: []FieldError{{
+				Field:   "pictures[0].url",
+				Code:    CodeURLScheme,
+				Message: `the scheme of the field "pictures[0].url" must be one of: https`,
+				Params:  map[string]interface{}{"allowed": []string{"https"}},
+			}},
+		},
+		{
+			name:        "Host not allowed",
+			inputPolicy: policy,
+			inputURL:    "https://evil-avito.ru/files/ad1.jpg",
+			expectedErrors: []FieldError{{
+				Field:   "pictures[0].url",
+				Code:    CodeURLHost,
+				Message: `the host of the field "pictures[0].url" is not allowed`,
+				Params:  map[string]interface{}{"allowed": []string{"avito.ru", "*.avito-st.ru", "localhost"}},
+			}},
+		},
+		{
+			name:        "Wildcard does not match the domain itself",
+			inputPolicy: NewLinkPolicy(LinkConfig{Hosts: []string{"*.avito-st.ru"}}),
+			inputURL:    "http://avito-st.ru/ad1.jpg",
+			expectedErrors: []FieldError{{
+				Field:   "pictures[0].url",
+				Code:    CodeURLHost,
+				Message: `the host of the field "pictures[0].url" is not allowed`,
+				Params:  map[string]interface{}{"allowed": []string{"*.avito-st.ru"}},
+			}},
+		},
+		{
+			name:        "No restrictions",
+			inputPolicy: NewLinkPolicy(LinkConfig{}, "localhost"),
+			inputURL:    "avito/files/ad1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := &ValidationError{}
+			test.inputPolicy.check("pictures[0].url", test.inputURL, errs)
+			assert.Equal(t, errs.Errors, test.expectedErrors)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+const (
+	DefaultPageSize    = 10
+	DefaultMaxPageSize = 100
+
+	// maxQueryLength bounds the text filter of the list
+	maxQueryLength = 100
+)
+
+type ListConfig struct {
+	// CursorSecret signs the list cursors. When it is empty a random secret
+	// is used and the cursors issued before a restart become invalid.
+	CursorSecret string `toml:"cursor_secret"`
+
+	// PageSize is used when the client does not ask for a page size, larger
+	// requests are cut to MaxPageSize.
+	PageSize    int `toml:"page_size"`
+	MaxPageSize int `toml:"max_page_size"`
+
+	// CountMode is how the total of the list is counted by default,
+	// model.CountExact or model.CountEstimated.
+	CountMode string `toml:"count_mode"`
+}
+
+// Validate checks the values set in the config file, zero values are
+// replaced by the defaults later.
+func (c ListConfig) Validate() error {
+	if c.PageSize < 0 || c.MaxPageSize < 0 {
+		return fmt.Errorf("list: page sizes can not be negative")
+	}
+	if c.CountMode != "" && !isCountMode(c.CountMode) {
+		return fmt.Errorf("list: unknown count_mode %q", c.CountMode)
+	}
+	return nil
+}
+
+func (c ListConfig) withDefaults() ListConfig {
+	if c.MaxPageSize == 0 {
+		c.MaxPageSize = DefaultMaxPageSize
+	}
+	if c.PageSize == 0 {
+		c.PageSize = DefaultPageSize
+	}
+	if c.PageSize > c.MaxPageSize {
+		c.PageSize = c.MaxPageSize
+	}
+	if c.CountMode == "" {
+		c.CountMode = model.CountExact
+	}
+	return c
+}
+
+// pageSize bounds the page size asked by the client, 0 stands for the
+// default one.
+func (c ListConfig) pageSize(size int) int {
+	switch {
+	case size <= 0:
+		return c.PageSize
+	case size > c.MaxPageSize:
+		return c.MaxPageSize
+	}
+	return size
+}
+
+func isCountMode(mode string) bool {
+	return mode == model.CountExact || mode == model.CountEstimated
+}
+
+// validateListQuery checks the filters the handler could parse: the ranges
+// must not be reversed and the text filter must be short enough.
+func validateListQuery(query model.ListQuery) error {
+	errs := &ValidationError{}
+
+	if query.Count != "" && !isCountMode(query.Count) {
+		errs.Add("count", CodeInvalid, fmt.Sprintf("the count must be %s or %s", model.CountExact, model.CountEstimated), nil)
+	}
+	if query.PriceMin != nil && *query.PriceMin < 0 {
+		errs.Add("price_min", CodeMinValue, `the field "price_min" must have a value greater than 0`,
+			map[string]interface{}{"min": 0})
+	}
+	if query.PriceMax != nil && *query.PriceMax < 0 {
+		errs.Add("price_max", CodeMinValue, `the field "price_max" must have a value greater than 0`,
+			map[string]interface{}{"min": 0})
+	}
+	if query.PriceMin != nil && query.PriceMax != nil && *query.PriceMin > *query.PriceMax {
+		errs.Add("price_max", CodeInvalid, `the field "price_max" must not be less than "price_min"`, nil)
+	}
+	if query.CreatedFrom != nil && query.CreatedTo != nil && query.CreatedFrom.After(*query.CreatedTo) {
+		errs.Add("created_to", CodeInvalid, `the field "created_to" must not be before "created_from"`, nil)
+	}
+	for _, attribute := range query.Attributes {
+		if attribute.Min != nil && attribute.Max != nil && *attribute.Min > *attribute.Max {
+			field := fmt.Sprintf("attr.%s_max", attribute.Name)
+			errs.Add(field, CodeInvalid, fmt.Sprintf(`the field "%s" must not be less than "attr.%s_min"`, field, attribute.Name), nil)
+		}
+	}
+	validateGeoQuery(query, errs)
+	if utf8.RuneCountInString(query.Q) > maxQueryLength {
+		errs.Add("q", CodeMaxLength, fmt.Sprintf(`length of the field "q" should not exceed %d`, maxQueryLength),
+			map[string]interface{}{"max": maxQueryLength})
+	}
+
+	return errs.Err()
+}
+
+// listTotal returns the total of a list page: page is 0 for a page selected
+// by a cursor, items is the number of adverts on the page and more tells
+// whether the list goes on. The last page of a numbered list tells the
+// total by itself, otherwise count is called. An estimated count is raised
+// to the number of adverts a numbered page proves to exist.
+func listTotal(page, offset, items int, more bool, count func() (int, error)) (int, error) {
+	known := offset + items
+	if page > 0 && !more && (items > 0 || page == 1) {
+		return known, nil
+	}
+
+	total, err := count()
+	if err != nil {
+		return 0, err
+	}
+	if page == 0 || items == 0 {
+		return total, nil
+	}
+	if more {
+		known++
+	}
+	if total < known {
+		total = known
+	}
+	return total, nil
+}
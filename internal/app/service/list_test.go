@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+func TestListConfig_pageSize(t *testing.T) {
+	config := ListConfig{}.withDefaults()
+	assert.Equal(t, config.PageSize, DefaultPageSize)
+	assert.Equal(t, config.MaxPageSize, DefaultMaxPageSize)
+	assert.Equal(t, config.CountMode, model.CountExact)
+
+	assert.Equal(t, config.pageSize(0), DefaultPageSize)
+	assert.Equal(t, config.pageSize(-5), DefaultPageSize)
+	assert.Equal(t, config.pageSize(25), 25)
+	assert.Equal(t, config.pageSize(1000), DefaultMaxPageSize)
+
+	// the default page size never exceeds the maximum
+	config = ListConfig{PageSize: 50, MaxPageSize: 20}.withDefaults()
+	assert.Equal(t, config.pageSize(0), 20)
+}
+
+func TestListConfig_Validate(t *testing.T) {
+	assert.Equal(t, ListConfig{}.Validate(), nil)
+	assert.Equal(t, ListConfig{PageSize: 20, MaxPageSize: 50, CountMode: model.CountEstimated}.Validate(), nil)
+	assert.NotEqual(t, ListConfig{PageSize: -1}.Validate(), nil)
+	assert.NotEqual(t, ListConfig{CountMode: "approximate"}.Validate(), nil)
+}
+
+func TestService_validateListQuery(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	from := time.Date(2021, 7, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	floorMin, floorMax := 5.0, 2.0
+
+	assert.Equal(t, validateListQuery(model.ListQuery{PriceMin: intPtr(100), PriceMax: intPtr(100), CreatedFrom: &to, CreatedTo: &from}), nil)
+
+	err := validateListQuery(model.ListQuery{
+		Count:       "approximate",
+		PriceMin:    intPtr(-1),
+		CreatedFrom: &from,
+		CreatedTo:   &to,
+		Q:           strings.Repeat("я", maxQueryLength+1),
+		Attributes:  []model.AttributeFilter{{Name: "floor", Min: &floorMin, Max: &floorMax}},
+	})
+	assert.Equal(t, err, &ValidationError{Errors: []FieldError{
+		{Field: "count", Code: CodeInvalid, Message: "the count must be exact or estimated"},
+		{Field: "price_min", Code: CodeMinValue, Message: `the field "price_min" must have a value greater than 0`, Params: map[string]interface{}{"min": 0}},
+		{Field: "created_to", Code: CodeInvalid, Message: `the field "created_to" must not be before "created_from"`},
+		{Field: "attr.floor_max", Code: CodeInvalid, Message: `the field "attr.floor_max" must not be less than "attr.floor_min"`},
+		{Field: "q", Code: CodeMaxLength, Message: `length of the field "q" should not exceed 100`, Params: map[string]interface{}{"max": maxQueryLength}},
+	}})
+}
+
+func TestService_listTotal(t *testing.T) {
+	count := func(total int) func() (int, error) {
+		return func() (int, error) { return total, nil }
+	}
+	unexpected := func() (int, error) { return 0, errors.New("unexpected count") }
+
+	tests := []struct {
+		name          string
+		page, offset  int
+		items         int
+		more          bool
+		count         func() (int, error)
+		expectedTotal int
+	}{
+		{name: "Last numbered page", page: 3, offset: 20, items: 4, count: unexpected, expectedTotal: 24},
+		{name: "Empty first page", page: 1, count: unexpected, expectedTotal: 0},
+		{name: "More pages", page: 1, items: 10, more: true, count: count(35), expectedTotal: 35},
+		{name: "Estimate too low", page: 2, offset: 10, items: 10, more: true, count: count(12), expectedTotal: 21},
+		{name: "Past the end", page: 9, offset: 80, count: count(35), expectedTotal: 35},
+		{name: "Cursor page", page: 0, items: 3, count: count(35), expectedTotal: 35},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			total, err := listTotal(test.page, test.offset, test.items, test.more, test.count)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, total, test.expectedTotal)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+const (
+	DefaultModerationLease = 10 * time.Minute
+	DefaultQueueLimit      = 10
+	MaxQueueLimit          = 50
+)
+
+var ErrInvalidDecision = apperror.New(apperror.ErrValidation, "invalid moderation decision")
+
+type ModerationService struct {
+	repo  repository.ModerationRepository
+	index SearchIndex
+	lease time.Duration
+}
+
+// NewModerationService creates the service, index may be nil when the
+// search index does not need to know about approved adverts.
+func NewModerationService(repo repository.ModerationRepository, index SearchIndex, lease time.Duration) *ModerationService {
+	if lease <= 0 {
+		lease = DefaultModerationLease
+	}
+	return &ModerationService{repo: repo, index: index, lease: lease}
+}
+
+func (s *ModerationService) ClaimQueue(moderator string, limit int) ([]model.ModerationTask, error) {
+	if limit < 1 {
+		limit = DefaultQueueLimit
+	}
+	if limit > MaxQueueLimit {
+		limit = MaxQueueLimit
+	}
+
+	tasks, err := s.repo.ClaimPending(moderator, limit, s.lease)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = make([]model.ModerationTask, 0)
+	}
+	return tasks, nil
+}
+
+func (s *ModerationService) Decide(decision model.ModerationDecision) (model.ModerationDecision, error) {
+	if err := validateDecision(decision); err != nil {
+		return decision, err
+	}
+
+	// the decisions have their own transitions of a pending advert, the
+	// status is checked when the decision is saved
+	to := model.StatusActive
+	if decision.Decision == model.DecisionReject {
+		to = model.StatusDraft
+	}
+
+	saved, err := s.repo.SaveDecision(decision, model.StatusPending, to)
+	if errors.Is(err, repository.ErrStatusConflict) {
+		return decision, fmt.Errorf("%w: advertisement is not pending moderation", ErrTransitionNotAllowed)
+	}
+	if err != nil {
+		return saved, err
+	}
+	updateIndex(s.index, decision.AdvertId)
+	return saved, nil
+}
+
+func (s *ModerationService) GetDecisions(advertId int) ([]model.ModerationDecision, error) {
+	return s.repo.GetDecisions(advertId)
+}
+
+func validateDecision(decision model.ModerationDecision) error {
+	switch decision.Decision {
+	case model.DecisionApprove:
+		if decision.ReasonCode != "" && !model.ReasonCodes[decision.ReasonCode] {
+			return fmt.Errorf("%w: unknown reason code %q", ErrInvalidDecision, decision.ReasonCode)
+		}
+	case model.DecisionReject:
+		if !model.ReasonCodes[decision.ReasonCode] {
+			return fmt.Errorf("%w: rejection requires a known reason code", ErrInvalidDecision)
+		}
+	default:
+		return fmt.Errorf("%w: decision must be approve or reject", ErrInvalidDecision)
+	}
+
+	if utf8.RuneCountInString(decision.Comment) > 1000 {
+		return fmt.Errorf("%w: comment should not exceed 1000", ErrInvalidDecision)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+func TestModerationService_ClaimQueue(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputLimit    int
+		expectedLimit int
+	}{
+		{name: "Default limit", inputLimit: 0, expectedLimit: DefaultQueueLimit},
+		{name: "Custom limit", inputLimit: 5, expectedLimit: 5},
+		{name: "Limit is capped", inputLimit: 1000, expectedLimit: MaxQueueLimit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockModerationRepository(c)
+			mockRepository.EXPECT().ClaimPending("moderator-1", test.expectedLimit, DefaultModerationLease).Return(nil, nil)
+
+			service := NewModerationService(mockRepository, nil, 0)
+
+			tasks, err := service.ClaimQueue("moderator-1", test.inputLimit)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, tasks, []model.ModerationTask{})
+		})
+	}
+}
+
+func TestModerationService_Decide(t *testing.T) {
+	type mockBehaviortype func(*mock.MockModerationRepository, model.ModerationDecision)
+	tests := []struct {
+		name          string
+		inputDecision model.ModerationDecision
+		mockBehavior  mockBehaviortype
+		expectedError error
+	}{
+		{
+			name:          "Approve",
+			inputDecision: model.ModerationDecision{AdvertId: 1, Moderator: "moderator-1", Decision: model.DecisionApprove},
+			mockBehavior: func(r *mock.MockModerationRepository, decision model.ModerationDecision) {
+				r.EXPECT().SaveDecision(decision, model.StatusPending, model.StatusActive).Return(decision, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "Reject",
+			inputDecision: model.ModerationDecision{AdvertId: 1, Moderator: "moderator-1", Decision: model.DecisionReject, ReasonCode: "spam"},
+			mockBehavior: func(r *mock.MockModerationRepository, decision model.ModerationDecision) {
+				r.EXPECT().SaveDecision(decision, model.StatusPending, model.StatusDraft).Return(decision, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "Reject without reason",
+			inputDecision: model.ModerationDecision{AdvertId: 1, Moderator: "moderator-1", Decision: model.DecisionReject},
+			mockBehavior:  func(r *mock.MockModerationRepository, decision model.ModerationDecision) {},
+			expectedError: ErrInvalidDecision,
+		},
+		{
+			name:          "Unknown reason",
+			inputDecision: model.ModerationDecision{AdvertId: 1, Moderator: "moderator-1", Decision: model.DecisionApprove, ReasonCode: "looks-fine"},
+			mockBehavior:  func(r *mock.MockModerationRepository, decision model.ModerationDecision) {},
+			expectedError: ErrInvalidDecision,
+		},
+		{
+			name:          "Unknown decision",
+			inputDecision: model.ModerationDecision{AdvertId: 1, Moderator: "moderator-1", Decision: "postpone"},
+			mockBehavior:  func(r *mock.MockModerationRepository, decision model.ModerationDecision) {},
+			expectedError: ErrInvalidDecision,
+		},
+		{
+			name:          "Long comment",
+			inputDecision: model.ModerationDecision{AdvertId: 1, Moderator: "moderator-1", Decision: model.DecisionApprove, Comment: strings.Repeat("c", 1001)},
+			mockBehavior:  func(r *mock.MockModerationRepository, decision model.ModerationDecision) {},
+			expectedError: ErrInvalidDecision,
+		},
+		{
+			name:          "Advert is not pending anymore",
+			inputDecision: model.ModerationDecision{AdvertId: 1, Moderator: "moderator-1", Decision: model.DecisionApprove},
+			mockBehavior: func(r *mock.MockModerationRepository, decision model.ModerationDecision) {
+				r.EXPECT().SaveDecision(decision, model.StatusPending, model.StatusActive).Return(decision, repository.ErrStatusConflict)
+			},
+			expectedError: ErrTransitionNotAllowed,
+		},
+		{
+			name:          "Not claimed",
+			inputDecision: model.ModerationDecision{AdvertId: 1, Moderator: "moderator-1", Decision: model.DecisionApprove},
+			mockBehavior: func(r *mock.MockModerationRepository, decision model.ModerationDecision) {
+				r.EXPECT().SaveDecision(decision, model.StatusPending, model.StatusActive).Return(decision, repository.ErrNotClaimed)
+			},
+			expectedError: repository.ErrNotClaimed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockModerationRepository(c)
+			test.mockBehavior(mockRepository, test.inputDecision)
+			mockIndex := mock.NewMockSearchIndex(c)
+			if test.expectedError == nil {
+				mockIndex.EXPECT().Update(test.inputDecision.AdvertId).Return(nil)
+			}
+
+			service := NewModerationService(mockRepository, mockIndex, 0)
+
+			_, err := service.Decide(test.inputDecision)
+			if test.expectedError == nil {
+				assert.Equal(t, err, nil)
+			} else {
+				assert.Equal(t, errors.Is(err, test.expectedError), true)
+			}
+		})
+	}
+}
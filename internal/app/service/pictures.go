@@ -0,0 +1,169 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+const MaxPictures = 3
+
+// ReorderPictures puts the advert pictures in the order of pictureIds, which
+// must list every picture of the advert exactly once.
+func (s *AdvertService) ReorderPictures(principal model.Principal, advertId int, pictureIds []int) (model.Pictures, error) {
+	advert, err := s.repo.GetAdvertById(advertId)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeAdvert(principal, PermUpdateOwnAdverts, PermUpdateAnyAdverts, advert.OwnerId); err != nil {
+		return nil, err
+	}
+
+	pictures := make(map[int]model.Picture, len(advert.Pictures))
+	for _, picture := range advert.Pictures {
+		pictures[picture.Id] = picture
+	}
+
+	invalid := &ValidationError{}
+	invalid.Add("pictures", CodeInvalid, "the order must list every picture of the advertisement exactly once", nil)
+	if len(pictureIds) != len(advert.Pictures) {
+		return nil, invalid
+	}
+
+	reordered := make(model.Pictures, 0, len(pictureIds))
+	for i, pictureId := range pictureIds {
+		picture, ok := pictures[pictureId]
+		if !ok {
+			return nil, invalid
+		}
+		delete(pictures, pictureId)
+
+		picture.Position = i
+		reordered = append(reordered, picture)
+	}
+
+	if err := s.repo.ReorderPictures(advertId, pictureIds); err != nil {
+		return nil, err
+	}
+	updateIndex(s.index, advertId)
+	return reordered, nil
+}
+
+func (s *AdvertService) SetMainPicture(principal model.Principal, advertId, pictureId int) (model.Pictures, error) {
+	advert, err := s.repo.GetAdvertById(advertId)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeAdvert(principal, PermUpdateOwnAdverts, PermUpdateAnyAdverts, advert.OwnerId); err != nil {
+		return nil, err
+	}
+
+	found := false
+	for i, picture := range advert.Pictures {
+		advert.Pictures[i].IsMain = picture.Id == pictureId
+		found = found || picture.Id == pictureId
+	}
+	if !found {
+		return nil, repository.ErrPictureNotFound
+	}
+
+	if err := s.repo.SetMainPicture(advertId, pictureId); err != nil {
+		return nil, err
+	}
+	updateIndex(s.index, advertId)
+	return advert.Pictures, nil
+}
+
+func validatePictures(pictures model.Pictures, links LinkPolicy, errs *ValidationError) {
+	if len(pictures) > MaxPictures {
+		addPictureLimit(errs)
+	}
+
+	main := 0
+	seen := make(map[string]bool, len(pictures))
+	for i, picture := range pictures {
+		field := fmt.Sprintf("pictures[%d].url", i)
+		if strings.TrimSpace(picture.URL) == "" {
+			errs.Add(field, CodeRequired, fmt.Sprintf(`the field "%s" is required`, field), nil)
+		} else if utf8.RuneCountInString(picture.URL) > 1000 {
+			errs.Add(field, CodeMaxLength, fmt.Sprintf(`length of the field "%s" should not exceed 1000`, field),
+				map[string]interface{}{"max": 1000})
+		} else if seen[picture.URL] {
+			errs.Add(field, CodeInvalid, fmt.Sprintf(`the field "%s" repeats a picture listed before`, field), nil)
+		} else {
+			links.check(field, picture.URL, errs)
+		}
+		seen[picture.URL] = true
+
+		if picture.IsMain {
+			main++
+		}
+	}
+
+	if main > 1 {
+		errs.Add("pictures", CodeInvalid, "only one picture can be main", nil)
+	}
+}
+
+func addPictureLimit(errs *ValidationError) {
+	errs.Add("pictures", CodeMaxItems, fmt.Sprintf(`the field "pictures" must contain no more than %d photos`, MaxPictures),
+		map[string]interface{}{"max": MaxPictures})
+}
+
+// normalizePictures orders the pictures by position, numbers them from zero
+// and makes the first one main if no picture is marked as main. Ids are
+// dropped: the stored pictures are matched by url. Variants are dropped too,
+// they are generated by the service and never taken from clients.
+func normalizePictures(pictures model.Pictures) model.Pictures {
+	if len(pictures) == 0 {
+		return nil
+	}
+
+	normalized := make(model.Pictures, len(pictures))
+	copy(normalized, pictures)
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].Position < normalized[j].Position
+	})
+
+	hasMain := false
+	for i := range normalized {
+		normalized[i].Id = 0
+		normalized[i].Variants = nil
+		normalized[i].Position = i
+		hasMain = hasMain || normalized[i].IsMain
+	}
+	if !hasMain {
+		normalized[0].IsMain = true
+	}
+	return normalized
+}
+
+// deletePictureFiles deletes the stored files of the pictures removed from
+// the advert and of their variants. Only the files uploaded to this advert
+// are deleted, a link to the upload of another advert is left alone.
+func (s *AdvertService) deletePictureFiles(advertId int, pictures model.Pictures) {
+	if s.store == nil {
+		return
+	}
+	prefix := pictureKeyPrefix(advertId)
+	for _, picture := range pictures {
+		urls := []string{picture.URL}
+		for _, url := range picture.Variants {
+			urls = append(urls, url)
+		}
+		for _, url := range urls {
+			key, ok := s.store.Key(url)
+			if !ok || !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if err := s.store.Delete(key); err != nil {
+				log.Printf("failed to delete removed picture %s: %s", key, err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/storage"
+)
+
+func storedPictures() model.Pictures {
+	return model.Pictures{
+		{Id: 1, URL: "avito/files/ad1", Position: 0, IsMain: true},
+		{Id: 2, URL: "avito/files/ad2", Position: 1},
+		{Id: 3, URL: "avito/files/ad3", Position: 2},
+	}
+}
+
+func TestService_ReorderPictures(t *testing.T) {
+	invalidOrder := &ValidationError{Errors: []FieldError{{
+		Field:   "pictures",
+		Code:    CodeInvalid,
+		Message: "the order must list every picture of the advertisement exactly once",
+	}}}
+
+	type mockBehaviortype func(*mock.MockRepository)
+	tests := []struct {
+		name           string
+		inputIds       []int
+		mockBehavior   mockBehaviortype
+		expectedResult model.Pictures
+		expectedError  error
+	}{
+		{
+			name:     "OK",
+			inputIds: []int{3, 1, 2},
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures(), OwnerId: intPtr(7)}, nil)
+				r.EXPECT().ReorderPictures(1, []int{3, 1, 2}).Return(nil)
+			},
+			expectedResult: model.Pictures{
+				{Id: 3, URL: "avito/files/ad3", Position: 0},
+				{Id: 1, URL: "avito/files/ad1", Position: 1, IsMain: true},
+				{Id: 2, URL: "avito/files/ad2", Position: 2},
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Not every picture",
+			inputIds: []int{3, 1},
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures(), OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: invalidOrder,
+		},
+		{
+			name:     "Duplicated picture",
+			inputIds: []int{3, 3, 1},
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures(), OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: invalidOrder,
+		},
+		{
+			name:     "Advert not found",
+			inputIds: []int{3, 1, 2},
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{}, repository.ErrAdvertNotFound)
+			},
+			expectedError: repository.ErrAdvertNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockRepository(c)
+			test.mockBehavior(mockRepository)
+
+			service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{})
+
+			result, err := service.ReorderPictures(owner, 1, test.inputIds)
+			assert.Equal(t, err, test.expectedError)
+			assert.Equal(t, result, test.expectedResult)
+		})
+	}
+}
+
+func TestService_SetMainPicture(t *testing.T) {
+	type mockBehaviortype func(*mock.MockRepository)
+	tests := []struct {
+		name           string
+		inputId        int
+		mockBehavior   mockBehaviortype
+		expectedResult model.Pictures
+		expectedError  error
+	}{
+		{
+			name:    "OK",
+			inputId: 2,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures(), OwnerId: intPtr(7)}, nil)
+				r.EXPECT().SetMainPicture(1, 2).Return(nil)
+			},
+			expectedResult: model.Pictures{
+				{Id: 1, URL: "avito/files/ad1", Position: 0},
+				{Id: 2, URL: "avito/files/ad2", Position: 1, IsMain: true},
+				{Id: 3, URL: "avito/files/ad3", Position: 2},
+			},
+			expectedError: nil,
+		},
+		{
+			name:    "Picture not found",
+			inputId: 666,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures(), OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: repository.ErrPictureNotFound,
+		},
+		{
+			name:    "Not owner",
+			inputId: 2,
+			mockBehavior: func(r *mock.MockRepository) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures(), OwnerId: intPtr(8)}, nil)
+			},
+			expectedError: &PermissionError{Permission: PermUpdateAnyAdverts},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockRepository(c)
+			test.mockBehavior(mockRepository)
+
+			service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{})
+
+			result, err := service.SetMainPicture(owner, 1, test.inputId)
+			assert.Equal(t, err, test.expectedError)
+			assert.Equal(t, result, test.expectedResult)
+		})
+	}
+}
+
+func TestService_deletePictureFiles(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockRepository.EXPECT().GetAdvertOwner(1).Return(intPtr(7), nil)
+	mockRepository.EXPECT().UpdateAdvert(1, gomock.Any()).Return(model.Pictures{
+		{URL: "http://localhost/uploads/adverts/1/ad1.jpg", Variants: model.PictureVariants{model.VariantThumb: "http://localhost/uploads/adverts/1/ad1_thumb.jpg"}},
+		{URL: "https://example.com/ad2.jpg"},
+		{URL: "http://localhost/uploads/adverts/2/ad3.jpg"},
+	}, nil)
+
+	// the links to other sites and to the uploads of other adverts are kept
+	mockStore := mock.NewMockBlobStore(c)
+	mockStore.EXPECT().Key(gomock.Any()).DoAndReturn(storage.NewFileStore("", "http://localhost/uploads").Key).AnyTimes()
+	mockStore.EXPECT().Delete("adverts/1/ad1.jpg").Return(nil)
+	mockStore.EXPECT().Delete("adverts/1/ad1_thumb.jpg").Return(nil)
+
+	service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, mockStore, LinkPolicy{}, ListConfig{})
+	err := service.UpdateAdvert(owner, 1, model.Advert{Name: "name-test", Description: "desc-test", Price: 1000, CategoryId: 3})
+	assert.Equal(t, err, nil)
+}
+
+func TestService_validatePictures(t *testing.T) {
+	errs := &ValidationError{}
+	validatePictures(model.Pictures{
+		{URL: "avito/files/ad1", IsMain: true},
+		{URL: " ", IsMain: true},
+		{URL: "avito/files/ad1"},
+	}, LinkPolicy{}, errs)
+
+	assert.Equal(t, errs.Errors, []FieldError{
+		{Field: "pictures[1].url", Code: CodeRequired, Message: `the field "pictures[1].url" is required`},
+		{Field: "pictures[2].url", Code: CodeInvalid, Message: `the field "pictures[2].url" repeats a picture listed before`},
+		{Field: "pictures", Code: CodeInvalid, Message: "only one picture can be main"},
+	})
+}
+
+func TestService_normalizePictures(t *testing.T) {
+	tests := []struct {
+		name           string
+		inputPictures  model.Pictures
+		expectedResult model.Pictures
+	}{
+		{
+			name:           "Empty",
+			inputPictures:  model.Pictures{},
+			expectedResult: nil,
+		},
+		{
+			name: "Order by position and keep the main picture",
+			inputPictures: model.Pictures{
+				{Id: 7, URL: "avito/files/ad3", Position: 10, Variants: model.PictureVariants{model.VariantThumb: "evil.example.com/x.jpg"}},
+				{URL: "avito/files/ad1", Position: -1},
+				{URL: "avito/files/ad2", Position: 5, IsMain: true},
+			},
+			expectedResult: model.Pictures{
+				{URL: "avito/files/ad1", Position: 0},
+				{URL: "avito/files/ad2", Position: 1, IsMain: true},
+				{URL: "avito/files/ad3", Position: 2},
+			},
+		},
+		{
+			name: "Same positions keep the given order",
+			inputPictures: model.Pictures{
+				{URL: "avito/files/ad1"},
+				{URL: "avito/files/ad2"},
+			},
+			expectedResult: model.Pictures{
+				{URL: "avito/files/ad1", Position: 0, IsMain: true},
+				{URL: "avito/files/ad2", Position: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, normalizePictures(test.inputPictures), test.expectedResult)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+// Permission is an operation of the service. The _own permissions allow
+// the operation on the adverts of the principal, the _any ones on every
+// advert.
+type Permission string
+
+const (
+	PermReadAdverts      Permission = "adverts:read"
+	PermReadOwnAdverts   Permission = "adverts:read_own"
+	PermCreateAdverts    Permission = "adverts:create"
+	PermUpdateOwnAdverts Permission = "adverts:update_own"
+	PermUpdateAnyAdverts Permission = "adverts:update_any"
+	// deleting covers archiving, both hide the advert from the list
+	PermDeleteOwnAdverts Permission = "adverts:delete_own"
+	PermDeleteAnyAdverts Permission = "adverts:delete_any"
+	PermModerateAdverts  Permission = "adverts:moderate"
+	PermRestoreAdverts   Permission = "adverts:restore"
+	PermManageCategories Permission = "categories:manage"
+	PermManageAPIKeys    Permission = "api_keys:manage"
+	PermManageUsers      Permission = "users:manage"
+)
+
+var allPermissions = []Permission{
+	PermReadAdverts, PermReadOwnAdverts, PermCreateAdverts,
+	PermUpdateOwnAdverts, PermUpdateAnyAdverts, PermDeleteOwnAdverts, PermDeleteAnyAdverts,
+	PermModerateAdverts, PermRestoreAdverts, PermManageCategories, PermManageAPIKeys, PermManageUsers,
+}
+
+// rolePermissions is the permission table of the roles, the empty role is
+// the anonymous caller.
+var rolePermissions = map[model.Role][]Permission{
+	"":              {PermReadAdverts},
+	model.RoleBuyer: {PermReadAdverts},
+	model.RoleSeller: {
+		PermReadAdverts, PermReadOwnAdverts, PermCreateAdverts, PermUpdateOwnAdverts, PermDeleteOwnAdverts,
+	},
+	model.RoleModerator: {PermReadAdverts, PermModerateAdverts},
+	model.RoleAdmin:     allPermissions,
+}
+
+// scopePermissions is the permission table of the API key scopes. A caller
+// with a key is allowed the permissions both its role and its scopes allow.
+var scopePermissions = map[model.Scope][]Permission{
+	model.ScopeAdvertsRead: {PermReadAdverts, PermReadOwnAdverts},
+	model.ScopeAdvertsWrite: {
+		PermCreateAdverts, PermUpdateOwnAdverts, PermUpdateAnyAdverts, PermDeleteOwnAdverts, PermDeleteAnyAdverts,
+	},
+	model.ScopeAdmin: allPermissions,
+}
+
+// PermissionError names the permission the principal lacks.
+type PermissionError struct {
+	Permission Permission
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("permission %q is required", e.Permission)
+}
+
+// Is matches apperror.ErrForbidden and the error of the same permission.
+func (e *PermissionError) Is(target error) bool {
+	if t, ok := target.(*PermissionError); ok {
+		return t.Permission == e.Permission
+	}
+	return target == apperror.ErrForbidden
+}
+
+// Authorize checks the permission of the principal. An anonymous principal
+// is asked to authenticate instead.
+func Authorize(principal model.Principal, permission Permission) error {
+	if can(principal, permission) {
+		return nil
+	}
+	if principal.Anonymous() {
+		return ErrAuthRequired
+	}
+	return &PermissionError{Permission: permission}
+}
+
+// authorizeAdvert checks the permission to change the advert of the owner:
+// the any permission or the own one for the principal's advert.
+func authorizeAdvert(principal model.Principal, own, any Permission, ownerId *int) error {
+	if can(principal, any) || (principal.Owns(ownerId) && can(principal, own)) {
+		return nil
+	}
+	if principal.Owns(ownerId) {
+		return Authorize(principal, own)
+	}
+	return Authorize(principal, any)
+}
+
+func can(principal model.Principal, permission Permission) bool {
+	if !granted(rolePermissions[principal.Role], permission) {
+		return false
+	}
+	if principal.KeyId == 0 {
+		return true
+	}
+	for _, scope := range principal.Scopes {
+		if granted(scopePermissions[scope], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func granted(permissions []Permission, permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
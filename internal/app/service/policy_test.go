@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+func TestAuthorize(t *testing.T) {
+	buyer := model.Principal{UserId: 5, Role: model.RoleBuyer}
+	readKey := model.Principal{UserId: 7, Role: model.RoleSeller, KeyId: 3, Scopes: model.Scopes{model.ScopeAdvertsRead}}
+	writeKey := model.Principal{UserId: 7, Role: model.RoleSeller, KeyId: 4, Scopes: model.Scopes{model.ScopeAdvertsWrite}}
+	adminKey := model.Principal{Role: model.RoleAdmin, KeyId: 5, Scopes: model.Scopes{model.ScopeAdmin}}
+
+	tests := []struct {
+		name            string
+		inputPrincipal  model.Principal
+		inputPermission Permission
+		expectedError   error
+	}{
+		{name: "Anonymous reads", inputPrincipal: model.Principal{}, inputPermission: PermReadAdverts},
+		{name: "Anonymous creates", inputPrincipal: model.Principal{}, inputPermission: PermCreateAdverts, expectedError: ErrAuthRequired},
+		{name: "Buyer reads", inputPrincipal: buyer, inputPermission: PermReadAdverts},
+		{
+			name:            "Buyer creates",
+			inputPrincipal:  buyer,
+			inputPermission: PermCreateAdverts,
+			expectedError:   &PermissionError{Permission: PermCreateAdverts},
+		},
+		{name: "Seller creates", inputPrincipal: owner, inputPermission: PermCreateAdverts},
+		{
+			name:            "Seller moderates",
+			inputPrincipal:  owner,
+			inputPermission: PermModerateAdverts,
+			expectedError:   &PermissionError{Permission: PermModerateAdverts},
+		},
+		{name: "Moderator moderates", inputPrincipal: moderator, inputPermission: PermModerateAdverts},
+		{
+			name:            "Moderator manages categories",
+			inputPrincipal:  moderator,
+			inputPermission: PermManageCategories,
+			expectedError:   &PermissionError{Permission: PermManageCategories},
+		},
+		{name: "Admin manages users", inputPrincipal: admin, inputPermission: PermManageUsers},
+		{name: "Read key reads own", inputPrincipal: readKey, inputPermission: PermReadOwnAdverts},
+		{
+			// the role of the owner allows it, the scope of the key does not
+			name:            "Read key creates",
+			inputPrincipal:  readKey,
+			inputPermission: PermCreateAdverts,
+			expectedError:   &PermissionError{Permission: PermCreateAdverts},
+		},
+		{name: "Write key creates", inputPrincipal: writeKey, inputPermission: PermCreateAdverts},
+		{
+			// the scope of the key allows it, the role of the owner does not
+			name:            "Write key updates any",
+			inputPrincipal:  writeKey,
+			inputPermission: PermUpdateAnyAdverts,
+			expectedError:   &PermissionError{Permission: PermUpdateAnyAdverts},
+		},
+		{name: "Admin key manages keys", inputPrincipal: adminKey, inputPermission: PermManageAPIKeys},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, Authorize(test.inputPrincipal, test.inputPermission), test.expectedError)
+		})
+	}
+}
+
+func TestAuthorizeAdvert(t *testing.T) {
+	assert.Equal(t, authorizeAdvert(owner, PermUpdateOwnAdverts, PermUpdateAnyAdverts, intPtr(7)), nil)
+	assert.Equal(t, authorizeAdvert(owner, PermUpdateOwnAdverts, PermUpdateAnyAdverts, intPtr(8)),
+		&PermissionError{Permission: PermUpdateAnyAdverts})
+	assert.Equal(t, authorizeAdvert(owner, PermUpdateOwnAdverts, PermUpdateAnyAdverts, nil),
+		&PermissionError{Permission: PermUpdateAnyAdverts})
+	assert.Equal(t, authorizeAdvert(admin, PermUpdateOwnAdverts, PermUpdateAnyAdverts, nil), nil)
+
+	// a buyer turned from a seller keeps the adverts but cannot change them
+	buyer := model.Principal{UserId: 7, Role: model.RoleBuyer}
+	assert.Equal(t, authorizeAdvert(buyer, PermDeleteOwnAdverts, PermDeleteAnyAdverts, intPtr(7)),
+		&PermissionError{Permission: PermDeleteOwnAdverts})
+}
+
+func TestPermissionError(t *testing.T) {
+	err := error(&PermissionError{Permission: PermModerateAdverts})
+	assert.Equal(t, err.Error(), `permission "adverts:moderate" is required`)
+	assert.Equal(t, errors.Is(err, apperror.ErrForbidden), true)
+	assert.Equal(t, errors.Is(err, &PermissionError{Permission: PermModerateAdverts}), true)
+	assert.Equal(t, errors.Is(err, &PermissionError{Permission: PermManageUsers}), false)
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+var ErrContentBlocked = apperror.New(apperror.ErrValidation, "advertisement is blocked by content rules")
+
+// ContentRule inspects an advert before it is saved. A rule that does not
+// fire returns model.VerdictAllow.
+type ContentRule interface {
+	Name() string
+	Check(advert model.Advert) (model.Verdict, string)
+}
+
+// BlockedError carries the rules that fired on a blocked advert.
+type BlockedError struct {
+	Hits model.RuleHits
+}
+
+func (e *BlockedError) Error() string {
+	return ErrContentBlocked.Error()
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrContentBlocked
+}
+
+type ScreeningConfig struct {
+	BannedWordsFile     string `toml:"banned_words_file"`
+	BannedWordsVerdict  string `toml:"banned_words_verdict"`
+	ContactsVerdict     string `toml:"contacts_verdict"`
+	CapsTitleVerdict    string `toml:"caps_title_verdict"`
+	CapsTitleMinLetters int    `toml:"caps_title_min_letters"`
+	PriceOutlierVerdict string `toml:"price_outlier_verdict"`
+	PriceOutlierMin     int    `toml:"price_outlier_min"`
+	PriceOutlierMax     int    `toml:"price_outlier_max"`
+}
+
+// NewContentRules builds the built-in rules from the config. A rule with an
+// empty or "allow" verdict is switched off.
+func NewContentRules(config ScreeningConfig) ([]ContentRule, error) {
+	var rules []ContentRule
+
+	verdict, err := parseVerdict(config.BannedWordsVerdict)
+	if err != nil {
+		return nil, err
+	}
+	if verdict != model.VerdictAllow && config.BannedWordsFile != "" {
+		words, err := loadWordList(config.BannedWordsFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, NewBannedWordsRule(words, verdict))
+	}
+
+	verdict, err = parseVerdict(config.ContactsVerdict)
+	if err != nil {
+		return nil, err
+	}
+	if verdict != model.VerdictAllow {
+		rules = append(rules, NewContactsRule(verdict))
+	}
+
+	verdict, err = parseVerdict(config.CapsTitleVerdict)
+	if err != nil {
+		return nil, err
+	}
+	if verdict != model.VerdictAllow {
+		rules = append(rules, NewCapsTitleRule(config.CapsTitleMinLetters, verdict))
+	}
+
+	verdict, err = parseVerdict(config.PriceOutlierVerdict)
+	if err != nil {
+		return nil, err
+	}
+	if verdict != model.VerdictAllow {
+		rules = append(rules, NewPriceOutlierRule(config.PriceOutlierMin, config.PriceOutlierMax, verdict))
+	}
+
+	return rules, nil
+}
+
+// screen runs every rule and returns the strongest verdict with the rules
+// that fired.
+func screen(rules []ContentRule, advert model.Advert) (model.Verdict, model.RuleHits) {
+	result := model.VerdictAllow
+	hits := make(model.RuleHits, 0)
+	for _, rule := range rules {
+		verdict, message := rule.Check(advert)
+		if verdict == model.VerdictAllow {
+			continue
+		}
+		hits = append(hits, model.RuleHit{Rule: rule.Name(), Verdict: verdict, Message: message})
+		if verdict == model.VerdictBlock || result == model.VerdictAllow {
+			result = verdict
+		}
+	}
+	return result, hits
+}
+
+func parseVerdict(value string) (model.Verdict, error) {
+	switch model.Verdict(strings.ToLower(value)) {
+	case "", model.VerdictAllow:
+		return model.VerdictAllow, nil
+	case model.VerdictFlag:
+		return model.VerdictFlag, nil
+	case model.VerdictBlock:
+		return model.VerdictBlock, nil
+	}
+	return "", fmt.Errorf("unknown screening verdict %q", value)
+}
+
+func loadWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+type BannedWordsRule struct {
+	words   map[string]bool
+	verdict model.Verdict
+}
+
+func NewBannedWordsRule(words []string, verdict model.Verdict) *BannedWordsRule {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[strings.ToLower(word)] = true
+	}
+	return &BannedWordsRule{words: set, verdict: verdict}
+}
+
+func (r *BannedWordsRule) Name() string {
+	return "banned_words"
+}
+
+func (r *BannedWordsRule) Check(advert model.Advert) (model.Verdict, string) {
+	var found []string
+	seen := make(map[string]bool)
+	for _, word := range splitWords(advert.Name + " " + advert.Description) {
+		word = strings.ToLower(word)
+		if r.words[word] && !seen[word] {
+			seen[word] = true
+			found = append(found, word)
+		}
+	}
+	if len(found) == 0 {
+		return model.VerdictAllow, ""
+	}
+	return r.verdict, "banned words: " + strings.Join(found, ", ")
+}
+
+var (
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s\-()]{8,}\d`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+// ContactsRule catches phone numbers and e-mails in the description:
+// sellers are expected to communicate through the service.
+type ContactsRule struct {
+	verdict model.Verdict
+}
+
+func NewContactsRule(verdict model.Verdict) *ContactsRule {
+	return &ContactsRule{verdict: verdict}
+}
+
+func (r *ContactsRule) Name() string {
+	return "contacts_in_description"
+}
+
+func (r *ContactsRule) Check(advert model.Advert) (model.Verdict, string) {
+	if emailPattern.MatchString(advert.Description) {
+		return r.verdict, "description contains an e-mail address"
+	}
+	for _, match := range phonePattern.FindAllString(advert.Description, -1) {
+		if countDigits(match) >= 10 {
+			return r.verdict, "description contains a phone number"
+		}
+	}
+	return model.VerdictAllow, ""
+}
+
+type CapsTitleRule struct {
+	minLetters int
+	verdict    model.Verdict
+}
+
+func NewCapsTitleRule(minLetters int, verdict model.Verdict) *CapsTitleRule {
+	if minLetters < 1 {
+		minLetters = 5
+	}
+	return &CapsTitleRule{minLetters: minLetters, verdict: verdict}
+}
+
+func (r *CapsTitleRule) Name() string {
+	return "caps_title"
+}
+
+func (r *CapsTitleRule) Check(advert model.Advert) (model.Verdict, string) {
+	letters := 0
+	for _, c := range advert.Name {
+		if !unicode.IsLetter(c) {
+			continue
+		}
+		if unicode.IsLower(c) {
+			return model.VerdictAllow, ""
+		}
+		letters++
+	}
+	if letters < r.minLetters {
+		return model.VerdictAllow, ""
+	}
+	return r.verdict, "name is written in capital letters"
+}
+
+type PriceOutlierRule struct {
+	min     int
+	max     int
+	verdict model.Verdict
+}
+
+func NewPriceOutlierRule(min, max int, verdict model.Verdict) *PriceOutlierRule {
+	return &PriceOutlierRule{min: min, max: max, verdict: verdict}
+}
+
+func (r *PriceOutlierRule) Name() string {
+	return "price_outlier"
+}
+
+// Check flags the prices out of the range, a zero bound is not checked. The
+// free adverts (price 0) are given away on purpose and are never below the
+// range.
+func (r *PriceOutlierRule) Check(advert model.Advert) (model.Verdict, string) {
+	if r.min > 0 && advert.Price > 0 && advert.Price < r.min {
+		return r.verdict, fmt.Sprintf("price is lower than %d", r.min)
+	}
+	if r.max > 0 && advert.Price > r.max {
+		return r.verdict, fmt.Sprintf("price is higher than %d", r.max)
+	}
+	return model.VerdictAllow, ""
+}
+
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+}
+
+func countDigits(s string) int {
+	count := 0
+	for _, c := range s {
+		if unicode.IsDigit(c) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+func TestScreening_Rules(t *testing.T) {
+	tests := []struct {
+		name            string
+		rule            ContentRule
+		inputAdvert     model.Advert
+		expectedVerdict model.Verdict
+		expectedMessage string
+	}{
+		{
+			name:            "Banned word in name",
+			rule:            NewBannedWordsRule([]string{"Казино"}, model.VerdictBlock),
+			inputAdvert:     model.Advert{Name: "Фишки для казино", Description: "desc-test"},
+			expectedVerdict: model.VerdictBlock,
+			expectedMessage: "banned words: казино",
+		},
+		{
+			name:            "Banned word as a part of another word",
+			rule:            NewBannedWordsRule([]string{"casino"}, model.VerdictBlock),
+			inputAdvert:     model.Advert{Name: "casinoroyale", Description: "desc-test"},
+			expectedVerdict: model.VerdictAllow,
+		},
+		{
+			name:            "E-mail in description",
+			rule:            NewContactsRule(model.VerdictFlag),
+			inputAdvert:     model.Advert{Name: "name-test", Description: "write to seller@example.com"},
+			expectedVerdict: model.VerdictFlag,
+			expectedMessage: "description contains an e-mail address",
+		},
+		{
+			name:            "Phone in description",
+			rule:            NewContactsRule(model.VerdictFlag),
+			inputAdvert:     model.Advert{Name: "name-test", Description: "звоните +7 (999) 123-45-67"},
+			expectedVerdict: model.VerdictFlag,
+			expectedMessage: "description contains a phone number",
+		},
+		{
+			name:            "Short number in description",
+			rule:            NewContactsRule(model.VerdictFlag),
+			inputAdvert:     model.Advert{Name: "name-test", Description: "пробег 120 000 км"},
+			expectedVerdict: model.VerdictAllow,
+		},
+		{
+			name:            "Caps title",
+			rule:            NewCapsTitleRule(0, model.VerdictFlag),
+			inputAdvert:     model.Advert{Name: "ПРОДАМ ГАРАЖ!"},
+			expectedVerdict: model.VerdictFlag,
+			expectedMessage: "name is written in capital letters",
+		},
+		{
+			name:            "Short caps title",
+			rule:            NewCapsTitleRule(5, model.VerdictFlag),
+			inputAdvert:     model.Advert{Name: "BMW X5"},
+			expectedVerdict: model.VerdictAllow,
+		},
+		{
+			name:            "Price is too low",
+			rule:            NewPriceOutlierRule(10, 1000, model.VerdictFlag),
+			inputAdvert:     model.Advert{Price: 1},
+			expectedVerdict: model.VerdictFlag,
+			expectedMessage: "price is lower than 10",
+		},
+		{
+			name:            "Free advert",
+			rule:            NewPriceOutlierRule(10, 1000, model.VerdictFlag),
+			inputAdvert:     model.Advert{Price: 0},
+			expectedVerdict: model.VerdictAllow,
+		},
+		{
+			name:            "Price is too high",
+			rule:            NewPriceOutlierRule(10, 1000, model.VerdictFlag),
+			inputAdvert:     model.Advert{Price: 1001},
+			expectedVerdict: model.VerdictFlag,
+			expectedMessage: "price is higher than 1000",
+		},
+		{
+			name:            "Price in range",
+			rule:            NewPriceOutlierRule(10, 1000, model.VerdictFlag),
+			inputAdvert:     model.Advert{Price: 500},
+			expectedVerdict: model.VerdictAllow,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verdict, message := test.rule.Check(test.inputAdvert)
+			assert.Equal(t, verdict, test.expectedVerdict)
+			assert.Equal(t, message, test.expectedMessage)
+		})
+	}
+}
+
+func TestScreening_screen(t *testing.T) {
+	rules := []ContentRule{
+		NewCapsTitleRule(5, model.VerdictFlag),
+		NewBannedWordsRule([]string{"casino"}, model.VerdictBlock),
+		NewContactsRule(model.VerdictFlag),
+	}
+
+	verdict, hits := screen(rules, model.Advert{Name: "CASINO CHIPS", Description: "desc-test"})
+	assert.Equal(t, verdict, model.VerdictBlock)
+	assert.Equal(t, hits, model.RuleHits{
+		{Rule: "caps_title", Verdict: model.VerdictFlag, Message: "name is written in capital letters"},
+		{Rule: "banned_words", Verdict: model.VerdictBlock, Message: "banned words: casino"},
+	})
+
+	verdict, hits = screen(rules, model.Advert{Name: "chips", Description: "desc-test"})
+	assert.Equal(t, verdict, model.VerdictAllow)
+	assert.Equal(t, hits, model.RuleHits{})
+}
+
+func TestScreening_NewContentRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banned_words.txt")
+	if err := ioutil.WriteFile(path, []byte("# comment\ncasino\n\ndrugs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := NewContentRules(ScreeningConfig{
+		BannedWordsFile:    path,
+		BannedWordsVerdict: "block",
+		ContactsVerdict:    "allow",
+		CapsTitleVerdict:   "FLAG",
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rules), 2)
+	assert.Equal(t, rules[0].Name(), "banned_words")
+	assert.Equal(t, rules[1].Name(), "caps_title")
+
+	verdict, _ := rules[0].Check(model.Advert{Name: "drugs"})
+	assert.Equal(t, verdict, model.VerdictBlock)
+
+	_, err = NewContentRules(ScreeningConfig{ContactsVerdict: "reject"})
+	assert.Equal(t, err.Error(), `unknown screening verdict "reject"`)
+
+	_, err = NewContentRules(ScreeningConfig{BannedWordsFile: filepath.Join(t.TempDir(), "missing.txt"), BannedWordsVerdict: "block"})
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/search"
+)
+
+// SearchAdverts runs a full-text search narrowed by the list filters. The
+// results are ordered by relevance unless a list order is asked for, pages
+// are selected by number only.
+func (s *AdvertService) SearchAdverts(principal model.Principal, query model.SearchQuery) (model.SearchResult, error) {
+	if err := Authorize(principal, PermReadAdverts); err != nil {
+		return model.SearchResult{}, err
+	}
+	query.Text = strings.TrimSpace(query.Text)
+	if err := validateSearchQuery(query); err != nil {
+		return model.SearchResult{}, err
+	}
+	if err := s.resolveCategory(&query.ListQuery); err != nil {
+		return model.SearchResult{}, err
+	}
+	if query.Count == "" {
+		query.Count = s.list.CountMode
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	pageSize := s.list.pageSize(query.PageSize)
+
+	page := model.ListPage{Offset: (query.Page - 1) * pageSize, Limit: pageSize + 1, OrderField: model.SearchRelevance}
+	if query.OrderBy != "" && query.OrderBy != model.SearchRelevance {
+		order := strings.Split(query.OrderBy, "_")
+		page.OrderField, page.OrderDirect = order[0], order[1]
+	}
+
+	hits, err := s.index.Search(query, page)
+	if err != nil {
+		return model.SearchResult{}, err
+	}
+	more := len(hits) > pageSize
+	if more {
+		hits = hits[:pageSize]
+	}
+	if hits == nil {
+		hits = []model.SearchHit{}
+	}
+
+	result := model.SearchResult{Items: hits, Page: query.Page, PageSize: pageSize}
+	count := func() (int, error) { return s.index.Count(query, query.Count) }
+	if result.Total, err = listTotal(query.Page, page.Offset, len(hits), more, count); err != nil {
+		return model.SearchResult{}, err
+	}
+	result.TotalPages = (result.Total + pageSize - 1) / pageSize
+	return result, nil
+}
+
+// Suggest returns the advert names completing prefix. The limit falls back
+// to the default when it is not set and is cut to the maximum.
+func (s *AdvertService) Suggest(principal model.Principal, prefix string, limit int) (model.Suggestions, error) {
+	if err := Authorize(principal, PermReadAdverts); err != nil {
+		return model.Suggestions{}, err
+	}
+	errs := &ValidationError{}
+	if strings.TrimSpace(prefix) == "" {
+		errs.Add("prefix", CodeRequired, `the field "prefix" is required`, nil)
+	} else if utf8.RuneCountInString(prefix) > maxQueryLength {
+		errs.Add("prefix", CodeMaxLength, fmt.Sprintf(`length of the field "prefix" should not exceed %d`, maxQueryLength),
+			map[string]interface{}{"max": maxQueryLength})
+	}
+	if err := errs.Err(); err != nil {
+		return model.Suggestions{}, err
+	}
+
+	if limit < 1 {
+		limit = search.DefaultSuggestLimit
+	}
+	if limit > search.MaxSuggestLimit {
+		limit = search.MaxSuggestLimit
+	}
+	if s.suggester == nil {
+		return model.Suggestions{Prefix: prefix, Completions: []model.Completion{}}, nil
+	}
+	return s.suggester.Suggest(prefix, limit), nil
+}
+
+// WithUpdaters returns the index passing the changes of adverts on to the
+// updaters as well, e.g. to the suggester. The first error is returned.
+func WithUpdaters(index SearchIndex, updaters ...IndexUpdater) SearchIndex {
+	return &updatingIndex{SearchIndex: index, updaters: updaters}
+}
+
+type updatingIndex struct {
+	SearchIndex
+	updaters []IndexUpdater
+}
+
+func (x *updatingIndex) Update(advertId int) error {
+	err := x.SearchIndex.Update(advertId)
+	for _, updater := range x.updaters {
+		if updateErr := updater.Update(advertId); err == nil {
+			err = updateErr
+		}
+	}
+	return err
+}
+
+func (x *updatingIndex) Remove(advertId int) error {
+	err := x.SearchIndex.Remove(advertId)
+	for _, updater := range x.updaters {
+		if removeErr := updater.Remove(advertId); err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
+
+// updateIndex updates the advert in the search index, index may be nil. The
+// advert is already saved, so a failure is logged and does not fail the
+// request: the index catches up with the next change or rebuild.
+func updateIndex(index SearchIndex, advertId int) {
+	if index == nil {
+		return
+	}
+	if err := index.Update(advertId); err != nil {
+		log.Printf("failed to update advert %d in the search index: %s", advertId, err.Error())
+	}
+}
+
+func removeFromIndex(index SearchIndex, advertId int) {
+	if index == nil {
+		return
+	}
+	if err := index.Remove(advertId); err != nil {
+		log.Printf("failed to remove advert %d from the search index: %s", advertId, err.Error())
+	}
+}
+
+// validateSearchQuery checks the search text along with the list filters.
+// The substring filter q of the list is not used by the search.
+func validateSearchQuery(query model.SearchQuery) error {
+	errs := &ValidationError{}
+	if err := validateListQuery(query.ListQuery); err != nil {
+		errs = err.(*ValidationError)
+	}
+
+	if strings.HasPrefix(query.OrderBy, model.OrderDistance) {
+		errs.Add("order_by", CodeInvalid, "the search can not be ordered by distance", nil)
+	}
+	if query.Text == "" {
+		errs.Add("q", CodeRequired, `the field "q" is required`, nil)
+	} else if utf8.RuneCountInString(query.Text) > maxQueryLength {
+		errs.Add("q", CodeMaxLength, fmt.Sprintf(`length of the field "q" should not exceed %d`, maxQueryLength),
+			map[string]interface{}{"max": maxQueryLength})
+	}
+
+	return errs.Err()
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+func TestService_SearchAdverts(t *testing.T) {
+	hits := func(from, to int) []model.SearchHit {
+		list := []model.SearchHit{}
+		for id := from; id <= to; id++ {
+			list = append(list, model.SearchHit{Advert: model.Advert{Id: id}})
+		}
+		return list
+	}
+
+	type mockBehaviorType func(*mock.MockSearchIndex)
+	tests := []struct {
+		name          string
+		inputQuery    model.SearchQuery
+		mockBehavior  mockBehaviorType
+		expectedIds   []int
+		expectedTotal int
+		expectedError error
+	}{
+		{
+			name:       "Relevance",
+			inputQuery: model.SearchQuery{Text: "  bike  "},
+			mockBehavior: func(r *mock.MockSearchIndex) {
+				query := model.SearchQuery{ListQuery: model.ListQuery{Page: 1, Count: "exact"}, Text: "bike"}
+				r.EXPECT().Search(query, model.ListPage{Limit: 11, OrderField: "relevance"}).Return(hits(1, 3), nil)
+			},
+			expectedIds:   []int{1, 2, 3},
+			expectedTotal: 3,
+		},
+		{
+			name:       "Price order with more pages",
+			inputQuery: model.SearchQuery{ListQuery: model.ListQuery{Page: 2, OrderBy: "price_asc", Count: "estimated"}, Text: "bike"},
+			mockBehavior: func(r *mock.MockSearchIndex) {
+				query := model.SearchQuery{ListQuery: model.ListQuery{Page: 2, OrderBy: "price_asc", Count: "estimated"}, Text: "bike"}
+				r.EXPECT().Search(query, model.ListPage{Offset: 10, Limit: 11, OrderField: "price", OrderDirect: "asc"}).Return(hits(11, 21), nil)
+				r.EXPECT().Count(query, "estimated").Return(40, nil)
+			},
+			expectedIds:   []int{11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+			expectedTotal: 40,
+		},
+		{
+			name:          "Empty text",
+			inputQuery:    model.SearchQuery{Text: " "},
+			mockBehavior:  func(r *mock.MockSearchIndex) {},
+			expectedError: &ValidationError{Errors: []FieldError{{Field: "q", Code: CodeRequired, Message: `the field "q" is required`}}},
+		},
+		{
+			name:          "Invalid count",
+			inputQuery:    model.SearchQuery{ListQuery: model.ListQuery{Count: "roughly"}, Text: "bike"},
+			mockBehavior:  func(r *mock.MockSearchIndex) {},
+			expectedError: &ValidationError{Errors: []FieldError{{Field: "count", Code: CodeInvalid, Message: "the count must be exact or estimated"}}},
+		},
+		{
+			name:       "Index error",
+			inputQuery: model.SearchQuery{Text: "bike"},
+			mockBehavior: func(r *mock.MockSearchIndex) {
+				r.EXPECT().Search(gomock.Any(), gomock.Any()).Return(nil, errors.New("something went wrong"))
+			},
+			expectedError: errors.New("something went wrong"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockIndex := mock.NewMockSearchIndex(c)
+			test.mockBehavior(mockIndex)
+
+			service := NewAdvertService(mock.NewMockRepository(c), existingCategories(c), mockIndex, nil, nil, LinkPolicy{}, ListConfig{})
+			result, err := service.SearchAdverts(model.Principal{}, test.inputQuery)
+			assert.Equal(t, err, test.expectedError)
+			if err != nil {
+				return
+			}
+
+			ids := []int{}
+			for _, hit := range result.Items {
+				ids = append(ids, hit.Id)
+			}
+			assert.Equal(t, ids, test.expectedIds)
+			assert.Equal(t, result.Total, test.expectedTotal)
+		})
+	}
+}
+
+func TestService_updateIndex(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockIndex := mock.NewMockSearchIndex(c)
+	mockIndex.EXPECT().Update(1).Return(errors.New("something went wrong"))
+	mockIndex.EXPECT().Remove(2).Return(nil)
+
+	// index errors are logged only
+	updateIndex(mockIndex, 1)
+	removeFromIndex(mockIndex, 2)
+	updateIndex(nil, 3)
+	removeFromIndex(nil, 3)
+}
+
+func TestService_Suggest(t *testing.T) {
+	type mockBehaviorType func(*mock.MockSuggester)
+	tests := []struct {
+		name          string
+		inputPrefix   string
+		inputLimit    int
+		mockBehavior  mockBehaviorType
+		expected      model.Suggestions
+		expectedError error
+	}{
+		{
+			name:        "Default limit",
+			inputPrefix: "горный вел",
+			mockBehavior: func(s *mock.MockSuggester) {
+				s.EXPECT().Suggest("горный вел", 10).Return(model.Suggestions{Prefix: "горный вел", Completions: []model.Completion{{Text: "Горный велосипед", Count: 2}}})
+			},
+			expected: model.Suggestions{Prefix: "горный вел", Completions: []model.Completion{{Text: "Горный велосипед", Count: 2}}},
+		},
+		{
+			name:        "Limit is capped",
+			inputPrefix: "вел",
+			inputLimit:  100,
+			mockBehavior: func(s *mock.MockSuggester) {
+				s.EXPECT().Suggest("вел", 20).Return(model.Suggestions{Prefix: "вел", Completions: []model.Completion{}})
+			},
+			expected: model.Suggestions{Prefix: "вел", Completions: []model.Completion{}},
+		},
+		{
+			name:          "Empty prefix",
+			inputPrefix:   "  ",
+			mockBehavior:  func(s *mock.MockSuggester) {},
+			expectedError: &ValidationError{Errors: []FieldError{{Field: "prefix", Code: CodeRequired, Message: `the field "prefix" is required`}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockSuggester := mock.NewMockSuggester(c)
+			test.mockBehavior(mockSuggester)
+
+			service := NewAdvertService(mock.NewMockRepository(c), existingCategories(c), nil, mockSuggester, nil, LinkPolicy{}, ListConfig{})
+			suggestions, err := service.Suggest(model.Principal{}, test.inputPrefix, test.inputLimit)
+			assert.Equal(t, err, test.expectedError)
+			assert.Equal(t, suggestions, test.expected)
+		})
+	}
+}
+
+func TestWithUpdaters(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockIndex := mock.NewMockSearchIndex(c)
+	mockUpdater := mock.NewMockIndexUpdater(c)
+	mockIndex.EXPECT().Update(1).Return(nil)
+	mockUpdater.EXPECT().Update(1).Return(errors.New("something went wrong"))
+	mockIndex.EXPECT().Remove(2).Return(errors.New("connection reset"))
+	mockUpdater.EXPECT().Remove(2).Return(nil)
+
+	index := WithUpdaters(mockIndex, mockUpdater)
+	assert.Equal(t, index.Update(1), errors.New("something went wrong"))
+	assert.Equal(t, index.Remove(2), errors.New("connection reset"))
+}
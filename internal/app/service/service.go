@@ -1,9 +1,82 @@
 package service
 
-import "github.com/paramonies/avito-rest-advert/internal/app/model"
+import (
+	"io"
 
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+// Service runs the advert operations, every operation is checked against
+// the permissions of the principal.
 type Service interface {
-	CreateAdvert(model.Advert) (int, error)
-	GetAdvertById(int, []string) (model.Advert, error)
-	GetAdvertList(int, string) ([]model.Advert, error)
+	CreateAdvert(model.Principal, model.Advert) (int, model.RuleHits, error)
+	GetAdvertById(model.Principal, int, []string) (model.Advert, error)
+	GetOwnAdverts(model.Principal) ([]model.Advert, error)
+	GetAdvertList(model.Principal, model.ListQuery) (model.AdvertList, error)
+	SearchAdverts(model.Principal, model.SearchQuery) (model.SearchResult, error)
+	Suggest(model.Principal, string, int) (model.Suggestions, error)
+	UpdateAdvert(model.Principal, int, model.Advert) error
+	PatchAdvert(model.Principal, int, []byte) error
+	DeleteAdvert(model.Principal, int) error
+	ArchiveAdvert(model.Principal, int) error
+	RestoreAdvert(model.Principal, int) error
+	TransitionAdvert(model.Principal, int, model.AdvertStatus) error
+	ReorderPictures(model.Principal, int, []int) (model.Pictures, error)
+	SetMainPicture(model.Principal, int, int) (model.Pictures, error)
+}
+
+// IndexUpdater is told about changed adverts. Update reloads the advert and
+// drops it once it is no longer public.
+type IndexUpdater interface {
+	Update(int) error
+	Remove(int) error
+}
+
+// SearchIndex finds the public adverts by text.
+type SearchIndex interface {
+	IndexUpdater
+	Search(model.SearchQuery, model.ListPage) ([]model.SearchHit, error)
+	Count(model.SearchQuery, string) (int, error)
+}
+
+// Suggester completes typed prefixes to advert names.
+type Suggester interface {
+	Suggest(string, int) model.Suggestions
+}
+
+type Categories interface {
+	GetCategoryTree() ([]model.Category, error)
+	GetCategory(int) (model.Category, error)
+	CreateCategory(model.Category) (int, error)
+	UpdateCategory(int, model.Category) error
+	DeleteCategory(int) error
+}
+
+type Moderation interface {
+	ClaimQueue(string, int) ([]model.ModerationTask, error)
+	Decide(model.ModerationDecision) (model.ModerationDecision, error)
+	GetDecisions(int) ([]model.ModerationDecision, error)
+}
+
+type Uploader interface {
+	UploadPicture(model.Principal, int, io.Reader) (model.Picture, error)
+}
+
+// Users signs the users up and in. Authenticate returns the principal of a
+// bearer token issued by Login or by the external issuer. SetRole changes
+// the role of a user.
+type Users interface {
+	Register(model.Credentials, model.Role) (model.User, error)
+	Login(model.Credentials) (model.Token, error)
+	Authenticate(string) (model.Principal, error)
+	SetRole(int, model.Role) (model.User, error)
+}
+
+// APIKeys issues the keys of the partner feeds. AuthenticateAPIKey returns
+// the principal of a key and counts the request against its daily quota.
+type APIKeys interface {
+	IssueAPIKey(model.APIKey) (model.APIKey, error)
+	GetAPIKeys() ([]model.APIKey, error)
+	RevokeAPIKey(int) error
+	AuthenticateAPIKey(string) (model.Principal, error)
 }
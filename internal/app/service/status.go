@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+var ErrTransitionNotAllowed = apperror.New(apperror.ErrConflict, "status transition is not allowed")
+
+type transition struct {
+	from model.AdvertStatus
+	to   model.AdvertStatus
+}
+
+// transitions lists every legal status change of an advert; the value
+// tells whether the change may be made by a moderator only. A pending
+// advert is published by the decision of the moderator it is claimed by,
+// see ModerationService.Decide, never by a transition.
+var transitions = map[transition]bool{
+	{model.StatusDraft, model.StatusPending}:   false,
+	{model.StatusPending, model.StatusDraft}:   false,
+	{model.StatusActive, model.StatusExpired}:  false,
+	{model.StatusExpired, model.StatusPending}: false,
+	{model.StatusBlocked, model.StatusDraft}:   true,
+
+	{model.StatusDraft, model.StatusBlocked}:   true,
+	{model.StatusPending, model.StatusBlocked}: true,
+	{model.StatusActive, model.StatusBlocked}:  true,
+	{model.StatusExpired, model.StatusBlocked}: true,
+}
+
+// checkTransition reports whether the transition is allowed and whether it
+// is a moderator one.
+func checkTransition(from, to model.AdvertStatus) (bool, error) {
+	moderatorOnly, ok := transitions[transition{from, to}]
+	if !ok {
+		return false, fmt.Errorf("%w: from %s to %s", ErrTransitionNotAllowed, from, to)
+	}
+	return moderatorOnly, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/imaging"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/storage"
+)
+
+const DefaultMaxUploadBytes = 5 << 20
+
+// pictureTypes are the accepted picture formats and the extensions they are
+// stored with. The format is sniffed from the content, the name and the
+// declared type of the uploaded file are not trusted.
+var pictureTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// VariantQueue accepts uploaded pictures for variant generation, see
+// VariantPool.
+type VariantQueue interface {
+	Enqueue(VariantJob) bool
+}
+
+type UploadService struct {
+	repo     repository.Repository
+	index    SearchIndex
+	store    storage.BlobStore
+	variants VariantQueue
+	maxSize  int64
+}
+
+// NewUploadService creates the service, variants may be nil to store the
+// uploaded pictures only, index may be nil as well.
+func NewUploadService(repo repository.Repository, index SearchIndex, store storage.BlobStore, variants VariantQueue, maxSize int64) *UploadService {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxUploadBytes
+	}
+	return &UploadService{repo: repo, index: index, store: store, variants: variants, maxSize: maxSize}
+}
+
+// UploadPicture stores the file without metadata, appends it to the advert
+// pictures and queues generation of its variants. An active advert goes
+// back to pending with the new picture, so it leaves the search index until
+// a moderator approves it again.
+func (s *UploadService) UploadPicture(principal model.Principal, advertId int, file io.Reader) (model.Picture, error) {
+	advert, err := s.repo.GetAdvertById(advertId)
+	if err != nil {
+		return model.Picture{}, err
+	}
+	if err := authorizeAdvert(principal, PermUpdateOwnAdverts, PermUpdateAnyAdverts, advert.OwnerId); err != nil {
+		return model.Picture{}, err
+	}
+	if len(advert.Pictures) >= MaxPictures {
+		return model.Picture{}, pictureLimitError()
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, s.maxSize+1))
+	if err != nil {
+		return model.Picture{}, err
+	}
+	contentType, err := s.checkFile(data)
+	if err != nil {
+		return model.Picture{}, err
+	}
+	data, err = imaging.StripMetadata(data, contentType)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		errs := &ValidationError{}
+		errs.Add("file", CodeMaxSize, fmt.Sprintf("the picture should not exceed %d pixels", imaging.MaxPixels),
+			map[string]interface{}{"max": imaging.MaxPixels})
+		return model.Picture{}, errs
+	}
+	if err != nil {
+		errs := &ValidationError{}
+		errs.Add("file", CodeInvalid, "the file is not a valid picture", nil)
+		return model.Picture{}, errs
+	}
+
+	key, err := pictureKey(advertId, pictureTypes[contentType])
+	if err != nil {
+		return model.Picture{}, err
+	}
+	url, err := s.store.Put(key, contentType, data)
+	if err != nil {
+		return model.Picture{}, apperror.Wrap(apperror.ErrUnavailable, err)
+	}
+
+	picture, err := s.repo.AddPicture(advertId, url, MaxPictures)
+	if err != nil {
+		if err := s.store.Delete(key); err != nil {
+			log.Printf("failed to delete orphaned picture %s: %s", key, err.Error())
+		}
+		if errors.Is(err, repository.ErrPictureLimit) {
+			return model.Picture{}, pictureLimitError()
+		}
+		return model.Picture{}, err
+	}
+	updateIndex(s.index, advertId)
+
+	if s.variants != nil {
+		job := VariantJob{AdvertId: advertId, URL: url, Key: key, Data: data}
+		if !s.variants.Enqueue(job) {
+			log.Printf("variant queue is full, picture %s is left without variants", key)
+		}
+	}
+	return picture, nil
+}
+
+func (s *UploadService) checkFile(data []byte) (string, error) {
+	errs := &ValidationError{}
+	if len(data) == 0 {
+		errs.Add("file", CodeRequired, `the field "file" is required`, nil)
+		return "", errs
+	}
+	if int64(len(data)) > s.maxSize {
+		errs.Add("file", CodeMaxSize, fmt.Sprintf("the file should not exceed %d bytes", s.maxSize),
+			map[string]interface{}{"max": s.maxSize})
+		return "", errs
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := pictureTypes[contentType]; !ok {
+		allowed := []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+		errs.Add("file", CodeContentType, fmt.Sprintf("unsupported file type %s, allowed types: %s", contentType, strings.Join(allowed, ", ")),
+			map[string]interface{}{"allowed": allowed})
+		return "", errs
+	}
+	return contentType, nil
+}
+
+// pictureKey makes a random key, so uploaded files never overwrite each
+// other and their URLs can not be guessed.
+func pictureKey(advertId int, ext string) (string, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	return pictureKeyPrefix(advertId) + hex.EncodeToString(name) + ext, nil
+}
+
+func pictureKeyPrefix(advertId int) string {
+	return fmt.Sprintf("adverts/%d/", advertId)
+}
+
+func pictureLimitError() error {
+	errs := &ValidationError{}
+	addPictureLimit(errs)
+	return errs
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+var pngFile = func() []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	return buf.Bytes()
+}()
+
+func TestService_UploadPicture(t *testing.T) {
+	const url = "http://localhost:8080/uploads/adverts/1/photo.png"
+	stored := model.Picture{Id: 4, URL: url, Position: 1}
+
+	type mockBehaviortype func(*mock.MockRepository, *mock.MockBlobStore)
+	tests := []struct {
+		name           string
+		inputFile      []byte
+		mockBehavior   mockBehaviortype
+		expectedResult model.Picture
+		expectedError  error
+	}{
+		{
+			name:      "OK",
+			inputFile: pngFile,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures()[:1], OwnerId: intPtr(7)}, nil)
+				s.EXPECT().Put(gomock.Any(), "image/png", pngFile).Return(url, nil)
+				r.EXPECT().AddPicture(1, url, MaxPictures).Return(stored, nil)
+			},
+			expectedResult: stored,
+			expectedError:  nil,
+		},
+		{
+			name:      "Too many pictures",
+			inputFile: pngFile,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures(), OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "pictures",
+				Code:    CodeMaxItems,
+				Message: `the field "pictures" must contain no more than 3 photos`,
+				Params:  map[string]interface{}{"max": MaxPictures},
+			}}},
+		},
+		{
+			name:      "Limit reached concurrently",
+			inputFile: pngFile,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{Pictures: storedPictures()[:2], OwnerId: intPtr(7)}, nil)
+				s.EXPECT().Put(gomock.Any(), "image/png", pngFile).Return(url, nil)
+				r.EXPECT().AddPicture(1, url, MaxPictures).Return(model.Picture{}, repository.ErrPictureLimit)
+				s.EXPECT().Delete(gomock.Any()).Return(nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "pictures",
+				Code:    CodeMaxItems,
+				Message: `the field "pictures" must contain no more than 3 photos`,
+				Params:  map[string]interface{}{"max": MaxPictures},
+			}}},
+		},
+		{
+			name:      "Too large",
+			inputFile: append(pngFile, bytes.Repeat([]byte{0}, 128)...),
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "file",
+				Code:    CodeMaxSize,
+				Message: "the file should not exceed 128 bytes",
+				Params:  map[string]interface{}{"max": int64(128)},
+			}}},
+		},
+		{
+			name:      "Not a picture",
+			inputFile: []byte("<html><body>hello</body></html>"),
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "file",
+				Code:    CodeContentType,
+				Message: "unsupported file type text/html; charset=utf-8, allowed types: image/jpeg, image/png, image/gif, image/webp",
+				Params:  map[string]interface{}{"allowed": []string{"image/jpeg", "image/png", "image/gif", "image/webp"}},
+			}}},
+		},
+		{
+			name:      "Broken picture",
+			inputFile: pngFile[:20],
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "file",
+				Code:    CodeInvalid,
+				Message: "the file is not a valid picture",
+			}}},
+		},
+		{
+			name:      "Empty file",
+			inputFile: nil,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{OwnerId: intPtr(7)}, nil)
+			},
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "file",
+				Code:    CodeRequired,
+				Message: `the field "file" is required`,
+			}}},
+		},
+		{
+			name:      "Advert not found",
+			inputFile: pngFile,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{}, repository.ErrAdvertNotFound)
+			},
+			expectedError: repository.ErrAdvertNotFound,
+		},
+		{
+			name:      "Not owner",
+			inputFile: pngFile,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				r.EXPECT().GetAdvertById(1).Return(model.Advert{OwnerId: intPtr(8)}, nil)
+			},
+			expectedError: &PermissionError{Permission: PermUpdateAnyAdverts},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockRepository(c)
+			mockStore := mock.NewMockBlobStore(c)
+			test.mockBehavior(mockRepository, mockStore)
+
+			service := NewUploadService(mockRepository, nil, mockStore, nil, 128)
+
+			result, err := service.UploadPicture(owner, 1, bytes.NewReader(test.inputFile))
+			assert.Equal(t, err, test.expectedError)
+			assert.Equal(t, result, test.expectedResult)
+		})
+	}
+}
+
+func TestService_UploadPictureStoreFailure(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockStore := mock.NewMockBlobStore(c)
+	mockRepository.EXPECT().GetAdvertById(1).Return(model.Advert{OwnerId: intPtr(7)}, nil)
+	mockStore.EXPECT().Put(gomock.Any(), "image/png", pngFile).Return("", errors.New("connection refused"))
+
+	service := NewUploadService(mockRepository, nil, mockStore, nil, 0)
+
+	_, err := service.UploadPicture(owner, 1, bytes.NewReader(pngFile))
+	assert.Equal(t, errors.Is(err, apperror.ErrUnavailable), true)
+}
+
+func TestService_UploadPictureUpdatesIndex(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockStore := mock.NewMockBlobStore(c)
+	mockIndex := mock.NewMockSearchIndex(c)
+	mockRepository.EXPECT().GetAdvertById(1).Return(model.Advert{Status: model.StatusActive, OwnerId: intPtr(7)}, nil)
+	mockStore.EXPECT().Put(gomock.Any(), "image/png", pngFile).Return("uploads/a.png", nil)
+	// the repository sends the active advert back to pending, the index
+	// reloads it and drops it until the picture is moderated
+	mockRepository.EXPECT().AddPicture(1, "uploads/a.png", MaxPictures).Return(model.Picture{Id: 1, URL: "uploads/a.png", IsMain: true}, nil)
+	mockIndex.EXPECT().Update(1).Return(nil)
+
+	service := NewUploadService(mockRepository, mockIndex, mockStore, nil, 0)
+
+	_, err := service.UploadPicture(owner, 1, bytes.NewReader(pngFile))
+	assert.Equal(t, err, nil)
+}
+
+func TestService_UploadPictureQueuesVariants(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockStore := mock.NewMockBlobStore(c)
+	mockRepository.EXPECT().GetAdvertById(1).Return(model.Advert{OwnerId: intPtr(7)}, nil)
+	mockStore.EXPECT().Put(gomock.Any(), "image/png", pngFile).Return("uploads/a.png", nil)
+	mockRepository.EXPECT().AddPicture(1, "uploads/a.png", MaxPictures).Return(model.Picture{Id: 1, URL: "uploads/a.png", IsMain: true}, nil)
+
+	// the workers are not started, so the job stays in the queue
+	pool := NewVariantPool(mockRepository, nil, mockStore, 1)
+	service := NewUploadService(mockRepository, nil, mockStore, pool, 0)
+
+	_, err := service.UploadPicture(owner, 1, bytes.NewReader(pngFile))
+	assert.Equal(t, err, nil)
+
+	job := <-pool.jobs
+	assert.Equal(t, job.AdvertId, 1)
+	assert.Equal(t, job.URL, "uploads/a.png")
+	assert.Equal(t, job.Data, pngFile)
+	assert.Equal(t, strings.HasPrefix(job.Key, "adverts/1/"), true)
+}
+
+func TestPictureKey(t *testing.T) {
+	first, err := pictureKey(7, ".png")
+	assert.Equal(t, err, nil)
+	second, _ := pictureKey(7, ".png")
+
+	assert.Equal(t, strings.HasPrefix(first, "adverts/7/"), true)
+	assert.Equal(t, strings.HasSuffix(first, ".png"), true)
+	assert.NotEqual(t, first, second)
+}
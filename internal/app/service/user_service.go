@@ -0,0 +1,265 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+	"github.com/paramonies/avito-rest-advert/internal/app/auth"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	DefaultTokenTTL = time.Hour
+
+	maxEmail    = 254
+	minPassword = 8
+	// bcrypt ignores the bytes after the 72th
+	maxPassword = 72
+)
+
+var (
+	ErrAuthRequired       = apperror.New(apperror.ErrUnauthorized, "authentication required")
+	ErrInvalidCredentials = apperror.New(apperror.ErrUnauthorized, "invalid email or password")
+	ErrInvalidToken       = apperror.New(apperror.ErrUnauthorized, "invalid or expired token")
+)
+
+// AuthConfig configures the tokens issued on login. When TokenSecret is
+// empty a random secret is used and the tokens issued before a restart
+// become invalid. The tokens of an external issuer are accepted when JWKS,
+// a JWKS file or a directory of them, is set, their role is capped at
+// ExternalRole, a seller when empty. When Audience is set the tokens have
+// to be issued for it.
+type AuthConfig struct {
+	TokenSecret  string `toml:"token_secret"`
+	TokenTTLMin  int    `toml:"token_ttl_min"`
+	JWKS         string `toml:"jwks"`
+	Audience     string `toml:"audience"`
+	ExternalRole string `toml:"external_role"`
+}
+
+// roleRanks orders the roles for capping, a role outranks the roles with
+// fewer permissions.
+var roleRanks = map[model.Role]int{
+	model.RoleBuyer:     1,
+	model.RoleSeller:    2,
+	model.RoleModerator: 3,
+	model.RoleAdmin:     4,
+}
+
+type UserService struct {
+	repo         repository.UserRepository
+	key          auth.HS256
+	keys         *auth.KeySet
+	jwks         string
+	audience     string
+	externalRole model.Role
+	ttl          time.Duration
+	cost         int
+	now          func() time.Time
+
+	// dummyHash is compared with the password of an unknown email, so the
+	// login takes as long as for a known one
+	dummyHash []byte
+}
+
+func NewUserService(repo repository.UserRepository, config AuthConfig) (*UserService, error) {
+	ttl := time.Duration(config.TokenTTLMin) * time.Minute
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	s := newUserService(repo, auth.NewHS256(config.TokenSecret), ttl, bcrypt.DefaultCost)
+	s.jwks = config.JWKS
+	s.audience = config.Audience
+	if config.ExternalRole != "" {
+		s.externalRole = model.Role(config.ExternalRole)
+		if !s.externalRole.Valid() {
+			return nil, fmt.Errorf("auth: unknown external_role %q", config.ExternalRole)
+		}
+	}
+	if err := s.ReloadKeys(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newUserService(repo repository.UserRepository, key auth.HS256, ttl time.Duration, cost int) *UserService {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("password"), cost)
+	if err != nil {
+		panic(err)
+	}
+	return &UserService{repo: repo, key: key, keys: auth.NewKeySet(key.Key()), externalRole: model.RoleSeller, ttl: ttl, cost: cost, now: time.Now, dummyHash: dummyHash}
+}
+
+// ReloadKeys loads the keys of the external issuer again, the tokens of the
+// removed keys stop being accepted. The keys are kept when loading fails.
+func (s *UserService) ReloadKeys() error {
+	keys := []auth.Key{s.key.Key()}
+	if s.jwks != "" {
+		loaded, err := auth.LoadJWKS(s.jwks)
+		if err != nil {
+			return fmt.Errorf("auth: failed to load the keys: %w", err)
+		}
+		keys = append(keys, loaded...)
+	}
+	s.keys.Set(keys)
+	return nil
+}
+
+// Keys is the number of the keys tokens are verified with, the signing key
+// of the service included.
+func (s *UserService) Keys() int {
+	return s.keys.Len()
+}
+
+// Register creates a user with the lower-cased email, the password is
+// stored as a bcrypt hash. A user signs up as a buyer or a seller, a seller
+// when the role is not given.
+func (s *UserService) Register(credentials model.Credentials, role model.Role) (model.User, error) {
+	email := strings.ToLower(strings.TrimSpace(credentials.Email))
+	if role == "" {
+		role = model.RoleSeller
+	}
+	if err := validateCredentials(email, credentials.Password, role); err != nil {
+		return model.User{}, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), s.cost)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user := model.User{Email: email, Role: role, PasswordHash: string(hash)}
+	if user.Id, err = s.repo.CreateUser(user); err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// Login checks the password and issues a bearer token. An unknown email and
+// a wrong password are not told apart.
+func (s *UserService) Login(credentials model.Credentials) (model.Token, error) {
+	user, err := s.repo.GetUserByEmail(strings.TrimSpace(credentials.Email))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(credentials.Password))
+		return model.Token{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return model.Token{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credentials.Password)) != nil {
+		return model.Token{}, ErrInvalidCredentials
+	}
+
+	now := s.now()
+	expiresAt := now.Add(s.ttl).Truncate(time.Second)
+	claims := auth.Claims{
+		Subject:   strconv.Itoa(user.Id),
+		Role:      string(user.Role),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+	if s.audience != "" {
+		claims.Audience = auth.Audience{s.audience}
+	}
+	token := s.key.Sign(claims)
+	return model.Token{Token: token, TokenType: "Bearer", ExpiresAt: expiresAt.UTC()}, nil
+}
+
+// Authenticate returns the user the token was issued to, the token is
+// signed by the service or by the external issuer. The subject of the token
+// is the id of the user. The role is read from the users on every request,
+// so a changed role applies at once and the tokens of a removed user stop
+// working. A user of the external issuer unknown to the service has the
+// role of the token, a seller's without the claim. The role of an external
+// token never outranks externalRole.
+func (s *UserService) Authenticate(token string) (model.Principal, error) {
+	now := s.now()
+	claims, err := s.key.Verify(token, now)
+	external := err != nil
+	if external {
+		claims, err = s.keys.Verify(token, now)
+	}
+	if err != nil {
+		return model.Principal{}, ErrInvalidToken
+	}
+	if s.audience != "" && !claims.Audience.Contains(s.audience) {
+		return model.Principal{}, ErrInvalidToken
+	}
+	userId, err := strconv.Atoi(claims.Subject)
+	if err != nil || userId <= 0 {
+		return model.Principal{}, ErrInvalidToken
+	}
+	role := model.Role(claims.Role)
+	if role == "" {
+		role = model.RoleSeller
+	}
+	if !role.Valid() {
+		return model.Principal{}, ErrInvalidToken
+	}
+
+	user, err := s.repo.GetUserById(userId)
+	switch {
+	case err == nil:
+		role = user.Role
+	case !errors.Is(err, repository.ErrUserNotFound):
+		return model.Principal{}, err
+	case !external:
+		return model.Principal{}, ErrInvalidToken
+	}
+	if external && roleRanks[role] > roleRanks[s.externalRole] {
+		role = s.externalRole
+	}
+	return model.Principal{UserId: userId, Role: role}, nil
+}
+
+// SetRole gives the user another role.
+func (s *UserService) SetRole(userId int, role model.Role) (model.User, error) {
+	if !role.Valid() {
+		errs := &ValidationError{}
+		errs.Add("role", CodeInvalid, fmt.Sprintf("the role must be one of: %s, %s, %s, %s",
+			model.RoleBuyer, model.RoleSeller, model.RoleModerator, model.RoleAdmin), nil)
+		return model.User{}, errs.Err()
+	}
+	if err := s.repo.SetUserRole(userId, role); err != nil {
+		return model.User{}, err
+	}
+	return s.repo.GetUserById(userId)
+}
+
+func validateCredentials(email, password string, role model.Role) error {
+	errs := &ValidationError{}
+	switch {
+	case email == "":
+		errs.Add("email", CodeRequired, `the field "email" is required`, nil)
+	case len(email) > maxEmail:
+		errs.Add("email", CodeMaxLength, fmt.Sprintf(`length of the field "email" should not exceed %d`, maxEmail),
+			map[string]interface{}{"max": maxEmail})
+	default:
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			errs.Add("email", CodeInvalid, "the email address is invalid", nil)
+		}
+	}
+
+	switch {
+	case password == "":
+		errs.Add("password", CodeRequired, `the field "password" is required`, nil)
+	case len(password) < minPassword:
+		errs.Add("password", CodeMinLength, fmt.Sprintf(`length of the field "password" should be at least %d`, minPassword),
+			map[string]interface{}{"min": minPassword})
+	case len(password) > maxPassword:
+		errs.Add("password", CodeMaxLength, fmt.Sprintf(`length of the field "password" should not exceed %d`, maxPassword),
+			map[string]interface{}{"max": maxPassword})
+	}
+
+	if role != model.RoleBuyer && role != model.RoleSeller {
+		errs.Add("role", CodeInvalid, fmt.Sprintf("the role must be one of: %s, %s", model.RoleBuyer, model.RoleSeller), nil)
+	}
+	return errs.Err()
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/auth"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// owner is the signed in seller of the service tests, admin acts without
+	// an account
+	owner     = model.Principal{UserId: 7, Role: model.RoleSeller}
+	moderator = model.Principal{UserId: 9, Role: model.RoleModerator}
+	admin     = model.Principal{Role: model.RoleAdmin}
+)
+
+func TestUserService_Register(t *testing.T) {
+	type mockBehaviortype func(*mock.MockUserRepository)
+	tests := []struct {
+		name           string
+		inputEmail     string
+		inputPassword  string
+		inputRole      model.Role
+		mockBehavior   mockBehaviortype
+		expectedResult model.User
+		expectedError  error
+	}{
+		{
+			name:          "OK",
+			inputEmail:    " Ivan@Example.com ",
+			inputPassword: "correct horse",
+			mockBehavior: func(r *mock.MockUserRepository) {
+				r.EXPECT().CreateUser(gomock.Any()).DoAndReturn(func(user model.User) (int, error) {
+					assert.Equal(t, user.Email, "ivan@example.com")
+					assert.Equal(t, user.Role, model.RoleSeller)
+					assert.Equal(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("correct horse")), nil)
+					return 7, nil
+				})
+			},
+			expectedResult: model.User{Id: 7, Email: "ivan@example.com", Role: model.RoleSeller},
+		},
+		{
+			name:          "Buyer",
+			inputEmail:    "ivan@example.com",
+			inputPassword: "correct horse",
+			inputRole:     model.RoleBuyer,
+			mockBehavior: func(r *mock.MockUserRepository) {
+				r.EXPECT().CreateUser(gomock.Any()).Return(7, nil)
+			},
+			expectedResult: model.User{Id: 7, Email: "ivan@example.com", Role: model.RoleBuyer},
+		},
+		{
+			// the moderators and the admins are appointed, not signed up
+			name:          "Admin",
+			inputEmail:    "ivan@example.com",
+			inputPassword: "correct horse",
+			inputRole:     model.RoleAdmin,
+			mockBehavior:  func(r *mock.MockUserRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "role", Code: CodeInvalid, Message: "the role must be one of: buyer, seller"},
+			}},
+		},
+		{
+			name:          "Taken email",
+			inputEmail:    "ivan@example.com",
+			inputPassword: "correct horse",
+			mockBehavior: func(r *mock.MockUserRepository) {
+				r.EXPECT().CreateUser(gomock.Any()).Return(0, repository.ErrUserExists)
+			},
+			expectedError: repository.ErrUserExists,
+		},
+		{
+			name:          "Invalid email and short password",
+			inputEmail:    "Ivan <ivan@example.com>",
+			inputPassword: "horse",
+			mockBehavior:  func(r *mock.MockUserRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "email", Code: CodeInvalid, Message: "the email address is invalid"},
+				{Field: "password", Code: CodeMinLength, Message: `length of the field "password" should be at least 8`,
+					Params: map[string]interface{}{"min": 8}},
+			}},
+		},
+		{
+			name:         "Missing fields",
+			mockBehavior: func(r *mock.MockUserRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "email", Code: CodeRequired, Message: `the field "email" is required`},
+				{Field: "password", Code: CodeRequired, Message: `the field "password" is required`},
+			}},
+		},
+		{
+			name:          "Long password",
+			inputEmail:    "ivan@example.com",
+			inputPassword: string(make([]byte, 73)),
+			mockBehavior:  func(r *mock.MockUserRepository) {},
+			expectedError: &ValidationError{Errors: []FieldError{
+				{Field: "password", Code: CodeMaxLength, Message: `length of the field "password" should not exceed 72`,
+					Params: map[string]interface{}{"max": 72}},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockUserRepository(c)
+			test.mockBehavior(mockRepository)
+
+			service := newUserService(mockRepository, auth.NewHS256("secret"), time.Hour, bcrypt.MinCost)
+
+			result, err := service.Register(model.Credentials{Email: test.inputEmail, Password: test.inputPassword}, test.inputRole)
+			assert.Equal(t, err, test.expectedError)
+			result.PasswordHash = ""
+			assert.Equal(t, result, test.expectedResult)
+		})
+	}
+}
+
+func TestUserService_LoginAuthenticate(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	mockRepository := mock.NewMockUserRepository(c)
+	mockRepository.EXPECT().GetUserByEmail("ivan@example.com").Return(model.User{Id: 7, Email: "ivan@example.com", Role: model.RoleBuyer, PasswordHash: string(hash)}, nil).Times(2)
+	mockRepository.EXPECT().GetUserByEmail("nobody@example.com").Return(model.User{}, repository.ErrUserNotFound)
+	gomock.InOrder(
+		mockRepository.EXPECT().GetUserById(7).Return(model.User{Id: 7, Role: model.RoleBuyer}, nil),
+		mockRepository.EXPECT().GetUserById(7).Return(model.User{Id: 7, Role: model.RoleSeller}, nil),
+		mockRepository.EXPECT().GetUserById(7).Return(model.User{}, repository.ErrUserNotFound),
+	)
+
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	service := newUserService(mockRepository, auth.NewHS256("secret"), time.Hour, bcrypt.MinCost)
+	service.now = func() time.Time { return now }
+
+	token, err := service.Login(model.Credentials{Email: "ivan@example.com", Password: "correct horse"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, token.TokenType, "Bearer")
+	assert.Equal(t, token.ExpiresAt, now.Add(time.Hour))
+
+	principal, err := service.Authenticate(token.Token)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, principal, model.Principal{UserId: 7, Role: model.RoleBuyer})
+
+	// the role is read on every request, not taken from the token, and the
+	// token of a removed user stops working
+	principal, err = service.Authenticate(token.Token)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, principal, model.Principal{UserId: 7, Role: model.RoleSeller})
+	_, err = service.Authenticate(token.Token)
+	assert.Equal(t, err, ErrInvalidToken)
+
+	// a wrong password and an unknown email are not told apart
+	_, err = service.Login(model.Credentials{Email: "ivan@example.com", Password: "wrong horse"})
+	assert.Equal(t, err, ErrInvalidCredentials)
+	_, err = service.Login(model.Credentials{Email: "nobody@example.com", Password: "correct horse"})
+	assert.Equal(t, err, ErrInvalidCredentials)
+
+	// expired, forged and foreign tokens are rejected
+	service.now = func() time.Time { return now.Add(time.Hour) }
+	_, err = service.Authenticate(token.Token)
+	assert.Equal(t, err, ErrInvalidToken)
+
+	service.now = func() time.Time { return now }
+	for _, token := range []string{
+		"garbage",
+		auth.NewHS256("other").Sign(auth.Claims{Subject: "7"}),
+		auth.NewHS256("secret").Sign(auth.Claims{Subject: "admin"}),
+		auth.NewHS256("secret").Sign(auth.Claims{Subject: "7", Role: "root", ExpiresAt: now.Add(time.Hour).Unix()}),
+	} {
+		_, err = service.Authenticate(token)
+		assert.Equal(t, err, ErrInvalidToken)
+	}
+}
+
+func TestService_GetOwnAdverts(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	adverts := []model.Advert{{Id: 2, Name: "name-test", Status: model.StatusDraft}}
+	mockRepository := mock.NewMockRepository(c)
+	mockRepository.EXPECT().GetAdvertsByOwner(7).Return(adverts, nil)
+
+	service := NewAdvertService(mockRepository, existingCategories(c), nil, nil, nil, LinkPolicy{}, ListConfig{})
+
+	result, err := service.GetOwnAdverts(owner)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, result, adverts)
+
+	_, err = service.GetOwnAdverts(admin)
+	assert.Equal(t, err, ErrAuthRequired)
+}
+
+func TestUserService_SetRole(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockUserRepository(c)
+	mockRepository.EXPECT().SetUserRole(7, model.RoleModerator).Return(nil)
+	mockRepository.EXPECT().GetUserById(7).Return(model.User{Id: 7, Email: "ivan@example.com", Role: model.RoleModerator}, nil)
+	mockRepository.EXPECT().SetUserRole(8, model.RoleModerator).Return(repository.ErrUserNotFound)
+
+	service := newUserService(mockRepository, auth.NewHS256("secret"), time.Hour, bcrypt.MinCost)
+
+	user, err := service.SetRole(7, model.RoleModerator)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user, model.User{Id: 7, Email: "ivan@example.com", Role: model.RoleModerator})
+
+	_, err = service.SetRole(8, model.RoleModerator)
+	assert.Equal(t, err, repository.ErrUserNotFound)
+
+	_, err = service.SetRole(7, "root")
+	assert.Equal(t, err, &ValidationError{Errors: []FieldError{
+		{Field: "role", Code: CodeInvalid, Message: "the role must be one of: buyer, seller, moderator, admin"},
+	}})
+}
+
+// externalToken signs the claims with EdDSA the way an external issuer does.
+func externalToken(private ed25519.PrivateKey, kid string, claims auth.Claims) string {
+	head, _ := json.Marshal(map[string]string{"alg": auth.AlgEdDSA, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(head) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(private, []byte(signed)))
+}
+
+func TestUserService_ExternalKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPublic, oldPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	newPublic, newPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey := func(kid string, public ed25519.PublicKey) {
+		data := fmt.Sprintf(`{"kty":"OKP","crv":"Ed25519","kid":%q,"x":%q}`, kid, base64.RawURLEncoding.EncodeToString(public))
+		if err := ioutil.WriteFile(filepath.Join(dir, kid+".json"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeKey("old", oldPublic)
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockUserRepository(c)
+	mockRepository.EXPECT().GetUserById(7).Return(model.User{}, repository.ErrUserNotFound).AnyTimes()
+
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	service := newUserService(mockRepository, auth.NewHS256("secret"), time.Hour, bcrypt.MinCost)
+	service.jwks = dir
+	service.audience = "adverts"
+	service.now = func() time.Time { return now }
+	assert.Equal(t, service.ReloadKeys(), nil)
+	assert.Equal(t, service.Keys(), 2)
+
+	claims := auth.Claims{Subject: "7", Audience: auth.Audience{"billing", "adverts"}, ExpiresAt: now.Add(time.Hour).Unix()}
+	oldToken := externalToken(oldPrivate, "old", claims)
+	newToken := externalToken(newPrivate, "new", claims)
+
+	principal, err := service.Authenticate(oldToken)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, principal, model.Principal{UserId: 7, Role: model.RoleSeller})
+	_, err = service.Authenticate(newToken)
+	assert.Equal(t, err, ErrInvalidToken)
+
+	// during the rotation both keys are accepted, then the old one is removed
+	writeKey("new", newPublic)
+	assert.Equal(t, service.ReloadKeys(), nil)
+	for _, token := range []string{oldToken, newToken} {
+		_, err = service.Authenticate(token)
+		assert.Equal(t, err, nil)
+	}
+	os.Remove(filepath.Join(dir, "old.json"))
+	assert.Equal(t, service.ReloadKeys(), nil)
+	_, err = service.Authenticate(oldToken)
+	assert.Equal(t, err, ErrInvalidToken)
+	_, err = service.Authenticate(newToken)
+	assert.Equal(t, err, nil)
+
+	// a broken file keeps the loaded keys
+	ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"keys":`), 0600)
+	assert.NotEqual(t, service.ReloadKeys(), nil)
+	_, err = service.Authenticate(newToken)
+	assert.Equal(t, err, nil)
+
+	// tokens for another audience are rejected
+	claims.Audience = auth.Audience{"billing"}
+	_, err = service.Authenticate(externalToken(newPrivate, "new", claims))
+	assert.Equal(t, err, ErrInvalidToken)
+}
+
+func TestUserService_ExternalRole(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	mockRepository := mock.NewMockUserRepository(c)
+	mockRepository.EXPECT().GetUserById(1).Return(model.User{Id: 1, Role: model.RoleAdmin}, nil).AnyTimes()
+	mockRepository.EXPECT().GetUserById(7).Return(model.User{}, repository.ErrUserNotFound).AnyTimes()
+
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	service := newUserService(mockRepository, auth.NewHS256("secret"), time.Hour, bcrypt.MinCost)
+	service.keys.Set([]auth.Key{service.key.Key(), auth.NewEd25519Key("partner", public)})
+	service.now = func() time.Time { return now }
+
+	tests := []struct {
+		name         string
+		inputToken   string
+		externalRole model.Role
+		expectedRole model.Role
+	}{
+		{
+			name:         "Own token of an admin",
+			inputToken:   service.key.Sign(auth.Claims{Subject: "1", ExpiresAt: now.Add(time.Hour).Unix()}),
+			externalRole: model.RoleSeller,
+			expectedRole: model.RoleAdmin,
+		},
+		{
+			name:         "External token of an admin",
+			inputToken:   externalToken(private, "partner", auth.Claims{Subject: "1", ExpiresAt: now.Add(time.Hour).Unix()}),
+			externalRole: model.RoleSeller,
+			expectedRole: model.RoleSeller,
+		},
+		{
+			name:         "External token claiming admin",
+			inputToken:   externalToken(private, "partner", auth.Claims{Subject: "7", Role: "admin", ExpiresAt: now.Add(time.Hour).Unix()}),
+			externalRole: model.RoleSeller,
+			expectedRole: model.RoleSeller,
+		},
+		{
+			name:         "External token of a buyer",
+			inputToken:   externalToken(private, "partner", auth.Claims{Subject: "7", Role: "buyer", ExpiresAt: now.Add(time.Hour).Unix()}),
+			externalRole: model.RoleSeller,
+			expectedRole: model.RoleBuyer,
+		},
+		{
+			name:         "Moderators allowed",
+			inputToken:   externalToken(private, "partner", auth.Claims{Subject: "7", Role: "admin", ExpiresAt: now.Add(time.Hour).Unix()}),
+			externalRole: model.RoleModerator,
+			expectedRole: model.RoleModerator,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service.externalRole = test.externalRole
+			principal, err := service.Authenticate(test.inputToken)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, principal.Role, test.expectedRole)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/apperror"
+)
+
+// Validation error codes. Clients bind them to form fields, so the codes
+// are part of the API and must not be renamed.
+const (
+	CodeRequired    = "required"
+	CodeMinLength   = "min_length"
+	CodeMaxLength   = "max_length"
+	CodeMinValue    = "min_value"
+	CodeMaxValue    = "max_value"
+	CodeMaxItems    = "max_items"
+	CodeType        = "type"
+	CodeInvalid     = "invalid"
+	CodeMalformed   = "malformed"
+	CodeMaxSize     = "max_size"
+	CodeContentType = "content_type"
+	CodeURLScheme   = "url_scheme"
+	CodeURLHost     = "url_host"
+)
+
+// FieldError describes one invalid field. Params hold the limits of the
+// violated constraint, e.g. {"max": 200} for max_length.
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// ValidationError collects every invalid field of an input instead of
+// stopping at the first one.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldError := range e.Errors {
+		messages[i] = fieldError.Message
+	}
+	return strings.Join(messages, ", ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return apperror.ErrValidation
+}
+
+func (e *ValidationError) Add(field, code, message string, params map[string]interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Code: code, Message: message, Params: params})
+}
+
+// Err returns nil when no field errors were added, so the result can be
+// returned as error without a typed nil.
+func (e *ValidationError) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// IsMalformed reports whether the input could not be parsed or used at
+// all, e.g. a cursor of another query.
+func (e *ValidationError) IsMalformed() bool {
+	return len(e.Errors) == 1 && e.Errors[0].Code == CodeMalformed
+}
+
+// NewDecodeError turns a JSON decoding error into a ValidationError. Type
+// mismatches point to the offending field, anything else is reported as a
+// malformed body.
+func NewDecodeError(err error) *ValidationError {
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return &ValidationError{Errors: []FieldError{{
+			Field:   typeError.Field,
+			Code:    CodeType,
+			Message: fmt.Sprintf(`the field "%s" must be of type %s`, typeError.Field, typeError.Type),
+			Params:  map[string]interface{}{"type": typeError.Type.String()},
+		}}}
+	}
+
+	return &ValidationError{Errors: []FieldError{{
+		Field:   "",
+		Code:    CodeMalformed,
+		Message: "invalid input body",
+	}}}
+}
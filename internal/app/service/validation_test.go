@@ -0,0 +1,59 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+)
+
+func TestValidationError_Error(t *testing.T) {
+	err := validate(model.Advert{Price: -1}, LinkPolicy{})
+
+	var invalid *ValidationError
+	assert.Equal(t, errors.As(err, &invalid), true)
+	assert.Equal(t, len(invalid.Errors), 4)
+	assert.Equal(t, err.Error(),
+		`the field "name" is required, the field "description" is required, the field "category-id" is required, `+
+			`the field "price" must have a value greater than 0`)
+
+	assert.Equal(t, (&ValidationError{}).Err(), nil)
+}
+
+func TestValidationError_NewDecodeError(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputBody     string
+		expectedError *ValidationError
+	}{
+		{
+			name:      "Wrong type",
+			inputBody: `{"name":1}`,
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Field:   "name",
+				Code:    CodeType,
+				Message: `the field "name" must be of type string`,
+				Params:  map[string]interface{}{"type": "string"},
+			}}},
+		},
+		{
+			name:      "Syntax error",
+			inputBody: `{"name":`,
+			expectedError: &ValidationError{Errors: []FieldError{{
+				Code:    CodeMalformed,
+				Message: "invalid input body",
+			}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var advert model.Advert
+			err := NewDecodeError(json.Unmarshal([]byte(test.inputBody), &advert))
+			assert.Equal(t, err, test.expectedError)
+			assert.Equal(t, err.IsMalformed(), test.expectedError.Errors[0].Code == CodeMalformed)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/paramonies/avito-rest-advert/internal/app/imaging"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+	"github.com/paramonies/avito-rest-advert/internal/app/storage"
+)
+
+const (
+	DefaultVariantWorkers   = 2
+	DefaultVariantQueueSize = 32
+)
+
+// VariantJob asks to generate the variants of an uploaded picture stored in
+// the blob store under Key.
+type VariantJob struct {
+	AdvertId int
+	URL      string
+	Key      string
+	Data     []byte
+}
+
+// VariantPool generates picture variants in the background, so uploads do
+// not wait for image processing. Jobs are kept in memory: variants of the
+// pictures queued at shutdown are not generated.
+type VariantPool struct {
+	repo  repository.Repository
+	index SearchIndex
+	store storage.BlobStore
+	jobs  chan VariantJob
+	wg    sync.WaitGroup
+}
+
+// NewVariantPool creates the pool, index may be nil. The index is updated
+// once the variants are stored as the search shows the thumbnail.
+func NewVariantPool(repo repository.Repository, index SearchIndex, store storage.BlobStore, queueSize int) *VariantPool {
+	if queueSize <= 0 {
+		queueSize = DefaultVariantQueueSize
+	}
+	return &VariantPool{repo: repo, index: index, store: store, jobs: make(chan VariantJob, queueSize)}
+}
+
+// Start runs the workers, they exit once Stop is called and the queue is
+// drained.
+func (p *VariantPool) Start(workers int) {
+	if workers <= 0 {
+		workers = DefaultVariantWorkers
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				if err := p.Process(job); err != nil {
+					log.Printf("failed to generate variants of picture %s: %s", job.Key, err.Error())
+				}
+			}
+		}()
+	}
+}
+
+func (p *VariantPool) Stop() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+// Enqueue adds the job without blocking, false is returned when the queue
+// is full.
+func (p *VariantPool) Enqueue(job VariantJob) bool {
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Process generates and stores the variants of one picture. Pictures that
+// can not be decoded (webp) are left without variants.
+func (p *VariantPool) Process(job VariantJob) error {
+	images, err := imaging.Generate(job.Data)
+	if errors.Is(err, imaging.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(job.Key, path.Ext(job.Key))
+	variants := make(model.PictureVariants, len(images))
+	var keys []string
+	for _, img := range images {
+		key := base + "_" + img.Variant + img.Ext
+		url, err := p.store.Put(key, img.ContentType, img.Data)
+		if err != nil {
+			p.deleteBlobs(keys)
+			return err
+		}
+		keys = append(keys, key)
+		variants[img.Variant] = url
+	}
+
+	if err := p.repo.SetPictureVariants(job.AdvertId, job.URL, variants); err != nil {
+		// the picture was removed from the advert while it was processed
+		p.deleteBlobs(keys)
+		if errors.Is(err, repository.ErrPictureNotFound) {
+			return nil
+		}
+		return err
+	}
+	updateIndex(p.index, job.AdvertId)
+	return nil
+}
+
+func (p *VariantPool) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		if err := p.store.Delete(key); err != nil {
+			log.Printf("failed to delete picture variant %s: %s", key, err.Error())
+		}
+	}
+}
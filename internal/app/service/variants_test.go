@@ -0,0 +1,121 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/paramonies/avito-rest-advert/internal/app/mock"
+	"github.com/paramonies/avito-rest-advert/internal/app/model"
+	"github.com/paramonies/avito-rest-advert/internal/app/repository"
+)
+
+func jpegFile(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2000, 1000)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVariantPool_Process(t *testing.T) {
+	data := jpegFile(t)
+	variants := model.PictureVariants{
+		model.VariantThumb:  "uploads/adverts/1/a_thumb.jpg",
+		model.VariantMedium: "uploads/adverts/1/a_medium.jpg",
+		model.VariantLarge:  "uploads/adverts/1/a_large.jpg",
+	}
+
+	type mockBehaviortype func(*mock.MockRepository, *mock.MockBlobStore)
+	tests := []struct {
+		name          string
+		inputData     []byte
+		mockBehavior  mockBehaviortype
+		expectedError error
+	}{
+		{
+			name:      "OK",
+			inputData: data,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				for _, variant := range []string{"thumb", "medium", "large"} {
+					s.EXPECT().Put("adverts/1/a_"+variant+".jpg", "image/jpeg", gomock.Any()).Return("uploads/adverts/1/a_"+variant+".jpg", nil)
+				}
+				r.EXPECT().SetPictureVariants(1, "uploads/adverts/1/a.jpg", variants).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "Picture removed meanwhile",
+			inputData: data,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				s.EXPECT().Put(gomock.Any(), "image/jpeg", gomock.Any()).Return("uploads/variant.jpg", nil).Times(3)
+				r.EXPECT().SetPictureVariants(1, "uploads/adverts/1/a.jpg", gomock.Any()).Return(repository.ErrPictureNotFound)
+				s.EXPECT().Delete(gomock.Any()).Return(nil).Times(3)
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "Store failure",
+			inputData: data,
+			mockBehavior: func(r *mock.MockRepository, s *mock.MockBlobStore) {
+				s.EXPECT().Put("adverts/1/a_thumb.jpg", "image/jpeg", gomock.Any()).Return("uploads/adverts/1/a_thumb.jpg", nil)
+				s.EXPECT().Put("adverts/1/a_medium.jpg", "image/jpeg", gomock.Any()).Return("", errors.New("disk full"))
+				s.EXPECT().Delete("adverts/1/a_thumb.jpg").Return(nil)
+			},
+			expectedError: errors.New("disk full"),
+		},
+		{
+			name:          "Webp is left as is",
+			inputData:     []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+			mockBehavior:  func(r *mock.MockRepository, s *mock.MockBlobStore) {},
+			expectedError: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepository := mock.NewMockRepository(c)
+			mockStore := mock.NewMockBlobStore(c)
+			test.mockBehavior(mockRepository, mockStore)
+
+			pool := NewVariantPool(mockRepository, nil, mockStore, 1)
+
+			err := pool.Process(VariantJob{AdvertId: 1, URL: "uploads/adverts/1/a.jpg", Key: "adverts/1/a.jpg", Data: test.inputData})
+			assert.Equal(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestVariantPool_Workers(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockRepository := mock.NewMockRepository(c)
+	mockStore := mock.NewMockBlobStore(c)
+	mockStore.EXPECT().Put(gomock.Any(), "image/jpeg", gomock.Any()).Return("uploads/variant.jpg", nil).Times(6)
+	mockRepository.EXPECT().SetPictureVariants(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	pool := NewVariantPool(mockRepository, nil, mockStore, 2)
+	pool.Start(2)
+
+	data := jpegFile(t)
+	assert.Equal(t, pool.Enqueue(VariantJob{AdvertId: 1, URL: "a.jpg", Key: "a.jpg", Data: data}), true)
+	assert.Equal(t, pool.Enqueue(VariantJob{AdvertId: 2, URL: "b.jpg", Key: "b.jpg", Data: data}), true)
+
+	// Stop waits for the queued jobs
+	pool.Stop()
+}
+
+func TestVariantPool_EnqueueFull(t *testing.T) {
+	pool := NewVariantPool(nil, nil, nil, 1)
+
+	assert.Equal(t, pool.Enqueue(VariantJob{Key: "a.jpg"}), true)
+	assert.Equal(t, pool.Enqueue(VariantJob{Key: "b.jpg"}), false)
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore keeps files in a local directory. The directory is expected to
+// be served under BaseURL, see Config.ServePath.
+type FileStore struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewFileStore(dir, baseURL string) *FileStore {
+	return &FileStore{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (s *FileStore) Put(key, contentType string, data []byte) (string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first, so a failed upload never leaves a
+	// truncated file under the final name
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return s.BaseURL + "/" + key, nil
+}
+
+func (s *FileStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Key(url string) (string, bool) {
+	key := strings.TrimPrefix(url, s.BaseURL+"/")
+	if key == url || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+// path resolves key inside the store directory, keys escaping it are
+// rejected.
+func (s *FileStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + filepath.FromSlash(key))
+	if clean == string(filepath.Separator) || strings.Contains(key, "..") {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
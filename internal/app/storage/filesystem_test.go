@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore_PutDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := NewFileStore(dir, "http://localhost:8080/uploads/")
+
+	url, err := store.Put("adverts/1/photo.png", "image/png", []byte("png data"))
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/uploads/adverts/1/photo.png", url)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "adverts", "1", "photo.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "png data", string(data))
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "adverts", "1"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "temporary file must not be left behind")
+
+	key, ok := store.Key(url)
+	assert.True(t, ok)
+	assert.Equal(t, "adverts/1/photo.png", key)
+	_, ok = store.Key("https://example.com/adverts/1/photo.png")
+	assert.False(t, ok, "the url is not served by the store")
+
+	require.NoError(t, store.Delete("adverts/1/photo.png"))
+	_, err = os.Stat(filepath.Join(dir, "adverts", "1", "photo.png"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, store.Delete("adverts/1/photo.png"), "deleting a missing file is not an error")
+}
+
+func TestFileStore_InvalidKey(t *testing.T) {
+	store := NewFileStore("/tmp/blobs", "")
+
+	for _, key := range []string{"", "/", "../etc/passwd", "adverts/../../secret"} {
+		_, err := store.Put(key, "image/png", []byte("data"))
+		assert.Error(t, err, key)
+		assert.Error(t, store.Delete(key), key)
+	}
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm  = "AWS4-HMAC-SHA256"
+	s3Service    = "s3"
+	s3DateFormat = "20060102T150405Z"
+)
+
+// S3Store keeps files in a bucket of an S3 compatible storage (AWS S3,
+// MinIO, Ceph). Objects are addressed path style, {endpoint}/{bucket}/{key},
+// and requests are signed with AWS Signature Version 4.
+type S3Store struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	// PublicURL is the prefix of the URLs returned by Put, defaults to
+	// {endpoint}/{bucket}.
+	PublicURL string
+
+	Client *http.Client
+	now    func() time.Time
+}
+
+func NewS3Store(endpoint, region, bucket, accessKey, secretKey string) *S3Store {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Store{
+		Endpoint:  endpoint,
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		PublicURL: endpoint + "/" + bucket,
+		Client:    http.DefaultClient,
+		now:       time.Now,
+	}
+}
+
+func (s *S3Store) Put(key, contentType string, data []byte) (string, error) {
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, data)
+
+	if err := s.do(req); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(s.PublicURL, "/") + "/" + uriEncode(key, false), nil
+}
+
+func (s *S3Store) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	s.sign(req, nil)
+
+	return s.do(req)
+}
+
+func (s *S3Store) Key(publicURL string) (string, bool) {
+	key := strings.TrimPrefix(publicURL, strings.TrimRight(s.PublicURL, "/")+"/")
+	if key == publicURL || key == "" {
+		return "", false
+	}
+	key, err := url.PathUnescape(key)
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+func (s *S3Store) do(req *http.Request) error {
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("storage: s3 %s %s: %s %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func (s *S3Store) objectURL(key string) string {
+	return s.Endpoint + "/" + uriEncode(s.Bucket, false) + "/" + uriEncode(key, false)
+}
+
+// sign adds the Signature Version 4 headers to req.
+func (s *S3Store) sign(req *http.Request, payload []byte) {
+	now := s.now().UTC()
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", now.Format(s3DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signedHeaders = append(signedHeaders, "content-type")
+	}
+	sort.Strings(signedHeaders)
+
+	signature := s.signature(req.Method, req.URL.EscapedPath(), req.URL.Host, req.Header, signedHeaders, now)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.AccessKey, s.scope(now), strings.Join(signedHeaders, ";"), signature))
+}
+
+func (s *S3Store) signature(method, path, host string, header http.Header, signedHeaders []string, now time.Time) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := header.Get(name)
+		if name == "host" {
+			value = host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		"", // no query string
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3DateFormat),
+		s.scope(now),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s *S3Store) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.Region + "/" + s3Service + "/aws4_request"
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// uriEncode escapes everything but the unreserved characters the way
+// Signature Version 4 expects, slashes are kept unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
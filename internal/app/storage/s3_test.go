@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var authorizationRe = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=([0-9a-f]{64})$`)
+
+// s3Stub is a minimal MinIO-like server: it checks the request signature
+// and keeps objects in memory.
+type s3Stub struct {
+	t     *testing.T
+	store *S3Store
+
+	mu      sync.Mutex
+	objects map[string]string
+	types   map[string]string
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	match := authorizationRe.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil || match[1] != s.store.AccessKey || match[3] != s.store.Region {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if sha256Hex(body) != r.Header.Get("X-Amz-Content-Sha256") {
+		http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+		return
+	}
+
+	now, err := time.Parse(s3DateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	signature := s.store.signature(r.Method, r.URL.EscapedPath(), r.Host, r.Header, strings.Split(match[4], ";"), now)
+	if signature != match[5] {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[r.URL.Path] = string(body)
+		s.types[r.URL.Path] = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newS3Stub(t *testing.T) (*s3Stub, *httptest.Server) {
+	stub := &s3Stub{t: t, objects: map[string]string{}, types: map[string]string{}}
+	server := httptest.NewServer(stub)
+
+	stub.store = NewS3Store(server.URL, "eu-central-1", "pictures", "minio", "minio-secret")
+	stub.store.now = func() time.Time { return time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC) }
+	return stub, server
+}
+
+func TestS3Store_PutDelete(t *testing.T) {
+	stub, server := newS3Stub(t)
+	defer server.Close()
+
+	url, err := stub.store.Put("adverts/1/photo 1.png", "image/png", []byte("png data"))
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/pictures/adverts/1/photo%201.png", url)
+	assert.Equal(t, "png data", stub.objects["/pictures/adverts/1/photo 1.png"])
+	assert.Equal(t, "image/png", stub.types["/pictures/adverts/1/photo 1.png"])
+
+	key, ok := stub.store.Key(url)
+	assert.True(t, ok)
+	assert.Equal(t, "adverts/1/photo 1.png", key)
+	_, ok = stub.store.Key("https://example.com/pictures/adverts/1/photo.png")
+	assert.False(t, ok, "the url is not served by the store")
+
+	require.NoError(t, stub.store.Delete("adverts/1/photo 1.png"))
+	assert.Empty(t, stub.objects)
+}
+
+func TestS3Store_PublicURL(t *testing.T) {
+	stub, server := newS3Stub(t)
+	defer server.Close()
+	stub.store.PublicURL = "https://cdn.example.com/"
+
+	url, err := stub.store.Put("adverts/1/photo.png", "image/png", []byte("png data"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/adverts/1/photo.png", url)
+}
+
+func TestS3Store_WrongCredentials(t *testing.T) {
+	stub, server := newS3Stub(t)
+	defer server.Close()
+
+	store := NewS3Store(server.URL, "eu-central-1", "pictures", "minio", "wrong-secret")
+	store.now = stub.store.now
+
+	_, err := store.Put("adverts/1/photo.png", "image/png", []byte("png data"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+	assert.Empty(t, stub.objects)
+}
+
+func TestUriEncode(t *testing.T) {
+	assert.Equal(t, "adverts/1/a%20b~c_d-e.png", uriEncode("adverts/1/a b~c_d-e.png", false))
+	assert.Equal(t, "a%2Fb%2B%D1%84", uriEncode("a/b+ф", true))
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	BackendFilesystem = "filesystem"
+	BackendS3         = "s3"
+)
+
+// BlobStore keeps uploaded files. Put returns the public URL the stored
+// file is served from, Key returns the key of a file by that URL and false
+// for the URLs the store did not issue.
+type BlobStore interface {
+	Put(key, contentType string, data []byte) (string, error)
+	Delete(key string) error
+	Key(url string) (string, bool)
+}
+
+type Config struct {
+	Backend        string `toml:"backend"`
+	MaxUploadBytes int64  `toml:"max_upload_bytes"`
+
+	// resized variants of uploaded pictures
+	VariantWorkers   int `toml:"variant_workers"`
+	VariantQueueSize int `toml:"variant_queue_size"`
+
+	// filesystem backend
+	Dir       string `toml:"dir"`
+	ServePath string `toml:"serve_path"`
+	BaseURL   string `toml:"base_url"`
+
+	// s3 backend
+	S3Endpoint  string `toml:"s3_endpoint"`
+	S3Region    string `toml:"s3_region"`
+	S3Bucket    string `toml:"s3_bucket"`
+	S3AccessKey string `toml:"s3_access_key"`
+	S3SecretKey string `toml:"s3_secret_key"`
+	S3PublicURL string `toml:"s3_public_url"`
+}
+
+// NewBlobStore builds the store selected by config.Backend, the filesystem
+// store is used when the backend is not set.
+func NewBlobStore(config Config) (BlobStore, error) {
+	switch config.Backend {
+	case "", BackendFilesystem:
+		if config.Dir == "" {
+			return nil, errors.New("storage: dir is required for the filesystem backend")
+		}
+		return NewFileStore(config.Dir, config.BaseURL), nil
+	case BackendS3:
+		if config.S3Endpoint == "" || config.S3Bucket == "" {
+			return nil, errors.New("storage: s3_endpoint and s3_bucket are required for the s3 backend")
+		}
+		store := NewS3Store(config.S3Endpoint, config.S3Region, config.S3Bucket, config.S3AccessKey, config.S3SecretKey)
+		if config.S3PublicURL != "" {
+			store.PublicURL = config.S3PublicURL
+		}
+		store.Client = &http.Client{Timeout: 30 * time.Second}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("storage: unknown backend %q", config.Backend)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBlobStore(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        Config
+		expectedStore BlobStore
+		expectError   bool
+	}{
+		{
+			name:          "Default backend",
+			config:        Config{Dir: "uploads", BaseURL: "http://localhost:8080/uploads"},
+			expectedStore: &FileStore{Dir: "uploads", BaseURL: "http://localhost:8080/uploads"},
+		},
+		{
+			name:        "Filesystem without dir",
+			config:      Config{Backend: BackendFilesystem},
+			expectError: true,
+		},
+		{
+			name:        "S3 without bucket",
+			config:      Config{Backend: BackendS3, S3Endpoint: "http://minio:9000"},
+			expectError: true,
+		},
+		{
+			name:        "Unknown backend",
+			config:      Config{Backend: "ftp"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := NewBlobStore(test.config)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedStore, store)
+		})
+	}
+}
+
+func TestNewBlobStore_S3(t *testing.T) {
+	store, err := NewBlobStore(Config{
+		Backend:     BackendS3,
+		S3Endpoint:  "http://minio:9000/",
+		S3Bucket:    "pictures",
+		S3AccessKey: "minio",
+		S3SecretKey: "minio-secret",
+	})
+	assert.NoError(t, err)
+
+	s3Store, ok := store.(*S3Store)
+	assert.True(t, ok)
+	assert.Equal(t, "http://minio:9000", s3Store.Endpoint)
+	assert.Equal(t, "us-east-1", s3Store.Region)
+	assert.Equal(t, "http://minio:9000/pictures", s3Store.PublicURL)
+}
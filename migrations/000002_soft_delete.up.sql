@@ -0,0 +1,4 @@
+ALTER TABLE adverts ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE adverts ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX adverts_visible_createdat_idx ON adverts (createdAt) WHERE deleted_at IS NULL AND archived_at IS NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE adverts ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'draft'
+    CHECK (status IN ('draft', 'pending', 'active', 'expired', 'blocked'));
+
+-- adverts created before moderation existed were already public
+UPDATE adverts SET status = 'active';
+
+CREATE INDEX adverts_status_createdat_idx ON adverts (status, createdAt) WHERE deleted_at IS NULL;
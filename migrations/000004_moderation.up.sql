@@ -0,0 +1,18 @@
+CREATE TABLE moderation_claims (
+    advert_id INTEGER PRIMARY KEY REFERENCES adverts (id),
+    moderator VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE moderation_decisions (
+    id SERIAL PRIMARY KEY,
+    advert_id INTEGER NOT NULL REFERENCES adverts (id),
+    moderator VARCHAR(100) NOT NULL,
+    decision VARCHAR(20) NOT NULL CHECK (decision IN ('approve', 'reject')),
+    reason_code VARCHAR(50) NOT NULL DEFAULT '',
+    comment VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX moderation_decisions_advert_id_idx ON moderation_decisions (advert_id, created_at);
+CREATE INDEX adverts_pending_queue_idx ON adverts (createdAt, id) WHERE status = 'pending' AND deleted_at IS NULL AND archived_at IS NULL;
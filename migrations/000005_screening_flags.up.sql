@@ -0,0 +1 @@
+ALTER TABLE adverts ADD COLUMN screening_flags JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,19 @@
+CREATE TABLE advert_pictures (
+    id SERIAL PRIMARY KEY,
+    advert_id INTEGER NOT NULL REFERENCES adverts (id),
+    url VARCHAR(1000) NOT NULL,
+    position INTEGER NOT NULL,
+    is_main BOOLEAN NOT NULL DEFAULT FALSE,
+    -- checked at the end of a statement, so pictures can be reordered by a single UPDATE
+    CONSTRAINT advert_pictures_position_key UNIQUE (advert_id, position) DEFERRABLE INITIALLY IMMEDIATE
+);
+
+CREATE UNIQUE INDEX advert_pictures_main_idx ON advert_pictures (advert_id) WHERE is_main;
+
+-- the first link of the comma separated list was the main picture
+INSERT INTO advert_pictures (advert_id, url, position, is_main)
+SELECT a.id, p.url, p.ord - 1, p.ord = 1
+FROM adverts a, unnest(string_to_array(a.pictures, ',')) WITH ORDINALITY AS p(url, ord)
+WHERE a.pictures IS NOT NULL AND p.url <> '';
+
+ALTER TABLE adverts DROP COLUMN pictures;
@@ -0,0 +1 @@
+ALTER TABLE advert_pictures ADD COLUMN variants JSONB NOT NULL DEFAULT '{}';
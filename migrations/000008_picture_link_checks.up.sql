@@ -0,0 +1,4 @@
+ALTER TABLE advert_pictures ADD COLUMN broken BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE advert_pictures ADD COLUMN checked_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX advert_pictures_checked_at_idx ON advert_pictures (checked_at NULLS FIRST);
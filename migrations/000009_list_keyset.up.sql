@@ -0,0 +1,8 @@
+UPDATE adverts SET price = 0 WHERE price IS NULL;
+UPDATE adverts SET createdAt = NOW() WHERE createdAt IS NULL;
+ALTER TABLE adverts ALTER COLUMN price SET NOT NULL;
+ALTER TABLE adverts ALTER COLUMN createdAt SET NOT NULL;
+
+-- keyset pagination compares (sort key, id) pairs
+CREATE INDEX adverts_public_createdat_id_idx ON adverts (createdAt, id) WHERE status = 'active' AND deleted_at IS NULL;
+CREATE INDEX adverts_public_price_id_idx ON adverts (price, id) WHERE status = 'active' AND deleted_at IS NULL;
@@ -0,0 +1,5 @@
+-- the q filter of the list matches substrings with ILIKE, trigram indexes serve it
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX adverts_name_trgm_idx ON adverts USING gin (name gin_trgm_ops);
+CREATE INDEX adverts_description_trgm_idx ON adverts USING gin (description gin_trgm_ops);
@@ -0,0 +1,9 @@
+-- full-text search over the name (weight A) and the description (weight B).
+-- The russian configuration stems Cyrillic words with the Russian snowball
+-- stemmer and Latin words with the English one, so one vector serves both.
+ALTER TABLE adverts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX adverts_search_vector_idx ON adverts USING gin (search_vector);
@@ -0,0 +1,21 @@
+-- a tree of any depth: the root categories have no parent
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES categories (id),
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX categories_parent_id_idx ON categories (parent_id);
+-- the names of the children of one parent are unique regardless of the case
+CREATE UNIQUE INDEX categories_name_key ON categories (COALESCE(parent_id, 0), lower(name));
+
+-- the adverts created before the categories are moved to a root category of
+-- their own, the owners may pick a better one on the next update
+INSERT INTO categories (name) VALUES ('Разное');
+
+ALTER TABLE adverts ADD COLUMN category_id INTEGER REFERENCES categories (id);
+UPDATE adverts SET category_id = (SELECT id FROM categories WHERE parent_id IS NULL AND name = 'Разное');
+ALTER TABLE adverts ALTER COLUMN category_id SET NOT NULL;
+
+CREATE INDEX adverts_category_id_idx ON adverts (category_id);
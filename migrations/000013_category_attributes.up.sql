@@ -0,0 +1,7 @@
+-- the attribute schema of a category: a JSON array of {name, type, required,
+-- enum, min, max}, the values of an advert are a JSON object keyed by name
+ALTER TABLE categories ADD COLUMN attributes JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE adverts ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+-- the equality filters of the list are containment queries
+CREATE INDEX adverts_attributes_idx ON adverts USING gin (attributes jsonb_path_ops);
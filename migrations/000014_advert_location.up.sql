@@ -0,0 +1,17 @@
+-- the location of an advert is optional, the coordinates go together
+ALTER TABLE adverts ADD COLUMN lat DOUBLE PRECISION;
+ALTER TABLE adverts ADD COLUMN lon DOUBLE PRECISION;
+ALTER TABLE adverts ADD COLUMN city VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE adverts ADD COLUMN region VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE adverts ADD CONSTRAINT adverts_location_check CHECK (
+    (lat IS NULL) = (lon IS NULL) AND lat BETWEEN -90 AND 90 AND lon BETWEEN -180 AND 180
+);
+
+-- the radius filter of the list tests earth_box(...) @> ll_to_earth(lat, lon),
+-- a GiST index of the cube serves it; both modules ship with Postgres
+CREATE EXTENSION IF NOT EXISTS cube;
+CREATE EXTENSION IF NOT EXISTS earthdistance;
+
+CREATE INDEX adverts_location_idx ON adverts USING gist (ll_to_earth(lat, lon)) WHERE lat IS NOT NULL;
+-- the bounding box filter compares the coordinates
+CREATE INDEX adverts_lat_lon_idx ON adverts (lat, lon) WHERE lat IS NOT NULL;
@@ -0,0 +1,16 @@
+-- the emails are stored lower-cased by the service, the index keeps them
+-- unique regardless
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(254) NOT NULL,
+    password_hash VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX users_email_key ON users (lower(email));
+
+-- the adverts created before the accounts have no owner and are changed by
+-- the admin only
+ALTER TABLE adverts ADD COLUMN owner_id INTEGER REFERENCES users (id);
+
+CREATE INDEX adverts_owner_id_idx ON adverts (owner_id, createdAt) WHERE deleted_at IS NULL;
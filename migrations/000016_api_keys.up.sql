@@ -0,0 +1,22 @@
+-- keys of the partner feeds, only the SHA-256 hash of a key is stored;
+-- a revoked key is kept to show its history
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    owner_id INTEGER REFERENCES users (id),
+    scopes JSONB NOT NULL DEFAULT '[]',
+    daily_quota INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- requests made with a key per UTC day, the daily quota is checked against it
+CREATE TABLE api_key_usage (
+    key_id INTEGER NOT NULL REFERENCES api_keys (id),
+    day DATE NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
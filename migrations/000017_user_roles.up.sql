@@ -0,0 +1,4 @@
+-- the users signed up before the roles sell on the site, the moderators and
+-- the admins are appointed by an admin
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'seller'
+    CHECK (role IN ('buyer', 'seller', 'moderator', 'admin'));
@@ -0,0 +1,11 @@
+-- the pictures are matched by url when an advert is updated, so an advert
+-- can not list the same url twice
+DELETE FROM advert_pictures p USING advert_pictures d
+WHERE p.advert_id = d.advert_id AND p.url = d.url AND p.id > d.id;
+
+-- the advert of a removed main picture gets its first picture as main
+UPDATE advert_pictures p SET is_main = TRUE
+WHERE p.position = (SELECT MIN(position) FROM advert_pictures WHERE advert_id = p.advert_id)
+  AND NOT EXISTS (SELECT 1 FROM advert_pictures m WHERE m.advert_id = p.advert_id AND m.is_main);
+
+CREATE UNIQUE INDEX advert_pictures_url_idx ON advert_pictures (advert_id, url);